@@ -0,0 +1,243 @@
+// Package archive defines the format yata exports a user's data in.
+//
+// The format is a single JSON document. Its types are deliberately separate from the model so that the format only
+// changes when Version does, and new fields are only ever added in a way that readers of older archives can ignore.
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+)
+
+// Version is the version of the format written by this package.
+const Version = 1
+
+// Archive is all of a user's lists and folders.
+type Archive struct {
+	Version    int
+	ExportedAt time.Time
+	UserID     string
+	// Folders are in the order of their positions.
+	Folders []Folder
+	// Lists are in the order of their positions.
+	Lists []List
+}
+
+type Folder struct {
+	FolderID string
+	Title    string
+	Position int `json:",omitempty"`
+}
+
+type List struct {
+	ListID      string
+	Title       string
+	Description string `json:",omitempty"`
+	Color       string `json:",omitempty"`
+	Icon        string `json:",omitempty"`
+	Position    int    `json:",omitempty"`
+	FolderID    string `json:",omitempty"`
+	Archived    bool   `json:",omitempty"`
+	Template    bool   `json:",omitempty"`
+	// Sections are in the order of their positions.
+	Sections []Section
+	// Items are in no particular order. Sub-tasks refer to their parent by ParentID.
+	Items []Item
+}
+
+type Section struct {
+	SectionID string
+	Title     string
+	Position  int `json:",omitempty"`
+}
+
+type Item struct {
+	ItemID     string
+	ParentID   string `json:",omitempty"`
+	SectionID  string `json:",omitempty"`
+	Content    string
+	Notes      string `json:",omitempty"`
+	Completed  bool
+	Priority   int        `json:",omitempty"`
+	DueAt      *time.Time `json:",omitempty"`
+	AssigneeID string     `json:",omitempty"`
+	Tags       []string   `json:",omitempty"`
+	CreatedAt  time.Time
+}
+
+// FromFolder returns the archived form of a folder.
+func FromFolder(f model.YataFolder) Folder {
+	return Folder{
+		FolderID: string(f.FolderID),
+		Title:    f.Title,
+		Position: f.Position,
+	}
+}
+
+// FromList returns the archived form of a list with its sections and items.
+func FromList(yl model.YataList, sections []model.YataSection, items []model.YataItem) List {
+	l := List{
+		ListID:      string(yl.ListID),
+		Title:       yl.Title,
+		Description: yl.Description,
+		Color:       yl.Color,
+		Icon:        yl.Icon,
+		Position:    yl.Position,
+		FolderID:    string(yl.FolderID),
+		Archived:    yl.Archived,
+		Template:    yl.Template,
+		Sections:    []Section{},
+		Items:       []Item{},
+	}
+	for _, s := range sections {
+		l.Sections = append(l.Sections, Section{
+			SectionID: string(s.SectionID),
+			Title:     s.Title,
+			Position:  s.Position,
+		})
+	}
+	for _, i := range items {
+		l.Items = append(l.Items, Item{
+			ItemID:     string(i.ItemID),
+			ParentID:   string(i.ParentID),
+			SectionID:  string(i.SectionID),
+			Content:    i.Content,
+			Notes:      i.Notes,
+			Completed:  i.Completed,
+			Priority:   i.Priority,
+			DueAt:      i.DueAt,
+			AssigneeID: string(i.AssigneeID),
+			Tags:       i.Tags,
+			CreatedAt:  i.CreatedAt,
+		})
+	}
+	return l
+}
+
+// Writer writes an archive one list at a time so that the whole archive never has to be held in memory.
+type Writer struct {
+	w     io.Writer
+	lists int
+}
+
+// NewWriter writes the start of an archive, up to its lists, to w.
+func NewWriter(w io.Writer, uid model.UserID, exportedAt time.Time, folders []Folder) (*Writer, error) {
+	if folders == nil {
+		folders = []Folder{}
+	}
+	header, err := json.Marshal(struct {
+		Version    int
+		ExportedAt time.Time
+		UserID     string
+		Folders    []Folder
+	}{
+		Version:    Version,
+		ExportedAt: exportedAt,
+		UserID:     string(uid),
+		Folders:    folders,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive header: %v", err)
+	}
+	// The header is an object; leave it open so that the lists can follow.
+	header = bytes.TrimSuffix(header, []byte("}"))
+	if _, err := fmt.Fprintf(w, `%s,"Lists":[`, header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WriteList writes the next list of the archive.
+func (aw *Writer) WriteList(l List) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal list: %v", err)
+	}
+	if aw.lists != 0 {
+		if _, err := io.WriteString(aw.w, ","); err != nil {
+			return err
+		}
+	}
+	aw.lists++
+	_, err = aw.w.Write(b)
+	return err
+}
+
+// Close writes the end of the archive. It does not close the underlying writer.
+func (aw *Writer) Close() error {
+	_, err := io.WriteString(aw.w, "]}\n")
+	return err
+}
+
+// Read reads an archive. It returns an error if the archive is of a version this package cannot read.
+func Read(r io.Reader) (Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return Archive{}, err
+	}
+	if a.Version < 1 || a.Version > Version {
+		return Archive{}, errors.New("unsupported archive version")
+	}
+	return a, nil
+}
+
+// ToFolder returns the folder f belonging to uid.
+func ToFolder(uid model.UserID, f Folder) model.YataFolder {
+	return model.YataFolder{
+		UserID:   uid,
+		FolderID: model.FolderID(f.FolderID),
+		Title:    f.Title,
+		Position: f.Position,
+	}
+}
+
+// ToList returns the list l, its sections and its items, all belonging to uid.
+func ToList(uid model.UserID, l List) (model.YataList, []model.YataSection, []model.YataItem) {
+	yl := model.YataList{
+		UserID:      uid,
+		ListID:      model.ListID(l.ListID),
+		Title:       l.Title,
+		Description: l.Description,
+		Color:       l.Color,
+		Icon:        l.Icon,
+		Position:    l.Position,
+		FolderID:    model.FolderID(l.FolderID),
+		Archived:    l.Archived,
+		Template:    l.Template,
+	}
+	sections := make([]model.YataSection, 0, len(l.Sections))
+	for _, s := range l.Sections {
+		sections = append(sections, model.YataSection{
+			UserID:    uid,
+			ListID:    yl.ListID,
+			SectionID: model.SectionID(s.SectionID),
+			Title:     s.Title,
+			Position:  s.Position,
+		})
+	}
+	items := make([]model.YataItem, 0, len(l.Items))
+	for _, i := range l.Items {
+		items = append(items, model.YataItem{
+			UserID:     uid,
+			ListID:     yl.ListID,
+			ItemID:     model.ItemID(i.ItemID),
+			ParentID:   model.ItemID(i.ParentID),
+			SectionID:  model.SectionID(i.SectionID),
+			Content:    i.Content,
+			Notes:      i.Notes,
+			Completed:  i.Completed,
+			Priority:   i.Priority,
+			DueAt:      i.DueAt,
+			AssigneeID: model.UserID(i.AssigneeID),
+			Tags:       i.Tags,
+			CreatedAt:  i.CreatedAt,
+		})
+	}
+	return yl, sections, items
+}
@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	exportedAt := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	due := time.Date(2021, 2, 1, 9, 0, 0, 0, time.UTC)
+	folders := []Folder{FromFolder(model.YataFolder{UserID: "me", FolderID: "home", Title: "Home"})}
+	lists := []List{
+		FromList(
+			model.YataList{UserID: "me", ListID: "groceries", Title: "Groceries", FolderID: "home", Color: "#1e90ff"},
+			[]model.YataSection{{UserID: "me", ListID: "groceries", SectionID: "dairy", Title: "Dairy", Position: 1}},
+			[]model.YataItem{
+				{UserID: "me", ListID: "groceries", ItemID: "milk", SectionID: "dairy", Content: "Milk", DueAt: &due, Tags: []string{"weekly"}, CreatedAt: exportedAt},
+				{UserID: "me", ListID: "groceries", ItemID: "oat", ParentID: "milk", SectionID: "dairy", Content: "Oat", Completed: true, CreatedAt: exportedAt},
+			},
+		),
+		FromList(model.YataList{UserID: "me", ListID: "empty", Title: "Empty", Archived: true, Template: true}, nil, nil),
+	}
+
+	var buf bytes.Buffer
+	aw, err := NewWriter(&buf, "me", exportedAt, folders)
+	require.NoError(t, err)
+	for _, l := range lists {
+		require.NoError(t, aw.WriteList(l))
+	}
+	require.NoError(t, aw.Close())
+
+	got, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, Archive{Version: Version, ExportedAt: exportedAt, UserID: "me", Folders: folders, Lists: lists}, got)
+}
+
+func TestWriter_NoLists(t *testing.T) {
+	var buf bytes.Buffer
+	aw, err := NewWriter(&buf, "me", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	require.NoError(t, err)
+	require.NoError(t, aw.Close())
+	assert.JSONEq(t, `{"Version":1,"ExportedAt":"2021-01-01T00:00:00Z","UserID":"me","Folders":[],"Lists":[]}`, buf.String())
+}
+
+func TestRead_UnsupportedVersion(t *testing.T) {
+	_, err := Read(bytes.NewBufferString(`{"Version":2,"Lists":[]}`))
+	assert.EqualError(t, err, "unsupported archive version")
+}
+
+func TestToList(t *testing.T) {
+	created := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	yl := model.YataList{UserID: "me", ListID: "groceries", Title: "Groceries", FolderID: "home", Position: 2}
+	sections := []model.YataSection{{UserID: "me", ListID: "groceries", SectionID: "dairy", Title: "Dairy"}}
+	items := []model.YataItem{
+		{UserID: "me", ListID: "groceries", ItemID: "milk", SectionID: "dairy", Content: "Milk", Priority: 2, AssigneeID: "me", CreatedAt: created},
+	}
+
+	gotList, gotSections, gotItems := ToList("me", FromList(yl, sections, items))
+	assert.Equal(t, yl, gotList)
+	assert.Equal(t, sections, gotSections)
+	assert.Equal(t, items, gotItems)
+
+	assert.Equal(t, model.YataFolder{UserID: "you", FolderID: "home", Title: "Home"}, ToFolder("you", Folder{FolderID: "home", Title: "Home"}))
+}
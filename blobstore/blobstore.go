@@ -0,0 +1,15 @@
+package blobstore
+
+import (
+	"io"
+)
+
+// BlobStore stores blobs of data under a key.
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing any blob already stored under key.
+	Put(key string, r io.Reader) error
+	// Get returns a reader for the blob stored under key. The caller must close the reader.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a blob that does not exist is not an error.
+	Delete(key string) error
+}
@@ -0,0 +1,13 @@
+package blobstore
+
+import (
+	"fmt"
+)
+
+type BlobNotFoundError struct {
+	key string
+}
+
+func (e BlobNotFoundError) Error() string {
+	return fmt.Sprintf("blob not found. Key: %q", e.key)
+}
@@ -0,0 +1,66 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalBlobStore stores blobs as files in a directory on the local filesystem.
+var _ BlobStore = &LocalBlobStore{}
+
+type LocalBlobStore struct {
+	Dir string
+}
+
+func (bs *LocalBlobStore) Put(key string, r io.Reader) error {
+	if err := os.MkdirAll(bs.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	// Write to a temporary file first so a failed write never leaves a partial blob behind.
+	f, err := ioutil.TempFile(bs.Dir, ".upload-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(f.Name()) // Fails harmlessly once the file has been renamed.
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+	if err := os.Rename(f.Name(), bs.path(key)); err != nil {
+		return fmt.Errorf("failed to rename temp file: %v", err)
+	}
+	return nil
+}
+
+func (bs *LocalBlobStore) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(bs.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, BlobNotFoundError{key: key}
+		}
+		return nil, fmt.Errorf("failed to open blob: %v", err)
+	}
+	return f, nil
+}
+
+func (bs *LocalBlobStore) Delete(key string) error {
+	if err := os.Remove(bs.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove blob: %v", err)
+	}
+	return nil
+}
+
+// path returns the file a blob is stored in.
+// Keys are hashed so that they can contain any character, including path separators, without escaping Dir.
+func (bs *LocalBlobStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(bs.Dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,45 @@
+package blobstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bs := &LocalBlobStore{Dir: dir}
+	key := "user/../../list/item"
+
+	// Getting a blob that does not exist.
+	_, err = bs.Get(key)
+	assert.Equal(t, BlobNotFoundError{key: key}, err)
+
+	// Putting, replacing and getting a blob.
+	require.NoError(t, bs.Put(key, bytes.NewBufferString("first")))
+	require.NoError(t, bs.Put(key, bytes.NewBufferString("second")))
+	rc, err := bs.Get(key)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "second", string(b))
+
+	// Keys never escape the directory.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Deleting a blob, twice.
+	require.NoError(t, bs.Delete(key))
+	require.NoError(t, bs.Delete(key))
+	_, err = bs.Get(key)
+	assert.Equal(t, BlobNotFoundError{key: key}, err)
+}
@@ -0,0 +1,58 @@
+package blobstore
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3BlobStore stores blobs as objects in an S3 bucket.
+// Any S3 compatible service, such as MinIO, can be used by pointing the S3 client at its endpoint.
+var _ BlobStore = &S3BlobStore{}
+
+type S3BlobStore struct {
+	Bucket   string
+	S3       *s3.S3
+	Uploader *s3manager.Uploader
+}
+
+func (bs *S3BlobStore) Put(key string, r io.Reader) error {
+	_, err := bs.Uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(bs.Bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload object: %v", err)
+	}
+	return nil
+}
+
+func (bs *S3BlobStore) Get(key string) (io.ReadCloser, error) {
+	out, err := bs.S3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bs.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, BlobNotFoundError{key: key}
+		}
+		return nil, fmt.Errorf("failed to get object: %v", err)
+	}
+	return out.Body, nil
+}
+
+func (bs *S3BlobStore) Delete(key string) error {
+	_, err := bs.S3.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bs.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+// Package cbor encodes and decodes values as CBOR (RFC 8949), a compact binary equivalent of JSON.
+//
+// Values are converted to and from CBOR through their JSON encoding, so the json struct tags, MarshalJSON and
+// UnmarshalJSON methods of a type decide its CBOR encoding too. Byte strings decode to base64 text as encoding/json
+// expects for []byte, and epoch-based date/time tags decode to RFC 3339 text so they can be decoded into a time.Time.
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// MediaType is the media type of CBOR.
+const MediaType = "application/cbor"
+
+// maxDepth is the deepest that arrays and maps can be nested when decoding.
+const maxDepth = 1000
+
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	additionalHalf  = 25
+	additionalFloat = 26
+	additionalDbl   = 27
+	indefinite      = 31
+	breakCode       = 0xff
+)
+
+const (
+	tagDateTime = 0
+	tagEpoch    = 1
+)
+
+// Marshal returns the CBOR encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return encodeValue(nil, dec)
+}
+
+// encodeValue appends the CBOR encoding of the next JSON value of dec to buf.
+func encodeValue(buf []byte, dec *json.Decoder) ([]byte, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case nil:
+		return append(buf, majorSimple<<5|simpleNull), nil
+	case bool:
+		if t {
+			return append(buf, majorSimple<<5|simpleTrue), nil
+		}
+		return append(buf, majorSimple<<5|simpleFalse), nil
+	case string:
+		return appendText(buf, t), nil
+	case json.Number:
+		return appendNumber(buf, t)
+	case json.Delim:
+		// The number of entries is not known until they have been read, so they are encoded on their own first.
+		var body []byte
+		n := uint64(0)
+		for dec.More() {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				body = appendText(body, key.(string))
+			}
+			if body, err = encodeValue(body, dec); err != nil {
+				return nil, err
+			}
+			n++
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		major := byte(majorArray)
+		if t == '{' {
+			major = majorMap
+		}
+		return append(appendHead(buf, major, n), body...), nil
+	}
+	return nil, fmt.Errorf("cbor: unexpected JSON token %v", tok)
+}
+
+// appendHead appends the initial bytes of a data item of the given major type with the argument n.
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, major<<5|25)
+		return append(buf, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		buf = append(buf, major<<5|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(n))
+		return buf
+	default:
+		buf = append(buf, major<<5|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], n)
+		return buf
+	}
+}
+
+func appendText(buf []byte, s string) []byte {
+	return append(appendHead(buf, majorText, uint64(len(s))), s...)
+}
+
+// appendNumber appends a JSON number as an integer if it is one, or else as the smallest float that holds it exactly.
+func appendNumber(buf []byte, n json.Number) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		if i < 0 {
+			return appendHead(buf, majorNegInt, uint64(-1-i)), nil
+		}
+		return appendHead(buf, majorUint, uint64(i)), nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return appendHead(buf, majorUint, u), nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return nil, err
+	}
+	if f32 := float32(f); float64(f32) == f {
+		buf = append(buf, majorSimple<<5|additionalFloat, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], math.Float32bits(f32))
+		return buf, nil
+	}
+	buf = append(buf, majorSimple<<5|additionalDbl, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], math.Float64bits(f))
+	return buf, nil
+}
+
+// Unmarshal decodes the CBOR data item in data into v, which must be a pointer. Map keys must be text strings, and
+// floats must be finite, as in JSON.
+func Unmarshal(data []byte, v interface{}) error {
+	d := decoder{data: data}
+	var buf bytes.Buffer
+	if err := d.value(&buf, 0); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("cbor: unexpected data after the top level data item")
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+// Encoder writes CBOR data items to a writer.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the CBOR encoding of v.
+func (e *Encoder) Encode(v interface{}) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+// errUnexpectedEnd is returned when the data ends in the middle of a data item.
+var errUnexpectedEnd = errors.New("cbor: unexpected end of data")
+
+// decoder converts CBOR data to JSON.
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) byte() (byte, error) {
+	if d.off >= len(d.data) {
+		return 0, errUnexpectedEnd
+	}
+	b := d.data[d.off]
+	d.off++
+	return b, nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errUnexpectedEnd
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head reads the initial bytes of a data item. indef is true for indefinite lengths, in which case n is zero.
+func (d *decoder) head() (major byte, additional byte, n uint64, indef bool, err error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	major, additional = b>>5, b&0x1f
+	switch {
+	case additional < 24:
+		return major, additional, uint64(additional), false, nil
+	case additional <= 27:
+		arg, err := d.bytes(1 << (additional - 24))
+		if err != nil {
+			return 0, 0, 0, false, err
+		}
+		for _, a := range arg {
+			n = n<<8 | uint64(a)
+		}
+		return major, additional, n, false, nil
+	case additional == indefinite && major >= majorBytes && major <= majorMap:
+		return major, additional, 0, true, nil
+	case additional == indefinite && major == majorSimple:
+		return 0, 0, 0, false, errors.New("cbor: unexpected break")
+	}
+	return 0, 0, 0, false, fmt.Errorf("cbor: malformed initial byte %#x", b)
+}
+
+// isBreak reports whether the next byte ends an indefinite length item, and consumes it if so.
+func (d *decoder) isBreak() (bool, error) {
+	if d.off >= len(d.data) {
+		return false, errUnexpectedEnd
+	}
+	if d.data[d.off] == breakCode {
+		d.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+// value writes the next data item as JSON to buf.
+func (d *decoder) value(buf *bytes.Buffer, depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: data items nested too deeply")
+	}
+	major, additional, n, indef, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case majorUint:
+		buf.WriteString(strconv.FormatUint(n, 10))
+	case majorNegInt:
+		if n == math.MaxUint64 {
+			buf.WriteString("-18446744073709551616")
+		} else {
+			buf.WriteString("-" + strconv.FormatUint(n+1, 10))
+		}
+	case majorBytes:
+		b, err := d.str(majorBytes, n, indef)
+		if err != nil {
+			return err
+		}
+		return writeJSON(buf, base64.StdEncoding.EncodeToString(b))
+	case majorText:
+		b, err := d.str(majorText, n, indef)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(b) {
+			return errors.New("cbor: text string is not valid UTF-8")
+		}
+		return writeJSON(buf, string(b))
+	case majorArray:
+		buf.WriteByte('[')
+		for i := uint64(0); ; i++ {
+			if done, err := d.done(n, indef, i); err != nil || done {
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := d.value(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case majorMap:
+		buf.WriteByte('{')
+		for i := uint64(0); ; i++ {
+			if done, err := d.done(n, indef, i); err != nil || done {
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := d.key(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := d.value(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case majorTag:
+		if n == tagEpoch {
+			return d.epoch(buf)
+		}
+		// Other tags, including RFC 3339 date/times, are decoded as their content.
+		return d.value(buf, depth+1)
+	case majorSimple:
+		return d.simple(buf, additional, n)
+	}
+	return nil
+}
+
+// done reports whether the i'th entry of an array or map of n entries is past its end.
+func (d *decoder) done(n uint64, indef bool, i uint64) (bool, error) {
+	if indef {
+		return d.isBreak()
+	}
+	return i >= n, nil
+}
+
+// str reads the content of a byte or text string, joining the chunks of indefinite length strings.
+func (d *decoder) str(major byte, n uint64, indef bool) ([]byte, error) {
+	if !indef {
+		return d.bytes(n)
+	}
+	var b []byte
+	for {
+		done, err := d.isBreak()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return b, nil
+		}
+		chunkMajor, _, chunkN, chunkIndef, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkIndef {
+			return nil, errors.New("cbor: malformed indefinite length string")
+		}
+		chunk, err := d.bytes(chunkN)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+// key writes a map key as a JSON string. Keys must be text strings.
+func (d *decoder) key(buf *bytes.Buffer) error {
+	major, _, n, indef, err := d.head()
+	if err != nil {
+		return err
+	}
+	if major != majorText {
+		return errors.New("cbor: map keys must be text strings")
+	}
+	b, err := d.str(majorText, n, indef)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(b) {
+		return errors.New("cbor: text string is not valid UTF-8")
+	}
+	return writeJSON(buf, string(b))
+}
+
+// epoch writes the content of an epoch-based date/time as an RFC 3339 string.
+func (d *decoder) epoch(buf *bytes.Buffer) error {
+	var content bytes.Buffer
+	if err := d.value(&content, maxDepth); err != nil {
+		return err
+	}
+	seconds, err := strconv.ParseFloat(content.String(), 64)
+	if err != nil {
+		return errors.New("cbor: epoch-based date/time must be a number")
+	}
+	whole, frac := math.Modf(seconds)
+	t := time.Unix(int64(whole), int64(frac*1e9)).UTC()
+	return writeJSON(buf, t.Format(time.RFC3339Nano))
+}
+
+// simple writes a simple value or float. Undefined is written as null.
+func (d *decoder) simple(buf *bytes.Buffer, additional byte, n uint64) error {
+	var f float64
+	switch additional {
+	case simpleFalse:
+		buf.WriteString("false")
+		return nil
+	case simpleTrue:
+		buf.WriteString("true")
+		return nil
+	case simpleNull, simpleUndefined:
+		buf.WriteString("null")
+		return nil
+	case additionalHalf:
+		f = halfToFloat(uint16(n))
+	case additionalFloat:
+		f = float64(math.Float32frombits(uint32(n)))
+	case additionalDbl:
+		f = math.Float64frombits(n)
+	default:
+		return fmt.Errorf("cbor: unsupported simple value %d", n)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return errors.New("cbor: floats must be finite")
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	return nil
+}
+
+// halfToFloat returns the value of an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
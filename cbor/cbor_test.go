@@ -0,0 +1,160 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tagged struct {
+	A string `json:"a,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	tests := map[string]struct {
+		v   interface{}
+		hex string
+	}{
+		// Most of the expected encodings are from Appendix A of RFC 8949.
+		"zero":          {v: 0, hex: "00"},
+		"small":         {v: 23, hex: "17"},
+		"one-byte":      {v: 24, hex: "1818"},
+		"two-bytes":     {v: 1000, hex: "1903e8"},
+		"four-bytes":    {v: 1000000, hex: "1a000f4240"},
+		"eight-bytes":   {v: uint64(18446744073709551615), hex: "1bffffffffffffffff"},
+		"negative":      {v: -1000, hex: "3903e7"},
+		"whole-float":   {v: 100000.0, hex: "1a000186a0"},
+		"single":        {v: 3.4028234663852886e+38, hex: "fa7f7fffff"},
+		"double":        {v: 1.1, hex: "fb3ff199999999999a"},
+		"false":         {v: false, hex: "f4"},
+		"null":          {v: nil, hex: "f6"},
+		"text":          {v: "IETF", hex: "6449455446"},
+		"unicode":       {v: "水", hex: "63e6b0b4"},
+		"array":         {v: []interface{}{1, []int{2, 3}, []int{4, 5}}, hex: "8301820203820405"},
+		"map-in-order":  {v: struct{ A, B int }{1, 2}, hex: "a2614101614202"},
+		"json-tags":     {v: tagged{}, hex: "a0"},
+		"bytes-as-text": {v: []byte{1, 2}, hex: "644151493d"},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			b, err := Marshal(test.v)
+			require.NoError(t, err)
+			assert.Equal(t, test.hex, hex.EncodeToString(b))
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type item struct {
+		ItemID    string
+		Completed bool
+		Priority  int
+		Weight    float64
+		Data      []byte
+		DueAt     *time.Time
+		Tags      []string
+	}
+	due := time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		hex  string
+		want item
+		err  string
+	}{
+		"map": {
+			// {"ItemID": "milk", "Completed": true, "Priority": 2, "Tags": ["dairy"]}
+			hex:  "a4664974656d4944646d696c6b69436f6d706c65746564f5685072696f7269747902645461677381656461697279",
+			want: item{ItemID: "milk", Completed: true, Priority: 2, Tags: []string{"dairy"}},
+		},
+		"floats": {
+			// {"Weight": 1.5} with a half-precision float.
+			hex:  "a166576569676874f93e00",
+			want: item{Weight: 1.5},
+		},
+		"indefinite": {
+			// {_ "Tags": [_ "a", "b"], "ItemID": (_ "mi", "lk")}
+			hex:  "bf64546167739f61616162ff664974656d49447f626d69626c6bffff",
+			want: item{ItemID: "milk", Tags: []string{"a", "b"}},
+		},
+		"bytes": {
+			hex:  "a16444617461420102",
+			want: item{Data: []byte{1, 2}},
+		},
+		"rfc3339-tag": {
+			hex:  "a1654475654174c074323031332d30332d32315432303a30343a30305a",
+			want: item{DueAt: &due},
+		},
+		"epoch-tag": {
+			hex:  "a1654475654174c11a514b67b0",
+			want: item{DueAt: &due},
+		},
+		"undefined-is-null": {
+			hex:  "a1654475654174f7",
+			want: item{},
+		},
+		"non-text-key": {
+			hex: "a10102",
+			err: "cbor: map keys must be text strings",
+		},
+		"truncated": {
+			hex: "a2",
+			err: "cbor: unexpected end of data",
+		},
+		"trailing-data": {
+			hex: "a000",
+			err: "cbor: unexpected data after the top level data item",
+		},
+		"infinity": {
+			hex: "a166576569676874f97c00",
+			err: "cbor: floats must be finite",
+		},
+		"huge-length": {
+			hex: "7bffffffffffffffff",
+			err: "cbor: unexpected end of data",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			data, err := hex.DecodeString(test.hex)
+			require.NoError(t, err)
+			var got item
+			err = Unmarshal(data, &got)
+			if len(test.err) != 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	type list struct {
+		Title     string
+		Position  int
+		CreatedAt time.Time
+		Items     []map[string]interface{}
+	}
+	in := list{
+		Title:     "Groceries",
+		Position:  -3,
+		CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC),
+		Items:     []map[string]interface{}{{"Content": "Milk", "Priority": 2.5}},
+	}
+	b, err := Marshal(in)
+	require.NoError(t, err)
+	var out list
+	require.NoError(t, Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+}
@@ -1,14 +1,122 @@
 package database
 
 import (
+	"time"
+
 	"github.com/TheYeung1/yata-server/model"
 )
 
+// Lists and items are soft deleted by moving them to the trash. Trashed lists and items are left out of the results
+// of every method except GetTrash and GetExpiredTrash.
+
+// MaxMoveWrites is the most writes a single MoveItems call can make. Moving an item or an attachment takes two writes.
+const MaxMoveWrites = 100
+
+// ItemMove is an item to move to another list, along with its attachments.
+type ItemMove struct {
+	// From is the item before the move and To is the item after it. They must have different lists.
+	From model.YataItem
+	To   model.YataItem
+	// Attachments are From's attachments. They are moved to To.
+	Attachments []model.YataAttachment
+}
+
+// MaxItemUpdates is the most updates a single UpdateItems call can make.
+const MaxItemUpdates = 100
+
+// ItemUpdate is a change to one of an item's fields. Exactly one of Completed, DeletedAt, AddTag and RemoveTag is set.
+type ItemUpdate struct {
+	UserID model.UserID
+	ListID model.ListID
+	ItemID model.ItemID
+	// Completed marks the item as completed or not.
+	Completed *bool
+	// DeletedAt moves the item to the trash.
+	DeletedAt *time.Time
+	// AddTag adds a tag to the item unless it already has it, and RemoveTag removes a tag from the item if it has it.
+	// Tags are the item's tags when it was read. Other changes to them since are kept.
+	AddTag    string
+	RemoveTag string
+	Tags      []string
+}
+
 type YataDatabase interface {
 	GetList(model.UserID, model.ListID) (model.YataList, error)
 	GetLists(model.UserID) ([]model.YataList, error)
+	// InsertList creates a list. It returns a ListExistsError if the user already has a list with its ID, or a
+	// ListInTrashError if that list is in the trash.
 	InsertList(model.UserID, model.YataList) error
+	// UpdateList replaces the fields of a list, other than its IDs and when it was trashed. It returns a
+	// ListNotFoundError if the list does not exist or is in the trash.
+	UpdateList(model.YataList) error
+	// SetListDetails replaces a list's title, description, color and icon. It returns a ListNotFoundError if the list
+	// does not exist or is in the trash.
+	SetListDetails(model.YataList) error
+	SetListArchived(uid model.UserID, lid model.ListID, archived bool) error
+	SetListTemplate(uid model.UserID, lid model.ListID, template bool) error
+	SetListPosition(uid model.UserID, lid model.ListID, position int) error
+	// SetListFolder moves a list into a folder, or out of its folder if the folder ID is empty.
+	SetListFolder(uid model.UserID, lid model.ListID, fid model.FolderID) error
+	GetFolderLists(model.UserID, model.FolderID) ([]model.YataList, error)
+	GetFolders(model.UserID) ([]model.YataFolder, error)
+	GetFolder(model.UserID, model.FolderID) (model.YataFolder, error)
+	InsertFolder(model.YataFolder) error
+	DeleteFolder(model.UserID, model.FolderID) error
+	TrashList(uid model.UserID, lid model.ListID, deletedAt time.Time) error
+	RestoreList(model.UserID, model.ListID) error
+	// DeleteList permanently deletes a list, whether or not it is in the trash.
+	DeleteList(model.UserID, model.ListID) error
 	GetAllItems(model.UserID) ([]model.YataItem, error)
 	GetListItems(model.UserID, model.ListID) ([]model.YataItem, error)
+	GetItem(model.UserID, model.ListID, model.ItemID) (model.YataItem, error)
 	InsertItem(model.YataItem) error
+	// InsertItems inserts items in batches. If some of them still cannot be written after retrying it returns an
+	// UnprocessedItemsError listing them.
+	InsertItems([]model.YataItem) error
+	// MoveItems moves items and their attachments to other lists in a single transaction; either all of them are moved
+	// or none are. It returns an ItemNotFoundError if an item no longer exists, an ItemExistsError if an item's new list
+	// already has an item with its ID, and a TooManyWritesError if the move takes more than MaxMoveWrites writes.
+	MoveItems([]ItemMove) error
+	// UpdateItems applies updates to different items in a single transaction. Updates of items that do not exist or are
+	// in the trash are left out, and returned in an ItemsNotFoundError once the others are applied. It returns a
+	// TooManyWritesError if there are more than MaxItemUpdates updates.
+	UpdateItems([]ItemUpdate) error
+	TrashItem(uid model.UserID, lid model.ListID, iid model.ItemID, deletedAt time.Time) error
+	RestoreItem(model.UserID, model.ListID, model.ItemID) error
+	// DeleteItem permanently deletes an item, whether or not it is in the trash.
+	DeleteItem(model.UserID, model.ListID, model.ItemID) error
+	// GetTrash returns a user's trashed lists and items.
+	GetTrash(model.UserID) ([]model.YataList, []model.YataItem, error)
+	// GetExpiredTrash returns every user's lists and items that were trashed before the given time.
+	GetExpiredTrash(before time.Time) ([]model.YataList, []model.YataItem, error)
+	GetListSections(model.UserID, model.ListID) ([]model.YataSection, error)
+	GetSection(model.UserID, model.ListID, model.SectionID) (model.YataSection, error)
+	InsertSection(model.YataSection) error
+	DeleteSection(model.UserID, model.ListID, model.SectionID) error
+	GetAllAttachments(model.UserID) ([]model.YataAttachment, error)
+	GetItemAttachments(model.UserID, model.ListID, model.ItemID) ([]model.YataAttachment, error)
+	GetAttachment(model.UserID, model.ListID, model.ItemID, model.AttachmentID) (model.YataAttachment, error)
+	InsertAttachment(model.YataAttachment) error
+	DeleteAttachment(model.UserID, model.ListID, model.ItemID, model.AttachmentID) error
+	GetListMembers(owner model.UserID, lid model.ListID) ([]model.YataListMember, error)
+	GetListMember(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error)
+	GetMemberships(member model.UserID) ([]model.YataListMember, error)
+	InsertListMember(model.YataListMember) error
+	DeleteListMember(owner model.UserID, lid model.ListID, member model.UserID) error
+	GetShareLink(token string) (model.YataShareLink, error)
+	GetListShareLinks(model.UserID, model.ListID) ([]model.YataShareLink, error)
+	InsertShareLink(model.YataShareLink) error
+	DeleteShareLink(token string) error
+	GetCalendarFeed(token string) (model.YataCalendarFeed, error)
+	GetCalendarFeeds(model.UserID) ([]model.YataCalendarFeed, error)
+	InsertCalendarFeed(model.YataCalendarFeed) error
+	DeleteCalendarFeed(token string) error
+	GetAppPassword(hash string) (model.YataAppPassword, error)
+	GetAppPasswords(model.UserID) ([]model.YataAppPassword, error)
+	InsertAppPassword(model.YataAppPassword) error
+	DeleteAppPassword(hash string) error
+	// GetListActivity returns up to limit of a list's activities, newest first, starting after the page token.
+	// It also returns the token of the next page, which is empty when there are no more activities.
+	GetListActivity(owner model.UserID, lid model.ListID, limit int64, pageToken string) ([]model.YataActivity, string, error)
+	InsertActivity(model.YataActivity) error
 }
@@ -1,7 +1,12 @@
 package database
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/TheYeung1/yata-server/model"
 	"github.com/aws/aws-sdk-go/aws"
@@ -10,23 +15,63 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
+// MembersUserIndexName is the name of the members table's global secondary index that is partitioned by the member's UserID.
+const MembersUserIndexName = "UserID-index"
+
+// ShareLinksListIndexName is the name of the share links table's global secondary index that is partitioned by list.
+const ShareLinksListIndexName = "OwnerID-ListID-index"
+
+// CalendarFeedsUserIndexName is the name of the calendar feeds table's global secondary index that is partitioned by
+// the feed's UserID.
+const CalendarFeedsUserIndexName = "UserID-index"
+
+// AppPasswordsUserIndexName is the name of the app passwords table's global secondary index that is partitioned by the
+// app password's UserID.
+const AppPasswordsUserIndexName = "UserID-index"
+
+// TrashIndexName is the name of the global secondary index of the lists and items tables that holds the lists and items
+// in the trash. It is partitioned by the Trash attribute, which only trashed lists and items have, and sorted by DeletedAt.
+const TrashIndexName = "Trash-DeletedAt-index"
+
+// UserTrashIndexName is the name of the global secondary index of the lists and items tables that holds each user's
+// lists and items in the trash. It is partitioned by UserID and sorted by DeletedAt, which only trashed lists and items
+// have.
+const UserTrashIndexName = "UserID-DeletedAt-index"
+
+// trashPartition is the Trash attribute of every list and item in the trash.
+const trashPartition = "trash"
+
+const (
+	// maxBatchWriteItems is the most requests DynamoDB accepts in one BatchWriteItem call.
+	maxBatchWriteItems = 25
+	// maxBatchWriteAttempts is how many times a batch is written before its unprocessed items are given up on.
+	maxBatchWriteAttempts = 5
+	// batchWriteBackoff is how long to wait before the first retry of a batch's unprocessed items. It doubles after
+	// every retry.
+	batchWriteBackoff = 50 * time.Millisecond
+	// maxItemUpdateAttempts is how many times UpdateItems writes its updates before giving up, when the items' tags keep
+	// being changed by someone else in between.
+	maxItemUpdateAttempts = 5
+)
+
 type DynamoDbYataDatabase struct {
-	ListsTableName string
-	ItemsTableName string
-	Dynamo         *dynamodb.DynamoDB
+	ListsTableName         string
+	FoldersTableName       string
+	ItemsTableName         string
+	SectionsTableName      string
+	AttachmentsTableName   string
+	MembersTableName       string
+	ShareLinksTableName    string
+	CalendarFeedsTableName string
+	AppPasswordsTableName  string
+	ActivityTableName      string
+	Dynamo                 *dynamodb.DynamoDB
 }
 
 func (db *DynamoDbYataDatabase) GetList(uid model.UserID, lid model.ListID) (model.YataList, error) {
 	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
 		TableName: aws.String(db.ListsTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"UserID": {
-				S: aws.String(string(uid)),
-			},
-			"ListID": {
-				S: aws.String(string(lid)),
-			},
-		},
+		Key:       listKey(uid, lid),
 	})
 	if err != nil {
 		return model.YataList{}, fmt.Errorf("failed to get item: %v", err)
@@ -44,13 +89,20 @@ func (db *DynamoDbYataDatabase) GetList(uid model.UserID, lid model.ListID) (mod
 	if err != nil {
 		return model.YataList{}, fmt.Errorf("failed to unmarshal map: %v", err)
 	}
+	if yl.DeletedAt != nil {
+		return model.YataList{}, ListNotFoundError{
+			uid: uid,
+			lid: lid,
+		}
+	}
 	return yl, nil
 }
 
 func (db *DynamoDbYataDatabase) GetLists(uid model.UserID) ([]model.YataList, error) {
-	queryResults, err := db.Dynamo.Query(&dynamodb.QueryInput{
+	results, err := db.queryAll(&dynamodb.QueryInput{
 		TableName:              aws.String(db.ListsTableName),
 		KeyConditionExpression: aws.String("UserID = :user"),
+		FilterExpression:       aws.String("attribute_not_exists(DeletedAt)"),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":user": {
 				S: aws.String(string(uid)),
@@ -62,7 +114,7 @@ func (db *DynamoDbYataDatabase) GetLists(uid model.UserID) ([]model.YataList, er
 	}
 
 	yl := []model.YataList{}
-	err = dynamodbattribute.UnmarshalListOfMaps(queryResults.Items, &yl)
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &yl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
 	}
@@ -83,11 +135,7 @@ func (db *DynamoDbYataDatabase) InsertList(uid model.UserID, yl model.YataList)
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
 			case dynamodb.ErrCodeConditionalCheckFailedException:
-				// TODO: get the item and check that the title matches before throwing a 409.
-				return ListExistsError{
-					uid: uid,
-					lid: yl.ListID,
-				}
+				return db.listConflict(uid, yl.ListID)
 			default:
 				return fmt.Errorf("failed to put item: %v", aerr)
 			}
@@ -97,10 +145,318 @@ func (db *DynamoDbYataDatabase) InsertList(uid model.UserID, yl model.YataList)
 	return nil
 }
 
+// listConflict returns the error of a list that could not be created because the user already has a list with its ID:
+// a ListInTrashError if that list is in the trash, or a ListExistsError if it is not.
+func (db *DynamoDbYataDatabase) listConflict(uid model.UserID, lid model.ListID) error {
+	out, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName:            aws.String(db.ListsTableName),
+		Key:                  listKey(uid, lid),
+		ConsistentRead:       aws.Bool(true),
+		ProjectionExpression: aws.String("DeletedAt"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get item: %v", err)
+	}
+	if _, ok := out.Item["DeletedAt"]; ok {
+		return ListInTrashError{
+			uid: uid,
+			lid: lid,
+		}
+	}
+	return ListExistsError{
+		uid: uid,
+		lid: lid,
+	}
+}
+
+var (
+	// listUpdateAttributes are the attributes of a list that UpdateList replaces.
+	listUpdateAttributes = []string{"Title", "Description", "Color", "Icon", "Position", "FolderID", "Archived", "Template"}
+	// listDetailsAttributes are the attributes of a list that SetListDetails replaces.
+	listDetailsAttributes = []string{"Title", "Description", "Color", "Icon"}
+)
+
+func (db *DynamoDbYataDatabase) UpdateList(yl model.YataList) error {
+	return db.updateListAttributes(yl, listUpdateAttributes)
+}
+
+func (db *DynamoDbYataDatabase) SetListDetails(yl model.YataList) error {
+	return db.updateListAttributes(yl, listDetailsAttributes)
+}
+
+// updateListAttributes replaces the attributes of a list that is not in the trash with those of yl. The list's other
+// attributes are left as they are.
+func (db *DynamoDbYataDatabase) updateListAttributes(yl model.YataList, attrs []string) error {
+	av, err := dynamodbattribute.MarshalMap(yl)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	var set, remove []string
+	for i, attr := range attrs {
+		name := "#a" + strconv.Itoa(i)
+		names[name] = aws.String(attr)
+		// Optional fields that are empty are left out when marshalling, so they are removed.
+		if v, ok := av[attr]; ok {
+			values[":a"+strconv.Itoa(i)] = v
+			set = append(set, name+" = :a"+strconv.Itoa(i))
+		} else {
+			remove = append(remove, name)
+		}
+	}
+	update := "SET " + strings.Join(set, ", ")
+	if len(remove) != 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	_, err = db.Dynamo.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(db.ListsTableName),
+		Key:                       listKey(yl.UserID, yl.ListID),
+		ConditionExpression:       aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ListNotFoundError{
+				uid: yl.UserID,
+				lid: yl.ListID,
+			}
+		}
+		return fmt.Errorf("failed to update item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) SetListArchived(uid model.UserID, lid model.ListID, archived bool) error {
+	return db.setListFlag(uid, lid, "Archived", archived)
+}
+
+func (db *DynamoDbYataDatabase) SetListTemplate(uid model.UserID, lid model.ListID, template bool) error {
+	return db.setListFlag(uid, lid, "Template", template)
+}
+
+// setListFlag sets the boolean attribute attr of a list that exists and is not in the trash.
+func (db *DynamoDbYataDatabase) setListFlag(uid model.UserID, lid model.ListID, attr string, value bool) error {
+	_, err := db.Dynamo.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(db.ListsTableName),
+		Key:                 listKey(uid, lid),
+		ConditionExpression: aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+		UpdateExpression:    aws.String("SET #attr = :value"),
+		ExpressionAttributeNames: map[string]*string{
+			"#attr": aws.String(attr),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":value": {
+				BOOL: aws.Bool(value),
+			},
+		},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ListNotFoundError{
+				uid: uid,
+				lid: lid,
+			}
+		}
+		return fmt.Errorf("failed to update item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) SetListPosition(uid model.UserID, lid model.ListID, position int) error {
+	_, err := db.Dynamo.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(db.ListsTableName),
+		Key:                 listKey(uid, lid),
+		ConditionExpression: aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+		UpdateExpression:    aws.String("SET #position = :position"),
+		ExpressionAttributeNames: map[string]*string{
+			"#position": aws.String("Position"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":position": {
+				N: aws.String(strconv.Itoa(position)),
+			},
+		},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ListNotFoundError{
+				uid: uid,
+				lid: lid,
+			}
+		}
+		return fmt.Errorf("failed to update item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) SetListFolder(uid model.UserID, lid model.ListID, fid model.FolderID) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:           aws.String(db.ListsTableName),
+		Key:                 listKey(uid, lid),
+		ConditionExpression: aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+	}
+	if len(fid) != 0 {
+		input.UpdateExpression = aws.String("SET FolderID = :folder")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":folder": {
+				S: aws.String(string(fid)),
+			},
+		}
+	} else {
+		input.UpdateExpression = aws.String("REMOVE FolderID")
+	}
+	if _, err := db.Dynamo.UpdateItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return ListNotFoundError{
+				uid: uid,
+				lid: lid,
+			}
+		}
+		return fmt.Errorf("failed to update item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetFolderLists(uid model.UserID, fid model.FolderID) ([]model.YataList, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.ListsTableName),
+		KeyConditionExpression: aws.String("UserID = :user"),
+		FilterExpression:       aws.String("FolderID = :folder AND attribute_not_exists(DeletedAt)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(uid)),
+			},
+			":folder": {
+				S: aws.String(string(fid)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	yl := []model.YataList{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &yl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return yl, nil
+}
+
+func (db *DynamoDbYataDatabase) GetFolders(uid model.UserID) ([]model.YataFolder, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.FoldersTableName),
+		KeyConditionExpression: aws.String("UserID = :user"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(uid)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	folders := []model.YataFolder{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &folders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return folders, nil
+}
+
+func (db *DynamoDbYataDatabase) GetFolder(uid model.UserID, fid model.FolderID) (model.YataFolder, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.FoldersTableName),
+		Key:       folderKey(uid, fid),
+	})
+	if err != nil {
+		return model.YataFolder{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataFolder{}, FolderNotFoundError{
+			uid: uid,
+			fid: fid,
+		}
+	}
+
+	folder := model.YataFolder{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &folder)
+	if err != nil {
+		return model.YataFolder{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return folder, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertFolder(folder model.YataFolder) error {
+	av, err := dynamodbattribute.MarshalMap(folder)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(db.FoldersTableName),
+		Item:      av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteFolder(uid model.UserID, fid model.FolderID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.FoldersTableName),
+		Key:       folderKey(uid, fid),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) TrashList(uid model.UserID, lid model.ListID, deletedAt time.Time) error {
+	err := db.setDeletedAt(db.ListsTableName, listKey(uid, lid), &deletedAt)
+	if err == errDeletedAtNotChanged {
+		return ListNotFoundError{
+			uid: uid,
+			lid: lid,
+		}
+	}
+	return err
+}
+
+func (db *DynamoDbYataDatabase) RestoreList(uid model.UserID, lid model.ListID) error {
+	err := db.setDeletedAt(db.ListsTableName, listKey(uid, lid), nil)
+	if err == errDeletedAtNotChanged {
+		return ListNotFoundError{
+			uid: uid,
+			lid: lid,
+		}
+	}
+	return err
+}
+
+func (db *DynamoDbYataDatabase) DeleteList(uid model.UserID, lid model.ListID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.ListsTableName),
+		Key:       listKey(uid, lid),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
 func (db *DynamoDbYataDatabase) GetAllItems(uid model.UserID) ([]model.YataItem, error) {
-	queryResults, err := db.Dynamo.Query(&dynamodb.QueryInput{
+	results, err := db.queryAll(&dynamodb.QueryInput{
 		TableName:              aws.String(db.ItemsTableName),
 		KeyConditionExpression: aws.String("UserID = :user"),
+		FilterExpression:       aws.String("attribute_not_exists(DeletedAt)"),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":user": {
 				S: aws.String(string(uid)),
@@ -112,7 +468,7 @@ func (db *DynamoDbYataDatabase) GetAllItems(uid model.UserID) ([]model.YataItem,
 	}
 
 	items := []model.YataItem{}
-	err = dynamodbattribute.UnmarshalListOfMaps(queryResults.Items, &items)
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &items)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
 	}
@@ -120,15 +476,16 @@ func (db *DynamoDbYataDatabase) GetAllItems(uid model.UserID) ([]model.YataItem,
 }
 
 func (db *DynamoDbYataDatabase) GetListItems(uid model.UserID, lid model.ListID) ([]model.YataItem, error) {
-	queryResults, err := db.Dynamo.Query(&dynamodb.QueryInput{
+	results, err := db.queryAll(&dynamodb.QueryInput{
 		TableName:              aws.String(db.ItemsTableName),
 		KeyConditionExpression: aws.String("UserID = :user AND begins_with(#listIDuserID, :list)"),
+		FilterExpression:       aws.String("attribute_not_exists(DeletedAt)"),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":user": {
 				S: aws.String(string(uid)),
 			},
 			":list": {
-				S: aws.String(string(lid)),
+				S: aws.String(string(lid) + ":"),
 			},
 		},
 		ExpressionAttributeNames: map[string]*string{
@@ -140,11 +497,51 @@ func (db *DynamoDbYataDatabase) GetListItems(uid model.UserID, lid model.ListID)
 	}
 
 	items := []model.YataItem{}
-	err = dynamodbattribute.UnmarshalListOfMaps(queryResults.Items, &items)
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &items)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
 	}
-	return items, nil
+
+	// Keys are split on ":" which may also appear in IDs so the prefix can match items of other lists.
+	listItems := []model.YataItem{}
+	for _, item := range items {
+		if item.ListID == lid {
+			listItems = append(listItems, item)
+		}
+	}
+	return listItems, nil
+}
+
+func (db *DynamoDbYataDatabase) GetItem(uid model.UserID, lid model.ListID, iid model.ItemID) (model.YataItem, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.ItemsTableName),
+		Key:       itemKey(uid, lid, iid),
+	})
+	if err != nil {
+		return model.YataItem{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataItem{}, ItemNotFoundError{
+			uid: uid,
+			lid: lid,
+			iid: iid,
+		}
+	}
+
+	item := model.YataItem{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &item)
+	if err != nil {
+		return model.YataItem{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	if item.DeletedAt != nil {
+		return model.YataItem{}, ItemNotFoundError{
+			uid: uid,
+			lid: lid,
+			iid: iid,
+		}
+	}
+	return item, nil
 }
 
 func (db *DynamoDbYataDatabase) InsertItem(item model.YataItem) error {
@@ -154,9 +551,7 @@ func (db *DynamoDbYataDatabase) InsertItem(item model.YataItem) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal map: %v", err)
 	}
-	av["ListID-ItemID"] = &dynamodb.AttributeValue{
-		S: aws.String(string(item.ListID) + ":" + string(item.ItemID)),
-	}
+	av["ListID-ItemID"] = itemKey(item.UserID, item.ListID, item.ItemID)["ListID-ItemID"]
 	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
 		TableName: aws.String(db.ItemsTableName),
 		Item:      av,
@@ -166,3 +561,1119 @@ func (db *DynamoDbYataDatabase) InsertItem(item model.YataItem) error {
 	}
 	return nil
 }
+
+func (db *DynamoDbYataDatabase) InsertItems(items []model.YataItem) error {
+	var unprocessed []model.YataItem
+	for start := 0; start < len(items); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+		requests := make([]*dynamodb.WriteRequest, len(batch))
+		for i, item := range batch {
+			av, err := dynamodbattribute.MarshalMap(item)
+			if err != nil {
+				return fmt.Errorf("failed to marshal map: %v", err)
+			}
+			av["ListID-ItemID"] = itemKey(item.UserID, item.ListID, item.ItemID)["ListID-ItemID"]
+			requests[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: av}}
+		}
+		left, err := db.batchWrite(db.ItemsTableName, requests)
+		if err != nil {
+			return err
+		}
+		for _, req := range left {
+			item := model.YataItem{}
+			if err := dynamodbattribute.UnmarshalMap(req.PutRequest.Item, &item); err != nil {
+				return fmt.Errorf("failed to unmarshal map: %v", err)
+			}
+			unprocessed = append(unprocessed, item)
+		}
+	}
+	if len(unprocessed) != 0 {
+		return UnprocessedItemsError{Items: unprocessed}
+	}
+	return nil
+}
+
+// batchWrite writes up to maxBatchWriteItems requests to a table, retrying unprocessed requests with exponential
+// backoff. It returns the requests that were still unprocessed after the last attempt.
+func (db *DynamoDbYataDatabase) batchWrite(table string, requests []*dynamodb.WriteRequest) ([]*dynamodb.WriteRequest, error) {
+	backoff := batchWriteBackoff
+	for attempt := 1; len(requests) != 0; attempt++ {
+		out, err := db.Dynamo.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{table: requests},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch write items: %v", err)
+		}
+		requests = out.UnprocessedItems[table]
+		if len(requests) == 0 || attempt == maxBatchWriteAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return requests, nil
+}
+
+func (db *DynamoDbYataDatabase) UpdateItems(updates []ItemUpdate) error {
+	if len(updates) > MaxItemUpdates {
+		return TooManyWritesError{writes: len(updates), max: MaxItemUpdates}
+	}
+
+	pending := updates
+	var missing []ItemUpdate
+	for attempt := 1; ; attempt++ {
+		var writes []*dynamodb.TransactWriteItem
+		// written holds the update of the write with the same index.
+		var written []ItemUpdate
+		for _, u := range pending {
+			w, err := db.itemUpdateWrite(u)
+			if err != nil {
+				return err
+			}
+			if w == nil {
+				continue
+			}
+			writes = append(writes, &dynamodb.TransactWriteItem{Update: w})
+			written = append(written, u)
+		}
+		if len(writes) == 0 {
+			break
+		}
+
+		_, err := db.Dynamo.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: writes})
+		if err == nil {
+			break
+		}
+		tce, ok := err.(*dynamodb.TransactionCanceledException)
+		if !ok || attempt == maxItemUpdateAttempts {
+			return fmt.Errorf("failed to transact write items: %v", err)
+		}
+		// Updates whose condition failed are left out if their item is gone, and otherwise retried with the item's
+		// current tags. The other updates were cancelled along with them and are retried as they are.
+		pending = nil
+		for i, u := range written {
+			if i >= len(tce.CancellationReasons) || aws.StringValue(tce.CancellationReasons[i].Code) != "ConditionalCheckFailed" {
+				pending = append(pending, u)
+				continue
+			}
+			current := tce.CancellationReasons[i].Item
+			item := model.YataItem{}
+			if err := dynamodbattribute.UnmarshalMap(current, &item); err != nil {
+				return fmt.Errorf("failed to unmarshal map: %v", err)
+			}
+			if current == nil || item.DeletedAt != nil {
+				missing = append(missing, u)
+				continue
+			}
+			u.Tags = item.Tags
+			pending = append(pending, u)
+		}
+	}
+
+	if len(missing) != 0 {
+		return ItemsNotFoundError{Updates: missing}
+	}
+	return nil
+}
+
+// itemUpdateWrite returns the conditional write of an item update, or nil if the update does not change the item. The
+// write only applies to an item that exists and is not in the trash, and, for tag updates, whose tags are still the
+// update's Tags as far as the update depends on them. The item is returned if the condition fails.
+func (db *DynamoDbYataDatabase) itemUpdateWrite(u ItemUpdate) (*dynamodb.Update, error) {
+	w := &dynamodb.Update{
+		TableName:                           aws.String(db.ItemsTableName),
+		Key:                                 itemKey(u.UserID, u.ListID, u.ItemID),
+		ConditionExpression:                 aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
+	}
+	switch {
+	case u.Completed != nil:
+		w.UpdateExpression = aws.String("SET Completed = :completed")
+		w.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":completed": {BOOL: u.Completed},
+		}
+	case u.DeletedAt != nil:
+		at, err := dynamodbattribute.Marshal(u.DeletedAt.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal: %v", err)
+		}
+		// Trashed items are put in the trash index, as setDeletedAt does.
+		w.UpdateExpression = aws.String("SET DeletedAt = :deletedAt, Trash = :trash")
+		w.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":deletedAt": at,
+			":trash":     {S: aws.String(trashPartition)},
+		}
+	case len(u.AddTag) != 0:
+		for _, t := range u.Tags {
+			if t == u.AddTag {
+				return nil, nil
+			}
+		}
+		w.ConditionExpression = aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt) AND NOT contains(Tags, :tag)")
+		w.UpdateExpression = aws.String("SET Tags = list_append(if_not_exists(Tags, :empty), :tags)")
+		w.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":tag":   {S: aws.String(u.AddTag)},
+			":tags":  {L: []*dynamodb.AttributeValue{{S: aws.String(u.AddTag)}}},
+			":empty": {L: []*dynamodb.AttributeValue{}},
+		}
+	case len(u.RemoveTag) != 0:
+		i := -1
+		for j, t := range u.Tags {
+			if t == u.RemoveTag {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return nil, nil
+		}
+		// The tag is removed by its index, so the update only applies if the tag is still there.
+		path := fmt.Sprintf("Tags[%d]", i)
+		w.ConditionExpression = aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt) AND " + path + " = :tag")
+		w.UpdateExpression = aws.String("REMOVE " + path)
+		w.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":tag": {S: aws.String(u.RemoveTag)},
+		}
+	default:
+		return nil, nil
+	}
+	return w, nil
+}
+
+func (db *DynamoDbYataDatabase) MoveItems(moves []ItemMove) error {
+	var writes []*dynamodb.TransactWriteItem
+	// errs holds the error to return if the write with the same index fails its condition.
+	var errs []error
+	for _, m := range moves {
+		av, err := dynamodbattribute.MarshalMap(m.To)
+		if err != nil {
+			return fmt.Errorf("failed to marshal map: %v", err)
+		}
+		av["ListID-ItemID"] = itemKey(m.To.UserID, m.To.ListID, m.To.ItemID)["ListID-ItemID"]
+		writes = append(writes,
+			&dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+				TableName:           aws.String(db.ItemsTableName),
+				Key:                 itemKey(m.From.UserID, m.From.ListID, m.From.ItemID),
+				ConditionExpression: aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)"),
+			}},
+			&dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+				TableName:           aws.String(db.ItemsTableName),
+				Item:                av,
+				ConditionExpression: aws.String("attribute_not_exists(UserID)"),
+			}},
+		)
+		errs = append(errs,
+			ItemNotFoundError{uid: m.From.UserID, lid: m.From.ListID, iid: m.From.ItemID},
+			ItemExistsError{uid: m.To.UserID, lid: m.To.ListID, iid: m.To.ItemID},
+		)
+
+		for _, a := range m.Attachments {
+			moved := a
+			moved.UserID = m.To.UserID
+			moved.ListID = m.To.ListID
+			moved.ItemID = m.To.ItemID
+			av, err := dynamodbattribute.MarshalMap(moved)
+			if err != nil {
+				return fmt.Errorf("failed to marshal map: %v", err)
+			}
+			av["ListID-ItemID-AttachmentID"] = attachmentKey(moved.UserID, moved.ListID, moved.ItemID, moved.AttachmentID)["ListID-ItemID-AttachmentID"]
+			writes = append(writes,
+				&dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+					TableName: aws.String(db.AttachmentsTableName),
+					Key:       attachmentKey(a.UserID, a.ListID, a.ItemID, a.AttachmentID),
+				}},
+				&dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+					TableName: aws.String(db.AttachmentsTableName),
+					Item:      av,
+				}},
+			)
+			errs = append(errs, nil, nil)
+		}
+	}
+	if len(writes) > MaxMoveWrites {
+		return TooManyWritesError{writes: len(writes), max: MaxMoveWrites}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	_, err := db.Dynamo.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	if err != nil {
+		if tce, ok := err.(*dynamodb.TransactionCanceledException); ok {
+			for i, reason := range tce.CancellationReasons {
+				if i < len(errs) && errs[i] != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+					return errs[i]
+				}
+			}
+		}
+		return fmt.Errorf("failed to transact write items: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteItem(uid model.UserID, lid model.ListID, iid model.ItemID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.ItemsTableName),
+		Key:       itemKey(uid, lid, iid),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) TrashItem(uid model.UserID, lid model.ListID, iid model.ItemID, deletedAt time.Time) error {
+	err := db.setDeletedAt(db.ItemsTableName, itemKey(uid, lid, iid), &deletedAt)
+	if err == errDeletedAtNotChanged {
+		return ItemNotFoundError{
+			uid: uid,
+			lid: lid,
+			iid: iid,
+		}
+	}
+	return err
+}
+
+func (db *DynamoDbYataDatabase) RestoreItem(uid model.UserID, lid model.ListID, iid model.ItemID) error {
+	err := db.setDeletedAt(db.ItemsTableName, itemKey(uid, lid, iid), nil)
+	if err == errDeletedAtNotChanged {
+		return ItemNotFoundError{
+			uid: uid,
+			lid: lid,
+			iid: iid,
+		}
+	}
+	return err
+}
+
+func (db *DynamoDbYataDatabase) GetTrash(uid model.UserID) ([]model.YataList, []model.YataItem, error) {
+	trashed := func(table string) ([]map[string]*dynamodb.AttributeValue, error) {
+		results, err := db.queryAll(&dynamodb.QueryInput{
+			TableName:              aws.String(table),
+			IndexName:              aws.String(UserTrashIndexName),
+			KeyConditionExpression: aws.String("UserID = :user"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":user": {
+					S: aws.String(string(uid)),
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %v", table, err)
+		}
+		return results, nil
+	}
+
+	listResults, err := trashed(db.ListsTableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	lists := []model.YataList{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(listResults, &lists); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+
+	itemResults, err := trashed(db.ItemsTableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := []model.YataItem{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(itemResults, &items); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return lists, items, nil
+}
+
+func (db *DynamoDbYataDatabase) GetExpiredTrash(before time.Time) ([]model.YataList, []model.YataItem, error) {
+	// DeletedAt is stored without trailing zeros in its fraction of a second, so it only sorts as a string to the second.
+	// The query returns everything trashed before the second after before, and the rest is left out here.
+	bound := before.UTC().Truncate(time.Second).Add(time.Second).Format(time.RFC3339)
+	trashed := func(table string) ([]map[string]*dynamodb.AttributeValue, error) {
+		results, err := db.queryAll(&dynamodb.QueryInput{
+			TableName:              aws.String(table),
+			IndexName:              aws.String(TrashIndexName),
+			KeyConditionExpression: aws.String("Trash = :trash AND DeletedAt < :before"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":trash": {
+					S: aws.String(trashPartition),
+				},
+				":before": {
+					S: aws.String(bound),
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %v", table, err)
+		}
+		return results, nil
+	}
+
+	listResults, err := trashed(db.ListsTableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var trashedLists []model.YataList
+	if err := dynamodbattribute.UnmarshalListOfMaps(listResults, &trashedLists); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	lists := []model.YataList{}
+	for _, yl := range trashedLists {
+		if yl.DeletedAt.Before(before) {
+			lists = append(lists, yl)
+		}
+	}
+
+	itemResults, err := trashed(db.ItemsTableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var trashedItems []model.YataItem
+	if err := dynamodbattribute.UnmarshalListOfMaps(itemResults, &trashedItems); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	items := []model.YataItem{}
+	for _, item := range trashedItems {
+		if item.DeletedAt.Before(before) {
+			items = append(items, item)
+		}
+	}
+	return lists, items, nil
+}
+
+// errDeletedAtNotChanged is returned by setDeletedAt when there is nothing to move to, or restore from, the trash.
+var errDeletedAtNotChanged = errors.New("deletedAt not changed")
+
+// setDeletedAt moves the record with the key to the trash, or restores it from the trash if deletedAt is nil. Records
+// in the trash are put in the trash index, in UTC so that they sort by when they were trashed.
+func (db *DynamoDbYataDatabase) setDeletedAt(table string, key map[string]*dynamodb.AttributeValue, deletedAt *time.Time) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key:       key,
+	}
+	if deletedAt != nil {
+		at, err := dynamodbattribute.Marshal(deletedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %v", err)
+		}
+		input.ConditionExpression = aws.String("attribute_exists(UserID) AND attribute_not_exists(DeletedAt)")
+		input.UpdateExpression = aws.String("SET DeletedAt = :deletedAt, Trash = :trash")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":deletedAt": at,
+			":trash": {
+				S: aws.String(trashPartition),
+			},
+		}
+	} else {
+		input.ConditionExpression = aws.String("attribute_exists(DeletedAt)")
+		input.UpdateExpression = aws.String("REMOVE DeletedAt, Trash")
+	}
+
+	if _, err := db.Dynamo.UpdateItem(input); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return errDeletedAtNotChanged
+		}
+		return fmt.Errorf("failed to update item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetListSections(uid model.UserID, lid model.ListID) ([]model.YataSection, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.SectionsTableName),
+		KeyConditionExpression: aws.String("UserID = :user AND begins_with(#sectionKey, :list)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(uid)),
+			},
+			":list": {
+				S: aws.String(string(lid) + ":"),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#sectionKey": aws.String("ListID-SectionID"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	sections := []model.YataSection{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &sections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+
+	// Keys are split on ":" which may also appear in IDs so the prefix can match sections of other lists.
+	listSections := []model.YataSection{}
+	for _, section := range sections {
+		if section.ListID == lid {
+			listSections = append(listSections, section)
+		}
+	}
+	return listSections, nil
+}
+
+func (db *DynamoDbYataDatabase) GetSection(uid model.UserID, lid model.ListID, sid model.SectionID) (model.YataSection, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.SectionsTableName),
+		Key:       sectionKey(uid, lid, sid),
+	})
+	if err != nil {
+		return model.YataSection{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataSection{}, SectionNotFoundError{
+			uid: uid,
+			lid: lid,
+			sid: sid,
+		}
+	}
+
+	section := model.YataSection{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &section)
+	if err != nil {
+		return model.YataSection{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return section, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertSection(section model.YataSection) error {
+	av, err := dynamodbattribute.MarshalMap(section)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	av["ListID-SectionID"] = sectionKey(section.UserID, section.ListID, section.SectionID)["ListID-SectionID"]
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(db.SectionsTableName),
+		Item:      av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteSection(uid model.UserID, lid model.ListID, sid model.SectionID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.SectionsTableName),
+		Key:       sectionKey(uid, lid, sid),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetAllAttachments(uid model.UserID) ([]model.YataAttachment, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.AttachmentsTableName),
+		KeyConditionExpression: aws.String("UserID = :user"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(uid)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	attachments := []model.YataAttachment{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &attachments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return attachments, nil
+}
+
+func (db *DynamoDbYataDatabase) GetItemAttachments(uid model.UserID, lid model.ListID, iid model.ItemID) ([]model.YataAttachment, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.AttachmentsTableName),
+		KeyConditionExpression: aws.String("UserID = :user AND begins_with(#attachmentKey, :item)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(uid)),
+			},
+			":item": {
+				S: aws.String(string(lid) + ":" + string(iid) + ":"),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#attachmentKey": aws.String("ListID-ItemID-AttachmentID"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	attachments := []model.YataAttachment{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &attachments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+
+	// Keys are split on ":" which may also appear in IDs so the prefix can match attachments of other items.
+	itemAttachments := []model.YataAttachment{}
+	for _, a := range attachments {
+		if a.ListID == lid && a.ItemID == iid {
+			itemAttachments = append(itemAttachments, a)
+		}
+	}
+	return itemAttachments, nil
+}
+
+func (db *DynamoDbYataDatabase) GetAttachment(uid model.UserID, lid model.ListID, iid model.ItemID, aid model.AttachmentID) (model.YataAttachment, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.AttachmentsTableName),
+		Key:       attachmentKey(uid, lid, iid, aid),
+	})
+	if err != nil {
+		return model.YataAttachment{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataAttachment{}, AttachmentNotFoundError{
+			uid: uid,
+			lid: lid,
+			iid: iid,
+			aid: aid,
+		}
+	}
+
+	a := model.YataAttachment{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &a)
+	if err != nil {
+		return model.YataAttachment{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return a, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertAttachment(a model.YataAttachment) error {
+	av, err := dynamodbattribute.MarshalMap(a)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	av["ListID-ItemID-AttachmentID"] = attachmentKey(a.UserID, a.ListID, a.ItemID, a.AttachmentID)["ListID-ItemID-AttachmentID"]
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(db.AttachmentsTableName),
+		Item:      av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteAttachment(uid model.UserID, lid model.ListID, iid model.ItemID, aid model.AttachmentID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.AttachmentsTableName),
+		Key:       attachmentKey(uid, lid, iid, aid),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetListMembers(owner model.UserID, lid model.ListID) ([]model.YataListMember, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.MembersTableName),
+		KeyConditionExpression: aws.String("#list = :list"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":list": {
+				S: aws.String(string(owner) + ":" + string(lid)),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#list": aws.String("OwnerID-ListID"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	members := []model.YataListMember{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &members)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return members, nil
+}
+
+func (db *DynamoDbYataDatabase) GetListMember(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.MembersTableName),
+		Key:       memberKey(owner, lid, member),
+	})
+	if err != nil {
+		return model.YataListMember{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataListMember{}, MemberNotFoundError{
+			owner:  owner,
+			lid:    lid,
+			member: member,
+		}
+	}
+
+	m := model.YataListMember{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &m)
+	if err != nil {
+		return model.YataListMember{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return m, nil
+}
+
+func (db *DynamoDbYataDatabase) GetMemberships(member model.UserID) ([]model.YataListMember, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.MembersTableName),
+		IndexName:              aws.String(MembersUserIndexName),
+		KeyConditionExpression: aws.String("UserID = :user"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":user": {
+				S: aws.String(string(member)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	members := []model.YataListMember{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &members)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return members, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertListMember(m model.YataListMember) error {
+	av, err := dynamodbattribute.MarshalMap(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	av["OwnerID-ListID"] = memberKey(m.OwnerID, m.ListID, m.UserID)["OwnerID-ListID"]
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(db.MembersTableName),
+		Item:      av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteListMember(owner model.UserID, lid model.ListID, member model.UserID) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.MembersTableName),
+		Key:       memberKey(owner, lid, member),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetShareLink(token string) (model.YataShareLink, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.ShareLinksTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Token": {
+				S: aws.String(token),
+			},
+		},
+	})
+	if err != nil {
+		return model.YataShareLink{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataShareLink{}, ShareLinkNotFoundError{}
+	}
+
+	sl := model.YataShareLink{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &sl)
+	if err != nil {
+		return model.YataShareLink{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return sl, nil
+}
+
+func (db *DynamoDbYataDatabase) GetListShareLinks(uid model.UserID, lid model.ListID) ([]model.YataShareLink, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.ShareLinksTableName),
+		IndexName:              aws.String(ShareLinksListIndexName),
+		KeyConditionExpression: aws.String("#list = :list"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":list": {
+				S: aws.String(string(uid) + ":" + string(lid)),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#list": aws.String("OwnerID-ListID"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	links := []model.YataShareLink{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return links, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertShareLink(sl model.YataShareLink) error {
+	av, err := dynamodbattribute.MarshalMap(sl)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	av["OwnerID-ListID"] = &dynamodb.AttributeValue{
+		S: aws.String(string(sl.UserID) + ":" + string(sl.ListID)),
+	}
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(db.ShareLinksTableName),
+		ConditionExpression: aws.String("attribute_not_exists(#token)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#token": aws.String("Token"),
+		},
+		Item: av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteShareLink(token string) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.ShareLinksTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Token": {
+				S: aws.String(token),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetCalendarFeed(token string) (model.YataCalendarFeed, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.CalendarFeedsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Token": {
+				S: aws.String(token),
+			},
+		},
+	})
+	if err != nil {
+		return model.YataCalendarFeed{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataCalendarFeed{}, CalendarFeedNotFoundError{}
+	}
+
+	feed := model.YataCalendarFeed{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &feed)
+	if err != nil {
+		return model.YataCalendarFeed{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return feed, nil
+}
+
+func (db *DynamoDbYataDatabase) GetCalendarFeeds(uid model.UserID) ([]model.YataCalendarFeed, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.CalendarFeedsTableName),
+		IndexName:              aws.String(CalendarFeedsUserIndexName),
+		KeyConditionExpression: aws.String("UserID = :uid"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":uid": {
+				S: aws.String(string(uid)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	feeds := []model.YataCalendarFeed{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &feeds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return feeds, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertCalendarFeed(feed model.YataCalendarFeed) error {
+	av, err := dynamodbattribute.MarshalMap(feed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(db.CalendarFeedsTableName),
+		ConditionExpression: aws.String("attribute_not_exists(#token)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#token": aws.String("Token"),
+		},
+		Item: av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteCalendarFeed(token string) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.CalendarFeedsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Token": {
+				S: aws.String(token),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetAppPassword(hash string) (model.YataAppPassword, error) {
+	queryResults, err := db.Dynamo.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.AppPasswordsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Hash": {
+				S: aws.String(hash),
+			},
+		},
+	})
+	if err != nil {
+		return model.YataAppPassword{}, fmt.Errorf("failed to get item: %v", err)
+	}
+
+	if queryResults.Item == nil {
+		return model.YataAppPassword{}, AppPasswordNotFoundError{}
+	}
+
+	p := model.YataAppPassword{}
+	err = dynamodbattribute.UnmarshalMap(queryResults.Item, &p)
+	if err != nil {
+		return model.YataAppPassword{}, fmt.Errorf("failed to unmarshal map: %v", err)
+	}
+	return p, nil
+}
+
+func (db *DynamoDbYataDatabase) GetAppPasswords(uid model.UserID) ([]model.YataAppPassword, error) {
+	results, err := db.queryAll(&dynamodb.QueryInput{
+		TableName:              aws.String(db.AppPasswordsTableName),
+		IndexName:              aws.String(AppPasswordsUserIndexName),
+		KeyConditionExpression: aws.String("UserID = :uid"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":uid": {
+				S: aws.String(string(uid)),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+
+	passwords := []model.YataAppPassword{}
+	err = dynamodbattribute.UnmarshalListOfMaps(results, &passwords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+	return passwords, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertAppPassword(p model.YataAppPassword) error {
+	av, err := dynamodbattribute.MarshalMap(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(db.AppPasswordsTableName),
+		ConditionExpression: aws.String("attribute_not_exists(#hash)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#hash": aws.String("Hash"),
+		},
+		Item: av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) DeleteAppPassword(hash string) error {
+	_, err := db.Dynamo.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.AppPasswordsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Hash": {
+				S: aws.String(hash),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %v", err)
+	}
+	return nil
+}
+
+func (db *DynamoDbYataDatabase) GetListActivity(owner model.UserID, lid model.ListID, limit int64, pageToken string) ([]model.YataActivity, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(db.ActivityTableName),
+		KeyConditionExpression: aws.String("#list = :list"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":list": {
+				S: aws.String(string(owner) + ":" + string(lid)),
+			},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#list": aws.String("OwnerID-ListID"),
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(limit),
+	}
+	if len(pageToken) != 0 {
+		eventID, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil {
+			return nil, "", InvalidPageTokenError{}
+		}
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"OwnerID-ListID": input.ExpressionAttributeValues[":list"],
+			"EventID": {
+				S: aws.String(string(eventID)),
+			},
+		}
+	}
+	queryResults, err := db.Dynamo.Query(input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query: %v", err)
+	}
+
+	activities := []model.YataActivity{}
+	err = dynamodbattribute.UnmarshalListOfMaps(queryResults.Items, &activities)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal list of maps: %v", err)
+	}
+
+	var next string
+	if last, ok := queryResults.LastEvaluatedKey["EventID"]; ok && last.S != nil {
+		next = base64.RawURLEncoding.EncodeToString([]byte(*last.S))
+	}
+	return activities, next, nil
+}
+
+func (db *DynamoDbYataDatabase) InsertActivity(a model.YataActivity) error {
+	av, err := dynamodbattribute.MarshalMap(a)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %v", err)
+	}
+	av["OwnerID-ListID"] = &dynamodb.AttributeValue{
+		S: aws.String(string(a.UserID) + ":" + string(a.ListID)),
+	}
+	_, err = db.Dynamo.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(db.ActivityTableName),
+		Item:      av,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put item: %v", err)
+	}
+	return nil
+}
+
+// queryAll runs a query and returns the results of every page.
+func (db *DynamoDbYataDatabase) queryAll(input *dynamodb.QueryInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	var results []map[string]*dynamodb.AttributeValue
+	err := db.Dynamo.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		results = append(results, page.Items...)
+		return true
+	})
+	return results, err
+}
+
+// listKey returns the primary key of a list in the lists table.
+func listKey(uid model.UserID, lid model.ListID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"UserID": {
+			S: aws.String(string(uid)),
+		},
+		"ListID": {
+			S: aws.String(string(lid)),
+		},
+	}
+}
+
+// folderKey returns the primary key of a folder in the folders table.
+func folderKey(uid model.UserID, fid model.FolderID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"UserID": {
+			S: aws.String(string(uid)),
+		},
+		"FolderID": {
+			S: aws.String(string(fid)),
+		},
+	}
+}
+
+// itemKey returns the primary key of an item in the items table.
+func itemKey(uid model.UserID, lid model.ListID, iid model.ItemID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"UserID": {
+			S: aws.String(string(uid)),
+		},
+		"ListID-ItemID": {
+			S: aws.String(string(lid) + ":" + string(iid)),
+		},
+	}
+}
+
+// sectionKey returns the primary key of a section in the sections table.
+func sectionKey(uid model.UserID, lid model.ListID, sid model.SectionID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"UserID": {
+			S: aws.String(string(uid)),
+		},
+		"ListID-SectionID": {
+			S: aws.String(string(lid) + ":" + string(sid)),
+		},
+	}
+}
+
+// attachmentKey returns the primary key of an attachment in the attachments table.
+func attachmentKey(uid model.UserID, lid model.ListID, iid model.ItemID, aid model.AttachmentID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"UserID": {
+			S: aws.String(string(uid)),
+		},
+		"ListID-ItemID-AttachmentID": {
+			S: aws.String(string(lid) + ":" + string(iid) + ":" + string(aid)),
+		},
+	}
+}
+
+// memberKey returns the primary key of a list member in the members table.
+// User IDs never contain ":" so, unlike the other tables' keys, the owner and list can always be told apart.
+func memberKey(owner model.UserID, lid model.ListID, member model.UserID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"OwnerID-ListID": {
+			S: aws.String(string(owner) + ":" + string(lid)),
+		},
+		"UserID": {
+			S: aws.String(string(member)),
+		},
+	}
+}
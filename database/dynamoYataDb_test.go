@@ -0,0 +1,520 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDatabase returns a database whose DynamoDB calls are answered by handle, which is given the name of the
+// operation, such as "Query", and the request body. handle returns the operation's output, or an error to respond with.
+// The returned server must be closed once the test is done.
+func newTestDatabase(t *testing.T, handle func(op string, body []byte) (interface{}, error)) (*DynamoDbYataDatabase, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+		out, err := handle(op, body)
+		if tce, ok := err.(*dynamodb.TransactionCanceledException); ok {
+			// The cancellation reasons are sent along with the error.
+			b, err := jsonutil.BuildJSON(tce)
+			require.NoError(t, err)
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#` + dynamodb.ErrCodeTransactionCanceledException + `",`))
+			_, _ = w.Write(b[1:])
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#` + err.Error() + `","message":"` + err.Error() + `"}`))
+			return
+		}
+		b, err := jsonutil.BuildJSON(out)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		_, _ = w.Write(b)
+	}))
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:    aws.String(srv.URL),
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	require.NoError(t, err)
+	db := &DynamoDbYataDatabase{
+		ListsTableName:       "ListTable",
+		ItemsTableName:       "ItemsTable",
+		AttachmentsTableName: "AttachmentsTable",
+		MembersTableName:     "MembersTable",
+		Dynamo:               dynamodb.New(sess),
+	}
+	return db, srv
+}
+
+// decodeInput decodes the body of a DynamoDB request into the operation's input, such as a *dynamodb.QueryInput.
+func decodeInput(t *testing.T, body []byte, input interface{}) {
+	require.NoError(t, jsonutil.UnmarshalJSON(input, bytes.NewReader(body)))
+}
+
+// marshalItems marshals values into DynamoDB items.
+func marshalItems(t *testing.T, values ...interface{}) []map[string]*dynamodb.AttributeValue {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, v := range values {
+		av, err := dynamodbattribute.MarshalMap(v)
+		require.NoError(t, err)
+		items = append(items, av)
+	}
+	return items
+}
+
+func TestDynamoDbYataDatabase_GetListItems(t *testing.T) {
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		assert.Equal(t, "me", aws.StringValue(input.ExpressionAttributeValues[":user"].S))
+		// Without the separator the prefix would also match lists such as "groceries-old".
+		assert.Equal(t, "groceries:", aws.StringValue(input.ExpressionAttributeValues[":list"].S))
+		return &dynamodb.QueryOutput{Items: marshalItems(t,
+			model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk"},
+			// List IDs can contain the separator, so "groceries:old"'s items share the prefix.
+			model.YataItem{UserID: "me", ListID: "groceries:old", ItemID: "eggs"},
+		)}, nil
+	})
+	defer srv.Close()
+
+	items, err := db.GetListItems("me", "groceries")
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataItem{{UserID: "me", ListID: "groceries", ItemID: "milk"}}, items)
+}
+
+func TestDynamoDbYataDatabase_UpdateItems(t *testing.T) {
+	completed := true
+	deletedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.FixedZone("PST", -8*60*60))
+	milk := ItemUpdate{UserID: "me", ListID: "groceries", ItemID: "milk", Tags: []string{"dairy", "weekly"}}
+
+	tests := map[string]struct {
+		change func(u *ItemUpdate)
+		// update and condition are the expressions written, if the update is written at all.
+		update    string
+		condition string
+		values    map[string]*dynamodb.AttributeValue
+	}{
+		"complete": {
+			change:    func(u *ItemUpdate) { u.Completed = &completed },
+			update:    "SET Completed = :completed",
+			condition: "attribute_exists(UserID) AND attribute_not_exists(DeletedAt)",
+			values:    map[string]*dynamodb.AttributeValue{":completed": {BOOL: aws.Bool(true)}},
+		},
+		"trash": {
+			change:    func(u *ItemUpdate) { u.DeletedAt = &deletedAt },
+			update:    "SET DeletedAt = :deletedAt, Trash = :trash",
+			condition: "attribute_exists(UserID) AND attribute_not_exists(DeletedAt)",
+			values: map[string]*dynamodb.AttributeValue{
+				":deletedAt": {S: aws.String("2021-01-01T08:00:00Z")},
+				":trash":     {S: aws.String("trash")},
+			},
+		},
+		"add-tag": {
+			change:    func(u *ItemUpdate) { u.AddTag = "organic" },
+			update:    "SET Tags = list_append(if_not_exists(Tags, :empty), :tags)",
+			condition: "attribute_exists(UserID) AND attribute_not_exists(DeletedAt) AND NOT contains(Tags, :tag)",
+			values: map[string]*dynamodb.AttributeValue{
+				":tag":   {S: aws.String("organic")},
+				":tags":  {L: []*dynamodb.AttributeValue{{S: aws.String("organic")}}},
+				":empty": {L: []*dynamodb.AttributeValue{}},
+			},
+		},
+		"add-tag-already-tagged": {
+			change: func(u *ItemUpdate) { u.AddTag = "weekly" },
+		},
+		"remove-tag": {
+			change:    func(u *ItemUpdate) { u.RemoveTag = "weekly" },
+			update:    "REMOVE Tags[1]",
+			condition: "attribute_exists(UserID) AND attribute_not_exists(DeletedAt) AND Tags[1] = :tag",
+			values:    map[string]*dynamodb.AttributeValue{":tag": {S: aws.String("weekly")}},
+		},
+		"remove-tag-not-tagged": {
+			change: func(u *ItemUpdate) { u.RemoveTag = "organic" },
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var writes []*dynamodb.Update
+			db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+				require.Equal(t, "TransactWriteItems", op)
+				var input dynamodb.TransactWriteItemsInput
+				decodeInput(t, body, &input)
+				for _, w := range input.TransactItems {
+					writes = append(writes, w.Update)
+				}
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+			defer srv.Close()
+
+			u := milk
+			test.change(&u)
+			require.NoError(t, db.UpdateItems([]ItemUpdate{u}))
+			if len(test.update) == 0 {
+				assert.Empty(t, writes)
+				return
+			}
+			require.Len(t, writes, 1)
+			assert.Equal(t, "ItemsTable", aws.StringValue(writes[0].TableName))
+			assert.Equal(t, "groceries:milk", aws.StringValue(writes[0].Key["ListID-ItemID"].S))
+			assert.Equal(t, test.update, aws.StringValue(writes[0].UpdateExpression))
+			assert.Equal(t, test.condition, aws.StringValue(writes[0].ConditionExpression))
+			assert.Equal(t, test.values, writes[0].ExpressionAttributeValues)
+			assert.Equal(t, dynamodb.ReturnValuesOnConditionCheckFailureAllOld, aws.StringValue(writes[0].ReturnValuesOnConditionCheckFailure))
+		})
+	}
+}
+
+func TestDynamoDbYataDatabase_UpdateItems_Retries(t *testing.T) {
+	deletedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	gone := ItemUpdate{UserID: "me", ListID: "groceries", ItemID: "gone", RemoveTag: "weekly", Tags: []string{"weekly"}}
+	trashed := ItemUpdate{UserID: "me", ListID: "groceries", ItemID: "trashed", RemoveTag: "weekly", Tags: []string{"weekly"}}
+	milk := ItemUpdate{UserID: "me", ListID: "groceries", ItemID: "milk", RemoveTag: "weekly", Tags: []string{"weekly", "dairy"}}
+	eggs := ItemUpdate{UserID: "me", ListID: "groceries", ItemID: "eggs", RemoveTag: "weekly", Tags: []string{"weekly"}}
+
+	var removed [][]string
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "TransactWriteItems", op)
+		var input dynamodb.TransactWriteItemsInput
+		decodeInput(t, body, &input)
+		var writes []string
+		for _, w := range input.TransactItems {
+			writes = append(writes, aws.StringValue(w.Update.Key["ListID-ItemID"].S)+" "+aws.StringValue(w.Update.UpdateExpression))
+		}
+		removed = append(removed, writes)
+		if len(removed) > 1 {
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}
+		// One item is gone, one is in the trash, milk's tags were reordered and eggs was only cancelled along with them.
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("ConditionalCheckFailed"), Item: marshalItems(t, model.YataItem{UserID: "me", ListID: "groceries", ItemID: "trashed", DeletedAt: &deletedAt})[0]},
+			{Code: aws.String("ConditionalCheckFailed"), Item: marshalItems(t, model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", Tags: []string{"dairy", "weekly"}})[0]},
+			{Code: aws.String("None")},
+		}}
+	})
+	defer srv.Close()
+
+	err := db.UpdateItems([]ItemUpdate{gone, trashed, milk, eggs})
+	assert.Equal(t, ItemsNotFoundError{Updates: []ItemUpdate{gone, trashed}}, err)
+	assert.Equal(t, [][]string{
+		{"groceries:gone REMOVE Tags[0]", "groceries:trashed REMOVE Tags[0]", "groceries:milk REMOVE Tags[0]", "groceries:eggs REMOVE Tags[0]"},
+		{"groceries:milk REMOVE Tags[1]", "groceries:eggs REMOVE Tags[0]"},
+	}, removed)
+}
+
+func TestDynamoDbYataDatabase_UpdateItems_TooMany(t *testing.T) {
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		t.Errorf("unexpected operation %s", op)
+		return nil, errors.New("UnknownOperationException")
+	})
+	defer srv.Close()
+
+	updates := make([]ItemUpdate, MaxItemUpdates+1)
+	assert.Equal(t, TooManyWritesError{writes: MaxItemUpdates + 1, max: MaxItemUpdates}, db.UpdateItems(updates))
+}
+
+func TestDynamoDbYataDatabase_UpdateList(t *testing.T) {
+	tests := map[string]struct {
+		err     error
+		wantErr error
+	}{
+		"exists": {},
+		"does-not-exist": {
+			err:     errors.New(dynamodb.ErrCodeConditionalCheckFailedException),
+			wantErr: ListNotFoundError{uid: "me", lid: "groceries"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+				require.Equal(t, "UpdateItem", op)
+				var input dynamodb.UpdateItemInput
+				decodeInput(t, body, &input)
+				assert.Equal(t, "groceries", aws.StringValue(input.Key["ListID"].S))
+				assert.Equal(t, "attribute_exists(UserID) AND attribute_not_exists(DeletedAt)", aws.StringValue(input.ConditionExpression))
+				// Fields that are set are replaced and empty optional fields are removed.
+				assert.Equal(t, "SET #a0 = :a0, #a2 = :a2, #a5 = :a5 REMOVE #a1, #a3, #a4, #a6, #a7", aws.StringValue(input.UpdateExpression))
+				assert.Equal(t, "Title", aws.StringValue(input.ExpressionAttributeNames["#a0"]))
+				assert.Equal(t, "Groceries", aws.StringValue(input.ExpressionAttributeValues[":a0"].S))
+				assert.Equal(t, "Color", aws.StringValue(input.ExpressionAttributeNames["#a2"]))
+				assert.Equal(t, "#1e90ff", aws.StringValue(input.ExpressionAttributeValues[":a2"].S))
+				assert.Equal(t, "FolderID", aws.StringValue(input.ExpressionAttributeNames["#a5"]))
+				assert.Equal(t, "Description", aws.StringValue(input.ExpressionAttributeNames["#a1"]))
+				return &dynamodb.UpdateItemOutput{}, test.err
+			})
+			defer srv.Close()
+
+			err := db.UpdateList(model.YataList{UserID: "me", ListID: "groceries", Title: "Groceries", Color: "#1e90ff", FolderID: "home"})
+			assert.Equal(t, test.wantErr, err)
+		})
+	}
+}
+
+func TestDynamoDbYataDatabase_SetListTemplate(t *testing.T) {
+	tests := map[string]struct {
+		err     error
+		wantErr error
+	}{
+		"exists": {},
+		"does-not-exist": {
+			err:     errors.New(dynamodb.ErrCodeConditionalCheckFailedException),
+			wantErr: ListNotFoundError{uid: "me", lid: "onboarding"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+				require.Equal(t, "UpdateItem", op)
+				var input dynamodb.UpdateItemInput
+				decodeInput(t, body, &input)
+				assert.Equal(t, "onboarding", aws.StringValue(input.Key["ListID"].S))
+				assert.Equal(t, "attribute_exists(UserID) AND attribute_not_exists(DeletedAt)", aws.StringValue(input.ConditionExpression))
+				assert.Equal(t, "SET #attr = :value", aws.StringValue(input.UpdateExpression))
+				assert.Equal(t, "Template", aws.StringValue(input.ExpressionAttributeNames["#attr"]))
+				assert.True(t, aws.BoolValue(input.ExpressionAttributeValues[":value"].BOOL))
+				return &dynamodb.UpdateItemOutput{}, test.err
+			})
+			defer srv.Close()
+
+			assert.Equal(t, test.wantErr, db.SetListTemplate("me", "onboarding", true))
+		})
+	}
+}
+
+func TestDynamoDbYataDatabase_GetExpiredTrash(t *testing.T) {
+	before := time.Date(2021, 1, 1, 12, 0, 0, 500000000, time.UTC)
+	early := time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC)
+	sameSecond := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	late := time.Date(2021, 1, 1, 12, 0, 0, 700000000, time.UTC)
+
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		// Only the trash is read, rather than every list and item.
+		assert.Equal(t, TrashIndexName, aws.StringValue(input.IndexName))
+		assert.Equal(t, "Trash = :trash AND DeletedAt < :before", aws.StringValue(input.KeyConditionExpression))
+		assert.Equal(t, "trash", aws.StringValue(input.ExpressionAttributeValues[":trash"].S))
+		assert.Equal(t, "2021-01-01T12:00:01Z", aws.StringValue(input.ExpressionAttributeValues[":before"].S))
+		if aws.StringValue(input.TableName) == "ListTable" {
+			return &dynamodb.QueryOutput{Items: marshalItems(t,
+				model.YataList{UserID: "me", ListID: "old", DeletedAt: &early},
+				// Trashed in the same second as before, but after it.
+				model.YataList{UserID: "me", ListID: "new", DeletedAt: &late},
+			)}, nil
+		}
+		return &dynamodb.QueryOutput{Items: marshalItems(t,
+			model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", DeletedAt: &sameSecond},
+		)}, nil
+	})
+	defer srv.Close()
+
+	lists, items, err := db.GetExpiredTrash(before)
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataList{{UserID: "me", ListID: "old", DeletedAt: &early}}, lists)
+	assert.Equal(t, []model.YataItem{{UserID: "me", ListID: "groceries", ItemID: "milk", DeletedAt: &sameSecond}}, items)
+}
+
+func TestDynamoDbYataDatabase_TrashItem(t *testing.T) {
+	deletedAt := time.Date(2021, 1, 1, 4, 0, 0, 0, time.FixedZone("PST", -8*60*60))
+
+	var writes []*dynamodb.UpdateItemInput
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "UpdateItem", op)
+		var input dynamodb.UpdateItemInput
+		decodeInput(t, body, &input)
+		writes = append(writes, &input)
+		return &dynamodb.UpdateItemOutput{}, nil
+	})
+	defer srv.Close()
+
+	require.NoError(t, db.TrashItem("me", "groceries", "milk", deletedAt))
+	require.NoError(t, db.RestoreItem("me", "groceries", "milk"))
+
+	require.Len(t, writes, 2)
+	// Trashed items are put in the trash index, when they were trashed in UTC so that the index sorts by it.
+	assert.Equal(t, "SET DeletedAt = :deletedAt, Trash = :trash", aws.StringValue(writes[0].UpdateExpression))
+	assert.Equal(t, "2021-01-01T12:00:00Z", aws.StringValue(writes[0].ExpressionAttributeValues[":deletedAt"].S))
+	assert.Equal(t, "trash", aws.StringValue(writes[0].ExpressionAttributeValues[":trash"].S))
+	// Restored items are taken out of it.
+	assert.Equal(t, "REMOVE DeletedAt, Trash", aws.StringValue(writes[1].UpdateExpression))
+}
+
+func TestDynamoDbYataDatabase_InsertList_Conflict(t *testing.T) {
+	deletedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		existing model.YataList
+		wantErr  error
+	}{
+		"exists": {
+			existing: model.YataList{UserID: "me", ListID: "groceries"},
+			wantErr:  ListExistsError{uid: "me", lid: "groceries"},
+		},
+		"in-trash": {
+			existing: model.YataList{UserID: "me", ListID: "groceries", DeletedAt: &deletedAt},
+			wantErr:  ListInTrashError{uid: "me", lid: "groceries"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+				switch op {
+				case "PutItem":
+					return nil, errors.New(dynamodb.ErrCodeConditionalCheckFailedException)
+				case "GetItem":
+					var input dynamodb.GetItemInput
+					decodeInput(t, body, &input)
+					// The list may have been written a moment ago.
+					assert.True(t, aws.BoolValue(input.ConsistentRead))
+					return &dynamodb.GetItemOutput{Item: marshalItems(t, test.existing)[0]}, nil
+				}
+				t.Errorf("unexpected operation %s", op)
+				return nil, errors.New("unexpected operation")
+			})
+			defer srv.Close()
+
+			assert.Equal(t, test.wantErr, db.InsertList("me", model.YataList{UserID: "me", ListID: "groceries"}))
+		})
+	}
+}
+
+func TestDynamoDbYataDatabase_GetTrash(t *testing.T) {
+	deletedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		// Only the user's trash is read, rather than every one of their lists and items.
+		assert.Equal(t, UserTrashIndexName, aws.StringValue(input.IndexName))
+		assert.Equal(t, "me", aws.StringValue(input.ExpressionAttributeValues[":user"].S))
+		if aws.StringValue(input.TableName) == "ListTable" {
+			return &dynamodb.QueryOutput{Items: marshalItems(t, model.YataList{UserID: "me", ListID: "groceries", DeletedAt: &deletedAt})}, nil
+		}
+		// Every page of trashed items is read.
+		if input.ExclusiveStartKey == nil {
+			return &dynamodb.QueryOutput{
+				Items:            marshalItems(t, model.YataItem{UserID: "me", ListID: "groceries", ItemID: "eggs", DeletedAt: &deletedAt}),
+				LastEvaluatedKey: itemKey("me", "groceries", "eggs"),
+			}, nil
+		}
+		return &dynamodb.QueryOutput{Items: marshalItems(t, model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", DeletedAt: &deletedAt})}, nil
+	})
+	defer srv.Close()
+
+	lists, items, err := db.GetTrash("me")
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataList{{UserID: "me", ListID: "groceries", DeletedAt: &deletedAt}}, lists)
+	assert.Equal(t, []model.YataItem{
+		{UserID: "me", ListID: "groceries", ItemID: "eggs", DeletedAt: &deletedAt},
+		{UserID: "me", ListID: "groceries", ItemID: "milk", DeletedAt: &deletedAt},
+	}, items)
+}
+
+func TestDynamoDbYataDatabase_GetFolderLists(t *testing.T) {
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		assert.Equal(t, "home", aws.StringValue(input.ExpressionAttributeValues[":folder"].S))
+		// The folder is a filter, so a page can hold none of its lists and still be followed by more.
+		if input.ExclusiveStartKey == nil {
+			return &dynamodb.QueryOutput{LastEvaluatedKey: listKey("me", "chores")}, nil
+		}
+		return &dynamodb.QueryOutput{Items: marshalItems(t, model.YataList{UserID: "me", ListID: "groceries", FolderID: "home"})}, nil
+	})
+	defer srv.Close()
+
+	lists, err := db.GetFolderLists("me", "home")
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataList{{UserID: "me", ListID: "groceries", FolderID: "home"}}, lists)
+}
+
+func TestDynamoDbYataDatabase_GetAllAttachments(t *testing.T) {
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		// Every page is read so that attachment quotas count every attachment.
+		if input.ExclusiveStartKey == nil {
+			return &dynamodb.QueryOutput{
+				Items:            marshalItems(t, model.YataAttachment{UserID: "me", ListID: "groceries", ItemID: "milk", AttachmentID: "a.png", Size: 10}),
+				LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"UserID": {S: aws.String("me")}},
+			}, nil
+		}
+		return &dynamodb.QueryOutput{Items: marshalItems(t, model.YataAttachment{UserID: "me", ListID: "groceries", ItemID: "milk", AttachmentID: "b.png", Size: 20})}, nil
+	})
+	defer srv.Close()
+
+	attachments, err := db.GetAllAttachments("me")
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataAttachment{
+		{UserID: "me", ListID: "groceries", ItemID: "milk", AttachmentID: "a.png", Size: 10},
+		{UserID: "me", ListID: "groceries", ItemID: "milk", AttachmentID: "b.png", Size: 20},
+	}, attachments)
+}
+
+func TestDynamoDbYataDatabase_GetMemberships(t *testing.T) {
+	db, srv := newTestDatabase(t, func(op string, body []byte) (interface{}, error) {
+		require.Equal(t, "Query", op)
+		var input dynamodb.QueryInput
+		decodeInput(t, body, &input)
+		assert.Equal(t, MembersUserIndexName, aws.StringValue(input.IndexName))
+		// Every page is read so that no shared list is left out.
+		if input.ExclusiveStartKey == nil {
+			return &dynamodb.QueryOutput{
+				Items:            marshalItems(t, model.YataListMember{OwnerID: "owner", ListID: "groceries", UserID: "me", Role: model.RoleEditor}),
+				LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"UserID": {S: aws.String("me")}},
+			}, nil
+		}
+		return &dynamodb.QueryOutput{Items: marshalItems(t, model.YataListMember{OwnerID: "owner", ListID: "chores", UserID: "me", Role: model.RoleViewer})}, nil
+	})
+	defer srv.Close()
+
+	memberships, err := db.GetMemberships("me")
+	require.NoError(t, err)
+	assert.Equal(t, []model.YataListMember{
+		{OwnerID: "owner", ListID: "groceries", UserID: "me", Role: model.RoleEditor},
+		{OwnerID: "owner", ListID: "chores", UserID: "me", Role: model.RoleViewer},
+	}, memberships)
+}
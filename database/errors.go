@@ -23,3 +23,128 @@ type ListExistsError struct {
 func (e ListExistsError) Error() string {
 	return fmt.Sprintf("list %q already exists for user %q", e.lid, e.uid)
 }
+
+// ListInTrashError is returned when a list cannot be created because the user has a list with its ID in the trash.
+type ListInTrashError struct {
+	uid model.UserID
+	lid model.ListID
+}
+
+func (e ListInTrashError) Error() string {
+	return fmt.Sprintf("list %q of user %q is in the trash", e.lid, e.uid)
+}
+
+type FolderNotFoundError struct {
+	uid model.UserID
+	fid model.FolderID
+}
+
+func (e FolderNotFoundError) Error() string {
+	return fmt.Sprintf("folder not found. UserID: %q, FolderID: %q", e.uid, e.fid)
+}
+
+type ItemNotFoundError struct {
+	uid model.UserID
+	lid model.ListID
+	iid model.ItemID
+}
+
+func (e ItemNotFoundError) Error() string {
+	return fmt.Sprintf("item not found. UserID: %q, ListID: %q, ItemID: %q", e.uid, e.lid, e.iid)
+}
+
+type ItemExistsError struct {
+	uid model.UserID
+	lid model.ListID
+	iid model.ItemID
+}
+
+func (e ItemExistsError) Error() string {
+	return fmt.Sprintf("item already exists. UserID: %q, ListID: %q, ItemID: %q", e.uid, e.lid, e.iid)
+}
+
+type SectionNotFoundError struct {
+	uid model.UserID
+	lid model.ListID
+	sid model.SectionID
+}
+
+func (e SectionNotFoundError) Error() string {
+	return fmt.Sprintf("section not found. UserID: %q, ListID: %q, SectionID: %q", e.uid, e.lid, e.sid)
+}
+
+type AttachmentNotFoundError struct {
+	uid model.UserID
+	lid model.ListID
+	iid model.ItemID
+	aid model.AttachmentID
+}
+
+func (e AttachmentNotFoundError) Error() string {
+	return fmt.Sprintf("attachment not found. UserID: %q, ListID: %q, ItemID: %q, AttachmentID: %q", e.uid, e.lid, e.iid, e.aid)
+}
+
+type MemberNotFoundError struct {
+	owner  model.UserID
+	lid    model.ListID
+	member model.UserID
+}
+
+func (e MemberNotFoundError) Error() string {
+	return fmt.Sprintf("member not found. OwnerID: %q, ListID: %q, UserID: %q", e.owner, e.lid, e.member)
+}
+
+type ShareLinkNotFoundError struct{}
+
+func (e ShareLinkNotFoundError) Error() string {
+	// The token is deliberately left out; it is a secret.
+	return "share link not found"
+}
+
+type CalendarFeedNotFoundError struct{}
+
+func (e CalendarFeedNotFoundError) Error() string {
+	// The token is deliberately left out; it is a secret.
+	return "calendar feed not found"
+}
+
+type AppPasswordNotFoundError struct{}
+
+func (e AppPasswordNotFoundError) Error() string {
+	// The token is deliberately left out; it is a secret.
+	return "app password not found"
+}
+
+type InvalidPageTokenError struct{}
+
+func (e InvalidPageTokenError) Error() string {
+	return "invalid page token"
+}
+
+// UnprocessedItemsError is returned when some items of a batch could not be written. Every other item was written.
+type UnprocessedItemsError struct {
+	Items []model.YataItem
+}
+
+func (e UnprocessedItemsError) Error() string {
+	return fmt.Sprintf("%d items were not processed", len(e.Items))
+}
+
+// ItemsNotFoundError is returned by UpdateItems when some of the items it updates do not exist or are in the trash.
+// Every other update was applied.
+type ItemsNotFoundError struct {
+	Updates []ItemUpdate
+}
+
+func (e ItemsNotFoundError) Error() string {
+	return fmt.Sprintf("%d items were not found", len(e.Updates))
+}
+
+type TooManyWritesError struct {
+	writes int
+	max    int
+}
+
+func (e TooManyWritesError) Error() string {
+	return fmt.Sprintf("too many writes. Writes: %d, Max: %d", e.writes, e.max)
+}
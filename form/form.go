@@ -0,0 +1,125 @@
+// Package form decodes form-encoded values, such as the body of an HTML form, into structs.
+package form
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MediaType is the media type of form-encoded values.
+const MediaType = "application/x-www-form-urlencoded"
+
+// ErrNotStruct is returned when values are decoded into something other than a pointer to a struct.
+var ErrNotStruct = errors.New("form: values can only be decoded into a pointer to a struct")
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Decode sets the fields of the struct v points to from values. Fields are named as they are in JSON, by their json
+// struct tag if they have one. Strings, booleans, numbers, types that implement encoding.TextUnmarshaler, such as
+// time.Time, and pointers to them are set from a field's first value; slices of them are set from all of its values.
+// Values without a matching field are ignored, as are fields without a value.
+func Decode(values url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	return decodeStruct(values, rv.Elem())
+}
+
+func decodeStruct(values url.Values, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if len(f.PkgPath) != 0 {
+			continue // Unexported.
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); len(tag) != 0 {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; len(n) != 0 {
+				name = n
+			}
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+		if err := set(rv.Field(i), vs); err != nil {
+			return fmt.Errorf("form: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// set sets v from the values of a field.
+func set(v reflect.Value, vs []string) error {
+	if v.Kind() == reflect.Slice && !isText(v.Type()) {
+		s := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+		for i, value := range vs {
+			if err := setOne(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setOne(v, vs[0])
+}
+
+// isText reports whether values of t, or of the type t points to, decode themselves from text.
+func isText(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setOne(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setOne(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if isText(v.Type()) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("cannot decode a form value into a %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package form
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type role string
+
+type input struct {
+	ItemID     string
+	Content    string `json:"content,omitempty"`
+	Hidden     string `json:"-"`
+	Completed  bool
+	Priority   int
+	Weight     float64
+	Role       role
+	DueAt      *time.Time
+	Tags       []string
+	Position   *int
+	Nested     struct{ A string }
+	unexported string
+}
+
+func TestDecode(t *testing.T) {
+	due := time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC)
+	three := 3
+
+	tests := map[string]struct {
+		query string
+		want  input
+		err   string
+	}{
+		"everything": {
+			query: "ItemID=milk&content=Oat+milk&Completed=true&Priority=2&Weight=1.5&Role=editor&DueAt=2021-01-31T17:00:00Z&Tags=dairy&Tags=vegan&Position=3",
+			want:  input{ItemID: "milk", Content: "Oat milk", Completed: true, Priority: 2, Weight: 1.5, Role: "editor", DueAt: &due, Tags: []string{"dairy", "vegan"}, Position: &three},
+		},
+		"first-value-wins": {
+			query: "ItemID=a&ItemID=b",
+			want:  input{ItemID: "a"},
+		},
+		"ignored": {
+			query: "Content=x&Hidden=x&unexported=x&Unknown=x",
+			want:  input{},
+		},
+		"bad-number": {
+			query: "Priority=high",
+			err:   `form: Priority: strconv.ParseInt: parsing "high": invalid syntax`,
+		},
+		"bad-time": {
+			query: "DueAt=tomorrow",
+			err:   `form: DueAt: parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`,
+		},
+		"unsupported": {
+			query: "Nested=x",
+			err:   "form: Nested: cannot decode a form value into a struct { A string }",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			values, err := url.ParseQuery(test.query)
+			assert.NoError(t, err)
+			var got input
+			err = Decode(values, &got)
+			if len(test.err) != 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestDecode_NotStruct(t *testing.T) {
+	var items []input
+	assert.Equal(t, ErrNotStruct, Decode(url.Values{}, &items))
+	assert.Equal(t, ErrNotStruct, Decode(url.Values{}, input{}))
+}
@@ -0,0 +1,302 @@
+// Package ical reads and writes iCalendar (RFC 5545) data, such as calendar feeds of to-dos and events.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MediaType is the media type of iCalendar data.
+const MediaType = "text/calendar"
+
+const (
+	// DateTimeLayout is the layout of a UTC date-time value.
+	DateTimeLayout = "20060102T150405Z"
+	// DateLayout is the layout of a date value.
+	DateLayout = "20060102"
+)
+
+// maxDepth is how deeply components can be nested in decoded data.
+const maxDepth = 10
+
+// maxLineOctets is the longest a content line can be before it is folded, not counting the line break.
+const maxLineOctets = 75
+
+// Param is a property parameter, such as VALUE=DATE.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Property is a single property of a component, such as "SUMMARY:Buy milk".
+type Property struct {
+	Name   string
+	Params []Param
+	// Value is written as it is; text values have to be escaped with Text first.
+	Value string
+}
+
+// Component is a calendar component, such as a VCALENDAR, VTODO or VEVENT.
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []Component
+}
+
+// Get returns the first of the component's properties called name.
+func (c Component) Get(name string) (Property, bool) {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// Param returns the value of the property's parameter called name, or an empty string if it does not have one.
+func (p Property) Param(name string) string {
+	for _, param := range p.Params {
+		if param.Name == name {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+// Add appends a property to the component.
+func (c *Component) Add(name, value string, params ...Param) {
+	c.Properties = append(c.Properties, Property{Name: name, Params: params, Value: value})
+}
+
+// Text escapes s as a text value.
+func Text(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// TextList escapes each of values as a text value and joins them into a single multi-valued property value.
+func TextList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = Text(v)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// DateTime formats t as a UTC date-time value.
+func DateTime(t time.Time) string {
+	return t.UTC().Format(DateTimeLayout)
+}
+
+// ParseText unescapes a text value.
+func ParseText(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i == len(v)-1 {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// ParseTextList splits a multi-valued property value at its unescaped commas and unescapes each of the values.
+func ParseTextList(v string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, ParseText(v[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, ParseText(v[start:]))
+}
+
+// ParseDateTime parses the value of a date or date-time property. Dates are midnight UTC. Date-times are in the time
+// zone of their TZID parameter, or UTC when it is not a time zone known to the system; floating date-times, which have
+// neither a TZID nor a trailing Z, are also treated as UTC.
+func ParseDateTime(p Property) (time.Time, error) {
+	if p.Param("VALUE") == "DATE" || len(p.Value) == len(DateLayout) {
+		return time.Parse(DateLayout, p.Value)
+	}
+	if strings.HasSuffix(p.Value, "Z") {
+		return time.Parse(DateTimeLayout, p.Value)
+	}
+	loc := time.UTC
+	if tzid := p.Param("TZID"); len(tzid) != 0 {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(strings.TrimSuffix(DateTimeLayout, "Z"), p.Value, loc)
+}
+
+// Decode reads a single component, and the components within it, from r. Property and parameter names are made upper
+// case, since they are case-insensitive; values are left as they are.
+func Decode(r io.Reader) (Component, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	var (
+		stack []Component
+		done  *Component
+		lines []string
+	)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if len(lines) != 0 && len(line) != 0 && (line[0] == ' ' || line[0] == '\t') {
+			// A folded line continues the previous one.
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return Component{}, err
+	}
+
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if done != nil {
+			return Component{}, fmt.Errorf("ical: line %d: content after the end of the %s", i+1, done.Name)
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return Component{}, fmt.Errorf("ical: line %d: %v", i+1, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			if len(stack) == maxDepth {
+				return Component{}, fmt.Errorf("ical: line %d: components are nested too deeply", i+1)
+			}
+			stack = append(stack, Component{Name: strings.ToUpper(p.Value)})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return Component{}, fmt.Errorf("ical: line %d: unexpected END:%s", i+1, p.Value)
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				done = &c
+			} else {
+				parent := &stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+		default:
+			if len(stack) == 0 {
+				return Component{}, fmt.Errorf("ical: line %d: property %s is not in a component", i+1, p.Name)
+			}
+			c := &stack[len(stack)-1]
+			c.Properties = append(c.Properties, p)
+		}
+	}
+	if done == nil {
+		return Component{}, errors.New("ical: no complete component")
+	}
+	return *done, nil
+}
+
+// parseLine parses an unfolded content line into a property.
+func parseLine(line string) (Property, error) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return Property{}, errors.New("missing property name")
+	}
+	p := Property{Name: strings.ToUpper(line[:end])}
+	rest := line[end:]
+	for rest[0] == ';' {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return Property{}, errors.New("missing parameter value")
+		}
+		param := Param{Name: strings.ToUpper(rest[1:eq])}
+		rest = rest[eq+1:]
+		// Parameter values end at the next unquoted semicolon or colon. Quotes are dropped.
+		var v strings.Builder
+		quoted := false
+		for len(rest) != 0 && (quoted || (rest[0] != ';' && rest[0] != ':')) {
+			if rest[0] == '"' {
+				quoted = !quoted
+			} else {
+				v.WriteByte(rest[0])
+			}
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return Property{}, errors.New("missing property value")
+		}
+		param.Value = v.String()
+		p.Params = append(p.Params, param)
+	}
+	p.Value = rest[1:]
+	return p, nil
+}
+
+// Encode writes c, and the components within it, to w.
+func (c Component) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	c.encode(bw)
+	return bw.Flush()
+}
+
+func (c Component) encode(bw *bufio.Writer) {
+	writeLine(bw, "BEGIN:"+c.Name)
+	for _, p := range c.Properties {
+		var line strings.Builder
+		line.WriteString(p.Name)
+		for _, param := range p.Params {
+			line.WriteString(";" + param.Name + "=" + paramValue(param.Value))
+		}
+		line.WriteString(":" + p.Value)
+		writeLine(bw, line.String())
+	}
+	for _, sub := range c.Components {
+		sub.encode(bw)
+	}
+	writeLine(bw, "END:"+c.Name)
+}
+
+// paramValue quotes a parameter value if it contains characters that are not allowed in an unquoted value.
+func paramValue(v string) string {
+	v = strings.ReplaceAll(v, `"`, "'")
+	if strings.ContainsAny(v, ";:,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// writeLine writes a content line, folding it so that no line is longer than 75 octets. Lines are only folded
+// between characters so that multi-octet UTF-8 sequences are kept whole.
+func writeLine(bw *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		bw.WriteString(line[:cut])
+		bw.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length.
+		limit = maxLineOctets - 1
+	}
+	bw.WriteString(line)
+	bw.WriteString("\r\n")
+}
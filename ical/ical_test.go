@@ -0,0 +1,172 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"plain":      {in: "Buy milk", want: "Buy milk"},
+		"specials":   {in: `a;b,c\d`, want: `a\;b\,c\\d`},
+		"new-lines":  {in: "one\r\ntwo\nthree", want: `one\ntwo\nthree`},
+		"colon-kept": {in: "Note: soon", want: "Note: soon"},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, Text(test.in))
+		})
+	}
+}
+
+func TestTextList(t *testing.T) {
+	assert.Equal(t, `home,a\,b`, TextList([]string{"home", "a,b"}))
+}
+
+func TestComponent_Encode(t *testing.T) {
+	todo := Component{Name: "VTODO"}
+	todo.Add("UID", "milk@groceries")
+	todo.Add("DUE", DateTime(time.Date(2021, 1, 31, 9, 0, 0, 0, time.FixedZone("PST", -8*60*60))))
+	todo.Add("SUMMARY", Text("Buy milk, eggs; bread"))
+	todo.Add("X-LABEL", "v", Param{Name: "LANG", Value: "en:us"})
+	cal := Component{Name: "VCALENDAR", Components: []Component{todo}}
+	cal.Add("VERSION", "2.0")
+
+	var buf bytes.Buffer
+	require.NoError(t, cal.Encode(&buf))
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTODO",
+		"UID:milk@groceries",
+		"DUE:20210131T170000Z",
+		`SUMMARY:Buy milk\, eggs\; bread`,
+		`X-LABEL;LANG="en:us":v`,
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), buf.String())
+}
+
+func TestComponent_Encode_Folding(t *testing.T) {
+	c := Component{Name: "VTODO"}
+	c.Add("SUMMARY", strings.Repeat("a", 70)+strings.Repeat("é", 40))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Encode(&buf))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	require.Len(t, lines, 5)
+	for _, line := range lines {
+		assert.True(t, len(line) <= 75, "line %q is %d octets", line, len(line))
+		assert.True(t, strings.ToValidUTF8(line, "") == line, "line %q splits a character", line)
+	}
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("a", 67), lines[1])
+	unfolded := strings.ReplaceAll(strings.Join(lines[1:4], "\r\n"), "\r\n ", "")
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("a", 70)+strings.Repeat("é", 40), unfolded)
+}
+
+func TestDecode(t *testing.T) {
+	in := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VTODO",
+		"uid:milk@groceries",
+		"SUMMARY:Buy milk\\, eggs and a very long list of other things that needs to be fo",
+		" lded",
+		`DUE;TZID="America/New_York":20210131T090000`,
+		`X-LABEL;LANG="en:us";X-EMPTY=:v:w`,
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	got, err := Decode(strings.NewReader(in))
+	require.NoError(t, err)
+	assert.Equal(t, "VCALENDAR", got.Name)
+	require.Len(t, got.Components, 1)
+	todo := got.Components[0]
+	assert.Equal(t, Component{
+		Name: "VTODO",
+		Properties: []Property{
+			{Name: "UID", Value: "milk@groceries"},
+			{Name: "SUMMARY", Value: `Buy milk\, eggs and a very long list of other things that needs to be folded`},
+			{Name: "DUE", Params: []Param{{Name: "TZID", Value: "America/New_York"}}, Value: "20210131T090000"},
+			{Name: "X-LABEL", Params: []Param{{Name: "LANG", Value: "en:us"}, {Name: "X-EMPTY"}}, Value: "v:w"},
+		},
+	}, todo)
+
+	var buf bytes.Buffer
+	require.NoError(t, got.Encode(&buf))
+	again, err := Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, got, again)
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":            "",
+		"unterminated":     "BEGIN:VTODO\r\nUID:a\r\n",
+		"mismatched-end":   "BEGIN:VTODO\r\nEND:VEVENT\r\n",
+		"outside":          "UID:a\r\n",
+		"no-value":         "BEGIN:VTODO\r\nUID\r\nEND:VTODO\r\n",
+		"trailing-content": "BEGIN:VTODO\r\nEND:VTODO\r\nBEGIN:VTODO\r\nEND:VTODO\r\n",
+		"too-deep":         strings.Repeat("BEGIN:X\r\n", maxDepth+1),
+	}
+
+	for name, in := range tests {
+		name, in := name, in
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			_, err := Decode(strings.NewReader(in))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseText(t *testing.T) {
+	assert.Equal(t, "a;b,c\\d\none\ntwo", ParseText(`a\;b\,c\\d\none\Ntwo`))
+	assert.Equal(t, []string{"home", "a,b", ""}, ParseTextList(`home,a\,b,`))
+	for _, s := range []string{"Buy milk", "a;b,c\\d", "one\ntwo"} {
+		assert.Equal(t, s, ParseText(Text(s)))
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := map[string]struct {
+		p    Property
+		want time.Time
+	}{
+		"utc":      {p: Property{Value: "20210131T170000Z"}, want: time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC)},
+		"floating": {p: Property{Value: "20210131T170000"}, want: time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC)},
+		"date":     {p: Property{Params: []Param{{Name: "VALUE", Value: "DATE"}}, Value: "20210131"}, want: time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC)},
+		"unknown-zone": {
+			p:    Property{Params: []Param{{Name: "TZID", Value: "Nowhere/Special"}}, Value: "20210131T170000"},
+			want: time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseDateTime(test.p)
+			require.NoError(t, err)
+			assert.True(t, test.want.Equal(got), "got %v", got)
+		})
+	}
+
+	_, err := ParseDateTime(Property{Value: "tomorrow"})
+	assert.Error(t, err)
+}
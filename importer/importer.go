@@ -0,0 +1,171 @@
+// Package importer reads lists exported from yata and from other to-do apps into the archive format, so that they can
+// all be imported the same way.
+//
+// Formats other than yata's own do not have IDs that fit yata's, so lists and sections are given IDs made from their
+// titles and items are numbered in the order they appear.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/TheYeung1/yata-server/archive"
+)
+
+// Format is a format that can be imported.
+type Format string
+
+const (
+	// FormatYata is the archive yata exports.
+	FormatYata Format = "yata"
+	// FormatTodoist is the CSV Todoist exports a project as.
+	FormatTodoist Format = "todoist"
+	// FormatMicrosoftToDo is a JSON array of Microsoft To Do task lists, as returned by Microsoft Graph, each with its
+	// tasks in a "tasks" field.
+	FormatMicrosoftToDo Format = "microsoft-todo"
+	// FormatMarkdown is a Markdown document of checklists.
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats are the formats that can be imported.
+var Formats = []Format{FormatYata, FormatTodoist, FormatMicrosoftToDo, FormatMarkdown}
+
+// DefaultTitle is the title given to lists that do not have one.
+const DefaultTitle = "Imported list"
+
+// maxIDLength is the longest ID the importer makes.
+const maxIDLength = 100
+
+// Options change how some formats are read.
+type Options struct {
+	// Title is the title of the list for formats that do not give it one, such as Todoist's. It defaults to
+	// DefaultTitle.
+	Title string
+}
+
+func (o Options) title() string {
+	if len(o.Title) == 0 {
+		return DefaultTitle
+	}
+	return o.Title
+}
+
+// Parse reads an import in the given format.
+func Parse(format Format, r io.Reader, opts Options) (archive.Archive, error) {
+	var lists []archive.List
+	var err error
+	switch format {
+	case FormatYata:
+		return archive.Read(r)
+	case FormatTodoist:
+		lists, err = parseTodoist(r, opts)
+	case FormatMicrosoftToDo:
+		lists, err = parseMicrosoftToDo(r)
+	case FormatMarkdown:
+		lists, err = ParseMarkdown(r, opts)
+	default:
+		return archive.Archive{}, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return archive.Archive{}, err
+	}
+	return archive.Archive{Version: archive.Version, Folders: []archive.Folder{}, Lists: lists}, nil
+}
+
+// idSet hands out IDs that are unique within the set.
+type idSet map[string]bool
+
+// add returns an ID made from title that is not already in the set, and adds it.
+func (ids idSet) add(title, fallback string) string {
+	base := slug(title)
+	if len(base) == 0 {
+		base = fallback
+	}
+	id := base
+	for n := 2; ids[id]; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		id = strings.TrimRight(truncate(base, maxIDLength-len(suffix)), "-") + suffix
+	}
+	ids[id] = true
+	return id
+}
+
+// slug returns s in lower case with every run of characters other than letters and digits replaced by a dash.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() != 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return strings.TrimRight(truncate(b.String(), maxIDLength), "-")
+}
+
+// truncate returns at most the first n bytes of s without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !isRuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xc0 != 0x80
+}
+
+// listBuilder builds a list whose items are given in order, with sub-tasks nested under the closest earlier item that
+// is indented less than them.
+type listBuilder struct {
+	list     archive.List
+	sections idSet
+	section  string
+	// parents are the items that later items can be nested under, from the least indented to the most.
+	parents []indentedItem
+}
+
+type indentedItem struct {
+	indent int
+	id     string
+}
+
+func newListBuilder(id, title string) *listBuilder {
+	return &listBuilder{
+		list:     archive.List{ListID: id, Title: title, Sections: []archive.Section{}, Items: []archive.Item{}},
+		sections: idSet{},
+	}
+}
+
+// addSection starts a new section. Items added after it are in the section.
+func (b *listBuilder) addSection(title string) {
+	b.section = b.sections.add(title, "section")
+	b.list.Sections = append(b.list.Sections, archive.Section{SectionID: b.section, Title: title, Position: len(b.list.Sections)})
+	b.parents = nil
+}
+
+// addItem adds item at the given indent, and returns its index in the list's items.
+func (b *listBuilder) addItem(indent int, item archive.Item) int {
+	for len(b.parents) != 0 && b.parents[len(b.parents)-1].indent >= indent {
+		b.parents = b.parents[:len(b.parents)-1]
+	}
+	item.ItemID = strconv.Itoa(len(b.list.Items) + 1)
+	item.SectionID = b.section
+	if len(b.parents) != 0 {
+		item.ParentID = b.parents[len(b.parents)-1].id
+	}
+	b.parents = append(b.parents, indentedItem{indent: indent, id: item.ItemID})
+	b.list.Items = append(b.list.Items, item)
+	return len(b.list.Items) - 1
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		format Format
+		input  string
+		lists  []archive.List
+		err    string
+	}{
+		"yata": {
+			format: FormatYata,
+			input:  `{"Version":1,"UserID":"someone","Folders":[],"Lists":[{"ListID":"a","Title":"A","Sections":[],"Items":[]}]}`,
+			lists:  []archive.List{{ListID: "a", Title: "A", Sections: []archive.Section{}, Items: []archive.Item{}}},
+		},
+		"yata-unsupported-version": {
+			format: FormatYata,
+			input:  `{"Version":9}`,
+			err:    "unsupported archive version",
+		},
+		"markdown": {
+			format: FormatMarkdown,
+			input:  "- [ ] One",
+			lists:  []archive.List{{ListID: "imported-list", Title: "Imported list", Sections: []archive.Section{}, Items: []archive.Item{{ItemID: "1", Content: "One"}}}},
+		},
+		"unknown-format": {
+			format: "xml",
+			err:    `unknown format "xml"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, err := Parse(test.format, bytes.NewBufferString(test.input), Options{})
+			if len(test.err) != 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, archive.Version, a.Version)
+			assert.Equal(t, test.lists, a.Lists)
+		})
+	}
+}
+
+func TestIDSet_Add(t *testing.T) {
+	ids := idSet{}
+	assert.Equal(t, "weekly-groceries", ids.add("Weekly  Groceries!", "list"))
+	assert.Equal(t, "weekly-groceries-2", ids.add("weekly groceries", "list"))
+	assert.Equal(t, "weekly-groceries-3", ids.add("Weekly groceries?", "list"))
+	assert.Equal(t, "list", ids.add("!!!", "list"))
+	assert.Equal(t, "einkäufe", ids.add("Einkäufe", "list"))
+
+	long := string(bytes.Repeat([]byte("ab "), 50))
+	assert.Len(t, ids.add(long, "list"), 100)
+	assert.Equal(t, "ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-ab-2", ids.add(long, "list"))
+}
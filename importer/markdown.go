@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/archive"
+)
+
+var (
+	// markdownTaskPattern matches a GitHub style task list item such as "- [x] Buy milk".
+	markdownTaskPattern = regexp.MustCompile(`^([ \t]*)[-*+][ \t]+\[([ xX])\][ \t]+(.*)$`)
+	// markdownHeadingPattern matches an ATX heading such as "## Dairy".
+	markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+)
+
+// ParseMarkdown reads the task lists in a Markdown document. Each top level heading starts a new list titled by it,
+// and lower level headings start sections. Tasks before the first top level heading are put on a list titled by
+// opts. Tasks are nested under the closest earlier task that is indented less than them. Anything else in the
+// document is ignored.
+func ParseMarkdown(r io.Reader, opts Options) ([]archive.List, error) {
+	var builders []*listBuilder
+	ids := idSet{}
+	current := func() *listBuilder {
+		if len(builders) == 0 {
+			builders = append(builders, newListBuilder(ids.add(opts.title(), "list"), opts.title()))
+		}
+		return builders[len(builders)-1]
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil {
+			title := strings.TrimSpace(m[2])
+			if len(title) == 0 {
+				continue
+			}
+			if len(m[1]) == 1 {
+				builders = append(builders, newListBuilder(ids.add(title, "list"), title))
+			} else {
+				current().addSection(title)
+			}
+			continue
+		}
+		if m := markdownTaskPattern.FindStringSubmatch(line); m != nil {
+			content := strings.TrimSpace(m[3])
+			if len(content) == 0 {
+				continue
+			}
+			current().addItem(indentWidth(m[1]), archive.Item{Content: content, Completed: m[2] != " "})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	lists := make([]archive.List, 0, len(builders))
+	for i, b := range builders {
+		b.list.Position = i
+		lists = append(lists, b.list)
+	}
+	return lists, nil
+}
+
+// indentWidth returns how wide the leading white space s is, counting tabs as four spaces.
+func indentWidth(s string) int {
+	return len(s) + 3*strings.Count(s, "\t")
+}
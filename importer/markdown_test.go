@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		lists []archive.List
+	}{
+		"headings": {
+			input: "# Groceries\n\nSome text that is ignored.\n\n- [ ] Bread\n## Dairy ##\n- [x] Milk\n  - [X] Oat\n    * [ ] Barista\n+ [ ] Cheese\n\n# Chores\n- [ ] Laundry\n- plain bullet\n- [ ]\n",
+			lists: []archive.List{
+				{
+					ListID:   "groceries",
+					Title:    "Groceries",
+					Sections: []archive.Section{{SectionID: "dairy", Title: "Dairy"}},
+					Items: []archive.Item{
+						{ItemID: "1", Content: "Bread"},
+						{ItemID: "2", SectionID: "dairy", Content: "Milk", Completed: true},
+						{ItemID: "3", ParentID: "2", SectionID: "dairy", Content: "Oat", Completed: true},
+						{ItemID: "4", ParentID: "3", SectionID: "dairy", Content: "Barista"},
+						{ItemID: "5", SectionID: "dairy", Content: "Cheese"},
+					},
+				},
+				{
+					ListID:   "chores",
+					Title:    "Chores",
+					Position: 1,
+					Sections: []archive.Section{},
+					Items:    []archive.Item{{ItemID: "1", Content: "Laundry"}},
+				},
+			},
+		},
+		"no-heading": {
+			input: "- [ ] One\n\t- [ ] Two\n- [ ] Three\r\n",
+			lists: []archive.List{
+				{
+					ListID:   "todo",
+					Title:    "Todo",
+					Sections: []archive.Section{},
+					Items: []archive.Item{
+						{ItemID: "1", Content: "One"},
+						{ItemID: "2", ParentID: "1", Content: "Two"},
+						{ItemID: "3", Content: "Three"},
+					},
+				},
+			},
+		},
+		"same-titles": {
+			input: "# A\n# A\n",
+			lists: []archive.List{
+				{ListID: "a", Title: "A", Sections: []archive.Section{}, Items: []archive.Item{}},
+				{ListID: "a-2", Title: "A", Position: 1, Sections: []archive.Section{}, Items: []archive.Item{}},
+			},
+		},
+		"empty": {
+			input: "",
+			lists: []archive.List{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lists, err := ParseMarkdown(bytes.NewBufferString(test.input), Options{Title: "Todo"})
+			require.NoError(t, err)
+			assert.Equal(t, test.lists, lists)
+		})
+	}
+}
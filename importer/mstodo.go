@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+)
+
+// htmlTagPattern matches the tags in the HTML bodies of Microsoft To Do tasks.
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+type microsoftToDoList struct {
+	DisplayName string              `json:"displayName"`
+	Tasks       []microsoftToDoTask `json:"tasks"`
+}
+
+type microsoftToDoTask struct {
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	Importance string `json:"importance"`
+	Body       struct {
+		Content     string `json:"content"`
+		ContentType string `json:"contentType"`
+	} `json:"body"`
+	DueDateTime     *microsoftToDoDateTime `json:"dueDateTime"`
+	CreatedDateTime string                 `json:"createdDateTime"`
+	Categories      []string               `json:"categories"`
+	ChecklistItems  []struct {
+		DisplayName string `json:"displayName"`
+		IsChecked   bool   `json:"isChecked"`
+	} `json:"checklistItems"`
+}
+
+type microsoftToDoDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// parseMicrosoftToDo reads Microsoft To Do task lists. The lists can be given as an array, or as the "value" of a
+// Microsoft Graph response. Checklist items become sub-tasks and categories become tags.
+func parseMicrosoftToDo(r io.Reader) ([]archive.List, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var lists []microsoftToDoList
+	if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '{' {
+		var page struct {
+			Value []microsoftToDoList `json:"value"`
+		}
+		err = json.Unmarshal(data, &page)
+		lists = page.Value
+	} else {
+		err = json.Unmarshal(data, &lists)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := idSet{}
+	out := make([]archive.List, 0, len(lists))
+	for i, l := range lists {
+		title := strings.TrimSpace(l.DisplayName)
+		if len(title) == 0 {
+			title = DefaultTitle
+		}
+		b := newListBuilder(ids.add(title, "list"), title)
+		b.list.Position = i
+		for _, task := range l.Tasks {
+			item := archive.Item{
+				Content:   strings.TrimSpace(task.Title),
+				Notes:     microsoftToDoBody(task.Body.Content, task.Body.ContentType),
+				Completed: task.Status == "completed",
+				Tags:      task.Categories,
+			}
+			if task.Importance == "high" {
+				item.Priority = 3
+			}
+			if task.DueDateTime != nil {
+				if due, ok := task.DueDateTime.time(); ok {
+					item.DueAt = &due
+				}
+			}
+			if created, err := time.Parse(time.RFC3339Nano, task.CreatedDateTime); err == nil {
+				item.CreatedAt = created.UTC()
+			}
+			b.addItem(0, item)
+			for _, check := range task.ChecklistItems {
+				b.addItem(1, archive.Item{Content: strings.TrimSpace(check.DisplayName), Completed: check.IsChecked, CreatedAt: item.CreatedAt})
+			}
+		}
+		out = append(out, b.list)
+	}
+	return out, nil
+}
+
+// microsoftToDoBody returns a task's body as plain text.
+func microsoftToDoBody(content, contentType string) string {
+	if strings.EqualFold(contentType, "html") {
+		content = html.UnescapeString(htmlTagPattern.ReplaceAllString(content, ""))
+	}
+	return strings.TrimSpace(content)
+}
+
+// time returns the time dt is at. Times in time zones that are not known are read as UTC.
+func (dt microsoftToDoDateTime) time() (time.Time, bool) {
+	loc, err := time.LoadLocation(dt.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05.9999999", dt.DateTime, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMicrosoftToDo(t *testing.T) {
+	created := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	due := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	tasks := `[
+		{
+			"title": "Buy milk",
+			"status": "completed",
+			"importance": "high",
+			"body": {"content": "<p>Semi &amp; skimmed</p>", "contentType": "html"},
+			"dueDateTime": {"dateTime": "2021-01-03T00:00:00.0000000", "timeZone": "UTC"},
+			"createdDateTime": "2021-01-01T10:00:00.0000000Z",
+			"categories": ["Errands"],
+			"checklistItems": [{"displayName": "Oat", "isChecked": true}]
+		},
+		{"title": "Bread", "status": "notStarted", "importance": "normal", "body": {"content": "", "contentType": "text"}}
+	]`
+	lists := []archive.List{
+		{
+			ListID:   "groceries",
+			Title:    "Groceries",
+			Sections: []archive.Section{},
+			Items: []archive.Item{
+				{ItemID: "1", Content: "Buy milk", Notes: "Semi & skimmed", Completed: true, Priority: 3, DueAt: &due, Tags: []string{"Errands"}, CreatedAt: created},
+				{ItemID: "2", ParentID: "1", Content: "Oat", Completed: true, CreatedAt: created},
+				{ItemID: "3", Content: "Bread"},
+			},
+		},
+		{ListID: "imported-list", Title: "Imported list", Position: 1, Sections: []archive.Section{}, Items: []archive.Item{}},
+	}
+
+	tests := map[string]struct {
+		input string
+		lists []archive.List
+		err   string
+	}{
+		"array": {
+			input: `[{"displayName": "Groceries", "tasks": ` + tasks + `}, {"displayName": "", "tasks": []}]`,
+			lists: lists,
+		},
+		"graph-response": {
+			input: `{"@odata.context": "...", "value": [{"displayName": "Groceries", "tasks": ` + tasks + `}, {"displayName": " "}]}`,
+			lists: lists,
+		},
+		"malformed": {
+			input: `[{"displayName": 1}]`,
+			err:   "json: cannot unmarshal number",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lists, err := parseMicrosoftToDo(bytes.NewBufferString(test.input))
+			if len(test.err) != 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.lists, lists)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+)
+
+// todoistDateLayouts are the layouts of the due dates in Todoist's exports that can be read. Other due dates, such as
+// recurring ones, are kept in the item's notes instead.
+var todoistDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+	"2 Jan 2006",
+}
+
+// parseTodoist reads a Todoist project exported as CSV. Its rows are sections, tasks and comments, in order; comments
+// belong to the task before them. Labels in a task's content, such as "@errands", become tags.
+func parseTodoist(r io.Reader, opts Options) ([]archive.List, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("missing header row")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	// The first column name can start with a byte order mark.
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	b := newListBuilder(slug(opts.title()), opts.title())
+	if len(b.list.ListID) == 0 {
+		b.list.ListID = "list"
+	}
+	last := -1
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content := field(record, "CONTENT")
+		switch strings.ToLower(field(record, "TYPE")) {
+		case "section":
+			if len(content) != 0 {
+				b.addSection(content)
+				last = -1
+			}
+		case "task":
+			item := archive.Item{Notes: field(record, "DESCRIPTION"), Priority: todoistPriority(field(record, "PRIORITY"))}
+			item.Content, item.Tags = todoistLabels(content)
+			if date := field(record, "DATE"); len(date) != 0 {
+				if due, ok := parseTodoistDate(date, field(record, "TIMEZONE")); ok {
+					item.DueAt = &due
+				} else {
+					item.Notes = appendNote(item.Notes, "Due: "+date)
+				}
+			}
+			indent, err := strconv.Atoi(field(record, "INDENT"))
+			if err != nil {
+				indent = 1
+			}
+			last = b.addItem(indent, item)
+		case "note":
+			if last < 0 {
+				b.list.Description = appendNote(b.list.Description, content)
+				continue
+			}
+			b.list.Items[last].Notes = appendNote(b.list.Items[last].Notes, content)
+		}
+	}
+	return []archive.List{b.list}, nil
+}
+
+// todoistPriority returns the priority of a Todoist priority, where 1 is Todoist's highest priority and 4 means none.
+func todoistPriority(p string) int {
+	switch p {
+	case "1":
+		return 3
+	case "2":
+		return 2
+	case "3":
+		return 1
+	}
+	return 0
+}
+
+// todoistLabels returns a task's content without its labels, and the labels.
+func todoistLabels(content string) (string, []string) {
+	var words, labels []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && word[0] == '@' {
+			if label := word[1:]; !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		// A task that is nothing but labels keeps them as its content.
+		return content, nil
+	}
+	return strings.Join(words, " "), labels
+}
+
+func parseTodoistDate(date, timezone string) (time.Time, bool) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	for _, layout := range todoistDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// appendNote appends a paragraph to notes.
+func appendNote(notes, note string) string {
+	if len(note) == 0 {
+		return notes
+	}
+	if len(notes) == 0 {
+		return note
+	}
+	return notes + "\n\n" + note
+}
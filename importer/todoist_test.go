@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTodoist(t *testing.T) {
+	due := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		input string
+		lists []archive.List
+		err   string
+	}{
+		"project": {
+			input: "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+				"note,About this project,,,,,,,,\n" +
+				"task,Buy milk @errands @errands,Semi-skimmed,1,1,Me (1),,2021-01-03,en,UTC\n" +
+				"note,From the corner shop,,,,,,,,\n" +
+				"task,Check the date,,4,2,Me (1),,,en,UTC\n" +
+				",,,,,,,,,\n" +
+				"section,Chores,,,,,,,,\n" +
+				"task,Laundry,,2,1,Me (1),,every monday,en,UTC\n" +
+				"meta,view_style=list,,,,,,,,\n",
+			lists: []archive.List{
+				{
+					ListID:      "home",
+					Title:       "Home",
+					Description: "About this project",
+					Sections:    []archive.Section{{SectionID: "chores", Title: "Chores"}},
+					Items: []archive.Item{
+						{ItemID: "1", Content: "Buy milk", Notes: "Semi-skimmed\n\nFrom the corner shop", Priority: 3, DueAt: &due, Tags: []string{"errands"}},
+						{ItemID: "2", ParentID: "1", Content: "Check the date"},
+						{ItemID: "3", SectionID: "chores", Content: "Laundry", Notes: "Due: every monday", Priority: 2},
+					},
+				},
+			},
+		},
+		"missing-column": {
+			input: "TYPE,DESCRIPTION\n",
+			err:   "missing CONTENT column",
+		},
+		"empty": {
+			input: "",
+			err:   "missing header row",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lists, err := parseTodoist(bytes.NewBufferString(test.input), Options{Title: "Home"})
+			if len(test.err) != 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.lists, lists)
+		})
+	}
+}
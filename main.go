@@ -5,6 +5,7 @@ import (
 	"flag"
 	"io/ioutil"
 
+	"github.com/TheYeung1/yata-server/blobstore"
 	"github.com/TheYeung1/yata-server/config"
 	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/server"
@@ -12,16 +13,32 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
-	awsRegion            = flag.String("aws-region", "us-west-2", "aws region")
-	awsCredentialProfile = flag.String("aws-profile", "yata", "aws credential profile; create with 'aws configure --profile <name>'")
-	cognitoConfigFile    = flag.String("cognito-config", "env/CognitoConfig.json", "cognito config file; see env/SampleConfig.json for reference")
-	listsTableName       = flag.String("lists-table", "ListTable", "lists DynamoDB table name")
-	itemsTableName       = flag.String("items-table", "ItemsTable", "items DynamoDB table name")
-	logLevel             = flag.String("log-level", log.DebugLevel.String(), "log level")
+	awsRegion              = flag.String("aws-region", "us-west-2", "aws region")
+	awsCredentialProfile   = flag.String("aws-profile", "yata", "aws credential profile; create with 'aws configure --profile <name>'")
+	cognitoConfigFile      = flag.String("cognito-config", "env/CognitoConfig.json", "cognito config file; see env/SampleConfig.json for reference")
+	listsTableName         = flag.String("lists-table", "ListTable", "lists DynamoDB table name")
+	foldersTableName       = flag.String("folders-table", "FoldersTable", "folders DynamoDB table name")
+	itemsTableName         = flag.String("items-table", "ItemsTable", "items DynamoDB table name")
+	sectionsTableName      = flag.String("sections-table", "SectionsTable", "list sections DynamoDB table name")
+	attachmentsTableName   = flag.String("attachments-table", "AttachmentsTable", "attachments DynamoDB table name")
+	membersTableName       = flag.String("members-table", "MembersTable", "list members DynamoDB table name")
+	shareLinksTableName    = flag.String("share-links-table", "ShareLinksTable", "share links DynamoDB table name")
+	calendarFeedsTableName = flag.String("calendar-feeds-table", "CalendarFeedsTable", "calendar feeds DynamoDB table name")
+	appPasswordsTableName  = flag.String("app-passwords-table", "AppPasswordsTable", "app passwords DynamoDB table name")
+	activityTableName      = flag.String("activity-table", "ActivityTable", "list activity DynamoDB table name")
+	blobStoreKind          = flag.String("blob-store", "local", "where attachments are stored; one of 'local' or 's3'")
+	blobDir                = flag.String("blob-dir", "blobs", "directory attachments are stored in when using the local blob store")
+	s3Bucket               = flag.String("s3-bucket", "", "S3 bucket attachments are stored in when using the s3 blob store")
+	s3Endpoint             = flag.String("s3-endpoint", "", "S3 compatible endpoint, such as a MinIO server, to use instead of AWS S3")
+	attachmentQuota        = flag.Int64("attachment-quota", 100<<20, "number of bytes of attachments each user can store")
+	trashRetention         = flag.Duration("trash-retention", server.DefaultTrashRetention, "how long deleted lists and items stay in the trash")
+	logLevel               = flag.String("log-level", log.DebugLevel.String(), "log level")
 )
 
 func init() {
@@ -46,9 +63,37 @@ func main() {
 	}
 
 	yataDynamo := &database.DynamoDbYataDatabase{
-		Dynamo:         dynamodb.New(sess),
-		ListsTableName: *listsTableName,
-		ItemsTableName: *itemsTableName,
+		Dynamo:                 dynamodb.New(sess),
+		ListsTableName:         *listsTableName,
+		FoldersTableName:       *foldersTableName,
+		ItemsTableName:         *itemsTableName,
+		SectionsTableName:      *sectionsTableName,
+		AttachmentsTableName:   *attachmentsTableName,
+		MembersTableName:       *membersTableName,
+		ShareLinksTableName:    *shareLinksTableName,
+		CalendarFeedsTableName: *calendarFeedsTableName,
+		AppPasswordsTableName:  *appPasswordsTableName,
+		ActivityTableName:      *activityTableName,
+	}
+
+	var blobs blobstore.BlobStore
+	switch *blobStoreKind {
+	case "local":
+		blobs = &blobstore.LocalBlobStore{Dir: *blobDir}
+	case "s3":
+		cfg := aws.NewConfig()
+		if len(*s3Endpoint) != 0 {
+			// S3 compatible services are usually addressed by path rather than by a bucket subdomain.
+			cfg = cfg.WithEndpoint(*s3Endpoint).WithS3ForcePathStyle(true)
+		}
+		s3Client := s3.New(sess, cfg)
+		blobs = &blobstore.S3BlobStore{
+			Bucket:   *s3Bucket,
+			S3:       s3Client,
+			Uploader: s3manager.NewUploaderWithClient(s3Client),
+		}
+	default:
+		log.WithField("blobStore", *blobStoreKind).Fatal("unknown blob store")
 	}
 
 	cognitoCfgFile, err := ioutil.ReadFile(*cognitoConfigFile)
@@ -61,8 +106,11 @@ func main() {
 	}
 
 	s := server.Server{
-		CognitoCfg: cognitoConfig,
-		Ydb:        yataDynamo,
+		CognitoCfg:      cognitoConfig,
+		Ydb:             yataDynamo,
+		Blobs:           blobs,
+		AttachmentQuota: *attachmentQuota,
+		TrashRetention:  *trashRetention,
 	}
 	s.Start()
 }
@@ -10,8 +10,10 @@ import (
 func RequestLogger(newID func() string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := newID()
+			ctx := request.WithRequestID(r.Context(), id)
 			// TODO: We share part of this next line with request/context.go; consider refactor so we eliminate the risk of de-sync.
-			next.ServeHTTP(w, r.WithContext(request.WithLogger(r.Context(), logrus.WithField("requestID", newID()))))
+			next.ServeHTTP(w, r.WithContext(request.WithLogger(ctx, logrus.WithField("requestID", id))))
 		})
 	}
 }
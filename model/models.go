@@ -1,14 +1,133 @@
 package model
 
+import "time"
+
+// MaxPriority is the highest priority an item can have. A priority of zero means the item has no priority.
+const MaxPriority = 3
+
 type YataList struct {
 	UserID UserID
 	ListID ListID
 	Title  string
+	// Description, Color and Icon are optional. Color is a hex RGB color such as "#1e90ff" and Icon is a single emoji.
+	Description string `json:",omitempty" dynamodbav:",omitempty"`
+	Color       string `json:",omitempty" dynamodbav:",omitempty"`
+	Icon        string `json:",omitempty" dynamodbav:",omitempty"`
+	// Position orders the owner's lists; lower positions come first.
+	Position int `json:",omitempty" dynamodbav:",omitempty"`
+	// FolderID is the owner's folder the list is in, if any.
+	FolderID FolderID `json:",omitempty" dynamodbav:",omitempty"`
+	// Archived lists are hidden from list views by default and do not accept new items.
+	Archived bool `json:",omitempty" dynamodbav:",omitempty"`
+	// Template lists are hidden from list views by default and are meant to be copied rather than used directly.
+	Template bool `json:",omitempty" dynamodbav:",omitempty"`
+	// DeletedAt is when the list was moved to the trash. It is nil for lists that are not in the trash.
+	DeletedAt *time.Time `json:",omitempty" dynamodbav:",omitempty"`
 }
 
 type YataItem struct {
-	UserID  UserID
+	UserID   UserID
+	ListID   ListID
+	ItemID   ItemID
+	ParentID ItemID `json:",omitempty" dynamodbav:",omitempty"`
+	// SectionID is the section of the list the item is grouped under. Sub-tasks are always in their parent's section.
+	SectionID SectionID `json:",omitempty" dynamodbav:",omitempty"`
+	Content   string
+	Notes     string `json:",omitempty" dynamodbav:",omitempty"`
+	Completed bool
+	Priority  int
+	DueAt     *time.Time `json:",omitempty" dynamodbav:",omitempty"`
+	// AssigneeID is the user responsible for the item; either the list's owner or one of its members.
+	AssigneeID UserID `json:",omitempty" dynamodbav:",omitempty"`
+	// Tags are free form labels used to find related items across lists.
+	Tags      []string `json:",omitempty" dynamodbav:",omitempty"`
+	CreatedAt time.Time
+	// DeletedAt is when the item was moved to the trash. It is nil for items that are not in the trash.
+	// Items trashed along with their list or parent share its DeletedAt.
+	DeletedAt *time.Time `json:",omitempty" dynamodbav:",omitempty"`
+}
+
+// YataFolder groups a user's lists.
+type YataFolder struct {
+	UserID   UserID
+	FolderID FolderID
+	Title    string
+	// Position orders the user's folders; lower positions come first.
+	Position int `json:",omitempty" dynamodbav:",omitempty"`
+}
+
+// YataSection groups items on a list under a heading.
+type YataSection struct {
+	UserID    UserID
+	ListID    ListID
+	SectionID SectionID
+	Title     string
+	// Position orders the list's sections; lower positions come first.
+	Position int `json:",omitempty" dynamodbav:",omitempty"`
+}
+
+type YataAttachment struct {
+	UserID       UserID
+	ListID       ListID
+	ItemID       ItemID
+	AttachmentID AttachmentID
+	ContentType  string
+	Size         int64
+	// BlobKey is where the attachment's content is stored in the blob store.
+	BlobKey   string `json:"-" dynamodbav:"BlobKey"`
+	CreatedAt time.Time
+}
+
+type YataListMember struct {
+	OwnerID UserID
 	ListID  ListID
-	ItemID  ItemID
-	Content string
+	UserID  UserID
+	Role    Role
+}
+
+// YataShareLink lets anyone who knows its token view a list without authenticating.
+type YataShareLink struct {
+	Token     string
+	UserID    UserID
+	ListID    ListID
+	CreatedAt time.Time
+	ExpiresAt *time.Time `json:",omitempty" dynamodbav:",omitempty"`
+}
+
+// YataCalendarFeed lets calendar apps subscribe to the items a user has due without authenticating, by knowing its
+// token.
+type YataCalendarFeed struct {
+	Token     string
+	UserID    UserID
+	CreatedAt time.Time
+}
+
+// YataAppPassword lets apps that can only authenticate with a user name and password, such as CalDAV clients, act as a
+// user. Only a hash of the password is stored; the password itself is returned once, when the app password is created.
+type YataAppPassword struct {
+	// Hash is the hex encoded SHA-256 hash of the password, which app passwords are looked up by. It is never output.
+	Hash          string `json:"-" dynamodbav:"Hash"`
+	AppPasswordID AppPasswordID
+	UserID        UserID
+	// Name describes where the app password is used, such as "Phone".
+	Name      string `json:",omitempty" dynamodbav:",omitempty"`
+	CreatedAt time.Time
+}
+
+// YataActivity records a single change made to a list or anything on it.
+type YataActivity struct {
+	UserID UserID
+	ListID ListID
+	// EventID orders a list's activities by when they happened.
+	EventID   string
+	ActorID   UserID
+	Time      time.Time
+	RequestID string
+	Action    Action
+	// TargetType is what was changed; one of "list", "item", "attachment", "member" or "shareLink".
+	TargetType string
+	TargetID   string `json:",omitempty" dynamodbav:",omitempty"`
+	// Before and After hold the fields that changed, as they were before and after the change.
+	Before map[string]interface{} `json:",omitempty" dynamodbav:",omitempty"`
+	After  map[string]interface{} `json:",omitempty" dynamodbav:",omitempty"`
 }
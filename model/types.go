@@ -3,3 +3,31 @@ package model
 type UserID string
 type ListID string
 type ItemID string
+type AttachmentID string
+type SectionID string
+type FolderID string
+type AppPasswordID string
+
+// Role is what a user is allowed to do with a list.
+type Role string
+
+const (
+	// RoleOwner can do anything with a list, including sharing it. Only the user who created a list is its owner.
+	RoleOwner Role = "owner"
+	// RoleEditor can view a list and change its items.
+	RoleEditor Role = "editor"
+	// RoleViewer can only view a list and its items.
+	RoleViewer Role = "viewer"
+)
+
+// Action is the kind of change recorded by an activity.
+type Action string
+
+const (
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionComplete Action = "complete"
+	ActionShare    Action = "share"
+	ActionRestore  Action = "restore"
+)
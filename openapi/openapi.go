@@ -0,0 +1,273 @@
+// Package openapi describes HTTP APIs with OpenAPI 3 documents, whose schemas are generated from Go types.
+package openapi
+
+import (
+	"encoding"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the version of the OpenAPI specification documents follow.
+const Version = "3.0.3"
+
+// Methods are the HTTP methods operations can have, in the order they are listed in a path item.
+var Methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// Document is an OpenAPI document. Its paths are keyed by path template, such as "/lists/{listID}/".
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations on a path, keyed by lower case HTTP method.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security is nil for operations that do not require authentication.
+	Security []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// SecurityScheme is a way of authenticating requests, such as HTTP Basic authentication.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// SecurityRequirement maps the names of security schemes to the scopes they require.
+type SecurityRequirement map[string][]string
+
+// Schema is a JSON schema, as far as OpenAPI 3.0 supports them.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// AddOperation adds an operation on a path template. It returns an error if the method cannot be described by OpenAPI,
+// such as WebDAV's PROPFIND, or the path already has an operation with the method.
+func (d *Document) AddOperation(method string, pathTemplate string, op *Operation) error {
+	ok := false
+	for _, m := range Methods {
+		ok = ok || m == method
+	}
+	if !ok {
+		return fmt.Errorf("openapi: method %s cannot be described", method)
+	}
+	if d.Paths == nil {
+		d.Paths = map[string]PathItem{}
+	}
+	item, ok := d.Paths[pathTemplate]
+	if !ok {
+		item = PathItem{}
+		d.Paths[pathTemplate] = item
+	}
+	key := strings.ToLower(method)
+	if _, ok := item[key]; ok {
+		return fmt.Errorf("openapi: %s %s is described twice", method, pathTemplate)
+	}
+	item[key] = op
+	return nil
+}
+
+// componentsSchemaRef prefixes the names of schemas to refer to them.
+const componentsSchemaRef = "#/components/schemas/"
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// Schemas generates the schemas of Go values as encoding/json encodes them. The schemas of named struct types, and of
+// named types with an enum, are added to the components they are created with and referred to by name.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	enums      map[reflect.Type][]string
+}
+
+// NewSchemas returns Schemas that adds to components.
+func NewSchemas(components map[string]*Schema) *Schemas {
+	return &Schemas{components: components, names: map[reflect.Type]string{}, enums: map[reflect.Type][]string{}}
+}
+
+// Enum lists the values of t, which must be a string type, so that its schema is limited to them.
+func (s *Schemas) Enum(t reflect.Type, values ...string) {
+	s.enums[t] = values
+}
+
+// Define adds a named schema to the components, such as one that cannot be generated from a type.
+func (s *Schemas) Define(name string, schema *Schema) *Schema {
+	s.components[name] = schema
+	return &Schema{Ref: componentsSchemaRef + name}
+}
+
+// For returns the schema of t.
+func (s *Schemas) For(t reflect.Type) *Schema {
+	if name, ok := s.names[t]; ok {
+		return &Schema{Ref: componentsSchemaRef + name}
+	}
+
+	switch {
+	case t.Kind() == reflect.Ptr:
+		schema := s.For(t.Elem())
+		if len(schema.Ref) == 0 {
+			schema.Nullable = true
+		}
+		return schema
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	case t == emptyInterfaceType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		if values, ok := s.enums[t]; ok {
+			return s.define(t, func() *Schema { return &Schema{Type: "string", Enum: values} })
+		}
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"} // Base64, as encoding/json encodes []byte.
+		}
+		return &Schema{Type: "array", Items: s.For(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.For(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return s.structSchema(t)
+		}
+		return s.define(t, func() *Schema { return s.structSchema(t) })
+	}
+	panic(fmt.Sprintf("openapi: cannot describe values of type %s", t))
+}
+
+// define adds the schema of a named type to the components, before creating it so that recursive types refer to
+// themselves.
+func (s *Schemas) define(t reflect.Type, create func() *Schema) *Schema {
+	name := t.Name()
+	if _, ok := s.components[name]; ok {
+		// Another type has the same name, so this one is told apart by its package.
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *create()
+	return &Schema{Ref: componentsSchemaRef + name}
+}
+
+// structSchema returns the schema of a struct type's fields. Fields are named by their json struct tag, like
+// encoding/json, and those without omitempty are required. The fields of embedded structs are promoted.
+func (s *Schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+	return schema
+}
+
+func (s *Schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && len(name) == 0 && ft.Kind() == reflect.Struct {
+			s.addFields(schema, ft)
+			continue
+		}
+		if len(f.PkgPath) != 0 {
+			continue // Unexported.
+		}
+
+		if len(name) == 0 {
+			name = f.Name
+		}
+		omitEmpty, asString := false, false
+		for _, opt := range parts[1:] {
+			omitEmpty = omitEmpty || opt == "omitempty"
+			asString = asString || opt == "string"
+		}
+		fs := s.For(f.Type)
+		if asString {
+			fs = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = fs
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
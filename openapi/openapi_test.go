@@ -0,0 +1,104 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type color string
+
+type embedded struct {
+	Code string
+}
+
+type node struct {
+	embedded
+	Name     string
+	Color    color  `json:"color,omitempty"`
+	Skipped  string `json:"-"`
+	Count    int64  `json:",string"`
+	Children []node `json:",omitempty"`
+	Parent   *node  `json:",omitempty"`
+	hidden   string
+}
+
+func TestSchemas_For(t *testing.T) {
+	tests := map[string]struct {
+		v    interface{}
+		want *Schema
+	}{
+		"bool":      {v: true, want: &Schema{Type: "boolean"}},
+		"int":       {v: 1, want: &Schema{Type: "integer", Format: "int64"}},
+		"int32":     {v: int32(1), want: &Schema{Type: "integer", Format: "int32"}},
+		"float64":   {v: 1.5, want: &Schema{Type: "number", Format: "double"}},
+		"string":    {v: "", want: &Schema{Type: "string"}},
+		"time":      {v: time.Time{}, want: &Schema{Type: "string", Format: "date-time"}},
+		"time-ptr":  {v: &time.Time{}, want: &Schema{Type: "string", Format: "date-time", Nullable: true}},
+		"bytes":     {v: []byte{}, want: &Schema{Type: "string", Format: "byte"}},
+		"slice":     {v: []string{}, want: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		"map":       {v: map[string]int{}, want: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int64"}}},
+		"interface": {v: []interface{}{}, want: &Schema{Type: "array", Items: &Schema{}}},
+		"struct":    {v: node{}, want: &Schema{Ref: "#/components/schemas/node"}},
+		"anonymous": {v: struct{ A bool }{}, want: &Schema{Type: "object", Properties: map[string]*Schema{"A": {Type: "boolean"}}, Required: []string{"A"}}},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			s := NewSchemas(map[string]*Schema{})
+			assert.Equal(t, test.want, s.For(reflect.TypeOf(test.v)))
+		})
+	}
+}
+
+func TestSchemas_For_Struct(t *testing.T) {
+	components := map[string]*Schema{}
+	s := NewSchemas(components)
+	s.Enum(reflect.TypeOf(color("")), "red", "green")
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/node"}, s.For(reflect.TypeOf(node{})))
+	assert.Equal(t, map[string]*Schema{
+		"color": {Type: "string", Enum: []string{"red", "green"}},
+		"node": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"Code":     {Type: "string"},
+				"Name":     {Type: "string"},
+				"color":    {Ref: "#/components/schemas/color"},
+				"Count":    {Type: "string"},
+				"Children": {Type: "array", Items: &Schema{Ref: "#/components/schemas/node"}},
+				"Parent":   {Ref: "#/components/schemas/node"},
+			},
+			Required: []string{"Code", "Name", "Count"},
+		},
+	}, components)
+}
+
+func TestSchemas_For_SameName(t *testing.T) {
+	type node struct{}
+	components := map[string]*Schema{}
+	s := NewSchemas(components)
+	s.Define("node", &Schema{Type: "string"})
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/openapi.node"}, s.For(reflect.TypeOf(node{})))
+	assert.Equal(t, &Schema{Type: "object"}, components["openapi.node"])
+}
+
+func TestDocument_AddOperation(t *testing.T) {
+	var d Document
+	require.NoError(t, d.AddOperation("GET", "/lists", &Operation{OperationID: "GetLists"}))
+	require.NoError(t, d.AddOperation("PUT", "/lists", &Operation{OperationID: "InsertList"}))
+	assert.Equal(t, map[string]PathItem{"/lists": {
+		"get": {OperationID: "GetLists"},
+		"put": {OperationID: "InsertList"},
+	}}, d.Paths)
+
+	assert.EqualError(t, d.AddOperation("GET", "/lists", &Operation{}), "openapi: GET /lists is described twice")
+	assert.EqualError(t, d.AddOperation("PROPFIND", "/dav/", &Operation{}), "openapi: method PROPFIND cannot be described")
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultActivityPageSize = 50
+	maxActivityPageSize     = 100
+)
+
+type GetListActivityOutput struct {
+	// Activities are newest first.
+	Activities []model.YataActivity
+	// Next is passed as the "next" query parameter to get the next page. It is empty on the last page.
+	Next string `json:",omitempty"`
+}
+
+func (s *Server) GetListActivity(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list activity called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	limit := int64(defaultActivityPageSize)
+	if l := r.URL.Query().Get("limit"); len(l) != 0 {
+		var err error
+		limit, err = strconv.ParseInt(l, 10, 64)
+		if err != nil || limit < 1 || limit > maxActivityPageSize {
+			log.WithField("limit", l).Info("failed to validate input")
+			renderBadRequest(w, r, fmt.Sprintf("limit must be between 1 and %d", maxActivityPageSize))
+			return
+		}
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	activities, next, err := s.Ydb.GetListActivity(yl.UserID, listID, limit, r.URL.Query().Get("next"))
+	if err != nil {
+		if errpt, ok := err.(database.InvalidPageTokenError); ok {
+			log.WithError(errpt).Info("invalid page token")
+			renderBadRequest(w, r, "next is not a valid page token")
+			return
+		}
+		log.WithError(err).Error("failed to get list activity")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetListActivityOutput{Activities: activities, Next: next}
+	log.WithField("output", out).Debug("list activity retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+// recordActivity appends an activity to a list's activity log.
+// before is nil for creations and after is nil for deletions; otherwise only the fields that differ are recorded.
+// Failing to record an activity is logged rather than returned since the change it describes has already been made.
+func (s *Server) recordActivity(r *http.Request, yl model.YataList, action model.Action, targetType, targetID string, before, after interface{}) {
+	s.recordActorActivity(requestActor(r), yl, action, targetType, targetID, before, after)
+}
+
+// activityActor is whoever made a change, and the logger that failing to record it is logged to.
+type activityActor struct {
+	userID    model.UserID
+	requestID string
+	log       *logrus.Entry
+}
+
+// requestActor returns the caller of r as an activityActor. Jobs call it before they start, since they outlive the
+// request.
+func requestActor(r *http.Request) activityActor {
+	uid, _ := request.UserID(r.Context())
+	requestID, _ := request.RequestID(r.Context())
+	return activityActor{userID: uid, requestID: requestID, log: request.Logger(r.Context())}
+}
+
+// recordActorActivity is recordActivity for changes made by actor outside of a request's handler.
+func (s *Server) recordActorActivity(actor activityActor, yl model.YataList, action model.Action, targetType, targetID string, before, after interface{}) {
+	log := actor.log
+
+	b, a, err := activityDiff(before, after)
+	if err != nil {
+		log.WithError(err).Warn("failed to diff activity")
+		return
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.WithError(err).Warn("failed to generate a uuid")
+		return
+	}
+	now := time.Now().UTC()
+	activity := model.YataActivity{
+		UserID:     yl.UserID,
+		ListID:     yl.ListID,
+		EventID:    activityEventID(now, id.String()),
+		ActorID:    actor.userID,
+		Time:       now,
+		RequestID:  actor.requestID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     b,
+		After:      a,
+	}
+	if err := s.Ydb.InsertActivity(activity); err != nil {
+		log.WithError(err).WithField("activity", activity).Warn("failed to record activity")
+	}
+}
+
+// activityEventID returns an ID that sorts activities by when they happened.
+// The time is fixed width so that IDs sort the same way as strings; the suffix keeps simultaneous activities apart.
+func activityEventID(t time.Time, suffix string) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z") + "#" + suffix
+}
+
+// activityDiff returns the fields of before and after that differ.
+// If either is nil every field of the other is returned.
+func activityDiff(before, after interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	b, err := activityFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := activityFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b == nil || a == nil {
+		return b, a, nil
+	}
+
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(bv, av) {
+			changedBefore[k] = bv
+		}
+	}
+	for k, av := range a {
+		if bv, ok := b[k]; !ok || !reflect.DeepEqual(bv, av) {
+			changedAfter[k] = av
+		}
+	}
+	return changedBefore, changedAfter, nil
+}
+
+// activityFields returns the fields of v as they would appear in a response.
+func activityFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %v", err)
+	}
+	return fields, nil
+}
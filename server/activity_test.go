@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityEventID(t *testing.T) {
+	earlier := activityEventID(time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC), "b")
+	later := activityEventID(time.Date(2021, 1, 1, 10, 0, 0, 5, time.FixedZone("", 3600)), "a")
+
+	assert.Equal(t, "2021-01-01T09:00:00.000000000Z#b", earlier)
+	assert.Equal(t, "2021-01-01T09:00:00.000000005Z#a", later)
+	assert.True(t, earlier < later)
+}
+
+func TestActivityDiff(t *testing.T) {
+	tests := map[string]struct {
+		before     interface{}
+		after      interface{}
+		wantBefore map[string]interface{}
+		wantAfter  map[string]interface{}
+	}{
+		"create": {
+			before:    nil,
+			after:     model.YataListMember{OwnerID: "me", ListID: "list", UserID: "them", Role: model.RoleViewer},
+			wantAfter: map[string]interface{}{"OwnerID": "me", "ListID": "list", "UserID": "them", "Role": "viewer"},
+		},
+		"delete": {
+			before:     model.YataListMember{OwnerID: "me", ListID: "list", UserID: "them", Role: model.RoleViewer},
+			after:      nil,
+			wantBefore: map[string]interface{}{"OwnerID": "me", "ListID": "list", "UserID": "them", "Role": "viewer"},
+		},
+		"update-only-changed-fields": {
+			before:     model.YataItem{ItemID: "item", Content: "old", Priority: 1},
+			after:      model.YataItem{ItemID: "item", Content: "new", Priority: 1, Notes: "added"},
+			wantBefore: map[string]interface{}{"Content": "old"},
+			wantAfter:  map[string]interface{}{"Content": "new", "Notes": "added"},
+		},
+		"update-nothing-changed": {
+			before:     model.YataItem{ItemID: "item", Content: "same"},
+			after:      model.YataItem{ItemID: "item", Content: "same"},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			before, after, err := activityDiff(test.before, test.after)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantBefore, before)
+			assert.Equal(t, test.wantAfter, after)
+		})
+	}
+}
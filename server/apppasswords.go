@@ -0,0 +1,220 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// appPasswordRealm is the protection space of the routes that are authenticated with app passwords.
+const appPasswordRealm = "yata"
+
+// hashAppPassword returns the hash app passwords are stored and looked up by, so that the passwords themselves are never
+// stored.
+func hashAppPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+type GetAppPasswordsOutput struct {
+	AppPasswords []model.YataAppPassword
+}
+
+// GetAppPasswords returns the caller's app passwords, without the passwords themselves.
+func (s *Server) GetAppPasswords(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get app passwords called")
+
+	passwords, err := s.Ydb.GetAppPasswords(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get app passwords")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetAppPasswordsOutput{AppPasswords: passwords}
+	log.WithField("output", out).Debug("app passwords retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertAppPasswordInput struct {
+	// Name describes where the app password is used, such as "Phone". It is optional.
+	Name string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *InsertAppPasswordInput) Validate() error {
+	if len(input.Name) > 100 {
+		return errors.New("Name length cannot exceed 100 characters")
+	}
+	if len(input.Name) != len(strings.TrimSpace(input.Name)) {
+		return errors.New("Name cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
+
+type InsertAppPasswordOutput struct {
+	AppPasswordID model.AppPasswordID
+	// Username and Password are the credentials to give the app. The password cannot be retrieved again.
+	Username string
+	Password string
+	Name     string `json:",omitempty"`
+}
+
+// InsertAppPassword creates a new app password for the caller. The password is only returned in the response.
+func (s *Server) InsertAppPassword(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert app password called")
+
+	var input InsertAppPasswordInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	password, err := newShareToken()
+	if err != nil {
+		log.WithError(err).Error("failed to generate app password")
+		renderInternalServerError(w, r)
+		return
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.WithError(err).Error("failed to generate a uuid")
+		renderInternalServerError(w, r)
+		return
+	}
+	p := model.YataAppPassword{
+		Hash:          hashAppPassword(password),
+		AppPasswordID: model.AppPasswordID(id.String()),
+		UserID:        uid,
+		Name:          input.Name,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.Ydb.InsertAppPassword(p); err != nil {
+		log.WithError(err).Error("failed to insert app password")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := InsertAppPasswordOutput{AppPasswordID: p.AppPasswordID, Username: string(uid), Password: password, Name: input.Name}
+	log.WithField("appPasswordID", p.AppPasswordID).Debug("app password inserted") // The output is not logged; the password is a secret.
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteAppPasswordOutput struct {
+	AppPasswordID model.AppPasswordID
+}
+
+// DeleteAppPassword revokes one of the caller's app passwords.
+func (s *Server) DeleteAppPassword(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete app password called")
+
+	id := model.AppPasswordID(mux.Vars(r)["appPasswordID"])
+	log = log.WithField("appPasswordID", id)
+	// Only the caller's app passwords are searched, so other users' are indistinguishable from ones that do not exist.
+	passwords, err := s.Ydb.GetAppPasswords(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get app passwords")
+		renderInternalServerError(w, r)
+		return
+	}
+	hash := ""
+	for _, p := range passwords {
+		if p.AppPasswordID == id {
+			hash = p.Hash
+		}
+	}
+	if len(hash) == 0 {
+		log.Info("app password not found")
+		render(w, r, http.StatusNotFound, responseError{Code: "AppPasswordDoesNotExist", Message: "App password does not exist"})
+		return
+	}
+
+	if err := s.Ydb.DeleteAppPassword(hash); err != nil {
+		log.WithError(err).Error("failed to delete app password")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := DeleteAppPasswordOutput{AppPasswordID: id}
+	log.Debug("app password deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+// authenticateAppPassword is middleware that authenticates requests with HTTP Basic authentication, where the user
+// name is a user's ID and the password one of their app passwords. It is used instead of the Cognito middleware for
+// apps that cannot send a bearer token.
+func (s *Server) authenticateAppPassword(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := request.Logger(r.Context())
+		username, password, ok := r.BasicAuth()
+		if !ok || len(password) == 0 {
+			log.Info("request does not contain basic auth credentials")
+			renderUnauthorized(w, r)
+			return
+		}
+
+		p, err := s.Ydb.GetAppPassword(hashAppPassword(password))
+		if err != nil {
+			if _, ok := err.(database.AppPasswordNotFoundError); ok {
+				log.Info("app password not found")
+				renderUnauthorized(w, r)
+				return
+			}
+			log.WithError(err).Error("failed to get app password")
+			renderInternalServerError(w, r)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(p.UserID), []byte(username)) != 1 {
+			log.WithField("username", username).Info("app password belongs to another user")
+			renderUnauthorized(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(request.WithUserID(r.Context(), string(p.UserID))))
+	})
+}
+
+// renderUnauthorized asks the client to authenticate with an app password.
+func renderUnauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+appPasswordRealm+`", charset="UTF-8"`)
+	render(w, r, http.StatusUnauthorized, responseError{Code: "Unauthorized", Message: "A user ID and app password are required"})
+}
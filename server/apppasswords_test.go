@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_GetAppPasswords(t *testing.T) {
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ydb := mockYdb{
+		MockGetAppPasswords: func(id model.UserID) ([]model.YataAppPassword, error) {
+			return []model.YataAppPassword{
+				{Hash: hashAppPassword("secret"), AppPasswordID: "phone", UserID: id, Name: "Phone", CreatedAt: createdAt},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://does.not/app-passwords", nil)
+	req = req.WithContext(request.WithUserID(req.Context(), "me"))
+
+	srvr := Server{Ydb: ydb}
+	srvr.GetAppPasswords(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	// Neither the passwords nor their hashes are returned.
+	assert.Equal(t, "{\"AppPasswords\":[{\"AppPasswordID\":\"phone\",\"UserID\":\"me\",\"Name\":\"Phone\",\"CreatedAt\":\"2021-01-01T00:00:00Z\"}]}\n", rec.Body.String())
+}
+
+func TestServer_InsertAppPassword(t *testing.T) {
+	var inserted []model.YataAppPassword
+	ydb := mockYdb{
+		MockInsertAppPassword: func(p model.YataAppPassword) error {
+			inserted = append(inserted, p)
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "https://does.not/app-passwords", strings.NewReader(`{"Name":"Phone"}`))
+	req = req.WithContext(request.WithUserID(req.Context(), "me"))
+
+	srvr := Server{Ydb: ydb}
+	srvr.InsertAppPassword(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var out InsertAppPasswordOutput
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Equal(t, "me", out.Username)
+	assert.Equal(t, "Phone", out.Name)
+	assert.NotEmpty(t, out.Password)
+
+	require.Len(t, inserted, 1)
+	// Only the password's hash is stored, and the app password is identified by something else.
+	assert.Equal(t, hashAppPassword(out.Password), inserted[0].Hash)
+	assert.NotEqual(t, out.Password, inserted[0].Hash)
+	assert.Equal(t, out.AppPasswordID, inserted[0].AppPasswordID)
+	assert.NotContains(t, string(inserted[0].AppPasswordID), out.Password)
+	assert.Equal(t, model.UserID("me"), inserted[0].UserID)
+	assert.Equal(t, "Phone", inserted[0].Name)
+}
+
+func TestServer_DeleteAppPassword(t *testing.T) {
+	tests := map[string]struct {
+		appPasswordID string
+		outCode       int
+		outBody       string
+		deleted       []string
+	}{
+		"exists": {
+			appPasswordID: "phone",
+			outCode:       http.StatusOK,
+			outBody:       "{\"AppPasswordID\":\"phone\"}\n",
+			deleted:       []string{hashAppPassword("secret")},
+		},
+		"does-not-exist": {
+			appPasswordID: "laptop",
+			outCode:       http.StatusNotFound,
+			outBody:       "{\"Code\":\"AppPasswordDoesNotExist\",\"Message\":\"App password does not exist\"}\n",
+		},
+		"cannot-be-deleted-by-password": {
+			appPasswordID: "secret",
+			outCode:       http.StatusNotFound,
+			outBody:       "{\"Code\":\"AppPasswordDoesNotExist\",\"Message\":\"App password does not exist\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var deleted []string
+			ydb := mockYdb{
+				MockGetAppPasswords: func(id model.UserID) ([]model.YataAppPassword, error) {
+					assert.Equal(t, model.UserID("me"), id)
+					return []model.YataAppPassword{{Hash: hashAppPassword("secret"), AppPasswordID: "phone", UserID: id}}, nil
+				},
+				MockDeleteAppPassword: func(hash string) error {
+					deleted = append(deleted, hash)
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "https://does.not/app-passwords/"+test.appPasswordID, nil)
+			req = mux.SetURLVars(req, map[string]string{"appPasswordID": test.appPasswordID})
+			req = req.WithContext(request.WithUserID(req.Context(), "me"))
+
+			srvr := Server{Ydb: ydb}
+			srvr.DeleteAppPassword(rec, req)
+
+			assert.Equal(t, test.outCode, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.deleted, deleted)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type ArchiveListOutput struct {
+	ListID   model.ListID
+	Archived bool
+}
+
+// ArchiveList hides a list from list views without deleting it. Only the list's owner can archive it.
+func (s *Server) ArchiveList(w http.ResponseWriter, r *http.Request) {
+	s.setListFlag(w, r, archivedFlag, true)
+}
+
+// UnarchiveList returns an archived list to list views. Only the list's owner can unarchive it.
+func (s *Server) UnarchiveList(w http.ResponseWriter, r *http.Request) {
+	s.setListFlag(w, r, archivedFlag, false)
+}
+
+// listFlag is a boolean attribute of a list that only the list's owner can set.
+type listFlag struct {
+	// attr is the attribute's name.
+	attr string
+	// field returns the attribute's field of yl.
+	field func(yl *model.YataList) *bool
+	// write sets the attribute of a list in ydb.
+	write func(ydb database.YataDatabase, uid model.UserID, lid model.ListID, value bool) error
+	// output is what setting the attribute responds with.
+	output func(lid model.ListID, value bool) interface{}
+}
+
+var archivedFlag = listFlag{
+	attr:   "Archived",
+	field:  func(yl *model.YataList) *bool { return &yl.Archived },
+	write:  database.YataDatabase.SetListArchived,
+	output: func(lid model.ListID, value bool) interface{} { return ArchiveListOutput{ListID: lid, Archived: value} },
+}
+
+func (s *Server) setListFlag(w http.ResponseWriter, r *http.Request, flag listFlag, value bool) {
+	log := request.Logger(r.Context()).WithField("attr", flag.attr)
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).WithField("value", value).Debug("set list flag called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	out := flag.output(listID, value)
+	if *flag.field(&yl) == value {
+		log.WithField("output", out).Debug("list flag already in requested state")
+		render(w, r, http.StatusOK, out)
+		return
+	}
+
+	if err := flag.write(s.Ydb, yl.UserID, listID, value); err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to set list flag")
+		renderInternalServerError(w, r)
+		return
+	}
+	after := yl
+	*flag.field(&after) = value
+	s.recordActivity(r, yl, model.ActionUpdate, "list", string(listID), yl, after)
+
+	log.WithField("output", out).Debug("list flag set")
+	render(w, r, http.StatusOK, out)
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxAttachmentSize is the maximum size of a single attachment in bytes.
+const maxAttachmentSize = 10 << 20
+
+type GetListItemAttachmentsOutput struct {
+	Attachments []model.YataAttachment
+}
+
+func (s *Server) GetListItemAttachments(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list item attachments called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Ydb.GetItem(yl.UserID, listID, itemID); err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	attachments, err := s.Ydb.GetItemAttachments(yl.UserID, listID, itemID)
+	if err != nil {
+		log.WithError(err).Error("failed to get item attachments")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetListItemAttachmentsOutput{Attachments: attachments}
+	log.WithField("output", out).Debug("list item attachments retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type PutListItemAttachmentOutput struct {
+	AttachmentID string
+	Size         int64
+}
+
+// PutListItemAttachment stores the request body as an attachment, replacing any attachment with the same ID.
+// The request's "Content-Type" header is stored and used when the attachment is downloaded.
+func (s *Server) PutListItemAttachment(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("put list item attachment called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	attachmentID := model.AttachmentID(v["attachmentID"])
+	if err := validateAttachmentID(attachmentID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, "Content-Type is not a valid media type")
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Ydb.GetItem(yl.UserID, listID, itemID); err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	// Attachments are stored under the list's owner, so they count towards the owner's quota even when an editor
+	// uploads them.
+	attachments, err := s.Ydb.GetAllAttachments(yl.UserID)
+	if err != nil {
+		log.WithError(err).Error("failed to get all attachments")
+		renderInternalServerError(w, r)
+		return
+	}
+	var used int64
+	var replaced *model.YataAttachment
+	for i, a := range attachments {
+		if a.ListID == listID && a.ItemID == itemID && a.AttachmentID == attachmentID {
+			// The attachment being replaced does not count towards the quota.
+			replaced = &attachments[i]
+			continue
+		}
+		used += a.Size
+	}
+	limit, tooLarge := attachmentLimit(s.AttachmentQuota-used, r.ContentLength)
+	if tooLarge != nil {
+		log.WithField("used", used).WithField("contentLength", r.ContentLength).Info("attachment too large")
+		render(w, r, http.StatusRequestEntityTooLarge, *tooLarge)
+		return
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.WithError(err).Error("failed to generate a uuid")
+		renderInternalServerError(w, r)
+		return
+	}
+	a := model.YataAttachment{
+		UserID:       yl.UserID,
+		ListID:       listID,
+		ItemID:       itemID,
+		AttachmentID: attachmentID,
+		ContentType:  contentType,
+		// Every upload gets its own blob so a failed replacement never clobbers the attachment it was replacing.
+		BlobKey:   strings.Join([]string{string(yl.UserID), id.String()}, "/"),
+		CreatedAt: time.Now().UTC(),
+	}
+	body := &limitedReader{r: r.Body, n: limit}
+	if err := s.Blobs.Put(a.BlobKey, body); err != nil {
+		log.WithError(err).Error("failed to put blob")
+		s.deleteBlob(log, a.BlobKey)
+		if body.exceeded {
+			// The body had no or a wrong Content-Length; work out which limit was hit as if it had been given.
+			_, tooLarge := attachmentLimit(s.AttachmentQuota-used, limit+1)
+			render(w, r, http.StatusRequestEntityTooLarge, *tooLarge)
+			return
+		}
+		renderInternalServerError(w, r)
+		return
+	}
+	a.Size = body.read
+
+	log.WithField("attachment", a).Debug("inserting attachment")
+	if err := s.Ydb.InsertAttachment(a); err != nil {
+		log.WithError(err).Error("failed to insert attachment")
+		s.deleteBlob(log, a.BlobKey)
+		renderInternalServerError(w, r)
+		return
+	}
+	if replaced != nil {
+		s.deleteBlob(log, replaced.BlobKey)
+		s.recordActivity(r, yl, model.ActionUpdate, "attachment", attachmentActivityID(a), *replaced, a)
+	} else {
+		s.recordActivity(r, yl, model.ActionCreate, "attachment", attachmentActivityID(a), nil, a)
+	}
+
+	out := PutListItemAttachmentOutput{AttachmentID: string(attachmentID), Size: a.Size}
+	log.WithField("output", out).Debug("attachment inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+func (s *Server) GetListItemAttachment(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list item attachment called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	attachmentID := model.AttachmentID(v["attachmentID"])
+	if err := validateAttachmentID(attachmentID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	a, err := s.Ydb.GetAttachment(yl.UserID, listID, itemID, attachmentID)
+	if err != nil {
+		if errnf, ok := err.(database.AttachmentNotFoundError); ok {
+			log.WithError(errnf).Info("attachment not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "AttachmentDoesNotExist", Message: "Attachment does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get attachment")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	blob, err := s.Blobs.Get(a.BlobKey)
+	if err != nil {
+		// A missing blob means the metadata and the blob store have drifted apart; there is nothing the caller can do about it.
+		log.WithError(err).Error("failed to get blob")
+		renderInternalServerError(w, r)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": string(a.AttachmentID)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.WithError(err).Warn("failed to write attachment")
+	}
+}
+
+type DeleteListItemAttachmentOutput struct {
+	AttachmentID string
+}
+
+func (s *Server) DeleteListItemAttachment(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list item attachment called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	attachmentID := model.AttachmentID(v["attachmentID"])
+	if err := validateAttachmentID(attachmentID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	a, err := s.Ydb.GetAttachment(yl.UserID, listID, itemID, attachmentID)
+	if err != nil {
+		if errnf, ok := err.(database.AttachmentNotFoundError); ok {
+			log.WithError(errnf).Info("attachment not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "AttachmentDoesNotExist", Message: "Attachment does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get attachment")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if err := s.deleteAttachment(log, a); err != nil {
+		log.WithError(err).Error("failed to delete attachment")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionDelete, "attachment", attachmentActivityID(a), a, nil)
+
+	out := DeleteListItemAttachmentOutput{AttachmentID: string(attachmentID)}
+	log.WithField("output", out).Debug("attachment deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+// deleteItemAttachments deletes every attachment of an item.
+func (s *Server) deleteItemAttachments(log *logrus.Entry, uid model.UserID, lid model.ListID, iid model.ItemID) error {
+	attachments, err := s.Ydb.GetItemAttachments(uid, lid, iid)
+	if err != nil {
+		return fmt.Errorf("failed to get item attachments: %v", err)
+	}
+	for _, a := range attachments {
+		if err := s.deleteAttachment(log, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAttachment deletes an attachment's metadata and then its blob.
+// The metadata is deleted first so that an attachment is never listed without its content.
+func (s *Server) deleteAttachment(log *logrus.Entry, a model.YataAttachment) error {
+	if err := s.Ydb.DeleteAttachment(a.UserID, a.ListID, a.ItemID, a.AttachmentID); err != nil {
+		return fmt.Errorf("failed to delete attachment %q: %v", a.AttachmentID, err)
+	}
+	s.deleteBlob(log, a.BlobKey)
+	return nil
+}
+
+// deleteBlob deletes a blob, logging instead of failing when it cannot be deleted.
+// A leftover blob wastes space but is never visible to users.
+func (s *Server) deleteBlob(log *logrus.Entry, key string) {
+	if err := s.Blobs.Delete(key); err != nil {
+		log.WithError(err).WithField("blobKey", key).Warn("failed to delete blob")
+	}
+}
+
+// attachmentLimit returns how many bytes an attachment can be given how much of the user's quota remains.
+// If contentLength, which is -1 when unknown, is already over the limit a response error explaining which limit was hit is returned.
+func attachmentLimit(remainingQuota, contentLength int64) (int64, *responseError) {
+	if contentLength > maxAttachmentSize {
+		return 0, &responseError{Code: "AttachmentTooLarge", Message: fmt.Sprintf("Attachments cannot exceed %d bytes", maxAttachmentSize)}
+	}
+	if remainingQuota < 0 {
+		remainingQuota = 0
+	}
+	if contentLength > remainingQuota {
+		return 0, &responseError{Code: "AttachmentQuotaExceeded", Message: fmt.Sprintf("Attachment quota has %d bytes remaining", remainingQuota)}
+	}
+	if remainingQuota < maxAttachmentSize {
+		return remainingQuota, nil
+	}
+	return maxAttachmentSize, nil
+}
+
+// errReadLimitExceeded is returned by a limitedReader once more than its limit has been read.
+var errReadLimitExceeded = errors.New("read limit exceeded")
+
+// limitedReader reads from r until more than n bytes have been read, at which point it fails.
+// Unlike io.LimitedReader it lets the caller tell a body that was too large apart from one that was exactly the limit.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	read     int64
+	exceeded bool
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.exceeded {
+		return 0, errReadLimitExceeded
+	}
+	if remaining := lr.n - lr.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lr.r.Read(p)
+	lr.read += int64(n)
+	if lr.read > lr.n {
+		lr.exceeded = true
+		return n, errReadLimitExceeded
+	}
+	return n, err
+}
+
+// attachmentActivityID identifies an attachment in the activity log of its list.
+func attachmentActivityID(a model.YataAttachment) string {
+	return string(a.ItemID) + "/" + string(a.AttachmentID)
+}
+
+func validateAttachmentID(id model.AttachmentID) error {
+	if len(id) == 0 {
+		return errors.New("AttachmentID cannot be empty")
+	}
+	if len(id) > 100 {
+		return errors.New("AttachmentID length cannot exceed 100 characters")
+	}
+	if len(id) != len(strings.TrimSpace(string(id))) {
+		return errors.New("AttachmentID cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentLimit(t *testing.T) {
+	tests := map[string]struct {
+		remainingQuota int64
+		contentLength  int64
+		limit          int64
+		errCode        string
+	}{
+		"unknown-length-plenty-of-quota": {
+			remainingQuota: 100 << 20,
+			contentLength:  -1,
+			limit:          maxAttachmentSize,
+		},
+		"unknown-length-little-quota": {
+			remainingQuota: 10,
+			contentLength:  -1,
+			limit:          10,
+		},
+		"known-length-fits": {
+			remainingQuota: 10,
+			contentLength:  10,
+			limit:          10,
+		},
+		"known-length-over-quota": {
+			remainingQuota: 10,
+			contentLength:  11,
+			errCode:        "AttachmentQuotaExceeded",
+		},
+		"known-length-over-max-size": {
+			remainingQuota: 100 << 20,
+			contentLength:  maxAttachmentSize + 1,
+			errCode:        "AttachmentTooLarge",
+		},
+		"quota-already-exceeded": {
+			remainingQuota: -5,
+			contentLength:  1,
+			errCode:        "AttachmentQuotaExceeded",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			limit, respErr := attachmentLimit(test.remainingQuota, test.contentLength)
+			assert.Equal(t, test.limit, limit)
+			if len(test.errCode) == 0 {
+				assert.Nil(t, respErr)
+			} else if assert.NotNil(t, respErr) {
+				assert.Equal(t, test.errCode, respErr.Code)
+			}
+		})
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	// Reading exactly the limit.
+	lr := &limitedReader{r: bytes.NewBufferString("12345"), n: 5}
+	b, err := ioutil.ReadAll(lr)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", string(b))
+	assert.Equal(t, int64(5), lr.read)
+	assert.False(t, lr.exceeded)
+
+	// Reading past the limit.
+	lr = &limitedReader{r: bytes.NewBufferString("123456"), n: 5}
+	_, err = ioutil.ReadAll(lr)
+	assert.Equal(t, errReadLimitExceeded, err)
+	assert.True(t, lr.exceeded)
+}
+
+func TestServer_PutListItemAttachment_Editor(t *testing.T) {
+	owned := model.YataAttachment{UserID: "owner", ListID: "groceries", ItemID: "milk", AttachmentID: "label.png", Size: 90, BlobKey: "owner/old"}
+	tests := map[string]struct {
+		attachmentID string
+		body         string
+		code         int
+		action       model.Action
+		deleted      []string
+	}{
+		"counts-towards-owners-quota": {
+			attachmentID: "receipt.png",
+			body:         "more than ten bytes",
+			code:         http.StatusRequestEntityTooLarge,
+		},
+		"fits-owners-quota": {
+			attachmentID: "receipt.png",
+			body:         "ten bytes!",
+			code:         http.StatusCreated,
+			action:       model.ActionCreate,
+		},
+		"replaces-owners-attachment": {
+			attachmentID: "label.png",
+			body:         "new label",
+			code:         http.StatusCreated,
+			action:       model.ActionUpdate,
+			deleted:      []string{"owner/old"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			var actions []model.Action
+			blobs := &mockBlobStore{}
+			srvr := Server{
+				AttachmentQuota: 100,
+				Blobs:           blobs,
+				Ydb: mockYdb{
+					MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+						return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleEditor}, nil
+					},
+					MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+						return model.YataList{UserID: id, ListID: lid}, nil
+					},
+					MockGetItem: func(id model.UserID, lid model.ListID, iid model.ItemID) (model.YataItem, error) {
+						return model.YataItem{UserID: id, ListID: lid, ItemID: iid}, nil
+					},
+					MockGetAllAttachments: func(id model.UserID) ([]model.YataAttachment, error) {
+						if id != "owner" {
+							return nil, nil
+						}
+						return []model.YataAttachment{owned}, nil
+					},
+					MockInsertAttachment: func(a model.YataAttachment) error {
+						assert.Equal(t, model.UserID("owner"), a.UserID)
+						return nil
+					},
+					MockInsertActivity: func(a model.YataActivity) error {
+						actions = append(actions, a.Action)
+						return nil
+					},
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "https://does.not/matter?owner=owner", bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries", "itemID": "milk", "attachmentID": test.attachmentID})
+			srvr.PutListItemAttachment(rec, req.WithContext(request.WithUserID(req.Context(), "editor")))
+
+			require.Equal(t, test.code, rec.Code, rec.Body.String())
+			if test.code != http.StatusCreated {
+				assert.Empty(t, blobs.put)
+				return
+			}
+			assert.Equal(t, []model.Action{test.action}, actions)
+			assert.Equal(t, test.deleted, blobs.deleted)
+		})
+	}
+}
+
+// mockBlobStore records the keys of the blobs that are put and deleted.
+type mockBlobStore struct {
+	mu      sync.Mutex
+	put     []string
+	deleted []string
+}
+
+func (bs *mockBlobStore) Put(key string, r io.Reader) error {
+	if _, err := ioutil.ReadAll(r); err != nil {
+		return err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.put = append(bs.put, key)
+	return nil
+}
+
+func (bs *mockBlobStore) Get(key string) (io.ReadCloser, error) {
+	panic("implement me")
+}
+
+func (bs *mockBlobStore) Delete(key string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.deleted = append(bs.deleted, key)
+	return nil
+}
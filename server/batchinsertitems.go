@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+// maxBatchItems is the most items that can be inserted in one batch.
+const maxBatchItems = 500
+
+type InsertListItemsResult struct {
+	ItemID string
+	// Error is why the item was not inserted. It is left out for items that were inserted.
+	Error *responseError `json:",omitempty"`
+}
+
+type InsertListItemsOutput struct {
+	// Results are in the same order as the items in the input.
+	Results []InsertListItemsResult
+}
+
+// InsertListItems inserts a batch of items, given as a JSON array of the input to InsertListItem, on a list. Each item
+// is validated and inserted on its own; one failing does not stop the others from being inserted.
+// Sub-tasks can be given a parent that is earlier in the batch.
+// Items can also be given as todo.txt tasks, one per line, with a Content-Type of text/plain.
+func (s *Server) InsertListItems(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert list items called")
+
+	var input []InsertListItemInput
+	var err error
+	todoTxt := requestMediaType(r) == mediaTypeTodoTxt
+	if todoTxt {
+		input, err = bindTodoTxtItems(r.Body)
+	} else {
+		err = bind(r, &input)
+	}
+	if err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if len(input) == 0 {
+		log.Info("no items in batch")
+		renderBadRequest(w, r, "items cannot be empty")
+		return
+	}
+	if len(input) > maxBatchItems {
+		log.WithField("items", len(input)).Info("too many items in batch")
+		renderBadRequest(w, r, fmt.Sprintf("items cannot contain more than %d items", maxBatchItems))
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	if todoTxt {
+		input = keepTodoTxtItemFields(input, items)
+	}
+
+	b, err := s.newItemBatch(yl, items, sections, input)
+	if err != nil {
+		log.WithError(err).Error("failed to prepare batch")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if err := s.Ydb.InsertItems(b.writes); err != nil {
+		errup, ok := err.(database.UnprocessedItemsError)
+		if !ok {
+			log.WithError(err).Error("failed to insert items")
+			renderInternalServerError(w, r)
+			return
+		}
+		log.WithError(errup).Warn("some items were not inserted")
+		for _, item := range errup.Items {
+			for _, i := range b.causes[item.ItemID] {
+				b.results[i].Error = &responseError{Code: "ItemNotProcessed", Message: "The item could not be inserted; try again"}
+			}
+		}
+	}
+
+	for i, result := range b.results {
+		if result.Error == nil {
+			e := b.entries[i]
+			s.recordItemInserted(r, yl, e.before, e.exists, e.item)
+		}
+	}
+
+	out := InsertListItemsOutput{Results: b.results}
+	log.WithField("output", out).Debug("items inserted")
+	render(w, r, http.StatusOK, out)
+}
+
+// batchEntry is an item of a batch that passed validation.
+type batchEntry struct {
+	item   model.YataItem
+	before model.YataItem
+	exists bool
+}
+
+// itemBatch is a batch of items that is ready to be written.
+type itemBatch struct {
+	results []InsertListItemsResult
+	// entries are indexed the same as results. Only the entries of results without an error are set.
+	entries []batchEntry
+	// writes are the items to write, including sub-tasks that move to a new section along with their parent.
+	writes []model.YataItem
+	// causes maps the IDs of writes to the indexes of the results that fail if they are not written.
+	causes map[model.ItemID][]int
+}
+
+// newItemBatch validates each item of input against the list's items and sections, and the items before it.
+// Items that do not pass validation are given an error result and are not written.
+func (s *Server) newItemBatch(yl model.YataList, items []model.YataItem, sections []model.YataSection, input []InsertListItemInput) (itemBatch, error) {
+	b := itemBatch{
+		results: make([]InsertListItemsResult, len(input)),
+		entries: make([]batchEntry, len(input)),
+		causes:  map[model.ItemID][]int{},
+	}
+
+	// all holds the list's items as they will be once the batch is written.
+	all := append([]model.YataItem(nil), items...)
+	index := map[model.ItemID]int{}
+	for i, item := range all {
+		index[item.ItemID] = i
+	}
+	writeIndex := map[model.ItemID]int{}
+	put := func(item model.YataItem, cause int) {
+		if i, ok := index[item.ItemID]; ok {
+			all[i] = item
+		} else {
+			index[item.ItemID] = len(all)
+			all = append(all, item)
+		}
+		if i, ok := writeIndex[item.ItemID]; ok {
+			b.writes[i] = item
+		} else {
+			writeIndex[item.ItemID] = len(b.writes)
+			b.writes = append(b.writes, item)
+		}
+		b.causes[item.ItemID] = append(b.causes[item.ItemID], cause)
+	}
+
+	hasSection := map[model.SectionID]bool{}
+	for _, section := range sections {
+		hasSection[section.SectionID] = true
+	}
+	isMember := map[model.UserID]bool{}
+	seen := map[string]bool{}
+
+	for i, in := range input {
+		b.results[i].ItemID = in.ItemID
+		fail := func(code, msg string) {
+			b.results[i].Error = &responseError{Code: code, Message: msg}
+		}
+
+		if err := in.Validate(); err != nil {
+			fail("BadRequest", err.Error())
+			continue
+		}
+		if seen[in.ItemID] {
+			fail("BadRequest", fmt.Sprintf("ItemID %q cannot be inserted more than once in a batch", in.ItemID))
+			continue
+		}
+		seen[in.ItemID] = true
+
+		var before model.YataItem
+		j, exists := index[model.ItemID(in.ItemID)]
+		if exists {
+			before = all[j]
+		}
+		if !exists && yl.Archived {
+			fail("ListArchived", "Items cannot be added to an archived list")
+			continue
+		}
+
+		yi := itemFromInput(yl, in, before, exists)
+		if len(yi.AssigneeID) != 0 {
+			ok, checked := isMember[yi.AssigneeID]
+			if !checked {
+				var err error
+				if ok, err = s.isListMember(yl, yi.AssigneeID); err != nil {
+					return itemBatch{}, err
+				}
+				isMember[yi.AssigneeID] = ok
+			}
+			if !ok {
+				fail("BadRequest", "AssigneeID must be the list's owner or one of its members")
+				continue
+			}
+		}
+		if len(yi.ParentID) != 0 {
+			if err := validateItemNesting(all, yi); err != nil {
+				fail("BadRequest", err.Error())
+				continue
+			}
+			parent := all[index[yi.ParentID]]
+			if len(yi.SectionID) != 0 && yi.SectionID != parent.SectionID {
+				fail("BadRequest", "SectionID must be the same as the parent's SectionID")
+				continue
+			}
+			yi.SectionID = parent.SectionID
+		} else if len(yi.SectionID) != 0 && !hasSection[yi.SectionID] {
+			fail("BadRequest", "SectionID must be the ID of one of the list's sections")
+			continue
+		}
+
+		put(yi, i)
+		if exists && before.SectionID != yi.SectionID {
+			// Sub-tasks move along with their parent.
+			for _, descendant := range itemDescendants(all, yi.ItemID) {
+				descendant.SectionID = yi.SectionID
+				put(descendant, i)
+			}
+		}
+		b.entries[i] = batchEntry{item: yi, before: before, exists: exists}
+	}
+	return b, nil
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_InsertListItems(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		body        string
+		archived    bool
+		unprocessed []model.ItemID
+		code        int
+		results     []InsertListItemsResult
+		written     []model.ItemID
+	}{
+		"inserts-valid-items": {
+			body: `[{"ItemID":"eggs","Content":"Eggs","SectionID":"dairy"},{"ItemID":"free-range","ParentID":"eggs","Content":"Free range"}]`,
+			code: http.StatusOK,
+			results: []InsertListItemsResult{
+				{ItemID: "eggs"},
+				{ItemID: "free-range"},
+			},
+			written: []model.ItemID{"eggs", "free-range"},
+		},
+		"reports-invalid-items": {
+			body: `[{"ItemID":"eggs","Content":""},{"ItemID":"milk","Content":"Milk"},{"ItemID":"milk","Content":"Oat milk"},{"ItemID":"oat","ParentID":"nope","Content":"Oat"},{"ItemID":"bread","Content":"Bread","SectionID":"bakery"}]`,
+			code: http.StatusOK,
+			results: []InsertListItemsResult{
+				{ItemID: "eggs", Error: &responseError{Code: "BadRequest", Message: "Content cannot be empty"}},
+				{ItemID: "milk"},
+				{ItemID: "milk", Error: &responseError{Code: "BadRequest", Message: `ItemID "milk" cannot be inserted more than once in a batch`}},
+				{ItemID: "oat", Error: &responseError{Code: "BadRequest", Message: `parent item "nope" does not exist`}},
+				{ItemID: "bread", Error: &responseError{Code: "BadRequest", Message: "SectionID must be the ID of one of the list's sections"}},
+			},
+			written: []model.ItemID{"milk"},
+		},
+		"updates-existing-items-on-archived-list": {
+			body:     `[{"ItemID":"butter","Content":"Salted butter"},{"ItemID":"jam","Content":"Jam"}]`,
+			archived: true,
+			code:     http.StatusOK,
+			results: []InsertListItemsResult{
+				{ItemID: "butter"},
+				{ItemID: "jam", Error: &responseError{Code: "ListArchived", Message: "Items cannot be added to an archived list"}},
+			},
+			written: []model.ItemID{"butter"},
+		},
+		"reports-unprocessed-items": {
+			body:        `[{"ItemID":"eggs","Content":"Eggs"},{"ItemID":"milk","Content":"Milk"}]`,
+			unprocessed: []model.ItemID{"milk"},
+			code:        http.StatusOK,
+			results: []InsertListItemsResult{
+				{ItemID: "eggs"},
+				{ItemID: "milk", Error: &responseError{Code: "ItemNotProcessed", Message: "The item could not be inserted; try again"}},
+			},
+			written: []model.ItemID{"eggs", "milk"},
+		},
+		"todo-txt": {
+			contentType: "text/plain; charset=utf-8",
+			body:        "(A) Eggs +breakfast id:eggs\n\nx Salted butter id:butter\n",
+			code:        http.StatusOK,
+			results: []InsertListItemsResult{
+				{ItemID: "eggs"},
+				{ItemID: "butter"},
+			},
+			written: []model.ItemID{"eggs", "butter"},
+		},
+		"empty": {
+			body: `[]`,
+			code: http.StatusBadRequest,
+		},
+		"not-an-array": {
+			body: `{"ItemID":"eggs","Content":"Eggs"}`,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var written []model.ItemID
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid, Archived: test.archived}, nil
+				},
+				MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+					return []model.YataItem{{UserID: id, ListID: lid, ItemID: "butter", Content: "Butter"}}, nil
+				},
+				MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+					return []model.YataSection{{UserID: id, ListID: lid, SectionID: "dairy"}}, nil
+				},
+				MockInsertItems: func(items []model.YataItem) error {
+					var unprocessed []model.YataItem
+					for _, item := range items {
+						written = append(written, item.ItemID)
+						for _, id := range test.unprocessed {
+							if item.ItemID == id {
+								unprocessed = append(unprocessed, item)
+							}
+						}
+					}
+					if len(unprocessed) != 0 {
+						return database.UnprocessedItemsError{Items: unprocessed}
+					}
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+			req.Header.Set("Content-Type", test.contentType)
+
+			srvr := Server{Ydb: ydb}
+			srvr.InsertListItems(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			if test.code != http.StatusOK {
+				return
+			}
+			var out InsertListItemsOutput
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+			assert.Equal(t, test.results, out.Results)
+			assert.Equal(t, test.written, written)
+		})
+	}
+}
+
+func TestNewItemBatch_SubTasksFollowParentSection(t *testing.T) {
+	yl := model.YataList{UserID: "me", ListID: "groceries"}
+	items := []model.YataItem{
+		{UserID: "me", ListID: "groceries", ItemID: "eggs", Content: "Eggs"},
+		{UserID: "me", ListID: "groceries", ItemID: "free-range", ParentID: "eggs", Content: "Free range"},
+	}
+	sections := []model.YataSection{{UserID: "me", ListID: "groceries", SectionID: "dairy"}}
+	input := []InsertListItemInput{{ItemID: "eggs", Content: "Eggs", SectionID: "dairy"}}
+
+	srvr := Server{}
+	b, err := srvr.newItemBatch(yl, items, sections, input)
+	assert.NoError(t, err)
+
+	sectionOf := map[model.ItemID]model.SectionID{}
+	for _, item := range b.writes {
+		sectionOf[item.ItemID] = item.SectionID
+	}
+	assert.Equal(t, map[model.ItemID]model.SectionID{"eggs": "dairy", "free-range": "dairy"}, sectionOf)
+	assert.Equal(t, []int{0}, b.causes["free-range"])
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxSyncBulkItems is the most items a bulk action can apply to for its response to wait until it is done.
+	// Larger actions respond straight away and carry on in the background.
+	maxSyncBulkItems = 100
+	// maxBulkItemIDs is the most item IDs a bulk action can be given.
+	maxBulkItemIDs = 1000
+)
+
+// BulkAction is what a bulk action does to each item it applies to.
+type BulkAction string
+
+const (
+	BulkComplete   BulkAction = "complete"
+	BulkUncomplete BulkAction = "uncomplete"
+	// BulkDelete moves items, along with their sub-tasks, to the trash.
+	BulkDelete BulkAction = "delete"
+	// BulkMove moves items, along with their sub-tasks, to another list.
+	BulkMove  BulkAction = "move"
+	BulkTag   BulkAction = "tag"
+	BulkUntag BulkAction = "untag"
+)
+
+// ItemFilter selects items. Items must match every field that is set.
+type ItemFilter struct {
+	Tag       string
+	Completed *bool
+	// DueAfter and DueBefore select items due in a range; DueAfter is inclusive and DueBefore is exclusive.
+	// Items without a due date never match them.
+	DueAfter  *time.Time
+	DueBefore *time.Time
+}
+
+// Matches returns true if item matches the filter.
+func (f ItemFilter) Matches(item model.YataItem) bool {
+	if len(f.Tag) != 0 && !hasTag(item, f.Tag) {
+		return false
+	}
+	if f.Completed != nil && item.Completed != *f.Completed {
+		return false
+	}
+	if f.DueAfter != nil && (item.DueAt == nil || item.DueAt.Before(*f.DueAfter)) {
+		return false
+	}
+	if f.DueBefore != nil && (item.DueAt == nil || !item.DueAt.Before(*f.DueBefore)) {
+		return false
+	}
+	return true
+}
+
+func (f ItemFilter) isEmpty() bool {
+	return len(f.Tag) == 0 && f.Completed == nil && f.DueAfter == nil && f.DueBefore == nil
+}
+
+type BulkListItemsInput struct {
+	Action BulkAction
+	// ItemIDs are the items to apply the action to. If it is empty the action applies to the items matching Filter
+	// instead, which is every item on the list if Filter is empty too.
+	ItemIDs []string
+	Filter  ItemFilter
+	// ListID and OwnerID are the list to move items to. OwnerID defaults to the caller.
+	ListID  string
+	OwnerID string
+	// Tag is the tag to add or remove.
+	Tag string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *BulkListItemsInput) Validate() error {
+	switch input.Action {
+	case BulkComplete, BulkUncomplete, BulkDelete:
+	case BulkMove:
+		if err := validateListID(model.ListID(input.ListID)); err != nil {
+			return err
+		}
+	case BulkTag, BulkUntag:
+		if err := validateTag(input.Tag); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Action must be one of %q, %q, %q, %q, %q or %q", BulkComplete, BulkUncomplete, BulkDelete, BulkMove, BulkTag, BulkUntag)
+	}
+	if len(input.ItemIDs) != 0 && !input.Filter.isEmpty() {
+		return errors.New("ItemIDs and Filter cannot both be set")
+	}
+	if len(input.ItemIDs) > maxBulkItemIDs {
+		return fmt.Errorf("ItemIDs cannot contain more than %d items", maxBulkItemIDs)
+	}
+	seen := map[string]bool{}
+	for _, id := range input.ItemIDs {
+		if err := validateItemID(model.ItemID(id)); err != nil {
+			return err
+		}
+		if seen[id] {
+			return fmt.Errorf("ItemIDs cannot contain %q more than once", id)
+		}
+		seen[id] = true
+	}
+	if len(input.Filter.Tag) != 0 {
+		if err := validateTag(input.Filter.Tag); err != nil {
+			return err
+		}
+	}
+	if input.Filter.DueAfter != nil && input.Filter.DueBefore != nil && !input.Filter.DueAfter.Before(*input.Filter.DueBefore) {
+		return errors.New("Filter.DueAfter must be before Filter.DueBefore")
+	}
+	return nil
+}
+
+type BulkListItemsOutput struct {
+	// Job is the action's progress. It has finished unless the action applies to many items, in which case the
+	// response is 202 Accepted and its progress can be followed with GetJob.
+	Job JobStatus
+}
+
+// BulkListItems applies an action to many items on a list at once.
+func (s *Server) BulkListItems(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("bulk list items called")
+
+	var input BulkListItemsInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+	var dst model.YataList
+	if input.Action == BulkMove {
+		if dst, _, ok = s.authorizeOwnedList(w, r, model.UserID(input.OwnerID), model.ListID(input.ListID), model.RoleEditor); !ok {
+			return
+		}
+		if dst.UserID == yl.UserID && dst.ListID == yl.ListID {
+			log.Info("items moved to their own list")
+			renderBadRequest(w, r, "ListID must be a different list")
+			return
+		}
+		if dst.Archived {
+			log.Info("list is archived")
+			render(w, r, http.StatusConflict, responseError{Code: "ListArchived", Message: "Items cannot be added to an archived list"})
+			return
+		}
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	matched, missing := selectItems(items, input)
+
+	// The job outlives the request, so it only keeps who the caller is.
+	actor := requestActor(r)
+	j, err := s.jobs.start(uid, "bulk", func(j *job) {
+		j.addTotal(len(missing))
+		for _, id := range missing {
+			j.fail(string(id), responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+		}
+		err := s.applyBulkAction(actor, j, yl, dst, items, matched, input)
+		if err != nil {
+			log.WithError(err).Error("bulk action failed")
+		}
+		j.finish(nil, err)
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to start job")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	code := http.StatusAccepted
+	if len(matched) <= maxSyncBulkItems {
+		<-j.done
+		code = http.StatusOK
+	}
+	out := BulkListItemsOutput{Job: j.Status()}
+	log.WithField("output", out).Debug("bulk action started")
+	render(w, r, code, out)
+}
+
+// selectItems returns the items the input applies to, and the IDs the input names that are not items.
+func selectItems(items []model.YataItem, input BulkListItemsInput) ([]model.YataItem, []model.ItemID) {
+	var matched []model.YataItem
+	if len(input.ItemIDs) == 0 {
+		for _, item := range items {
+			if input.Filter.Matches(item) {
+				matched = append(matched, item)
+			}
+		}
+		return matched, nil
+	}
+
+	byID := map[model.ItemID]model.YataItem{}
+	for _, item := range items {
+		byID[item.ItemID] = item
+	}
+	var missing []model.ItemID
+	for _, id := range input.ItemIDs {
+		item, ok := byID[model.ItemID(id)]
+		if !ok {
+			missing = append(missing, model.ItemID(id))
+			continue
+		}
+		matched = append(matched, item)
+	}
+	return matched, missing
+}
+
+// applyBulkAction applies the input's action to the matched items on yl, whose items are items. dst is the list items
+// are moved to. The job's progress is updated as it goes; the returned error is for failures that stop the action.
+func (s *Server) applyBulkAction(actor activityActor, j *job, yl, dst model.YataList, items, matched []model.YataItem, input BulkListItemsInput) error {
+	if input.Action == BulkMove {
+		return s.bulkMove(actor, j, yl, dst, items, matched)
+	}
+
+	// Deleting an item deletes its sub-tasks too. Each sub-task is only counted once.
+	if input.Action == BulkDelete {
+		seen := map[model.ItemID]bool{}
+		var targets []model.YataItem
+		for _, item := range matched {
+			for _, target := range append(itemDescendants(items, item.ItemID), item) {
+				if !seen[target.ItemID] {
+					seen[target.ItemID] = true
+					targets = append(targets, target)
+				}
+			}
+		}
+		matched = targets
+	}
+	j.addTotal(len(matched))
+
+	deletedAt := time.Now().UTC()
+	type change struct{ before, after model.YataItem }
+	var changes []change
+	for _, before := range matched {
+		after, changed := bulkApply(before, input, deletedAt)
+		if !changed {
+			j.succeed(1)
+			continue
+		}
+		changes = append(changes, change{before: before, after: after})
+	}
+
+	for len(changes) != 0 {
+		chunk := changes
+		if len(chunk) > database.MaxItemUpdates {
+			chunk = chunk[:database.MaxItemUpdates]
+		}
+		changes = changes[len(chunk):]
+
+		// Only the field the action changes is written, and only if the item still exists, so that concurrent changes
+		// to the item's other fields are kept and deleted items are not recreated.
+		updates := make([]database.ItemUpdate, len(chunk))
+		for i, c := range chunk {
+			updates[i] = bulkUpdate(c.before, input, deletedAt)
+		}
+		notFound := map[model.ItemID]bool{}
+		if err := s.Ydb.UpdateItems(updates); err != nil {
+			nfe, ok := err.(database.ItemsNotFoundError)
+			if !ok {
+				return fmt.Errorf("failed to update items: %v", err)
+			}
+			for _, u := range nfe.Updates {
+				notFound[u.ItemID] = true
+			}
+		}
+
+		for _, c := range chunk {
+			id := c.after.ItemID
+			if notFound[id] {
+				j.fail(string(id), responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+				continue
+			}
+			switch input.Action {
+			case BulkDelete:
+				s.recordActorActivity(actor, yl, model.ActionDelete, "item", string(id), c.before, nil)
+			case BulkComplete:
+				s.recordActorActivity(actor, yl, model.ActionComplete, "item", string(id), c.before, c.after)
+			default:
+				s.recordActorActivity(actor, yl, model.ActionUpdate, "item", string(id), c.before, c.after)
+			}
+			j.succeed(1)
+		}
+	}
+	return nil
+}
+
+// bulkUpdate returns the update that applies the input's action to item. It does not handle BulkMove.
+func bulkUpdate(item model.YataItem, input BulkListItemsInput, deletedAt time.Time) database.ItemUpdate {
+	u := database.ItemUpdate{UserID: item.UserID, ListID: item.ListID, ItemID: item.ItemID, Tags: item.Tags}
+	switch input.Action {
+	case BulkComplete, BulkUncomplete:
+		completed := input.Action == BulkComplete
+		u.Completed = &completed
+	case BulkDelete:
+		u.DeletedAt = &deletedAt
+	case BulkTag:
+		u.AddTag = input.Tag
+	case BulkUntag:
+		u.RemoveTag = input.Tag
+	}
+	return u
+}
+
+// bulkApply returns item with the input's action applied to it, and whether that changed it.
+// It does not handle BulkMove.
+func bulkApply(item model.YataItem, input BulkListItemsInput, deletedAt time.Time) (model.YataItem, bool) {
+	switch input.Action {
+	case BulkComplete, BulkUncomplete:
+		completed := input.Action == BulkComplete
+		if item.Completed == completed {
+			return item, false
+		}
+		item.Completed = completed
+	case BulkDelete:
+		item.DeletedAt = &deletedAt
+	case BulkTag:
+		if hasTag(item, input.Tag) {
+			return item, false
+		}
+		item.Tags = append(append([]string(nil), item.Tags...), input.Tag)
+	case BulkUntag:
+		if !hasTag(item, input.Tag) {
+			return item, false
+		}
+		var tags []string
+		for _, tag := range item.Tags {
+			if tag != input.Tag {
+				tags = append(tags, tag)
+			}
+		}
+		item.Tags = tags
+	}
+	return item, true
+}
+
+// bulkMove moves the matched items on yl, along with their sub-tasks, to dst. Each item is moved in its own
+// transaction. Sub-tasks whose ancestor is also matched are moved along with it.
+func (s *Server) bulkMove(actor activityActor, j *job, yl, dst model.YataList, items, matched []model.YataItem) error {
+	isMatched := map[model.ItemID]bool{}
+	for _, item := range matched {
+		isMatched[item.ItemID] = true
+	}
+	parents := map[model.ItemID]model.ItemID{}
+	for _, item := range items {
+		parents[item.ItemID] = item.ParentID
+	}
+	hasMatchedAncestor := func(iid model.ItemID) bool {
+		for pid, depth := parents[iid], 0; len(pid) != 0 && depth <= maxItemNesting; pid, depth = parents[pid], depth+1 {
+			if isMatched[pid] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var roots []model.YataItem
+	total := 0
+	for _, item := range matched {
+		if hasMatchedAncestor(item.ItemID) {
+			continue
+		}
+		roots = append(roots, item)
+		total += 1 + len(itemDescendants(items, item.ItemID))
+	}
+	j.addTotal(total)
+
+	for _, root := range roots {
+		moving := append(itemDescendants(items, root.ItemID), root)
+		moves, errResp, err := s.itemMoves(moving, root.ItemID, dst, "")
+		if err != nil {
+			return err
+		}
+		if errResp == nil {
+			if err := s.Ydb.MoveItems(moves); err != nil {
+				if _, errResp = moveErrorResponse(err); errResp == nil {
+					return fmt.Errorf("failed to move items: %v", err)
+				}
+			}
+		}
+		if errResp != nil {
+			// The item's sub-tasks stay with it.
+			for _, item := range moving {
+				j.fail(string(item.ItemID), *errResp)
+			}
+			continue
+		}
+		for _, m := range moves {
+			s.recordActorActivity(actor, yl, model.ActionDelete, "item", string(m.From.ItemID), m.From, nil)
+			s.recordActorActivity(actor, dst, model.ActionCreate, "item", string(m.To.ItemID), nil, m.To)
+		}
+		j.succeed(len(moves))
+	}
+	return nil
+}
+
+func hasTag(item model.YataItem, tag string) bool {
+	for _, t := range item.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
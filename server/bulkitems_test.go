@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkListItemsInput_Validate(t *testing.T) {
+	completed := true
+	now := time.Now()
+
+	tests := map[string]struct {
+		input BulkListItemsInput
+		err   error
+	}{
+		"complete-by-filter": {
+			input: BulkListItemsInput{Action: BulkComplete, Filter: ItemFilter{Completed: &completed}},
+		},
+		"delete-by-id": {
+			input: BulkListItemsInput{Action: BulkDelete, ItemIDs: []string{"a", "b"}},
+		},
+		"unknown-action": {
+			input: BulkListItemsInput{Action: "archive"},
+			err:   errors.New(`Action must be one of "complete", "uncomplete", "delete", "move", "tag" or "untag"`),
+		},
+		"move-without-list": {
+			input: BulkListItemsInput{Action: BulkMove},
+			err:   errors.New("ListID cannot be empty"),
+		},
+		"tag-without-tag": {
+			input: BulkListItemsInput{Action: BulkTag},
+			err:   errors.New("Tag cannot be empty"),
+		},
+		"ids-and-filter": {
+			input: BulkListItemsInput{Action: BulkComplete, ItemIDs: []string{"a"}, Filter: ItemFilter{Tag: "work"}},
+			err:   errors.New("ItemIDs and Filter cannot both be set"),
+		},
+		"duplicate-id": {
+			input: BulkListItemsInput{Action: BulkComplete, ItemIDs: []string{"a", "a"}},
+			err:   errors.New(`ItemIDs cannot contain "a" more than once`),
+		},
+		"empty-due-range": {
+			input: BulkListItemsInput{Action: BulkComplete, Filter: ItemFilter{DueAfter: &now, DueBefore: &now}},
+			err:   errors.New("Filter.DueAfter must be before Filter.DueBefore"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestItemFilter_Matches(t *testing.T) {
+	completed := true
+	jan := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	item := model.YataItem{Completed: true, DueAt: &jan, Tags: []string{"work"}}
+
+	tests := map[string]struct {
+		filter ItemFilter
+		want   bool
+	}{
+		"empty":          {filter: ItemFilter{}, want: true},
+		"tag":            {filter: ItemFilter{Tag: "work"}, want: true},
+		"other-tag":      {filter: ItemFilter{Tag: "home"}, want: false},
+		"completed":      {filter: ItemFilter{Completed: &completed}, want: true},
+		"due-after":      {filter: ItemFilter{DueAfter: &jan}, want: true},
+		"due-before":     {filter: ItemFilter{DueBefore: &jan}, want: false},
+		"due-in-range":   {filter: ItemFilter{DueAfter: &jan, DueBefore: &feb}, want: true},
+		"due-after-feb":  {filter: ItemFilter{DueAfter: &feb}, want: false},
+		"due-before-feb": {filter: ItemFilter{DueBefore: &feb}, want: true},
+		"must-match-all": {filter: ItemFilter{Tag: "work", DueAfter: &feb}, want: false},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, test.filter.Matches(item))
+		})
+	}
+	assert.False(t, ItemFilter{DueBefore: &feb}.Matches(model.YataItem{}), "items without a due date never match a due range")
+}
+
+func TestServer_BulkListItems(t *testing.T) {
+	due := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []model.YataItem{
+		{UserID: "me", ListID: "todo", ItemID: "a", Completed: true},
+		{UserID: "me", ListID: "todo", ItemID: "a1", ParentID: "a"},
+		{UserID: "me", ListID: "todo", ItemID: "b", DueAt: &due, Tags: []string{"work"}},
+		{UserID: "me", ListID: "todo", ItemID: "c", Completed: true, Tags: []string{"work"}},
+		// gone is deleted by someone else after the list's items are read.
+		{UserID: "me", ListID: "todo", ItemID: "gone", Tags: []string{"work"}},
+	}
+
+	tests := map[string]struct {
+		body    string
+		code    int
+		written []string
+		moved   []model.ItemID
+		status  JobStatus
+	}{
+		"clear-completed": {
+			body:    `{"Action":"delete","Filter":{"Completed":true}}`,
+			code:    http.StatusOK,
+			written: []string{"trash a1", "trash a", "trash c"},
+			status:  JobStatus{Kind: "bulk", State: JobSucceeded, Total: 3, Processed: 3},
+		},
+		"complete-by-tag": {
+			body:    `{"Action":"complete","Filter":{"Tag":"work"}}`,
+			code:    http.StatusOK,
+			written: []string{"complete b", "complete gone"},
+			status: JobStatus{
+				Kind: "bulk", State: JobSucceeded, Total: 3, Processed: 3, Failed: 1,
+				Errors: []JobError{{ID: "gone", responseError: responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"}}},
+			},
+		},
+		"uncomplete-by-id": {
+			body:    `{"Action":"uncomplete","ItemIDs":["a","b"]}`,
+			code:    http.StatusOK,
+			written: []string{"uncomplete a"},
+			status:  JobStatus{Kind: "bulk", State: JobSucceeded, Total: 2, Processed: 2},
+		},
+		"tag-by-id": {
+			body:    `{"Action":"tag","Tag":"home","ItemIDs":["a","missing"]}`,
+			code:    http.StatusOK,
+			written: []string{"tag a home"},
+			status: JobStatus{
+				Kind: "bulk", State: JobSucceeded, Total: 2, Processed: 2, Failed: 1,
+				Errors: []JobError{{ID: "missing", responseError: responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"}}},
+			},
+		},
+		"untag-by-tag": {
+			body:    `{"Action":"untag","Tag":"work","Filter":{"Tag":"work","Completed":true}}`,
+			code:    http.StatusOK,
+			written: []string{"untag c work"},
+			status:  JobStatus{Kind: "bulk", State: JobSucceeded, Total: 1, Processed: 1},
+		},
+		"move-with-sub-tasks": {
+			body:   `{"Action":"move","ListID":"done","ItemIDs":["a","a1"]}`,
+			code:   http.StatusOK,
+			moved:  []model.ItemID{"a", "a1"},
+			status: JobStatus{Kind: "bulk", State: JobSucceeded, Total: 2, Processed: 2},
+		},
+		"invalid": {
+			body: `{"Action":"archive"}`,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var written []string
+			var moved []model.ItemID
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid}, nil
+				},
+				MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+					return items, nil
+				},
+				MockGetItemAttachments: func(id model.UserID, lid model.ListID, iid model.ItemID) ([]model.YataAttachment, error) {
+					return nil, nil
+				},
+				MockUpdateItems: func(updates []database.ItemUpdate) error {
+					mu.Lock()
+					defer mu.Unlock()
+					var nfe database.ItemsNotFoundError
+					for _, u := range updates {
+						iid := string(u.ItemID)
+						switch {
+						case u.Completed != nil && *u.Completed:
+							written = append(written, "complete "+iid)
+						case u.Completed != nil:
+							written = append(written, "uncomplete "+iid)
+						case u.DeletedAt != nil:
+							written = append(written, "trash "+iid)
+						case len(u.AddTag) != 0:
+							written = append(written, "tag "+iid+" "+u.AddTag)
+						case len(u.RemoveTag) != 0:
+							written = append(written, "untag "+iid+" "+u.RemoveTag)
+						}
+						if u.ItemID == "gone" {
+							nfe.Updates = append(nfe.Updates, u)
+						}
+					}
+					if len(nfe.Updates) != 0 {
+						return nfe
+					}
+					return nil
+				},
+				MockMoveItems: func(moves []database.ItemMove) error {
+					mu.Lock()
+					defer mu.Unlock()
+					for _, m := range moves {
+						moved = append(moved, m.To.ItemID)
+					}
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "todo"})
+
+			srvr := Server{Ydb: ydb}
+			srvr.BulkListItems(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			if test.code != http.StatusOK {
+				return
+			}
+			var out BulkListItemsOutput
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+			assert.NotEmpty(t, out.Job.JobID)
+			assert.NotNil(t, out.Job.FinishedAt)
+			out.Job.JobID, out.Job.CreatedAt, out.Job.FinishedAt = "", time.Time{}, nil
+			assert.Equal(t, test.status, out.Job)
+
+			assert.Equal(t, test.written, written)
+			sort.Slice(moved, func(i, j int) bool { return moved[i] < moved[j] })
+			assert.Equal(t, test.moved, moved)
+		})
+	}
+}
+
+func TestServer_BulkListItems_Chunks(t *testing.T) {
+	var items []model.YataItem
+	for i := 0; i < database.MaxItemUpdates+50; i++ {
+		items = append(items, model.YataItem{UserID: "me", ListID: "todo", ItemID: model.ItemID(fmt.Sprintf("i%d", i))})
+	}
+
+	var mu sync.Mutex
+	var chunks []int
+	ydb := mockYdb{
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return items, nil
+		},
+		MockUpdateItems: func(updates []database.ItemUpdate) error {
+			mu.Lock()
+			defer mu.Unlock()
+			chunks = append(chunks, len(updates))
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(`{"Action":"complete"}`))
+	req = mux.SetURLVars(req, map[string]string{"listID": "todo"})
+
+	srvr := Server{Ydb: ydb}
+	srvr.BulkListItems(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var out BulkListItemsOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	j, ok := srvr.jobs.get("me", out.Job.JobID)
+	assert.True(t, ok)
+	<-j.done
+	status := j.Status()
+	status.JobID, status.CreatedAt, status.FinishedAt = "", time.Time{}, nil
+	assert.Equal(t, JobStatus{Kind: "bulk", State: JobSucceeded, Total: len(items), Processed: len(items)}, status)
+	assert.Equal(t, []int{database.MaxItemUpdates, 50}, chunks)
+}
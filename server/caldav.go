@@ -0,0 +1,1018 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+// Lists are served over CalDAV (RFC 4791) as task collections: calendars of VTODOs, one for each item, so that apps
+// such as Apple Reminders, Thunderbird and DAVx5 can sync them. Clients authenticate with app passwords.
+//
+//	/dav/                                       the caller's principal
+//	/dav/lists/                                 the caller's calendar home
+//	/dav/lists/{owner}/{listID}/                a list the caller owns or is a member of
+//	/dav/lists/{owner}/{listID}/{itemID}.ics    an item on the list
+
+const (
+	davNamespace    = "DAV:"
+	calDAVNamespace = "urn:ietf:params:xml:ns:caldav"
+	// calendarServerNamespace and appleICalNamespace are Apple's extensions, which most clients support.
+	calendarServerNamespace = "http://calendarserver.org/ns/"
+	appleICalNamespace      = "http://apple.com/ns/ical/"
+)
+
+// davPrefixes are the prefixes the XML the server writes uses for the namespaces it knows about.
+var davPrefixes = map[string]string{
+	davNamespace:            "d",
+	calDAVNamespace:         "c",
+	calendarServerNamespace: "cs",
+	appleICalNamespace:      "ical",
+}
+
+const (
+	davPrincipalPath    = "/dav/"
+	davCalendarHomePath = "/dav/lists/"
+)
+
+// mediaTypeXML is the media type of WebDAV request and response bodies.
+const mediaTypeXML = "application/xml"
+
+// calendarObjectMediaType is the media type of an item's calendar object resource.
+const calendarObjectMediaType = ical.MediaType + "; charset=utf-8; component=VTODO"
+
+// maxDAVBodySize is the maximum size of a PROPFIND or REPORT request body, and maxCalendarObjectSize of a calendar
+// object resource, in bytes.
+const (
+	maxDAVBodySize        = 1 << 20
+	maxCalendarObjectSize = 1 << 20
+)
+
+var (
+	davResourceType               = xml.Name{Space: davNamespace, Local: "resourcetype"}
+	davDisplayName                = xml.Name{Space: davNamespace, Local: "displayname"}
+	davGetETag                    = xml.Name{Space: davNamespace, Local: "getetag"}
+	davGetContentType             = xml.Name{Space: davNamespace, Local: "getcontenttype"}
+	davCurrentUserPrincipal       = xml.Name{Space: davNamespace, Local: "current-user-principal"}
+	davPrincipalURL               = xml.Name{Space: davNamespace, Local: "principal-URL"}
+	davCurrentUserPrivilegeSet    = xml.Name{Space: davNamespace, Local: "current-user-privilege-set"}
+	davSupportedReportSet         = xml.Name{Space: davNamespace, Local: "supported-report-set"}
+	davSupportedReport            = xml.Name{Space: davNamespace, Local: "supported-report"}
+	calDAVCalendarHomeSet         = xml.Name{Space: calDAVNamespace, Local: "calendar-home-set"}
+	calDAVCalendarDescription     = xml.Name{Space: calDAVNamespace, Local: "calendar-description"}
+	calDAVSupportedComponentSet   = xml.Name{Space: calDAVNamespace, Local: "supported-calendar-component-set"}
+	calDAVCalendarData            = xml.Name{Space: calDAVNamespace, Local: "calendar-data"}
+	calDAVCalendarMultiget        = xml.Name{Space: calDAVNamespace, Local: "calendar-multiget"}
+	calDAVCalendarQuery           = xml.Name{Space: calDAVNamespace, Local: "calendar-query"}
+	calDAVSupportedCalendarData   = xml.Name{Space: calDAVNamespace, Local: "supported-calendar-data"}
+	calDAVValidCalendarData       = xml.Name{Space: calDAVNamespace, Local: "valid-calendar-data"}
+	calDAVSupportedComponent      = xml.Name{Space: calDAVNamespace, Local: "supported-calendar-component"}
+	calDAVMaxResourceSize         = xml.Name{Space: calDAVNamespace, Local: "max-resource-size"}
+	calendarServerGetCTag         = xml.Name{Space: calendarServerNamespace, Local: "getctag"}
+	appleCalendarColor            = xml.Name{Space: appleICalNamespace, Local: "calendar-color"}
+	appleCalendarOrder            = xml.Name{Space: appleICalNamespace, Local: "calendar-order"}
+	davCollectionResourceType     = davElementXML(xml.Name{Space: davNamespace, Local: "collection"}, "")
+	calDAVCalendarResourceType    = davElementXML(xml.Name{Space: calDAVNamespace, Local: "calendar"}, "")
+	davPrincipalResourceType      = davElementXML(xml.Name{Space: davNamespace, Local: "principal"}, "")
+	davReadPrivileges             = []string{"read", "read-current-user-privilege-set"}
+	davWritePrivileges            = []string{"write", "write-content", "bind", "unbind"}
+	calDAVSupportedComponentNames = []string{"VTODO"}
+)
+
+// davResource is a resource in a PROPFIND or REPORT response.
+type davResource struct {
+	Href string
+	// Props are the resource's properties, keyed by name, with their XML content.
+	Props map[xml.Name]string
+	// Status is set instead of Props for resources that cannot be returned, such as those that do not exist.
+	Status int
+}
+
+// davPropRequest is the properties a PROPFIND or REPORT asks for. Every property but calendar-data is returned when
+// Names is nil, as it is for allprop.
+type davPropRequest struct {
+	Names []xml.Name
+}
+
+// requested returns true if the property called name was asked for by name.
+func (p davPropRequest) requested(name xml.Name) bool {
+	for _, n := range p.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+type davPropfindBody struct {
+	XMLName xml.Name     `xml:"DAV: propfind"`
+	Prop    *davPropBody `xml:"DAV: prop"`
+}
+
+type davPropBody struct {
+	Names []davElement `xml:",any"`
+}
+
+type davElement struct {
+	XMLName xml.Name
+}
+
+type davReportBody struct {
+	XMLName xml.Name
+	Prop    *davPropBody   `xml:"DAV: prop"`
+	Hrefs   []string       `xml:"DAV: href"`
+	Filter  *davFilterBody `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type davFilterBody struct {
+	CompFilter davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type davCompFilter struct {
+	Name        string          `xml:"name,attr"`
+	CompFilters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// propRequest returns the properties a PROPFIND or REPORT body's prop element asks for.
+func (b *davPropBody) propRequest() davPropRequest {
+	if b == nil {
+		return davPropRequest{}
+	}
+	req := davPropRequest{Names: []xml.Name{}}
+	for _, e := range b.Names {
+		req.Names = append(req.Names, e.XMLName)
+	}
+	return req
+}
+
+// OptionsDAV tells clients which parts of WebDAV and CalDAV the server supports.
+func (s *Server) OptionsDAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PropfindDAVPrincipal returns the properties of the caller's principal, which point clients to their calendar home,
+// and with a depth of 1 those of the calendar home too.
+func (s *Server) PropfindDAVPrincipal(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("propfind dav principal called")
+
+	req, ok := bindPropfind(w, r)
+	if !ok {
+		return
+	}
+
+	resources := []davResource{principalResource(uid)}
+	if davDepth(r) != 0 {
+		resources = append(resources, calendarHomeResource())
+	}
+	renderMultistatus(w, r, req, resources)
+}
+
+// PropfindDAVCalendarHome returns the properties of the caller's calendar home and with a depth of 1 those of the
+// lists in it: the lists the caller owns or is a member of, except archived lists and templates.
+func (s *Server) PropfindDAVCalendarHome(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("propfind dav calendar home called")
+
+	req, ok := bindPropfind(w, r)
+	if !ok {
+		return
+	}
+
+	resources := []davResource{calendarHomeResource()}
+	if davDepth(r) != 0 {
+		lists, items, err := s.visibleItems(uid)
+		if err != nil {
+			log.WithError(err).Error("failed to get items")
+			renderInternalServerError(w, r)
+			return
+		}
+		listItems := map[listRef][]model.YataItem{}
+		for _, item := range items {
+			listItems[itemListRef(item)] = append(listItems[itemListRef(item)], item)
+		}
+		var calendars []visibleList
+		for _, l := range lists {
+			if !l.List.Archived && !l.List.Template {
+				calendars = append(calendars, l)
+			}
+		}
+		sort.Slice(calendars, func(i, j int) bool {
+			a, b := calendars[i].List, calendars[j].List
+			if a.Position != b.Position {
+				return a.Position < b.Position
+			}
+			if a.UserID != b.UserID {
+				return a.UserID < b.UserID
+			}
+			return a.ListID < b.ListID
+		})
+		for _, l := range calendars {
+			resources = append(resources, calendarResource(l, listItems[listRef{UserID: l.List.UserID, ListID: l.List.ListID}]))
+		}
+	}
+	renderMultistatus(w, r, req, resources)
+}
+
+// PropfindDAVCalendar returns the properties of a list and with a depth of 1 those of its items.
+func (s *Server) PropfindDAVCalendar(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("propfind dav calendar called")
+
+	req, ok := bindPropfind(w, r)
+	if !ok {
+		return
+	}
+	yl, role, ok := s.davList(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, yl.ListID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	resources := []davResource{calendarResource(visibleList{List: yl, Role: role}, items)}
+	if davDepth(r) != 0 {
+		for _, item := range items {
+			resources = append(resources, objectResource(item, req.requested(calDAVCalendarData)))
+		}
+	}
+	renderMultistatus(w, r, req, resources)
+}
+
+// PropfindDAVObject returns the properties of an item.
+func (s *Server) PropfindDAVObject(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("propfind dav object called")
+
+	req, ok := bindPropfind(w, r)
+	if !ok {
+		return
+	}
+	yl, _, ok := s.davList(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	item, _, ok := s.davItem(w, r, yl, true)
+	if !ok {
+		return
+	}
+
+	renderMultistatus(w, r, req, []davResource{objectResource(item, req.requested(calDAVCalendarData))})
+}
+
+// ReportDAVCalendar answers calendar-multiget and calendar-query reports on a list. Queries return every item unless
+// they only ask for components other than VTODOs; their other filters, such as time ranges, are not applied, so
+// clients are given more items than they asked for rather than fewer.
+func (s *Server) ReportDAVCalendar(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("report dav calendar called")
+
+	var body davReportBody
+	if ok := bindDAVBody(w, r, &body); !ok {
+		return
+	}
+	if body.XMLName != calDAVCalendarMultiget && body.XMLName != calDAVCalendarQuery {
+		log.WithField("report", body.XMLName).Info("report not supported")
+		renderDAVError(w, r, http.StatusForbidden, davSupportedReport)
+		return
+	}
+	req := body.Prop.propRequest()
+
+	yl, _, ok := s.davList(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	items, err := s.Ydb.GetListItems(yl.UserID, yl.ListID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	var resources []davResource
+	withData := req.Names == nil || req.requested(calDAVCalendarData)
+	if body.XMLName == calDAVCalendarMultiget {
+		byID := map[model.ItemID]model.YataItem{}
+		for _, item := range items {
+			byID[item.ItemID] = item
+		}
+		for _, href := range body.Hrefs {
+			href = strings.TrimSpace(href)
+			item, ok := byID[davHrefItemID(yl, href)]
+			if !ok {
+				resources = append(resources, davResource{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			resources = append(resources, objectResource(item, withData))
+		}
+	} else if body.Filter == nil || queriesTodos(body.Filter.CompFilter) {
+		for _, item := range items {
+			resources = append(resources, objectResource(item, withData))
+		}
+	}
+	renderMultistatus(w, r, req, resources)
+}
+
+// GetDAVObject returns an item as a calendar object resource.
+func (s *Server) GetDAVObject(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get dav object called")
+
+	yl, _, ok := s.davList(w, r, model.RoleViewer)
+	if !ok {
+		return
+	}
+	item, _, ok := s.davItem(w, r, yl, true)
+	if !ok {
+		return
+	}
+
+	etag := itemETag(item)
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); len(inm) != 0 && etagListContains(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", calendarObjectMediaType)
+	w.WriteHeader(http.StatusOK)
+	if err := itemCalendarObject(item).Encode(w); err != nil {
+		log.WithError(err).Warn("failed to render calendar object")
+	}
+	log.Debug("dav object retrieved")
+}
+
+// PutDAVObject creates or updates an item from a calendar object resource with a single VTODO, whose UID must be the
+// item's ID. The item keeps the fields VTODOs do not have, such as its section and assignee; a sub-task moves to its
+// parent's section.
+func (s *Server) PutDAVObject(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("put dav object called")
+
+	if len(r.Header.Get("Content-Type")) != 0 && requestMediaType(r) != ical.MediaType {
+		log.WithField("contentType", r.Header.Get("Content-Type")).Info("unsupported media type")
+		renderDAVError(w, r, http.StatusForbidden, calDAVSupportedCalendarData)
+		return
+	}
+	body := &limitedReader{r: r.Body, n: maxCalendarObjectSize}
+	cal, err := ical.Decode(body)
+	if body.exceeded {
+		log.Info("calendar object too large")
+		renderDAVError(w, r, http.StatusForbidden, calDAVMaxResourceSize)
+		return
+	}
+	if err != nil || cal.Name != "VCALENDAR" {
+		log.WithError(err).Info("failed to decode calendar object")
+		renderDAVError(w, r, http.StatusForbidden, calDAVValidCalendarData)
+		return
+	}
+	var todos []ical.Component
+	for _, c := range cal.Components {
+		switch c.Name {
+		case "VTODO":
+			todos = append(todos, c)
+		case "VTIMEZONE":
+			// Time zones are looked up by their TZID instead.
+		default:
+			todos = nil
+		}
+	}
+	if len(todos) != 1 {
+		log.Info("calendar object is not a single VTODO")
+		renderDAVError(w, r, http.StatusForbidden, calDAVSupportedComponent)
+		return
+	}
+
+	yl, _, ok := s.davList(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	before, exists, ok := s.davItem(w, r, yl, false)
+	if !ok {
+		return
+	}
+	if !davPreconditionsMet(r, before, exists) {
+		log.Info("precondition failed")
+		render(w, r, http.StatusPreconditionFailed, responseError{Code: "PreconditionFailed", Message: "Item has changed"})
+		return
+	}
+	if !exists && yl.Archived {
+		log.Info("list is archived")
+		render(w, r, http.StatusConflict, responseError{Code: "ListArchived", Message: "Items cannot be added to an archived list"})
+		return
+	}
+
+	input, err := todoInput(todos[0], davItemID(r), before, exists)
+	if err == nil {
+		err = input.Validate()
+	}
+	if err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yi := itemFromInput(yl, input, before, exists)
+	// Items only change sections when they become sub-tasks of an item in another section.
+	var items []model.YataItem
+	if len(yi.ParentID) != 0 {
+		items, err = s.Ydb.GetListItems(yl.UserID, yl.ListID)
+		if err != nil {
+			log.WithError(err).Error("failed to get list items")
+			renderInternalServerError(w, r)
+			return
+		}
+	}
+	if len(yi.ParentID) != 0 {
+		if err := validateItemNesting(items, yi); err != nil {
+			log.WithError(err).Info("failed to validate item nesting")
+			renderBadRequest(w, r, err.Error())
+			return
+		}
+		for _, parent := range items {
+			if parent.ItemID == yi.ParentID {
+				yi.SectionID = parent.SectionID
+			}
+		}
+	}
+	log.WithField("item", yi).Debug("inserting item")
+	if err := s.Ydb.InsertItem(yi); err != nil {
+		log.WithError(err).Error("failed to insert item")
+		renderInternalServerError(w, r)
+		return
+	}
+	if exists && before.SectionID != yi.SectionID {
+		// Sub-tasks move along with their parent.
+		for _, descendant := range itemDescendants(items, yi.ItemID) {
+			descendant.SectionID = yi.SectionID
+			if err := s.Ydb.InsertItem(descendant); err != nil {
+				log.WithError(err).WithField("itemID", descendant.ItemID).Error("failed to move sub-task to section")
+				renderInternalServerError(w, r)
+				return
+			}
+		}
+	}
+	s.recordItemInserted(r, yl, before, exists, yi)
+
+	w.Header().Set("ETag", itemETag(yi))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	log.Debug("dav object put")
+}
+
+// DeleteDAVObject moves an item, and all of its sub-tasks, to the trash of the list's owner.
+func (s *Server) DeleteDAVObject(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete dav object called")
+
+	yl, _, ok := s.davList(w, r, model.RoleEditor)
+	if !ok {
+		return
+	}
+	item, _, ok := s.davItem(w, r, yl, true)
+	if !ok {
+		return
+	}
+	if !davPreconditionsMet(r, item, true) {
+		log.Info("precondition failed")
+		render(w, r, http.StatusPreconditionFailed, responseError{Code: "PreconditionFailed", Message: "Item has changed"})
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, yl.ListID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	trashed, err := s.trashItem(r, yl, items, item)
+	if err != nil {
+		log.WithError(err).Error("failed to trash item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.WithField("itemIDs", trashed).Debug("dav object deleted")
+}
+
+// davList returns the list a CalDAV request is for if the caller has at least the role min.
+// If the list cannot be returned an error response is rendered and false is returned.
+func (s *Server) davList(w http.ResponseWriter, r *http.Request, min model.Role) (model.YataList, model.Role, bool) {
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		request.Logger(r.Context()).WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return model.YataList{}, "", false
+	}
+	return s.authorizeOwnedList(w, r, model.UserID(v["owner"]), listID, min)
+}
+
+// davItem returns the item a CalDAV request is for and whether it exists. Items that do not exist are an error when
+// mustExist is set. If the item cannot be returned an error response is rendered and false is returned.
+func (s *Server) davItem(w http.ResponseWriter, r *http.Request, yl model.YataList, mustExist bool) (model.YataItem, bool, bool) {
+	log := request.Logger(r.Context())
+	itemID := davItemID(r)
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return model.YataItem{}, false, false
+	}
+	item, err := s.Ydb.GetItem(yl.UserID, yl.ListID, itemID)
+	if err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			if !mustExist {
+				return model.YataItem{}, false, true
+			}
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return model.YataItem{}, false, false
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return model.YataItem{}, false, false
+	}
+	return item, true, true
+}
+
+// davItemID returns the ID of the item a CalDAV request is for.
+func davItemID(r *http.Request) model.ItemID {
+	return model.ItemID(mux.Vars(r)["itemID"])
+}
+
+// davHrefItemID returns the ID of the item on the list yl that href, an absolute URL or path, refers to. It is empty if
+// href does not refer to an item on the list.
+func davHrefItemID(yl model.YataList, href string) model.ItemID {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimPrefix(u.EscapedPath(), davCalendarPath(listRef{UserID: yl.UserID, ListID: yl.ListID}))
+	if name == u.EscapedPath() || !strings.HasSuffix(name, ".ics") || strings.Contains(name, "/") {
+		return ""
+	}
+	id, err := url.PathUnescape(strings.TrimSuffix(name, ".ics"))
+	if err != nil {
+		return ""
+	}
+	return model.ItemID(id)
+}
+
+// queriesTodos returns true if a calendar-query filter can match VTODOs.
+func queriesTodos(f davCompFilter) bool {
+	if !strings.EqualFold(f.Name, "VCALENDAR") {
+		return false
+	}
+	for _, sub := range f.CompFilters {
+		if !strings.EqualFold(sub.Name, "VTODO") {
+			return false
+		}
+	}
+	return true
+}
+
+// davDepth returns the Depth of a request, either 0 or 1. Requests for an infinite depth, the default, are answered
+// as if they were for a depth of 1.
+func davDepth(r *http.Request) int {
+	if strings.TrimSpace(r.Header.Get("Depth")) == "0" {
+		return 0
+	}
+	return 1
+}
+
+// davPreconditionsMet returns false if the request's If-Match or If-None-Match header does not match the resource of
+// the item, which may not exist.
+func davPreconditionsMet(r *http.Request, item model.YataItem, exists bool) bool {
+	var etag string
+	if exists {
+		etag = itemETag(item)
+	}
+	if im := r.Header.Get("If-Match"); len(im) != 0 && (!exists || !etagListContains(im, etag)) {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); len(inm) != 0 && exists && etagListContains(inm, etag) {
+		return false
+	}
+	return true
+}
+
+// etagListContains returns true if header, the value of an If-Match or If-None-Match header, matches etag.
+func etagListContains(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bindPropfind reads the properties a PROPFIND asks for. An empty body asks for all of them.
+// If the body cannot be read an error response is rendered and false is returned.
+func bindPropfind(w http.ResponseWriter, r *http.Request) (davPropRequest, bool) {
+	var body davPropfindBody
+	if ok := bindDAVBody(w, r, &body); !ok {
+		return davPropRequest{}, false
+	}
+	return body.Prop.propRequest(), true
+}
+
+// bindDAVBody decodes an XML request body into v, leaving v as it is if the body is empty.
+// If the body cannot be decoded an error response is rendered and false is returned.
+func bindDAVBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	log := request.Logger(r.Context())
+	body := &limitedReader{r: r.Body, n: maxDAVBodySize}
+	b, err := ioutil.ReadAll(body)
+	if body.exceeded {
+		log.Info("request body too large")
+		render(w, r, http.StatusRequestEntityTooLarge, responseError{Code: "RequestTooLarge", Message: fmt.Sprintf("Request bodies cannot be larger than %d bytes", maxDAVBodySize)})
+		return false
+	}
+	if err != nil {
+		log.WithError(err).Info("failed to read request body")
+		renderBadRequest(w, r, "Request body could not be read")
+		return false
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return true
+	}
+	if err := xml.Unmarshal(b, v); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBadRequest(w, r, "Request body must be a WebDAV XML document")
+		return false
+	}
+	return true
+}
+
+// principalResource returns the principal of the user uid.
+func principalResource(uid model.UserID) davResource {
+	return davResource{Href: davPrincipalPath, Props: map[xml.Name]string{
+		davResourceType:         davCollectionResourceType + davPrincipalResourceType,
+		davDisplayName:          davText(string(uid)),
+		davCurrentUserPrincipal: davHref(davPrincipalPath),
+		davPrincipalURL:         davHref(davPrincipalPath),
+		calDAVCalendarHomeSet:   davHref(davCalendarHomePath),
+	}}
+}
+
+// calendarHomeResource returns the caller's calendar home, which holds their lists.
+func calendarHomeResource() davResource {
+	return davResource{Href: davCalendarHomePath, Props: map[xml.Name]string{
+		davResourceType:         davCollectionResourceType,
+		davDisplayName:          "Lists",
+		davCurrentUserPrincipal: davHref(davPrincipalPath),
+	}}
+}
+
+// calendarResource returns a list, with the items on it, as a calendar.
+func calendarResource(l visibleList, items []model.YataItem) davResource {
+	privileges := davReadPrivileges
+	if roleAllows(l.Role, model.RoleEditor) {
+		privileges = append(append([]string(nil), davReadPrivileges...), davWritePrivileges...)
+	}
+	var privilegeSet strings.Builder
+	for _, p := range privileges {
+		privilegeSet.WriteString(davElementXML(xml.Name{Space: davNamespace, Local: "privilege"}, davElementXML(xml.Name{Space: davNamespace, Local: p}, "")))
+	}
+	var componentSet strings.Builder
+	for _, c := range calDAVSupportedComponentNames {
+		componentSet.WriteString(`<c:comp name="` + c + `"/>`)
+	}
+	var reportSet strings.Builder
+	for _, report := range []xml.Name{calDAVCalendarMultiget, calDAVCalendarQuery} {
+		reportSet.WriteString(davElementXML(davSupportedReport, davElementXML(xml.Name{Space: davNamespace, Local: "report"}, davElementXML(report, ""))))
+	}
+
+	res := davResource{Href: davCalendarPath(listRef{UserID: l.List.UserID, ListID: l.List.ListID}), Props: map[xml.Name]string{
+		davResourceType:             davCollectionResourceType + calDAVCalendarResourceType,
+		davDisplayName:              davText(l.List.Title),
+		davCurrentUserPrincipal:     davHref(davPrincipalPath),
+		davCurrentUserPrivilegeSet:  privilegeSet.String(),
+		davSupportedReportSet:       reportSet.String(),
+		calDAVSupportedComponentSet: componentSet.String(),
+		calendarServerGetCTag:       davText(calendarCTag(l.List, items)),
+		appleCalendarOrder:          strconv.Itoa(l.List.Position),
+	}}
+	if len(l.List.Description) != 0 {
+		res.Props[calDAVCalendarDescription] = davText(l.List.Description)
+	}
+	if len(l.List.Color) != 0 {
+		res.Props[appleCalendarColor] = davText(l.List.Color)
+	}
+	return res
+}
+
+// objectResource returns an item as a calendar object resource, with its calendar data if withData is set.
+func objectResource(item model.YataItem, withData bool) davResource {
+	res := davResource{Href: davObjectPath(item), Props: map[xml.Name]string{
+		davResourceType:   "",
+		davGetETag:        davText(itemETag(item)),
+		davGetContentType: davText(calendarObjectMediaType),
+	}}
+	if withData {
+		var b strings.Builder
+		_ = itemCalendarObject(item).Encode(&b) // Writing to a strings.Builder cannot fail.
+		res.Props[calDAVCalendarData] = davText(b.String())
+	}
+	return res
+}
+
+// davCalendarPath returns the path of a list's calendar.
+func davCalendarPath(ref listRef) string {
+	return davCalendarHomePath + url.PathEscape(string(ref.UserID)) + "/" + url.PathEscape(string(ref.ListID)) + "/"
+}
+
+// davObjectPath returns the path of an item's calendar object resource.
+func davObjectPath(item model.YataItem) string {
+	return davCalendarPath(itemListRef(item)) + url.PathEscape(string(item.ItemID)) + ".ics"
+}
+
+// itemCalendarObject returns an item as a calendar object resource: a calendar with a single VTODO whose UID is the
+// item's ID. It is stamped with the item's creation time rather than the time it is written so that it, like the
+// item's entity tag, only changes when the item does.
+func itemCalendarObject(item model.YataItem) ical.Component {
+	todo := itemComponent("VTODO", string(item.ItemID), item, item.CreatedAt)
+	if len(item.ParentID) != 0 {
+		todo.Add("RELATED-TO", ical.Text(string(item.ParentID)), ical.Param{Name: "RELTYPE", Value: "PARENT"})
+	}
+	cal := ical.Component{Name: "VCALENDAR", Components: []ical.Component{todo}}
+	cal.Add("VERSION", "2.0")
+	cal.Add("PRODID", calendarProductID)
+	return cal
+}
+
+// todoInput returns the input that makes before, or a new item with the ID itemID if it does not exist, match a
+// VTODO. The fields VTODOs do not have are kept. Items do not repeat, so a VTODO's recurrence rules and dates are
+// ignored.
+func todoInput(todo ical.Component, itemID model.ItemID, before model.YataItem, exists bool) (InsertListItemInput, error) {
+	input := InsertListItemInput{ItemID: string(itemID)}
+	if exists {
+		input = itemInput(before)
+	}
+	if p, ok := todo.Get("UID"); !ok || ical.ParseText(p.Value) != string(itemID) {
+		return input, errors.New("UID must be the same as the resource's name without .ics")
+	}
+
+	input.Content = ""
+	if p, ok := todo.Get("SUMMARY"); ok {
+		input.Content = strings.TrimSpace(ical.ParseText(p.Value))
+	}
+	input.Notes = ""
+	if p, ok := todo.Get("DESCRIPTION"); ok {
+		input.Notes = ical.ParseText(p.Value)
+	}
+	if p, ok := todo.Get("STATUS"); ok {
+		input.Completed = strings.EqualFold(p.Value, "COMPLETED")
+	} else {
+		_, input.Completed = todo.Get("COMPLETED")
+	}
+	input.Priority = 0
+	if p, ok := todo.Get("PRIORITY"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(p.Value))
+		if err != nil {
+			return input, errors.New("PRIORITY must be an integer")
+		}
+		input.Priority = todoPriority(n)
+	}
+	input.DueAt = nil
+	if p, ok := todo.Get("DUE"); ok {
+		due, err := ical.ParseDateTime(p)
+		if err != nil {
+			return input, errors.New("DUE must be a date or date-time")
+		}
+		due = due.UTC()
+		input.DueAt = &due
+	}
+	input.Tags = nil
+	seen := map[string]bool{}
+	for _, p := range todo.Properties {
+		if p.Name != "CATEGORIES" {
+			continue
+		}
+		for _, tag := range ical.ParseTextList(p.Value) {
+			tag = strings.TrimSpace(tag)
+			if len(tag) != 0 && !seen[tag] {
+				seen[tag] = true
+				input.Tags = append(input.Tags, tag)
+			}
+		}
+	}
+	input.ParentID = ""
+	for _, p := range todo.Properties {
+		if reltype := p.Param("RELTYPE"); p.Name == "RELATED-TO" && (len(reltype) == 0 || strings.EqualFold(reltype, "PARENT")) {
+			input.ParentID = ical.ParseText(p.Value)
+			break
+		}
+	}
+	return input, nil
+}
+
+// todoPriority returns the item priority of an iCalendar priority; the reverse of calendarPriority.
+func todoPriority(p int) int {
+	switch {
+	case p >= 1 && p <= 4:
+		return model.MaxPriority
+	case p == 5:
+		return 2
+	case p >= 6 && p <= 9:
+		return 1
+	}
+	return 0
+}
+
+// itemETag returns the entity tag of an item's calendar object resource. It changes whenever the item does.
+func itemETag(item model.YataItem) string {
+	b, _ := json.Marshal(item) // Items always marshal.
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// calendarCTag returns a tag that changes whenever a list or any of the items on it do. Clients compare it to the one
+// they last saw to find out whether they need to sync the list.
+func calendarCTag(yl model.YataList, items []model.YataItem) string {
+	etags := make([]string, len(items))
+	for i, item := range items {
+		etags[i] = itemETag(item)
+	}
+	sort.Strings(etags)
+	b, _ := json.Marshal(yl) // Lists always marshal.
+	h := sha256.New()
+	h.Write(b)
+	for _, etag := range etags {
+		io.WriteString(h, etag)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// renderMultistatus renders a 207 Multi-Status response with the properties of resources that req asks for.
+func renderMultistatus(w http.ResponseWriter, r *http.Request, req davPropRequest, resources []davResource) {
+	var b strings.Builder
+	b.WriteString(xml.Header + "<d:multistatus" + davNamespaceAttrs() + ">")
+	for _, res := range resources {
+		b.WriteString("<d:response>" + davHref(res.Href))
+		if res.Status != 0 {
+			b.WriteString(davStatus(res.Status) + "</d:response>")
+			continue
+		}
+		var found, missing strings.Builder
+		names := req.Names
+		if names == nil {
+			for name := range res.Props {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool { return names[i].Space+names[i].Local < names[j].Space+names[j].Local })
+		}
+		for _, name := range names {
+			if v, ok := res.Props[name]; ok {
+				found.WriteString(davElementXML(name, v))
+			} else {
+				missing.WriteString(davElementXML(name, ""))
+			}
+		}
+		if found.Len() != 0 || missing.Len() == 0 {
+			b.WriteString("<d:propstat><d:prop>" + found.String() + "</d:prop>" + davStatus(http.StatusOK) + "</d:propstat>")
+		}
+		if missing.Len() != 0 {
+			b.WriteString("<d:propstat><d:prop>" + missing.String() + "</d:prop>" + davStatus(http.StatusNotFound) + "</d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", mediaTypeXML+"; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		request.Logger(r.Context()).WithError(err).Warn("failed to render multistatus")
+	}
+}
+
+// renderDAVError renders a WebDAV error response naming the precondition that failed.
+func renderDAVError(w http.ResponseWriter, r *http.Request, code int, condition xml.Name) {
+	w.Header().Set("Content-Type", mediaTypeXML+"; charset=utf-8")
+	w.WriteHeader(code)
+	body := xml.Header + "<d:error" + davNamespaceAttrs() + ">" + davElementXML(condition, "") + "</d:error>"
+	if _, err := io.WriteString(w, body); err != nil {
+		request.Logger(r.Context()).WithError(err).Warn("failed to render error")
+	}
+}
+
+// davNamespaceAttrs returns the attributes that declare the prefixes of davPrefixes.
+func davNamespaceAttrs() string {
+	spaces := make([]string, 0, len(davPrefixes))
+	for space := range davPrefixes {
+		spaces = append(spaces, space)
+	}
+	sort.Strings(spaces)
+	var b strings.Builder
+	for _, space := range spaces {
+		b.WriteString(` xmlns:` + davPrefixes[space] + `="` + davText(space) + `"`)
+	}
+	return b.String()
+}
+
+// davElementXML returns an element called name with the XML content inner. Elements in namespaces that are not in
+// davPrefixes declare their own.
+func davElementXML(name xml.Name, inner string) string {
+	tag, attrs := name.Local, ""
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if len(name.Space) != 0 {
+		tag, attrs = "x:"+name.Local, ` xmlns:x="`+davText(name.Space)+`"`
+	}
+	if len(inner) == 0 {
+		return "<" + tag + attrs + "/>"
+	}
+	return "<" + tag + attrs + ">" + inner + "</" + tag + ">"
+}
+
+// davHref returns an href element with the path p.
+func davHref(p string) string {
+	return "<d:href>" + davText(p) + "</d:href>"
+}
+
+// davStatus returns a status element with the status code.
+func davStatus(code int) string {
+	return "<d:status>HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code) + "</d:status>"
+}
+
+// davText escapes s as XML character data.
+func davText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s)) // Writing to a strings.Builder cannot fail.
+	return b.String()
+}
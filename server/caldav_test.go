@@ -0,0 +1,535 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoInput(t *testing.T) {
+	due := time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC)
+	before := model.YataItem{
+		UserID:     "me",
+		ListID:     "groceries",
+		ItemID:     "milk",
+		SectionID:  "dairy",
+		Content:    "Milk",
+		Notes:      "Oat",
+		Priority:   1,
+		AssigneeID: "them",
+		Tags:       []string{"old"},
+	}
+
+	tests := map[string]struct {
+		lines   []string
+		exists  bool
+		want    InsertListItemInput
+		wantErr bool
+	}{
+		"new": {
+			lines: []string{
+				"UID:milk",
+				`SUMMARY: Milk\, whole `,
+				`DESCRIPTION:2 litres\nor more`,
+				"STATUS:COMPLETED",
+				"PRIORITY:2",
+				"DUE;VALUE=DATE:20210131",
+				"CATEGORIES:dairy,@shop",
+				"CATEGORIES:dairy",
+				"RELATED-TO;RELTYPE=SIBLING:eggs",
+				"RELATED-TO:shopping",
+			},
+			want: InsertListItemInput{
+				ItemID:    "milk",
+				ParentID:  "shopping",
+				Content:   "Milk, whole",
+				Notes:     "2 litres\nor more",
+				Completed: true,
+				Priority:  3,
+				DueAt:     &due,
+				Tags:      []string{"dairy", "@shop"},
+			},
+		},
+		"keeps-fields-vtodos-do-not-have": {
+			lines:  []string{"UID:milk", "SUMMARY:Milk", "COMPLETED:20210130T100000Z"},
+			exists: true,
+			want: InsertListItemInput{
+				ItemID:     "milk",
+				SectionID:  "dairy",
+				Content:    "Milk",
+				Completed:  true,
+				AssigneeID: "them",
+			},
+		},
+		"status-wins": {
+			lines:  []string{"UID:milk", "SUMMARY:Milk", "STATUS:NEEDS-ACTION", "COMPLETED:20210130T100000Z"},
+			exists: true,
+			want: InsertListItemInput{
+				ItemID:     "milk",
+				SectionID:  "dairy",
+				Content:    "Milk",
+				AssigneeID: "them",
+			},
+		},
+		"uid-mismatch": {
+			lines:   []string{"UID:9f3c", "SUMMARY:Milk"},
+			wantErr: true,
+		},
+		"bad-priority": {
+			lines:   []string{"UID:milk", "SUMMARY:Milk", "PRIORITY:high"},
+			wantErr: true,
+		},
+		"bad-due": {
+			lines:   []string{"UID:milk", "SUMMARY:Milk", "DUE:soon"},
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			todo := decodeTodo(t, test.lines...)
+			got, err := todoInput(todo, "milk", before, test.exists)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestItemCalendarObject_RoundTrip(t *testing.T) {
+	due := time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC)
+	item := model.YataItem{
+		UserID:    "me",
+		ListID:    "groceries",
+		ItemID:    "oat",
+		ParentID:  "milk",
+		SectionID: "dairy",
+		Content:   "Oat milk; the good one",
+		Notes:     "Barista\nedition",
+		Completed: true,
+		Priority:  2,
+		DueAt:     &due,
+		Tags:      []string{"dairy", "a,b"},
+		CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, itemCalendarObject(item).Encode(&buf))
+	cal, err := ical.Decode(&buf)
+	require.NoError(t, err)
+	require.Len(t, cal.Components, 1)
+	got, err := todoInput(cal.Components[0], item.ItemID, item, true)
+	require.NoError(t, err)
+	assert.Equal(t, itemInput(item), got)
+}
+
+func TestItemETag(t *testing.T) {
+	item := model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", Content: "Milk"}
+	changed := item
+	changed.Completed = true
+	assert.Equal(t, itemETag(item), itemETag(item))
+	assert.NotEqual(t, itemETag(item), itemETag(changed))
+	assert.True(t, strings.HasPrefix(itemETag(item), `"`) && strings.HasSuffix(itemETag(item), `"`))
+
+	yl := model.YataList{UserID: "me", ListID: "groceries"}
+	assert.Equal(t, calendarCTag(yl, []model.YataItem{item, changed}), calendarCTag(yl, []model.YataItem{changed, item}))
+	assert.NotEqual(t, calendarCTag(yl, []model.YataItem{item}), calendarCTag(yl, []model.YataItem{changed}))
+}
+
+func TestDavHrefItemID(t *testing.T) {
+	yl := model.YataList{UserID: "me", ListID: "work stuff"}
+	tests := map[string]model.ItemID{
+		"/dav/lists/me/work%20stuff/report.ics":                      "report",
+		"https://does.not/dav/lists/me/work%20stuff/a%2Fb%20c.ics":   "a/b c",
+		"/dav/lists/me/other/report.ics":                             "",
+		"/dav/lists/me/work%20stuff/report":                          "",
+		"/dav/lists/me/work%20stuff/nested/report.ics":               "",
+		"/dav/lists/me/work%20stuff/":                                "",
+		"/somewhere/else/dav/lists/me/work%20stuff/report.ics":       "",
+		"/dav/lists/me/work%20stuff/%zz.ics":                         "",
+		"/dav/lists/me/work%20stuff/report.ics?query=ignored#anchor": "report",
+	}
+
+	for href, want := range tests {
+		href, want := href, want
+		t.Run(fmt.Sprintf(href), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, want, davHrefItemID(yl, href))
+		})
+	}
+}
+
+func TestQueriesTodos(t *testing.T) {
+	todos := davCompFilter{Name: "VCALENDAR", CompFilters: []davCompFilter{{Name: "VTODO"}}}
+	events := davCompFilter{Name: "VCALENDAR", CompFilters: []davCompFilter{{Name: "VEVENT"}}}
+	assert.True(t, queriesTodos(todos))
+	assert.True(t, queriesTodos(davCompFilter{Name: "VCALENDAR"}))
+	assert.False(t, queriesTodos(events))
+	assert.False(t, queriesTodos(davCompFilter{Name: "VTODO"}))
+}
+
+func TestServer_CalDAV(t *testing.T) {
+	milk := model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", Content: "Milk", SectionID: "dairy"}
+	eggs := model.YataItem{UserID: "me", ListID: "groceries", ItemID: "eggs", Content: "Eggs"}
+	report := model.YataItem{UserID: "them", ListID: "work", ItemID: "report", Content: "Report"}
+	items := map[model.ListID][]model.YataItem{
+		"groceries": {milk, eggs},
+		"work":      {report},
+	}
+	newYdb := func(inserted *[]model.YataItem, trashed *[]model.ItemID) mockYdb {
+		return mockYdb{
+			MockGetAppPassword: func(hash string) (model.YataAppPassword, error) {
+				if hash != hashAppPassword("secret") {
+					return model.YataAppPassword{}, database.AppPasswordNotFoundError{}
+				}
+				return model.YataAppPassword{Hash: hash, AppPasswordID: "phone", UserID: "me"}, nil
+			},
+			MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+				return []model.YataList{
+					{UserID: id, ListID: "groceries", Title: "Groceries & more", Color: "#1e90ff", Position: 2},
+					{UserID: id, ListID: "old", Title: "Old", Archived: true},
+				}, nil
+			},
+			MockGetAllItems: func(id model.UserID) ([]model.YataItem, error) {
+				return items["groceries"], nil
+			},
+			MockGetMemberships: func(member model.UserID) ([]model.YataListMember, error) {
+				return []model.YataListMember{{OwnerID: "them", ListID: "work", UserID: member, Role: model.RoleViewer}}, nil
+			},
+			MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+				if lid != "work" {
+					return model.YataListMember{}, database.MemberNotFoundError{}
+				}
+				return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleViewer}, nil
+			},
+			MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+				if _, ok := items[lid]; !ok {
+					return model.YataList{}, database.ListNotFoundError{}
+				}
+				return model.YataList{UserID: id, ListID: lid, Title: string(lid)}, nil
+			},
+			MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+				return items[lid], nil
+			},
+			MockGetItem: func(id model.UserID, lid model.ListID, iid model.ItemID) (model.YataItem, error) {
+				for _, item := range items[lid] {
+					if item.ItemID == iid {
+						return item, nil
+					}
+				}
+				return model.YataItem{}, database.ItemNotFoundError{}
+			},
+			MockInsertItem: func(item model.YataItem) error {
+				*inserted = append(*inserted, item)
+				return nil
+			},
+			MockTrashItem: func(id model.UserID, lid model.ListID, iid model.ItemID, deletedAt time.Time) error {
+				*trashed = append(*trashed, iid)
+				return nil
+			},
+		}
+	}
+	todo := func(uid, summary string) string {
+		return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:" + uid + "\r\nSUMMARY:" + summary + "\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	}
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		header   map[string]string
+		body     string
+		noAuth   bool
+		username string
+		code     int
+		// contains are strings the response body must contain and hrefs the hrefs of a multistatus response.
+		contains []string
+		hrefs    []string
+		inserted []model.ItemID
+		trashed  []model.ItemID
+	}{
+		"no-credentials": {
+			method: "PROPFIND",
+			path:   "/dav/",
+			noAuth: true,
+			code:   http.StatusUnauthorized,
+		},
+		"someone-elses-password": {
+			method:   "PROPFIND",
+			path:     "/dav/",
+			username: "them",
+			code:     http.StatusUnauthorized,
+		},
+		"well-known": {
+			method: http.MethodGet,
+			path:   "/.well-known/caldav",
+			noAuth: true,
+			code:   http.StatusMovedPermanently,
+		},
+		"options": {
+			method: http.MethodOptions,
+			path:   "/dav/lists/me/groceries/",
+			code:   http.StatusOK,
+		},
+		"principal": {
+			method:   "PROPFIND",
+			path:     "/dav/",
+			header:   map[string]string{"Depth": "0"},
+			body:     `<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><prop><current-user-principal/><C:calendar-home-set/><getlastmodified/></prop></propfind>`,
+			code:     http.StatusMultiStatus,
+			hrefs:    []string{"/dav/"},
+			contains: []string{"<c:calendar-home-set><d:href>/dav/lists/</d:href></c:calendar-home-set>", "<d:getlastmodified/></d:prop><d:status>HTTP/1.1 404 Not Found"},
+		},
+		"calendar-home": {
+			method: "PROPFIND",
+			path:   "/dav/lists/",
+			header: map[string]string{"Depth": "1"},
+			code:   http.StatusMultiStatus,
+			hrefs:  []string{"/dav/lists/", "/dav/lists/them/work/", "/dav/lists/me/groceries/"},
+			contains: []string{
+				"<d:displayname>Groceries &amp; more</d:displayname>",
+				"<ical:calendar-color>#1e90ff</ical:calendar-color>",
+				`<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`,
+				"<d:privilege><d:write-content/></d:privilege>",
+				"<cs:getctag>",
+			},
+		},
+		"calendar": {
+			method:   "PROPFIND",
+			path:     "/dav/lists/me/groceries/",
+			header:   map[string]string{"Depth": "1"},
+			body:     `<propfind xmlns="DAV:"><prop><getetag/><resourcetype/></prop></propfind>`,
+			code:     http.StatusMultiStatus,
+			hrefs:    []string{"/dav/lists/me/groceries/", "/dav/lists/me/groceries/milk.ics", "/dav/lists/me/groceries/eggs.ics"},
+			contains: []string{"<d:getetag>" + davText(itemETag(milk)) + "</d:getetag>"},
+		},
+		"read-only-calendar": {
+			method:   "PROPFIND",
+			path:     "/dav/lists/them/work/",
+			header:   map[string]string{"Depth": "0"},
+			code:     http.StatusMultiStatus,
+			hrefs:    []string{"/dav/lists/them/work/"},
+			contains: []string{"<d:current-user-privilege-set><d:privilege><d:read/></d:privilege><d:privilege><d:read-current-user-privilege-set/></d:privilege></d:current-user-privilege-set>"},
+		},
+		"not-a-member": {
+			method: "PROPFIND",
+			path:   "/dav/lists/them/secret/",
+			code:   http.StatusNotFound,
+		},
+		"multiget": {
+			method: "REPORT",
+			path:   "/dav/lists/me/groceries/",
+			body: `<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+				<D:prop><D:getetag/><C:calendar-data/></D:prop>
+				<D:href>/dav/lists/me/groceries/milk.ics</D:href>
+				<D:href>/dav/lists/me/groceries/gone.ics</D:href>
+			</C:calendar-multiget>`,
+			code:     http.StatusMultiStatus,
+			hrefs:    []string{"/dav/lists/me/groceries/milk.ics", "/dav/lists/me/groceries/gone.ics"},
+			contains: []string{"UID:milk&#xD;&#xA;", "<d:href>/dav/lists/me/groceries/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>"},
+		},
+		"query-todos": {
+			method: "REPORT",
+			path:   "/dav/lists/me/groceries/",
+			body: `<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+				<D:prop><D:getetag/></D:prop>
+				<C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VTODO"/></C:comp-filter></C:filter>
+			</C:calendar-query>`,
+			code:  http.StatusMultiStatus,
+			hrefs: []string{"/dav/lists/me/groceries/milk.ics", "/dav/lists/me/groceries/eggs.ics"},
+		},
+		"query-events": {
+			method: "REPORT",
+			path:   "/dav/lists/me/groceries/",
+			body: `<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+				<C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT"/></C:comp-filter></C:filter>
+			</C:calendar-query>`,
+			code: http.StatusMultiStatus,
+		},
+		"unsupported-report": {
+			method:   "REPORT",
+			path:     "/dav/lists/me/groceries/",
+			body:     `<D:sync-collection xmlns:D="DAV:"><D:sync-token/></D:sync-collection>`,
+			code:     http.StatusForbidden,
+			contains: []string{"<d:supported-report/>"},
+		},
+		"get": {
+			method:   http.MethodGet,
+			path:     "/dav/lists/me/groceries/milk.ics",
+			code:     http.StatusOK,
+			contains: []string{"BEGIN:VTODO\r\nUID:milk\r\n", "SUMMARY:Milk\r\n"},
+		},
+		"get-not-modified": {
+			method: http.MethodGet,
+			path:   "/dav/lists/me/groceries/milk.ics",
+			header: map[string]string{"If-None-Match": itemETag(milk)},
+			code:   http.StatusNotModified,
+		},
+		"get-missing": {
+			method: http.MethodGet,
+			path:   "/dav/lists/me/groceries/gone.ics",
+			code:   http.StatusNotFound,
+		},
+		"put-new": {
+			method:   http.MethodPut,
+			path:     "/dav/lists/me/groceries/butter.ics",
+			header:   map[string]string{"Content-Type": "text/calendar; charset=utf-8", "If-None-Match": "*"},
+			body:     todo("butter", "Butter"),
+			code:     http.StatusCreated,
+			inserted: []model.ItemID{"butter"},
+		},
+		"put-update": {
+			method:   http.MethodPut,
+			path:     "/dav/lists/me/groceries/milk.ics",
+			header:   map[string]string{"If-Match": itemETag(milk)},
+			body:     todo("milk", "Whole milk"),
+			code:     http.StatusNoContent,
+			inserted: []model.ItemID{"milk"},
+		},
+		"put-sub-task": {
+			method:   http.MethodPut,
+			path:     "/dav/lists/me/groceries/eggs.ics",
+			body:     strings.Replace(todo("eggs", "Eggs"), "END:VTODO", "RELATED-TO:milk\r\nEND:VTODO", 1),
+			code:     http.StatusNoContent,
+			inserted: []model.ItemID{"eggs"},
+		},
+		"put-stale": {
+			method: http.MethodPut,
+			path:   "/dav/lists/me/groceries/milk.ics",
+			header: map[string]string{"If-Match": `"stale"`},
+			body:   todo("milk", "Whole milk"),
+			code:   http.StatusPreconditionFailed,
+		},
+		"put-exists": {
+			method: http.MethodPut,
+			path:   "/dav/lists/me/groceries/milk.ics",
+			header: map[string]string{"If-None-Match": "*"},
+			body:   todo("milk", "Whole milk"),
+			code:   http.StatusPreconditionFailed,
+		},
+		"put-uid-mismatch": {
+			method: http.MethodPut,
+			path:   "/dav/lists/me/groceries/butter.ics",
+			body:   todo("margarine", "Butter"),
+			code:   http.StatusBadRequest,
+		},
+		"put-event": {
+			method:   http.MethodPut,
+			path:     "/dav/lists/me/groceries/butter.ics",
+			body:     strings.ReplaceAll(todo("butter", "Butter"), "VTODO", "VEVENT"),
+			code:     http.StatusForbidden,
+			contains: []string{"<c:supported-calendar-component/>"},
+		},
+		"put-not-ical": {
+			method:   http.MethodPut,
+			path:     "/dav/lists/me/groceries/butter.ics",
+			header:   map[string]string{"Content-Type": "application/json"},
+			body:     `{}`,
+			code:     http.StatusForbidden,
+			contains: []string{"<c:supported-calendar-data/>"},
+		},
+		"put-viewer": {
+			method: http.MethodPut,
+			path:   "/dav/lists/them/work/report.ics",
+			body:   todo("report", "Report"),
+			code:   http.StatusForbidden,
+		},
+		"delete": {
+			method:  http.MethodDelete,
+			path:    "/dav/lists/me/groceries/milk.ics",
+			header:  map[string]string{"If-Match": itemETag(milk)},
+			code:    http.StatusNoContent,
+			trashed: []model.ItemID{"milk"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			var inserted []model.YataItem
+			var trashed []model.ItemID
+			router := (&Server{Ydb: newYdb(&inserted, &trashed)}).Router()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "https://does.not"+test.path, strings.NewReader(test.body))
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+			if !test.noAuth {
+				username := test.username
+				if len(username) == 0 {
+					username = "me"
+				}
+				req.SetBasicAuth(username, "secret")
+			}
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, test.code, rec.Code, rec.Body.String())
+			for _, s := range test.contains {
+				assert.Contains(t, rec.Body.String(), s)
+			}
+			if test.code == http.StatusMultiStatus {
+				var ms testMultistatus
+				require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &ms))
+				var hrefs []string
+				for _, res := range ms.Responses {
+					hrefs = append(hrefs, res.Href)
+				}
+				assert.Equal(t, test.hrefs, hrefs)
+			}
+			var insertedIDs []model.ItemID
+			for _, item := range inserted {
+				insertedIDs = append(insertedIDs, item.ItemID)
+			}
+			assert.Equal(t, test.inserted, insertedIDs)
+			assert.Equal(t, test.trashed, trashed)
+
+			switch rec.Code {
+			case http.StatusUnauthorized:
+				assert.Equal(t, `Basic realm="yata", charset="UTF-8"`, rec.Header().Get("WWW-Authenticate"))
+			case http.StatusMovedPermanently:
+				assert.Equal(t, "/dav/", rec.Header().Get("Location"))
+			case http.StatusCreated, http.StatusNoContent:
+				if len(inserted) != 0 {
+					assert.Equal(t, itemETag(inserted[0]), rec.Header().Get("ETag"))
+				}
+			case http.StatusOK:
+				if test.method == http.MethodOptions {
+					assert.Contains(t, rec.Header().Get("DAV"), "calendar-access")
+				} else {
+					assert.Equal(t, itemETag(milk), rec.Header().Get("ETag"))
+					assert.Equal(t, calendarObjectMediaType, rec.Header().Get("Content-Type"))
+				}
+			}
+		})
+	}
+}
+
+// decodeTodo returns the VTODO with the properties lines.
+func decodeTodo(t *testing.T, lines ...string) ical.Component {
+	c, err := ical.Decode(strings.NewReader("BEGIN:VTODO\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VTODO\r\n"))
+	require.NoError(t, err)
+	return c
+}
+
+type testMultistatus struct {
+	Responses []struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"DAV: response"`
+}
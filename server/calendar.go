@@ -0,0 +1,315 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+// calendarProductID identifies the server in the calendars it writes.
+const calendarProductID = "-//yata//yata-server//EN"
+
+// calendarRefreshInterval is how often calendar apps are asked to refresh a calendar feed.
+const calendarRefreshInterval = "PT1H"
+
+type GetCalendarFeedsOutput struct {
+	CalendarFeeds []model.YataCalendarFeed
+}
+
+// GetCalendarFeeds returns the caller's calendar feeds.
+func (s *Server) GetCalendarFeeds(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get calendar feeds called")
+
+	feeds, err := s.Ydb.GetCalendarFeeds(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get calendar feeds")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetCalendarFeedsOutput{CalendarFeeds: feeds}
+	log.Debug("calendar feeds retrieved") // The output is not logged; tokens are secrets.
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertCalendarFeedOutput struct {
+	Token string
+	// Path is where calendar apps can subscribe to the feed, relative to the server's address.
+	Path string
+}
+
+// InsertCalendarFeed creates a new calendar feed of the caller's items.
+func (s *Server) InsertCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert calendar feed called")
+
+	token, err := newShareToken()
+	if err != nil {
+		log.WithError(err).Error("failed to generate calendar feed token")
+		renderInternalServerError(w, r)
+		return
+	}
+	feed := model.YataCalendarFeed{Token: token, UserID: uid, CreatedAt: time.Now().UTC()}
+	if err := s.Ydb.InsertCalendarFeed(feed); err != nil {
+		log.WithError(err).Error("failed to insert calendar feed")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := InsertCalendarFeedOutput{Token: token, Path: calendarFeedPath(token)}
+	log.Debug("calendar feed inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteCalendarFeedOutput struct {
+	Token string
+}
+
+// DeleteCalendarFeed revokes one of the caller's calendar feeds.
+func (s *Server) DeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete calendar feed called")
+
+	token := mux.Vars(r)["token"]
+	feed, err := s.Ydb.GetCalendarFeed(token)
+	if err == nil && feed.UserID != uid {
+		// Other users' calendar feeds are indistinguishable from calendar feeds that do not exist.
+		err = database.CalendarFeedNotFoundError{}
+	}
+	if err != nil {
+		if errnf, ok := err.(database.CalendarFeedNotFoundError); ok {
+			log.WithError(errnf).Info("calendar feed not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "CalendarFeedDoesNotExist", Message: "Calendar feed does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get calendar feed")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if err := s.Ydb.DeleteCalendarFeed(token); err != nil {
+		log.WithError(err).Error("failed to delete calendar feed")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := DeleteCalendarFeedOutput{Token: token}
+	log.Debug("calendar feed deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+// GetCalendar returns an iCalendar feed of the items with a due date on the lists of a calendar feed's user, including
+// lists shared with them. Archived lists and templates are left out. Items are events due at their due date, or
+// to-dos when the "component" query parameter is VTODO; most calendar apps only show events.
+// It is called without authenticating, since calendar apps cannot send an Authorization header.
+func (s *Server) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	log.Debug("get calendar called")
+
+	component := r.URL.Query().Get("component")
+	switch component {
+	case "":
+		component = "VEVENT"
+	case "VEVENT", "VTODO":
+	default:
+		log.WithField("component", component).Info("failed to validate input")
+		renderBadRequest(w, r, "component must be one of VEVENT or VTODO")
+		return
+	}
+
+	feed, err := s.Ydb.GetCalendarFeed(mux.Vars(r)["token"])
+	if err != nil {
+		if errnf, ok := err.(database.CalendarFeedNotFoundError); ok {
+			log.WithError(errnf).Info("calendar feed not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "CalendarFeedDoesNotExist", Message: "Calendar feed does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get calendar feed")
+		renderInternalServerError(w, r)
+		return
+	}
+	log = log.WithField("userID", feed.UserID)
+
+	lists, items, err := s.visibleItems(feed.UserID)
+	if err != nil {
+		log.WithError(err).Error("failed to get items")
+		renderInternalServerError(w, r)
+		return
+	}
+	rollUpCompletion(items)
+	items = filterItems(items, func(item model.YataItem) bool {
+		l, ok := lists[itemListRef(item)]
+		return ok && !l.List.Archived && !l.List.Template && item.DueAt != nil
+	})
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DueAt.Equal(*items[j].DueAt) {
+			return items[i].DueAt.Before(*items[j].DueAt)
+		}
+		return itemUID(items[i]) < itemUID(items[j])
+	})
+
+	cal := newCalendar("Yata")
+	now := time.Now()
+	for _, item := range items {
+		cal.Components = append(cal.Components, itemComponent(component, itemUID(item), item, now))
+	}
+
+	w.Header().Set("Content-Type", ical.MediaType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := cal.Encode(w); err != nil {
+		log.WithError(err).Warn("failed to render calendar")
+	}
+	log.WithField("items", len(items)).Debug("calendar retrieved")
+}
+
+// visibleList is a list a user can see and their role on it.
+type visibleList struct {
+	List model.YataList
+	Role model.Role
+}
+
+// visibleItems returns the lists a user can see, their own and those shared with them, keyed by owner and list ID,
+// and the items on them.
+func (s *Server) visibleItems(uid model.UserID) (map[listRef]visibleList, []model.YataItem, error) {
+	own, err := s.Ydb.GetLists(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get lists: %v", err)
+	}
+	items, err := s.Ydb.GetAllItems(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get all items: %v", err)
+	}
+	lists := map[listRef]visibleList{}
+	for _, l := range own {
+		lists[listRef{UserID: l.UserID, ListID: l.ListID}] = visibleList{List: l, Role: model.RoleOwner}
+	}
+
+	memberships, err := s.Ydb.GetMemberships(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get memberships: %v", err)
+	}
+	for _, m := range memberships {
+		shared, err := s.Ydb.GetList(m.OwnerID, m.ListID)
+		if err != nil {
+			if _, ok := err.(database.ListNotFoundError); ok {
+				// The membership outlived its list.
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to get shared list: %v", err)
+		}
+		sharedItems, err := s.Ydb.GetListItems(m.OwnerID, m.ListID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get shared list items: %v", err)
+		}
+		lists[listRef{UserID: shared.UserID, ListID: shared.ListID}] = visibleList{List: shared, Role: m.Role}
+		items = append(items, sharedItems...)
+	}
+	return lists, items, nil
+}
+
+// listRef identifies a list of any user.
+type listRef struct {
+	UserID model.UserID
+	ListID model.ListID
+}
+
+// itemListRef returns the key of an item's list in the lists returned by visibleItems.
+func itemListRef(item model.YataItem) listRef {
+	return listRef{UserID: item.UserID, ListID: item.ListID}
+}
+
+// calendarFeedPath returns the path of a calendar feed.
+func calendarFeedPath(token string) string {
+	return "/calendar/" + token + ".ics"
+}
+
+// newCalendar returns an empty calendar named name.
+func newCalendar(name string) ical.Component {
+	cal := ical.Component{Name: "VCALENDAR"}
+	cal.Add("VERSION", "2.0")
+	cal.Add("PRODID", calendarProductID)
+	cal.Add("CALSCALE", "GREGORIAN")
+	cal.Add("X-WR-CALNAME", ical.Text(name))
+	cal.Add("REFRESH-INTERVAL", calendarRefreshInterval, ical.Param{Name: "VALUE", Value: "DURATION"})
+	cal.Add("X-PUBLISHED-TTL", calendarRefreshInterval)
+	return cal
+}
+
+// itemUID returns the unique ID of an item in calendars. It stays the same for as long as the item is on its list.
+func itemUID(item model.YataItem) string {
+	return url.PathEscape(string(item.UserID)) + "/" + url.PathEscape(string(item.ListID)) + "/" + url.PathEscape(string(item.ItemID))
+}
+
+// itemComponent returns an item as a VEVENT or VTODO with the unique ID uid. Events start, and take no time, at the
+// item's due date, so items must have one to be events. now is when the component is written.
+func itemComponent(name string, uid string, item model.YataItem, now time.Time) ical.Component {
+	c := ical.Component{Name: name}
+	c.Add("UID", ical.Text(uid))
+	c.Add("DTSTAMP", ical.DateTime(now))
+	c.Add("CREATED", ical.DateTime(item.CreatedAt))
+	if name == "VTODO" {
+		if item.DueAt != nil {
+			c.Add("DUE", ical.DateTime(*item.DueAt))
+		}
+		if item.Completed {
+			c.Add("STATUS", "COMPLETED")
+		} else {
+			c.Add("STATUS", "NEEDS-ACTION")
+		}
+	} else {
+		c.Add("DTSTART", ical.DateTime(*item.DueAt))
+		c.Add("TRANSP", "TRANSPARENT")
+	}
+	c.Add("SUMMARY", ical.Text(item.Content))
+	if len(item.Notes) != 0 {
+		c.Add("DESCRIPTION", ical.Text(item.Notes))
+	}
+	if p := calendarPriority(item.Priority); p != 0 {
+		c.Add("PRIORITY", fmt.Sprint(p))
+	}
+	if len(item.Tags) != 0 {
+		c.Add("CATEGORIES", ical.TextList(item.Tags))
+	}
+	return c
+}
+
+// calendarPriority returns the iCalendar priority of an item's priority: 1 for high, 5 for medium and 9 for low. It is
+// zero, undefined, for items without a priority.
+func calendarPriority(p int) int {
+	switch {
+	case p >= model.MaxPriority:
+		return 1
+	case p == 2:
+		return 5
+	case p == 1:
+		return 9
+	}
+	return 0
+}
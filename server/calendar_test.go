@@ -0,0 +1,201 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemComponent(t *testing.T) {
+	due := time.Date(2021, 1, 31, 17, 0, 0, 0, time.UTC)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := model.YataItem{
+		UserID:    "me",
+		ListID:    "work stuff",
+		ItemID:    "report",
+		Content:   "Send report, finally",
+		Notes:     "To:\nthe boss",
+		Priority:  3,
+		DueAt:     &due,
+		Tags:      []string{"work", "@email"},
+		CreatedAt: now.Add(-time.Hour),
+	}
+
+	tests := map[string]struct {
+		name      string
+		completed bool
+		want      []string
+	}{
+		"event": {
+			name: "VEVENT",
+			want: []string{
+				"UID:me/work%20stuff/report",
+				"DTSTAMP:20210101T000000Z",
+				"CREATED:20201231T230000Z",
+				"DTSTART:20210131T170000Z",
+				"TRANSP:TRANSPARENT",
+				`SUMMARY:Send report\, finally`,
+				`DESCRIPTION:To:\nthe boss`,
+				"PRIORITY:1",
+				"CATEGORIES:work,@email",
+			},
+		},
+		"todo": {
+			name: "VTODO",
+			want: []string{
+				"UID:me/work%20stuff/report",
+				"DTSTAMP:20210101T000000Z",
+				"CREATED:20201231T230000Z",
+				"DUE:20210131T170000Z",
+				"STATUS:NEEDS-ACTION",
+				`SUMMARY:Send report\, finally`,
+				`DESCRIPTION:To:\nthe boss`,
+				"PRIORITY:1",
+				"CATEGORIES:work,@email",
+			},
+		},
+		"completed-todo": {
+			name:      "VTODO",
+			completed: true,
+			want: []string{
+				"UID:me/work%20stuff/report",
+				"DTSTAMP:20210101T000000Z",
+				"CREATED:20201231T230000Z",
+				"DUE:20210131T170000Z",
+				"STATUS:COMPLETED",
+				`SUMMARY:Send report\, finally`,
+				`DESCRIPTION:To:\nthe boss`,
+				"PRIORITY:1",
+				"CATEGORIES:work,@email",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			item := item
+			item.Completed = test.completed
+			c := itemComponent(test.name, itemUID(item), item, now)
+
+			var b strings.Builder
+			require.NoError(t, c.Encode(&b))
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+			assert.Equal(t, "BEGIN:"+test.name, lines[0])
+			assert.Equal(t, "END:"+test.name, lines[len(lines)-1])
+			assert.Equal(t, test.want, lines[1:len(lines)-1])
+		})
+	}
+}
+
+func TestCalendarPriority(t *testing.T) {
+	assert.Equal(t, []int{0, 9, 5, 1}, []int{calendarPriority(0), calendarPriority(1), calendarPriority(2), calendarPriority(3)})
+}
+
+func TestServer_GetCalendar(t *testing.T) {
+	early := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	late := early.Add(24 * time.Hour)
+	ydb := mockYdb{
+		MockGetCalendarFeed: func(token string) (model.YataCalendarFeed, error) {
+			if token != "secret" {
+				return model.YataCalendarFeed{}, database.CalendarFeedNotFoundError{}
+			}
+			return model.YataCalendarFeed{Token: token, UserID: "me"}, nil
+		},
+		MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+			return []model.YataList{
+				{UserID: id, ListID: "work"},
+				{UserID: id, ListID: "old", Archived: true},
+			}, nil
+		},
+		MockGetAllItems: func(id model.UserID) ([]model.YataItem, error) {
+			return []model.YataItem{
+				{UserID: id, ListID: "work", ItemID: "later", Content: "Later", DueAt: &late},
+				{UserID: id, ListID: "work", ItemID: "undated", Content: "Undated"},
+				{UserID: id, ListID: "old", ItemID: "archived", Content: "Archived", DueAt: &early},
+			}, nil
+		},
+		MockGetMemberships: func(member model.UserID) ([]model.YataListMember, error) {
+			return []model.YataListMember{
+				{OwnerID: "them", ListID: "shared", UserID: member},
+				{OwnerID: "them", ListID: "gone", UserID: member},
+			}, nil
+		},
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			if lid == "gone" {
+				return model.YataList{}, database.ListNotFoundError{}
+			}
+			return model.YataList{UserID: id, ListID: lid}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return []model.YataItem{{UserID: id, ListID: lid, ItemID: "sooner", Content: "Sooner", DueAt: &early}}, nil
+		},
+	}
+
+	tests := map[string]struct {
+		path string
+		code int
+		uids []string
+		kind string
+	}{
+		"events": {
+			path: "/calendar/secret.ics",
+			code: http.StatusOK,
+			uids: []string{"them/shared/sooner", "me/work/later"},
+			kind: "VEVENT",
+		},
+		"todos": {
+			path: "/calendar/secret.ics?component=VTODO",
+			code: http.StatusOK,
+			uids: []string{"them/shared/sooner", "me/work/later"},
+			kind: "VTODO",
+		},
+		"bad-component": {
+			path: "/calendar/secret.ics?component=VJOURNAL",
+			code: http.StatusBadRequest,
+		},
+		"revoked": {
+			path: "/calendar/revoked.ics",
+			code: http.StatusNotFound,
+		},
+	}
+
+	router := (&Server{Ydb: ydb}).Router()
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not"+test.path, nil)
+			req.Header.Set("Accept", "text/calendar, */*")
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, test.code, rec.Code, rec.Body.String())
+			if test.code != http.StatusOK {
+				return
+			}
+			assert.Equal(t, ical.MediaType+"; charset=utf-8", rec.Header().Get("Content-Type"))
+			body := rec.Body.String()
+			assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+			assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+			assert.Equal(t, len(test.uids), strings.Count(body, "BEGIN:"+test.kind+"\r\n"))
+			var uids []string
+			for _, line := range strings.Split(body, "\r\n") {
+				if strings.HasPrefix(line, "UID:") {
+					uids = append(uids, strings.TrimPrefix(line, "UID:"))
+				}
+			}
+			assert.Equal(t, test.uids, uids)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/TheYeung1/yata-server/cbor"
+	"github.com/TheYeung1/yata-server/form"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+// mediaTypeJSON is the media type of JSON; the default for request and response bodies.
+const mediaTypeJSON = "application/json" // FYI: https://stackoverflow.com/questions/477816/what-is-the-correct-json-content-type
+
+// codec decodes request bodies and encodes response bodies of one media type.
+type codec struct {
+	decode func(r io.Reader, v interface{}) error
+	// encode is nil for media types that are only accepted in requests.
+	encode func(w io.Writer, v interface{}) error
+}
+
+// codecs are keyed by media type.
+var codecs = map[string]codec{
+	mediaTypeJSON: {
+		decode: func(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) },
+		encode: func(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) },
+	},
+	cbor.MediaType: {
+		decode: decodeCBOR,
+		encode: func(w io.Writer, v interface{}) error { return cbor.NewEncoder(w).Encode(v) },
+	},
+	form.MediaType: {
+		decode: decodeForm,
+	},
+}
+
+// requestMediaTypes are the media types request bodies can be given as.
+var requestMediaTypes = []string{mediaTypeJSON, cbor.MediaType, form.MediaType}
+
+// responseMediaTypes are the media types responses can be encoded as, in order of preference.
+var responseMediaTypes = []string{mediaTypeJSON, cbor.MediaType}
+
+// routeResponseMediaTypes maps the methods and path templates of routes that do not respond with every one of
+// responseMediaTypes, as keyed by routeKey, to the media types they do respond with, in order of preference. "*/*" means
+// any media type.
+var routeResponseMediaTypes = map[string][]string{
+	routeKey(http.MethodGet, "/lists/{listID}/"):                                          listMediaTypes,
+	routeKey(http.MethodGet, "/lists/{listID}/items"):                                     listItemsMediaTypes,
+	routeKey(http.MethodGet, "/lists/{listID}/items/{itemID}/attachments/{attachmentID}"): {"*/*"},
+	routeKey(http.MethodGet, "/export"):                                                   {mediaTypeJSON},
+	routeKey(http.MethodGet, "/openapi.json"):                                             {mediaTypeJSON},
+	routeKey(http.MethodGet, "/calendar/{token}.ics"):                                     {ical.MediaType},
+	// CalDAV clients are not particular about the media types they accept.
+	routeKey(http.MethodGet, "/.well-known/caldav"):                         {"*/*"},
+	routeKey("PROPFIND", "/.well-known/caldav"):                             {"*/*"},
+	routeKey("PROPFIND", "/dav/"):                                           {"*/*"},
+	routeKey(http.MethodOptions, "/dav/"):                                   {"*/*"},
+	routeKey("PROPFIND", "/dav/lists/"):                                     {"*/*"},
+	routeKey("PROPFIND", "/dav/lists/{owner}/{listID}/"):                    {"*/*"},
+	routeKey("REPORT", "/dav/lists/{owner}/{listID}/"):                      {"*/*"},
+	routeKey("PROPFIND", "/dav/lists/{owner}/{listID}/{itemID}.ics"):        {"*/*"},
+	routeKey(http.MethodGet, "/dav/lists/{owner}/{listID}/{itemID}.ics"):    {"*/*"},
+	routeKey(http.MethodPut, "/dav/lists/{owner}/{listID}/{itemID}.ics"):    {"*/*"},
+	routeKey(http.MethodDelete, "/dav/lists/{owner}/{listID}/{itemID}.ics"): {"*/*"},
+}
+
+// routeKey returns the key of the route for method and path template in routeResponseMediaTypes.
+func routeKey(method, tmpl string) string {
+	return method + " " + tmpl
+}
+
+var (
+	// listMediaTypes are the media types GetList responds with.
+	listMediaTypes = withResponseMediaTypes(mediaTypeMarkdown)
+	// listItemsMediaTypes are the media types GetListItems responds with.
+	listItemsMediaTypes = withResponseMediaTypes(mediaTypeTodoTxt)
+)
+
+// withResponseMediaTypes returns responseMediaTypes followed by others.
+func withResponseMediaTypes(others ...string) []string {
+	return append(append([]string(nil), responseMediaTypes...), others...)
+}
+
+func decodeCBOR(r io.Reader, v interface{}) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return cbor.Unmarshal(b, v)
+}
+
+// decodeForm decodes form-encoded values into the struct v points to. Bodies that are a JSON object or array are
+// decoded as JSON instead, since that is what "curl -d" sends without a Content-Type.
+func decodeForm(r io.Reader, v interface{}) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) != 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return json.Unmarshal(trimmed, v)
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	if err := form.Decode(values, v); err != nil {
+		if err == form.ErrNotStruct {
+			return unsupportedMediaTypeError{mediaType: form.MediaType}
+		}
+		return err
+	}
+	return nil
+}
+
+// unsupportedMediaTypeError is returned by bind when a request body cannot be given as its media type.
+type unsupportedMediaTypeError struct {
+	mediaType string
+}
+
+func (e unsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q", e.mediaType)
+}
+
+// bind decodes the request's body into v, which must be a pointer, using the codec for its Content-Type. Bodies
+// without a Content-Type are decoded as JSON.
+func bind(r *http.Request, v interface{}) error {
+	mediaType := mediaTypeJSON
+	if len(r.Header.Get("Content-Type")) != 0 {
+		mediaType = requestMediaType(r)
+	}
+	c, ok := codecs[mediaType]
+	if !ok {
+		return unsupportedMediaTypeError{mediaType: mediaType}
+	}
+	return c.decode(r.Body, v)
+}
+
+// renderBindError renders why bind failed: either the request body's media type is not supported, or the body is
+// malformed.
+func renderBindError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(unsupportedMediaTypeError); ok {
+		renderUnsupportedMediaType(w, r, "Request bodies", requestMediaTypes...)
+		return
+	}
+	renderBadRequest(w, r, "malformed input")
+}
+
+// render sets the content type, writes the response code, and encodes v to w in the media type the request's Accept
+// header prefers. Responses fall back to JSON for requests that accept none of responseMediaTypes, which
+// requireAcceptable only lets through to routes that also respond with other media types.
+func render(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	mediaType, ok := negotiate(r, responseMediaTypes...)
+	if !ok {
+		mediaType = mediaTypeJSON
+	}
+	// Headers set after WriteHeader are not sent.
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(code)
+	if err := codecs[mediaType].encode(w, v); err != nil {
+		request.Logger(r.Context()).WithError(err).WithField("mediaType", mediaType).Warn("failed to render response")
+	}
+}
+
+// requireAcceptable responds with 406 Not Acceptable to requests that accept none of the media types their route
+// responds with, before the route's handler is called.
+func requireAcceptable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offers := responseMediaTypes
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				if o, ok := routeResponseMediaTypes[routeKey(r.Method, tmpl)]; ok {
+					offers = o
+				}
+			}
+		}
+		if offers[0] != "*/*" {
+			if _, ok := negotiate(r, offers...); !ok {
+				renderNotAcceptable(w, r, "Responses", offers...)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBind(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		body        string
+		want        InsertListSectionInput
+		unsupported bool
+		malformed   bool
+	}{
+		"no-content-type": {
+			body: `{"SectionID":"dairy","Title":"Dairy","Position":2}`,
+			want: InsertListSectionInput{SectionID: "dairy", Title: "Dairy", Position: 2},
+		},
+		"json": {
+			contentType: "application/json; charset=utf-8",
+			body:        `{"SectionID":"dairy","Title":"Dairy"}`,
+			want:        InsertListSectionInput{SectionID: "dairy", Title: "Dairy"},
+		},
+		"cbor": {
+			contentType: "application/cbor",
+			// {"SectionID": "dairy", "Position": 2}
+			body: mustDecodeHex("a26953656374696f6e494465646169727968506f736974696f6e02"),
+			want: InsertListSectionInput{SectionID: "dairy", Position: 2},
+		},
+		"form": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "SectionID=dairy&Title=Milk+%26+cheese&Position=2",
+			want:        InsertListSectionInput{SectionID: "dairy", Title: "Milk & cheese", Position: 2},
+		},
+		"form-from-curl": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        ` {"SectionID":"dairy"}`,
+			want:        InsertListSectionInput{SectionID: "dairy"},
+		},
+		"malformed-form": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "Position=first",
+			malformed:   true,
+		},
+		"malformed-cbor": {
+			contentType: "application/cbor",
+			body:        "\xa1",
+			malformed:   true,
+		},
+		"unsupported": {
+			contentType: "application/xml",
+			body:        `<section/>`,
+			unsupported: true,
+		},
+		"invalid-content-type": {
+			contentType: "application/",
+			body:        `{}`,
+			unsupported: true,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPut, "https://does.not/matter", bytes.NewBufferString(test.body))
+			if len(test.contentType) != 0 {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			var got InsertListSectionInput
+			err := bind(req, &got)
+			switch {
+			case test.unsupported:
+				assert.IsType(t, unsupportedMediaTypeError{}, err)
+			case test.malformed:
+				assert.Error(t, err)
+				assert.NotEqual(t, unsupportedMediaTypeError{}, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestBind_FormIntoSlice(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString("ItemID=eggs"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var input []InsertListItemInput
+	assert.Equal(t, unsupportedMediaTypeError{mediaType: "application/x-www-form-urlencoded"}, bind(req, &input))
+}
+
+func TestRender(t *testing.T) {
+	out := InsertListSectionOutput{SectionID: "dairy"}
+	tests := map[string]struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		"default": {
+			contentType: "application/json",
+			body:        `{"SectionID":"dairy"}` + "\n",
+		},
+		"cbor": {
+			accept:      "application/cbor",
+			contentType: "application/cbor",
+			body:        mustDecodeHex("a16953656374696f6e4944656461697279"),
+		},
+		"prefers-cbor": {
+			accept:      "application/json;q=0.1, application/cbor",
+			contentType: "application/cbor",
+			body:        mustDecodeHex("a16953656374696f6e4944656461697279"),
+		},
+		"falls-back-to-json": {
+			accept:      "text/markdown",
+			contentType: "application/json",
+			body:        `{"SectionID":"dairy"}` + "\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+			if len(test.accept) != 0 {
+				req.Header.Set("Accept", test.accept)
+			}
+			render(rec, req, http.StatusCreated, out)
+
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+			assert.Equal(t, test.body, rec.Body.String())
+			if test.contentType == cbor.MediaType {
+				var decoded InsertListSectionOutput
+				require.NoError(t, cbor.Unmarshal(rec.Body.Bytes(), &decoded))
+				assert.Equal(t, out, decoded)
+			}
+		})
+	}
+}
+
+func TestRenderBindError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "https://does.not/matter", nil)
+	renderBindError(rec, req, unsupportedMediaTypeError{mediaType: "application/xml"})
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	assert.Equal(t, `{"Code":"UnsupportedMediaType","Message":"Request bodies can be given as application/json, application/cbor or application/x-www-form-urlencoded"}`+"\n", rec.Body.String())
+}
+
+func TestRequireAcceptable(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		path   string
+		accept string
+		code   int
+	}{
+		// Requests that get past requireAcceptable are rejected by authentication instead, since they have no token.
+		"json":           {method: http.MethodGet, path: "/lists", accept: "application/json", code: http.StatusBadRequest},
+		"cbor":           {method: http.MethodGet, path: "/lists", accept: "application/cbor", code: http.StatusBadRequest},
+		"unacceptable":   {method: http.MethodGet, path: "/lists", accept: "text/html", code: http.StatusNotAcceptable},
+		"markdown-list":  {method: http.MethodGet, path: "/lists/groceries/", accept: "text/markdown", code: http.StatusBadRequest},
+		"markdown-items": {method: http.MethodGet, path: "/lists/groceries/items", accept: "text/markdown", code: http.StatusNotAcceptable},
+		"todo-txt-items": {method: http.MethodGet, path: "/lists/groceries/items", accept: "text/plain", code: http.StatusBadRequest},
+		"any-attachment": {method: http.MethodGet, path: "/lists/groceries/items/milk/attachments/label.png", accept: "image/png", code: http.StatusBadRequest},
+		// Other methods on the same paths only respond with responseMediaTypes.
+		"markdown-put-list":      {method: http.MethodPut, path: "/lists/groceries/", accept: "text/markdown", code: http.StatusNotAcceptable},
+		"markdown-delete-list":   {method: http.MethodDelete, path: "/lists/groceries/", accept: "text/markdown", code: http.StatusNotAcceptable},
+		"todo-txt-put-items":     {method: http.MethodPut, path: "/lists/groceries/items", accept: "text/plain", code: http.StatusNotAcceptable},
+		"put-any-attachment":     {method: http.MethodPut, path: "/lists/groceries/items/milk/attachments/label.png", accept: "image/png", code: http.StatusNotAcceptable},
+		"delete-any-attachment":  {method: http.MethodDelete, path: "/lists/groceries/items/milk/attachments/label.png", accept: "image/png", code: http.StatusNotAcceptable},
+		"json-delete-attachment": {method: http.MethodDelete, path: "/lists/groceries/items/milk/attachments/label.png", accept: "application/json", code: http.StatusBadRequest},
+		"json-only-export":       {method: http.MethodGet, path: "/export", accept: "application/cbor", code: http.StatusNotAcceptable},
+		"public":                 {method: http.MethodGet, path: "/shared/token", accept: "text/html", code: http.StatusNotAcceptable},
+		"calendar-only":          {method: http.MethodGet, path: "/calendar/token.ics", accept: "application/json", code: http.StatusNotAcceptable},
+		"unmatched-is-left":      {method: http.MethodGet, path: "/nowhere", accept: "text/html", code: http.StatusNotFound},
+	}
+
+	router := (&Server{}).Router()
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "https://does.not"+test.path, nil)
+			req.Header.Set("Accept", test.accept)
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, test.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func mustDecodeHex(s string) string {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
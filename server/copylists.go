@@ -0,0 +1,218 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+// maxShiftDueDays is the most days due dates can be shifted by in either direction when a list is copied.
+const maxShiftDueDays = 3650
+
+type CopyListInput struct {
+	// ListID is the ID of the new list.
+	ListID string
+	// Title is the new list's title. It defaults to the copied list's title.
+	Title string
+	// ResetCompleted marks every item on the new list as not completed.
+	ResetCompleted bool
+	// ShiftDueDays moves every item's due date this many days later, or earlier if it is negative.
+	ShiftDueDays int
+	// Template makes the new list a template.
+	Template bool
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *CopyListInput) Validate() error {
+	if err := validateListID(model.ListID(input.ListID)); err != nil {
+		return err
+	}
+	if len(input.Title) > 100 {
+		return errors.New("Title length cannot exceed 100 characters")
+	}
+	if len(input.Title) != len(strings.TrimSpace(input.Title)) {
+		return errors.New("Title cannot be prefixed or suffixed with spaces")
+	}
+	if input.ShiftDueDays > maxShiftDueDays || input.ShiftDueDays < -maxShiftDueDays {
+		return fmt.Errorf("ShiftDueDays cannot be more than %d days in either direction", maxShiftDueDays)
+	}
+	return nil
+}
+
+type CopyListOutput struct {
+	ListID string
+	// ItemCount is the number of items copied to the new list.
+	ItemCount int
+}
+
+// CopyList copies a list, its sections and its items to a new list owned by the caller. Anyone who can view a list can
+// copy it. Attachments and members are not copied, and only assignments to the caller are kept.
+func (s *Server) CopyList(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("copy list called")
+
+	var input CopyListInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	src, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	sections, err := s.Ydb.GetListSections(src.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	items, err := s.Ydb.GetListItems(src.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	yl := copyList(src, uid, model.ListID(input.ListID), input)
+	log.WithField("list", yl).Debug("inserting list")
+	if err := s.Ydb.InsertList(yl.UserID, yl); err != nil {
+		switch err := err.(type) {
+		case database.ListExistsError:
+			log.WithError(err).Info("list exists")
+			render(w, r, http.StatusConflict, responseError{Code: "ListExists", Message: "List already exists"})
+			return
+		case database.ListInTrashError:
+			log.WithError(err).Info("list in trash")
+			renderListInTrash(w, r)
+			return
+		}
+		log.WithError(err).Error("failed to insert list")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	copies := copyItems(items, yl, input, time.Now().UTC())
+	if err := s.writeListCopy(yl, sections, copies); err != nil {
+		log.WithError(err).Error("failed to copy list")
+		// The partial copy is removed so that copying the list again does not fail because its ID is taken.
+		if err := s.removeListCopy(yl, sections, copies); err != nil {
+			log.WithError(err).Error("failed to remove partial copy of list")
+		}
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionCreate, "list", string(yl.ListID), nil, yl)
+
+	out := CopyListOutput{ListID: input.ListID, ItemCount: len(copies)}
+	log.WithField("output", out).Debug("list copied")
+	render(w, r, http.StatusCreated, out)
+}
+
+// writeListCopy writes the sections and items of yl, a copy of a list that has just been inserted.
+func (s *Server) writeListCopy(yl model.YataList, sections []model.YataSection, items []model.YataItem) error {
+	for _, section := range sections {
+		section.UserID = yl.UserID
+		section.ListID = yl.ListID
+		if err := s.Ydb.InsertSection(section); err != nil {
+			return fmt.Errorf("failed to insert section %q: %v", section.SectionID, err)
+		}
+	}
+	if err := s.Ydb.InsertItems(items); err != nil {
+		return fmt.Errorf("failed to insert items: %v", err)
+	}
+	return nil
+}
+
+// removeListCopy deletes yl, a copy of a list that could not be written in full, along with whichever of its sections
+// and items were written. The list is deleted last so that a failure leaves it to be deleted by hand.
+func (s *Server) removeListCopy(yl model.YataList, sections []model.YataSection, items []model.YataItem) error {
+	for _, item := range items {
+		if err := s.Ydb.DeleteItem(yl.UserID, yl.ListID, item.ItemID); err != nil {
+			return fmt.Errorf("failed to delete item %q: %v", item.ItemID, err)
+		}
+	}
+	for _, section := range sections {
+		if err := s.Ydb.DeleteSection(yl.UserID, yl.ListID, section.SectionID); err != nil {
+			return fmt.Errorf("failed to delete section %q: %v", section.SectionID, err)
+		}
+	}
+	if err := s.Ydb.DeleteList(yl.UserID, yl.ListID); err != nil {
+		return fmt.Errorf("failed to delete list: %v", err)
+	}
+	return nil
+}
+
+// copyList returns a copy of src with the ID lid owned by uid. The copy keeps src's folder only if uid owns src, since
+// folders belong to a list's owner.
+func copyList(src model.YataList, uid model.UserID, lid model.ListID, input CopyListInput) model.YataList {
+	yl := model.YataList{
+		UserID:      uid,
+		ListID:      lid,
+		Title:       src.Title,
+		Description: src.Description,
+		Color:       src.Color,
+		Icon:        src.Icon,
+		Template:    input.Template,
+	}
+	if len(input.Title) != 0 {
+		yl.Title = input.Title
+	}
+	if src.UserID == uid {
+		yl.FolderID = src.FolderID
+	}
+	return yl
+}
+
+// copyItems returns copies of items that belong to yl, created at now. Assignments to anyone but yl's owner are dropped
+// because they are not members of the new list.
+func copyItems(items []model.YataItem, yl model.YataList, input CopyListInput, now time.Time) []model.YataItem {
+	copies := make([]model.YataItem, 0, len(items))
+	for _, item := range items {
+		item.UserID = yl.UserID
+		item.ListID = yl.ListID
+		item.CreatedAt = now
+		item.DeletedAt = nil
+		if input.ResetCompleted {
+			item.Completed = false
+		}
+		if item.DueAt != nil {
+			due := item.DueAt.AddDate(0, 0, input.ShiftDueDays)
+			item.DueAt = &due
+		}
+		if item.AssigneeID != yl.UserID {
+			item.AssigneeID = ""
+		}
+		copies = append(copies, item)
+	}
+	return copies
+}
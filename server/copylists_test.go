@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyListInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input CopyListInput
+		err   error
+	}{
+		"valid": {
+			input: CopyListInput{ListID: "copy", ResetCompleted: true, ShiftDueDays: -7},
+		},
+		"list-id-empty": {
+			input: CopyListInput{},
+			err:   errors.New("ListID cannot be empty"),
+		},
+		"title-with-spaces": {
+			input: CopyListInput{ListID: "copy", Title: " Copy"},
+			err:   errors.New("Title cannot be prefixed or suffixed with spaces"),
+		},
+		"shift-too-far": {
+			input: CopyListInput{ListID: "copy", ShiftDueDays: maxShiftDueDays + 1},
+			err:   fmt.Errorf("ShiftDueDays cannot be more than %d days in either direction", maxShiftDueDays),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestCopyItems(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	due := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	shifted := due.AddDate(0, 0, 7)
+	deletedAt := now
+	items := []model.YataItem{
+		{UserID: "them", ListID: "src", ItemID: "a", Completed: true, DueAt: &due, AssigneeID: "them"},
+		{UserID: "them", ListID: "src", ItemID: "b", ParentID: "a", SectionID: "s", AssigneeID: "me", DeletedAt: &deletedAt},
+	}
+	yl := model.YataList{UserID: "me", ListID: "copy"}
+
+	tests := map[string]struct {
+		input CopyListInput
+		want  []model.YataItem
+	}{
+		"as-is": {
+			input: CopyListInput{},
+			want: []model.YataItem{
+				{UserID: "me", ListID: "copy", ItemID: "a", Completed: true, DueAt: &due, CreatedAt: now},
+				{UserID: "me", ListID: "copy", ItemID: "b", ParentID: "a", SectionID: "s", AssigneeID: "me", CreatedAt: now},
+			},
+		},
+		"reset-and-shift": {
+			input: CopyListInput{ResetCompleted: true, ShiftDueDays: 7},
+			want: []model.YataItem{
+				{UserID: "me", ListID: "copy", ItemID: "a", DueAt: &shifted, CreatedAt: now},
+				{UserID: "me", ListID: "copy", ItemID: "b", ParentID: "a", SectionID: "s", AssigneeID: "me", CreatedAt: now},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.want, copyItems(items, yl, test.input, now))
+		})
+	}
+	assert.Equal(t, time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC), due, "copying must not change the original items")
+}
+
+func TestServer_CopyList(t *testing.T) {
+	var inserted model.YataList
+	var sections []model.YataSection
+	var items []model.YataItem
+	ydb := mockYdb{
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid, Title: "Onboarding", FolderID: "work", Template: true}, nil
+		},
+		MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+			return []model.YataSection{{UserID: id, ListID: lid, SectionID: "day-1", Title: "Day 1"}}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return []model.YataItem{{UserID: id, ListID: lid, ItemID: "laptop", SectionID: "day-1", Completed: true}}, nil
+		},
+		MockInsertList: func(id model.UserID, yl model.YataList) error {
+			inserted = yl
+			return nil
+		},
+		MockInsertSection: func(section model.YataSection) error {
+			sections = append(sections, section)
+			return nil
+		},
+		MockInsertItems: func(i []model.YataItem) error {
+			items = i
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(`{"ListID":"week-12","Title":"Onboarding week 12","ResetCompleted":true}`))
+	req = mux.SetURLVars(req, map[string]string{"listID": "onboarding"})
+
+	srvr := Server{Ydb: ydb}
+	srvr.CopyList(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"ListID":"week-12","ItemCount":1}`, rec.Body.String())
+	assert.Equal(t, model.YataList{UserID: "me", ListID: "week-12", Title: "Onboarding week 12", FolderID: "work"}, inserted)
+	assert.Equal(t, []model.YataSection{{UserID: "me", ListID: "week-12", SectionID: "day-1", Title: "Day 1"}}, sections)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, model.ListID("week-12"), items[0].ListID)
+		assert.Equal(t, model.SectionID("day-1"), items[0].SectionID)
+		assert.False(t, items[0].Completed)
+	}
+}
+
+func TestServer_CopyList_RemovesPartialCopy(t *testing.T) {
+	var deleted []string
+	ydb := mockYdb{
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid, Title: "Onboarding"}, nil
+		},
+		MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+			return []model.YataSection{{UserID: id, ListID: lid, SectionID: "day-1", Title: "Day 1"}}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return []model.YataItem{{UserID: id, ListID: lid, ItemID: "laptop"}, {UserID: id, ListID: lid, ItemID: "badge"}}, nil
+		},
+		MockInsertList:    func(id model.UserID, yl model.YataList) error { return nil },
+		MockInsertSection: func(section model.YataSection) error { return nil },
+		MockInsertItems: func(i []model.YataItem) error {
+			return database.UnprocessedItemsError{Items: i[1:]}
+		},
+		MockDeleteItem: func(id model.UserID, lid model.ListID, iid model.ItemID) error {
+			deleted = append(deleted, string(id)+"/"+string(lid)+"/"+string(iid))
+			return nil
+		},
+		MockDeleteSection: func(id model.UserID, lid model.ListID, sid model.SectionID) error {
+			deleted = append(deleted, string(id)+"/"+string(lid)+"/sections/"+string(sid))
+			return nil
+		},
+		MockDeleteList: func(id model.UserID, lid model.ListID) error {
+			deleted = append(deleted, string(id)+"/"+string(lid))
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(`{"ListID":"week-12"}`))
+	req = mux.SetURLVars(req, map[string]string{"listID": "onboarding"})
+
+	srvr := Server{Ydb: ydb}
+	srvr.CopyList(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	// Everything that may have been written is deleted, so that the copy can be retried with the same ID.
+	assert.Equal(t, []string{"me/week-12/laptop", "me/week-12/badge", "me/week-12/sections/day-1", "me/week-12"}, deleted)
+}
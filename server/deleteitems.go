@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type DeleteListItemOutput struct {
+	// ItemIDs are the IDs of every item moved to the trash; the requested item and all of its sub-tasks.
+	ItemIDs []model.ItemID
+}
+
+// DeleteListItem moves an item, and all of its sub-tasks, to the trash of the list's owner.
+func (s *Server) DeleteListItem(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list item called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	item, err := s.Ydb.GetItem(yl.UserID, listID, itemID)
+	if err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	trashed, err := s.trashItem(r, yl, items, item)
+	if err != nil {
+		log.WithError(err).Error("failed to trash item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := DeleteListItemOutput{ItemIDs: trashed}
+	log.WithField("output", out).Debug("list item deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+// trashItem moves an item and its sub-tasks, found in items, to the trash and returns the IDs of those that were
+// trashed.
+func (s *Server) trashItem(r *http.Request, yl model.YataList, items []model.YataItem, item model.YataItem) ([]model.ItemID, error) {
+	// Sub-tasks are trashed before their parents so that a failure part way through never leaves a sub-task whose
+	// parent is in the trash. They share the same DeletedAt so that restoring the item also restores its sub-tasks.
+	// Attachments are kept until the item is purged from the trash.
+	deletedAt := time.Now().UTC()
+	trashed := []model.ItemID{}
+	for _, item := range append(itemDescendants(items, item.ItemID), item) {
+		if err := s.Ydb.TrashItem(yl.UserID, yl.ListID, item.ItemID, deletedAt); err != nil {
+			if _, ok := err.(database.ItemNotFoundError); ok {
+				// The item was deleted after the list's items were retrieved.
+				continue
+			}
+			return trashed, fmt.Errorf("failed to trash item %q: %v", item.ItemID, err)
+		}
+		s.recordActivity(r, yl, model.ActionDelete, "item", string(item.ItemID), item, nil)
+		trashed = append(trashed, item.ItemID)
+	}
+	return trashed, nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type DeleteListOutput struct {
+	ListID model.ListID
+	// ItemIDs are the IDs of every item moved to the trash along with the list.
+	ItemIDs []model.ItemID
+}
+
+// DeleteList moves a list, and every item on it, to the trash. Only the list's owner can delete it.
+func (s *Server) DeleteList(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	// Items are trashed before their list so that a failure part way through never leaves items on a trashed list.
+	// Everything shares the same DeletedAt so that restoring the list also restores its items.
+	deletedAt := time.Now().UTC()
+	out := DeleteListOutput{ListID: listID, ItemIDs: []model.ItemID{}}
+	for _, item := range items {
+		if err := s.Ydb.TrashItem(yl.UserID, listID, item.ItemID, deletedAt); err != nil {
+			if _, ok := err.(database.ItemNotFoundError); ok {
+				// The item was deleted after the list's items were retrieved.
+				continue
+			}
+			log.WithError(err).WithField("itemID", item.ItemID).Error("failed to trash item")
+			renderInternalServerError(w, r)
+			return
+		}
+		out.ItemIDs = append(out.ItemIDs, item.ItemID)
+	}
+	if err := s.Ydb.TrashList(yl.UserID, listID, deletedAt); err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to trash list")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionDelete, "list", string(listID), yl, nil)
+
+	log.WithField("output", out).Debug("list deleted")
+	render(w, r, http.StatusOK, out)
+}
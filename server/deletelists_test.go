@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_DeleteList(t *testing.T) {
+	tests := map[string]struct {
+		owner    string
+		trashErr map[model.ItemID]error
+		listErr  error
+		code     int
+		outBody  string
+		writes   []string
+	}{
+		"trashes-items-then-list": {
+			code:    http.StatusOK,
+			outBody: "{\"ListID\":\"groceries\",\"ItemIDs\":[\"eggs\",\"milk\"]}\n",
+			writes:  []string{"trash item eggs", "trash item milk", "trash list groceries"},
+		},
+		"item-deleted-in-the-meantime": {
+			trashErr: map[model.ItemID]error{"eggs": database.ItemNotFoundError{}},
+			code:     http.StatusOK,
+			outBody:  "{\"ListID\":\"groceries\",\"ItemIDs\":[\"milk\"]}\n",
+			writes:   []string{"trash item eggs", "trash item milk", "trash list groceries"},
+		},
+		"item-cannot-be-trashed": {
+			trashErr: map[model.ItemID]error{"eggs": errors.New("boom")},
+			code:     http.StatusInternalServerError,
+			outBody:  "{\"Code\":\"InternalServerError\"}\n",
+			// The list is kept so that no item is left on a list in the trash.
+			writes: []string{"trash item eggs"},
+		},
+		"list-deleted-in-the-meantime": {
+			listErr: database.ListNotFoundError{},
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"ListDoesNotExist\",\"Message\":\"List does not exist\"}\n",
+			writes:  []string{"trash item eggs", "trash item milk", "trash list groceries"},
+		},
+		"not-owner": {
+			owner:   "owner",
+			code:    http.StatusForbidden,
+			outBody: "{\"Code\":\"Forbidden\",\"Message\":\"You do not have permission to do that to this list\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var writes []string
+			var deletedAt []time.Time
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid}, nil
+				},
+				MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+					return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleEditor}, nil
+				},
+				MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+					return []model.YataItem{
+						{UserID: id, ListID: lid, ItemID: "eggs"},
+						{UserID: id, ListID: lid, ItemID: "milk"},
+					}, nil
+				},
+				MockTrashItem: func(id model.UserID, lid model.ListID, iid model.ItemID, at time.Time) error {
+					writes = append(writes, "trash item "+string(iid))
+					deletedAt = append(deletedAt, at)
+					return test.trashErr[iid]
+				},
+				MockTrashList: func(id model.UserID, lid model.ListID, at time.Time) error {
+					writes = append(writes, "trash list "+string(lid))
+					deletedAt = append(deletedAt, at)
+					return test.listErr
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			target := "https://does.not/lists/groceries/"
+			if len(test.owner) != 0 {
+				target += "?owner=" + test.owner
+			}
+			req := httptest.NewRequest(http.MethodDelete, target, nil)
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+
+			srvr := Server{Ydb: ydb}
+			srvr.DeleteList(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.writes, writes)
+			// Everything is trashed at the same time so that restoring the list also restores its items.
+			for _, at := range deletedAt {
+				assert.Equal(t, deletedAt[0], at)
+			}
+		})
+	}
+}
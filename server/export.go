@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+)
+
+// Export streams all of the caller's own folders, lists, sections and items as an archive. Lists shared with the
+// caller and anything in the trash are left out.
+func (s *Server) Export(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("export called")
+
+	folders, err := s.Ydb.GetFolders(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get folders")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortFolders(folders)
+	lists, err := s.Ydb.GetLists(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get lists")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortLists(lists, uid)
+	items, err := s.Ydb.GetAllItems(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get all items")
+		renderInternalServerError(w, r)
+		return
+	}
+	itemsByList := map[model.ListID][]model.YataItem{}
+	for _, item := range items {
+		itemsByList[item.ListID] = append(itemsByList[item.ListID], item)
+	}
+
+	archivedFolders := make([]archive.Folder, len(folders))
+	for i, f := range folders {
+		archivedFolders[i] = archive.FromFolder(f)
+	}
+
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="yata-export-%s.json"`, now.Format("2006-01-02")))
+	w.WriteHeader(http.StatusOK)
+
+	// Once the archive has started there is no way to report an error other than stopping; the archive is then
+	// incomplete and is not valid JSON.
+	aw, err := archive.NewWriter(w, uid, now, archivedFolders)
+	if err != nil {
+		log.WithError(err).Warn("failed to write archive header")
+		return
+	}
+	for _, yl := range lists {
+		sections, err := s.Ydb.GetListSections(uid, yl.ListID)
+		if err != nil {
+			log.WithError(err).WithField("listID", yl.ListID).Error("failed to get list sections")
+			return
+		}
+		sortSections(sections)
+		if err := aw.WriteList(archive.FromList(yl, sections, itemsByList[yl.ListID])); err != nil {
+			log.WithError(err).WithField("listID", yl.ListID).Warn("failed to write list")
+			return
+		}
+	}
+	if err := aw.Close(); err != nil {
+		log.WithError(err).Warn("failed to write archive")
+		return
+	}
+	log.WithField("lists", len(lists)).WithField("items", len(items)).Debug("exported")
+}
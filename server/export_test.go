@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Export(t *testing.T) {
+	ydb := mockYdb{
+		MockGetFolders: func(id model.UserID) ([]model.YataFolder, error) {
+			return []model.YataFolder{{UserID: id, FolderID: "work", Title: "Work", Position: 1}, {UserID: id, FolderID: "home", Title: "Home"}}, nil
+		},
+		MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+			return []model.YataList{
+				{UserID: id, ListID: "b", Title: "B", Position: 1, Archived: true},
+				{UserID: id, ListID: "a", Title: "A", FolderID: "home"},
+			}, nil
+		},
+		MockGetAllItems: func(id model.UserID) ([]model.YataItem, error) {
+			return []model.YataItem{
+				{UserID: id, ListID: "a", ItemID: "1", Content: "One", SectionID: "s"},
+				{UserID: id, ListID: "b", ItemID: "2", Content: "Two", Completed: true},
+			}, nil
+		},
+		MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+			if lid == "a" {
+				return []model.YataSection{{UserID: id, ListID: lid, SectionID: "s", Title: "S"}}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+
+	srvr := Server{Ydb: ydb}
+	srvr.Export(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+
+	a, err := archive.Read(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, archive.Version, a.Version)
+	assert.Equal(t, "me", a.UserID)
+	assert.Equal(t, []archive.Folder{{FolderID: "home", Title: "Home"}, {FolderID: "work", Title: "Work", Position: 1}}, a.Folders)
+	assert.Equal(t, []archive.List{
+		{
+			ListID:   "a",
+			Title:    "A",
+			FolderID: "home",
+			Sections: []archive.Section{{SectionID: "s", Title: "S"}},
+			Items:    []archive.Item{{ItemID: "1", Content: "One", SectionID: "s"}},
+		},
+		{
+			ListID:   "b",
+			Title:    "B",
+			Position: 1,
+			Archived: true,
+			Sections: []archive.Section{},
+			Items:    []archive.Item{{ItemID: "2", Content: "Two", Completed: true}},
+		},
+	}, a.Lists)
+}
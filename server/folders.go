@@ -0,0 +1,383 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type GetFoldersOutput struct {
+	// Folders are in the order of their positions.
+	Folders []model.YataFolder
+}
+
+func (s *Server) GetFolders(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get folders called")
+
+	folders, err := s.Ydb.GetFolders(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get folders")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortFolders(folders)
+
+	out := GetFoldersOutput{Folders: folders}
+	log.WithField("output", out).Debug("folders retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type GetFolderOutput struct {
+	Folder model.YataFolder
+}
+
+func (s *Server) GetFolder(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get folder called")
+
+	folderID := model.FolderID(mux.Vars(r)["folderID"])
+	if err := validateFolderID(folderID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	folder, ok := s.getFolder(w, r, uid, folderID)
+	if !ok {
+		return
+	}
+
+	out := GetFolderOutput{Folder: folder}
+	log.WithField("output", out).Debug("folder retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertFolderInput struct {
+	FolderID string
+	Title    string
+	Position int
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *InsertFolderInput) Validate() error {
+	if err := validateFolderID(model.FolderID(input.FolderID)); err != nil {
+		return err
+	}
+	if len(input.Title) == 0 {
+		return errors.New("Title cannot be empty")
+	}
+	if len(input.Title) > 100 {
+		return errors.New("Title length cannot exceed 100 characters")
+	}
+	if len(input.Title) != len(strings.TrimSpace(input.Title)) {
+		return errors.New("Title cannot be prefixed or suffixed with spaces")
+	}
+	if input.Position < 0 {
+		return errors.New("Position cannot be negative")
+	}
+	return nil
+}
+
+type InsertFolderOutput struct {
+	FolderID string
+}
+
+// InsertFolder creates a folder, or renames and repositions an existing one.
+func (s *Server) InsertFolder(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert folder called")
+
+	var input InsertFolderInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	folder := model.YataFolder{
+		UserID:   uid,
+		FolderID: model.FolderID(input.FolderID),
+		Title:    input.Title,
+		Position: input.Position,
+	}
+	log.WithField("folder", folder).Debug("inserting folder")
+	if err := s.Ydb.InsertFolder(folder); err != nil {
+		log.WithError(err).Error("failed to insert folder")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := InsertFolderOutput{FolderID: input.FolderID}
+	log.WithField("output", out).Debug("folder inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteFolderOutput struct {
+	FolderID model.FolderID
+	// ListIDs are the IDs of the lists that were in the folder. They are kept but no longer belong to a folder. Lists in
+	// the trash are not included; they are taken out of the folder when they are restored.
+	ListIDs []model.ListID
+}
+
+// DeleteFolder deletes a folder. Its lists are kept without a folder.
+func (s *Server) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete folder called")
+
+	folderID := model.FolderID(mux.Vars(r)["folderID"])
+	if err := validateFolderID(folderID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	if _, ok := s.getFolder(w, r, uid, folderID); !ok {
+		return
+	}
+
+	lists, err := s.Ydb.GetFolderLists(uid, folderID)
+	if err != nil {
+		log.WithError(err).Error("failed to get folder lists")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	// Lists are taken out of the folder before it is deleted so that a failure part way through never leaves lists in
+	// a folder that does not exist.
+	out := DeleteFolderOutput{FolderID: folderID, ListIDs: []model.ListID{}}
+	for _, yl := range lists {
+		if err := s.Ydb.SetListFolder(uid, yl.ListID, ""); err != nil {
+			if _, ok := err.(database.ListNotFoundError); ok {
+				// The list was deleted after the folder's lists were retrieved.
+				continue
+			}
+			log.WithError(err).WithField("listID", yl.ListID).Error("failed to set list folder")
+			renderInternalServerError(w, r)
+			return
+		}
+		after := yl
+		after.FolderID = ""
+		s.recordActivity(r, yl, model.ActionUpdate, "list", string(yl.ListID), yl, after)
+		out.ListIDs = append(out.ListIDs, yl.ListID)
+	}
+	if err := s.Ydb.DeleteFolder(uid, folderID); err != nil {
+		log.WithError(err).Error("failed to delete folder")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	log.WithField("output", out).Debug("folder deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+type GetFolderListsOutput struct {
+	// Lists are in the order of their positions.
+	// Only archived lists are returned when the "archived" query parameter is true; otherwise archived lists are left out.
+	// Likewise only templates are returned when the "template" query parameter is true.
+	Lists []model.YataList
+}
+
+func (s *Server) GetFolderLists(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get folder lists called")
+
+	folderID := model.FolderID(mux.Vars(r)["folderID"])
+	if err := validateFolderID(folderID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	archived, template, err := parseListFilters(r)
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	if _, ok := s.getFolder(w, r, uid, folderID); !ok {
+		return
+	}
+
+	lists, err := s.Ydb.GetFolderLists(uid, folderID)
+	if err != nil {
+		log.WithError(err).Error("failed to get folder lists")
+		renderInternalServerError(w, r)
+		return
+	}
+	lists = filterLists(lists, archived, template)
+	sortLists(lists, uid)
+
+	out := GetFolderListsOutput{Lists: lists}
+	log.WithField("output", out).Debug("folder lists retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type SetListFolderInput struct {
+	// FolderID is the folder to move the list into. The list is taken out of its folder if it is empty.
+	FolderID string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *SetListFolderInput) Validate() error {
+	if len(input.FolderID) == 0 {
+		return nil
+	}
+	return validateFolderID(model.FolderID(input.FolderID))
+}
+
+type SetListFolderOutput struct {
+	ListID   model.ListID
+	FolderID model.FolderID `json:",omitempty"`
+}
+
+// SetListFolder moves a list into one of the caller's folders, or out of its folder.
+// Folders belong to a list's owner so only the owner can move it.
+func (s *Server) SetListFolder(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("set list folder called")
+
+	var input SetListFolderInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+	folderID := model.FolderID(input.FolderID)
+	if len(folderID) != 0 {
+		if _, ok := s.getFolder(w, r, uid, folderID); !ok {
+			return
+		}
+	}
+
+	out := SetListFolderOutput{ListID: listID, FolderID: folderID}
+	if yl.FolderID == folderID {
+		log.WithField("output", out).Debug("list already in requested folder")
+		render(w, r, http.StatusOK, out)
+		return
+	}
+	if err := s.Ydb.SetListFolder(yl.UserID, listID, folderID); err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to set list folder")
+		renderInternalServerError(w, r)
+		return
+	}
+	after := yl
+	after.FolderID = folderID
+	s.recordActivity(r, yl, model.ActionUpdate, "list", string(listID), yl, after)
+
+	log.WithField("output", out).Debug("list folder set")
+	render(w, r, http.StatusOK, out)
+}
+
+// getFolder returns one of the caller's folders. If it cannot be returned an error response is rendered and false is returned.
+func (s *Server) getFolder(w http.ResponseWriter, r *http.Request, uid model.UserID, fid model.FolderID) (model.YataFolder, bool) {
+	log := request.Logger(r.Context())
+	folder, err := s.Ydb.GetFolder(uid, fid)
+	if err != nil {
+		if errnf, ok := err.(database.FolderNotFoundError); ok {
+			log.WithError(errnf).Info("folder not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "FolderDoesNotExist", Message: "Folder does not exist"})
+			return model.YataFolder{}, false
+		}
+		log.WithError(err).Error("failed to get folder")
+		renderInternalServerError(w, r)
+		return model.YataFolder{}, false
+	}
+	return folder, true
+}
+
+// sortFolders orders folders by position. Folders with the same position are ordered by ID so that the order is stable.
+func sortFolders(folders []model.YataFolder) {
+	sort.SliceStable(folders, func(i, j int) bool {
+		if folders[i].Position != folders[j].Position {
+			return folders[i].Position < folders[j].Position
+		}
+		return folders[i].FolderID < folders[j].FolderID
+	})
+}
+
+func validateFolderID(id model.FolderID) error {
+	if len(id) == 0 {
+		return errors.New("FolderID cannot be empty")
+	}
+	if len(id) > 100 {
+		return errors.New("FolderID length cannot exceed 100 characters")
+	}
+	if len(id) != len(strings.TrimSpace(string(id))) {
+		return errors.New("FolderID cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
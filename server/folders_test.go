@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertFolderInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input InsertFolderInput
+		err   error
+	}{
+		"valid": {
+			input: InsertFolderInput{FolderID: "home", Title: "Home", Position: 1},
+		},
+		"folder-id-empty": {
+			input: InsertFolderInput{Title: "Home"},
+			err:   errors.New("FolderID cannot be empty"),
+		},
+		"folder-id-with-spaces": {
+			input: InsertFolderInput{FolderID: "home ", Title: "Home"},
+			err:   errors.New("FolderID cannot be prefixed or suffixed with spaces"),
+		},
+		"title-empty": {
+			input: InsertFolderInput{FolderID: "home"},
+			err:   errors.New("Title cannot be empty"),
+		},
+		"position-negative": {
+			input: InsertFolderInput{FolderID: "home", Title: "Home", Position: -1},
+			err:   errors.New("Position cannot be negative"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestSetListFolderInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input SetListFolderInput
+		err   error
+	}{
+		"into-folder": {
+			input: SetListFolderInput{FolderID: "home"},
+		},
+		"out-of-folder": {
+			input: SetListFolderInput{},
+		},
+		"folder-id-with-spaces": {
+			input: SetListFolderInput{FolderID: " home"},
+			err:   errors.New("FolderID cannot be prefixed or suffixed with spaces"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestSortFolders(t *testing.T) {
+	folders := []model.YataFolder{
+		{FolderID: "work", Position: 1},
+		{FolderID: "home", Position: 0},
+		{FolderID: "b", Position: 1},
+	}
+	sortFolders(folders)
+
+	var ids []model.FolderID
+	for _, f := range folders {
+		ids = append(ids, f.FolderID)
+	}
+	assert.Equal(t, []model.FolderID{"home", "b", "work"}, ids)
+}
+
+func TestServer_DeleteFolder(t *testing.T) {
+	tests := map[string]struct {
+		folderID string
+		setErr   map[model.ListID]error
+		code     int
+		outBody  string
+		writes   []string
+	}{
+		"takes-lists-out-of-folder": {
+			folderID: "home",
+			code:     http.StatusOK,
+			outBody:  "{\"FolderID\":\"home\",\"ListIDs\":[\"chores\",\"groceries\"]}\n",
+			// The lists are taken out of the folder before it is deleted.
+			writes: []string{"unfile chores", "unfile groceries", "delete home"},
+		},
+		"list-trashed-in-the-meantime": {
+			folderID: "home",
+			setErr:   map[model.ListID]error{"chores": database.ListNotFoundError{}},
+			code:     http.StatusOK,
+			outBody:  "{\"FolderID\":\"home\",\"ListIDs\":[\"groceries\"]}\n",
+			writes:   []string{"unfile chores", "unfile groceries", "delete home"},
+		},
+		"list-cannot-be-taken-out": {
+			folderID: "home",
+			setErr:   map[model.ListID]error{"groceries": errors.New("boom")},
+			code:     http.StatusInternalServerError,
+			outBody:  "{\"Code\":\"InternalServerError\"}\n",
+			// The folder is kept so that no list is left in a folder that does not exist.
+			writes: []string{"unfile chores", "unfile groceries"},
+		},
+		"folder-does-not-exist": {
+			folderID: "work",
+			code:     http.StatusNotFound,
+			outBody:  "{\"Code\":\"FolderDoesNotExist\",\"Message\":\"Folder does not exist\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var writes []string
+			ydb := mockYdb{
+				MockGetFolder: func(id model.UserID, fid model.FolderID) (model.YataFolder, error) {
+					if fid != "home" {
+						return model.YataFolder{}, database.FolderNotFoundError{}
+					}
+					return model.YataFolder{UserID: id, FolderID: fid, Title: "Home"}, nil
+				},
+				MockGetFolderLists: func(id model.UserID, fid model.FolderID) ([]model.YataList, error) {
+					return []model.YataList{
+						{UserID: id, ListID: "chores", FolderID: fid},
+						{UserID: id, ListID: "groceries", FolderID: fid},
+					}, nil
+				},
+				MockSetListFolder: func(id model.UserID, lid model.ListID, fid model.FolderID) error {
+					assert.Equal(t, model.FolderID(""), fid)
+					writes = append(writes, "unfile "+string(lid))
+					return test.setErr[lid]
+				},
+				MockDeleteFolder: func(id model.UserID, fid model.FolderID) error {
+					writes = append(writes, "delete "+string(fid))
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "https://does.not/folders/"+test.folderID, nil)
+			req = mux.SetURLVars(req, map[string]string{"folderID": test.folderID})
+
+			srvr := Server{Ydb: ydb}
+			srvr.DeleteFolder(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.writes, writes)
+		})
+	}
+}
+
+func TestServer_SetListFolder(t *testing.T) {
+	tests := map[string]struct {
+		folderID model.FolderID
+		body     string
+		owner    string
+		setErr   error
+		code     int
+		outBody  string
+		set      []model.FolderID
+	}{
+		"into-folder": {
+			body:    `{"FolderID":"home"}`,
+			code:    http.StatusOK,
+			outBody: "{\"ListID\":\"groceries\",\"FolderID\":\"home\"}\n",
+			set:     []model.FolderID{"home"},
+		},
+		"out-of-folder": {
+			folderID: "home",
+			body:     `{}`,
+			code:     http.StatusOK,
+			outBody:  "{\"ListID\":\"groceries\"}\n",
+			set:      []model.FolderID{""},
+		},
+		"already-in-folder": {
+			folderID: "home",
+			body:     `{"FolderID":"home"}`,
+			code:     http.StatusOK,
+			outBody:  "{\"ListID\":\"groceries\",\"FolderID\":\"home\"}\n",
+		},
+		"folder-does-not-exist": {
+			body:    `{"FolderID":"work"}`,
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"FolderDoesNotExist\",\"Message\":\"Folder does not exist\"}\n",
+		},
+		"invalid": {
+			body:    `{"FolderID":" home"}`,
+			code:    http.StatusBadRequest,
+			outBody: "{\"Code\":\"BadRequest\",\"Message\":\"FolderID cannot be prefixed or suffixed with spaces\"}\n",
+		},
+		"not-owner": {
+			body:    `{"FolderID":"home"}`,
+			owner:   "owner",
+			code:    http.StatusForbidden,
+			outBody: "{\"Code\":\"Forbidden\",\"Message\":\"You do not have permission to do that to this list\"}\n",
+		},
+		"trashed-in-the-meantime": {
+			body:    `{"FolderID":"home"}`,
+			setErr:  database.ListNotFoundError{},
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"ListDoesNotExist\",\"Message\":\"List does not exist\"}\n",
+			set:     []model.FolderID{"home"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var set []model.FolderID
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid, FolderID: test.folderID}, nil
+				},
+				MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+					return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleEditor}, nil
+				},
+				MockGetFolder: func(id model.UserID, fid model.FolderID) (model.YataFolder, error) {
+					if fid != "home" {
+						return model.YataFolder{}, database.FolderNotFoundError{}
+					}
+					return model.YataFolder{UserID: id, FolderID: fid, Title: "Home"}, nil
+				},
+				MockSetListFolder: func(id model.UserID, lid model.ListID, fid model.FolderID) error {
+					set = append(set, fid)
+					return test.setErr
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			target := "https://does.not/lists/groceries/folder"
+			if len(test.owner) != 0 {
+				target += "?owner=" + test.owner
+			}
+			req := httptest.NewRequest(http.MethodPut, target, bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+
+			srvr := Server{Ydb: ydb}
+			srvr.SetListFolder(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.set, set)
+		})
+	}
+}
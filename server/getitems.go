@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 
+	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/model"
 	"github.com/TheYeung1/yata-server/server/request"
 	"github.com/gorilla/mux"
@@ -12,6 +13,9 @@ type GetAllItemsOutput struct {
 	Items []model.YataItem
 }
 
+// GetAllItems returns every item on the caller's own lists.
+// When the "assignee" query parameter is given, either as a user ID or as "me", it instead returns the items assigned to
+// that user on every list the caller can see.
 func (s *Server) GetAllItems(w http.ResponseWriter, r *http.Request) {
 	log := request.Logger(r.Context())
 	uid, ok := request.UserID(r.Context())
@@ -22,19 +26,69 @@ func (s *Server) GetAllItems(w http.ResponseWriter, r *http.Request) {
 	}
 	log.WithField("userID", uid).Debug("get all items called")
 
+	sortKeys, err := parseItemSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	include, err := parseInclude(r.URL.Query().Get("include"), "notes")
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	assignee := model.UserID(r.URL.Query().Get("assignee"))
+	if assignee == "me" {
+		assignee = uid
+	}
+
 	items, err := s.Ydb.GetAllItems(uid)
 	if err != nil {
 		log.WithError(err).Error("failed to get all items")
 		renderInternalServerError(w, r)
 		return
 	}
+	if len(assignee) != 0 {
+		// Items can be assigned on any list the caller can see, not just the caller's own lists.
+		memberships, err := s.Ydb.GetMemberships(uid)
+		if err != nil {
+			log.WithError(err).Error("failed to get memberships")
+			renderInternalServerError(w, r)
+			return
+		}
+		for _, m := range memberships {
+			shared, err := s.Ydb.GetListItems(m.OwnerID, m.ListID)
+			if err != nil {
+				log.WithError(err).WithField("membership", m).Error("failed to get shared list items")
+				renderInternalServerError(w, r)
+				return
+			}
+			items = append(items, shared...)
+		}
+	}
+	rollUpCompletion(items)
+	if len(assignee) != 0 {
+		items = filterItems(items, func(item model.YataItem) bool {
+			return item.AssigneeID == assignee
+		})
+	}
+	sortItems(items, sortKeys)
+	if !include["notes"] {
+		stripNotes(items)
+	}
 
 	out := GetAllItemsOutput{Items: items}
 	log.WithField("output", out).Debug("items retrieved")
-	renderJSON(w, r, http.StatusOK, out)
+	render(w, r, http.StatusOK, out)
 }
 
 type GetListItemsOutput struct {
+	// Sections are the list's sections in the order of their positions.
+	Sections []model.YataSection
+	// Items are grouped by section, in the order of the sections, with items that are not in a section first.
+	// Within each group they are sorted by the "sort" query parameter.
 	Items []model.YataItem
 }
 
@@ -55,14 +109,216 @@ func (s *Server) GetListItems(w http.ResponseWriter, r *http.Request) {
 		renderBadRequest(w, r, err.Error())
 		return
 	}
+	sortKeys, err := parseItemSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	include, err := parseInclude(r.URL.Query().Get("include"), "notes")
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	contentType, ok := negotiate(r, listItemsMediaTypes...)
+	if !ok {
+		renderNotAcceptable(w, r, "Items", listItemsMediaTypes...)
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
 
-	items, err := s.Ydb.GetListItems(uid, listID)
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
 	if err != nil {
 		log.WithError(err).Error("failed to get list items")
 		renderInternalServerError(w, r)
+		return
+	}
+	sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortSections(sections)
+
+	rollUpCompletion(items)
+	sortItems(items, sortKeys)
+	groupItemsBySection(items, sections)
+	if !include["notes"] {
+		stripNotes(items)
 	}
 
-	out := GetListItemsOutput{Items: items}
+	if contentType == mediaTypeTodoTxt {
+		log.WithField("items", len(items)).Debug("list items retrieved as todo.txt")
+		renderTodoTxt(w, r, http.StatusOK, items)
+		return
+	}
+
+	out := GetListItemsOutput{Sections: sections, Items: items}
 	log.WithField("output", out).Debug("list items retrieved")
-	renderJSON(w, r, http.StatusOK, out)
+	render(w, r, http.StatusOK, out)
+}
+
+type GetListItemChildrenOutput struct {
+	Items []model.YataItem
+}
+
+func (s *Server) GetListItemChildren(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list item children called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	sortKeys, err := parseItemSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	include, err := parseInclude(r.URL.Query().Get("include"), "notes")
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Ydb.GetItem(yl.UserID, listID, itemID); err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	rollUpCompletion(items)
+
+	children := filterItems(items, func(item model.YataItem) bool {
+		return item.ParentID == itemID
+	})
+	sortItems(children, sortKeys)
+	if !include["notes"] {
+		stripNotes(children)
+	}
+
+	out := GetListItemChildrenOutput{Items: children}
+	log.WithField("output", out).Debug("list item children retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type GetListItemOutput struct {
+	Item model.YataItem
+}
+
+func (s *Server) GetListItem(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list item called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	item, err := s.Ydb.GetItem(yl.UserID, listID, itemID)
+	if err != nil {
+		if errnf, ok := err.(database.ItemNotFoundError); ok {
+			log.WithError(errnf).Info("item not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get item")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	rollUpCompletion(items)
+	for _, i := range items {
+		if i.ItemID == itemID {
+			item.Completed = i.Completed
+		}
+	}
+
+	out := GetListItemOutput{Item: item}
+	log.WithField("output", out).Debug("list item retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+// stripNotes removes the notes from every item.
+// Notes can be large, so they are left out of responses containing many items unless asked for.
+func stripNotes(items []model.YataItem) {
+	for i := range items {
+		items[i].Notes = ""
+	}
+}
+
+// filterItems returns the items for which keep returns true.
+func filterItems(items []model.YataItem, keep func(model.YataItem) bool) []model.YataItem {
+	kept := []model.YataItem{}
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
 }
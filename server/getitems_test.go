@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_GetAllItems_Assignee(t *testing.T) {
+	ydb := mockYdb{
+		MockGetAllItems: func(id model.UserID) ([]model.YataItem, error) {
+			assert.Equal(t, model.UserID("me"), id)
+			return []model.YataItem{
+				{UserID: "me", ListID: "mine", ItemID: "mine-to-me", AssigneeID: "me"},
+				{UserID: "me", ListID: "mine", ItemID: "mine-to-them", AssigneeID: "them"},
+				{UserID: "me", ListID: "mine", ItemID: "mine-unassigned"},
+			}, nil
+		},
+		MockGetMemberships: func(member model.UserID) ([]model.YataListMember, error) {
+			assert.Equal(t, model.UserID("me"), member)
+			return []model.YataListMember{{OwnerID: "them", ListID: "theirs", UserID: "me", Role: model.RoleEditor}}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			assert.Equal(t, model.UserID("them"), id)
+			assert.Equal(t, model.ListID("theirs"), lid)
+			return []model.YataItem{
+				{UserID: "them", ListID: "theirs", ItemID: "theirs-to-me", AssigneeID: "me"},
+				{UserID: "them", ListID: "theirs", ItemID: "theirs-to-them", AssigneeID: "them"},
+			}, nil
+		},
+	}
+
+	tests := map[string]struct {
+		query string
+		items []model.ItemID
+	}{
+		"no-assignee-returns-own-items": {
+			query: "",
+			items: []model.ItemID{"mine-to-me", "mine-to-them", "mine-unassigned"},
+		},
+		"assigned-to-me": {
+			query: "?assignee=me",
+			items: []model.ItemID{"mine-to-me", "theirs-to-me"},
+		},
+		"assigned-to-user": {
+			query: "?assignee=them",
+			items: []model.ItemID{"mine-to-them", "theirs-to-them"},
+		},
+		"assigned-to-nobody-known": {
+			query: "?assignee=stranger",
+			items: nil,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter"+test.query, nil)
+
+			srvr := Server{Ydb: ydb}
+			srvr.GetAllItems(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var out GetAllItemsOutput
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+			var items []model.ItemID
+			for _, item := range out.Items {
+				items = append(items, item.ItemID)
+			}
+			assert.Equal(t, test.items, items)
+		})
+	}
+}
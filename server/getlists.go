@@ -1,7 +1,10 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/model"
@@ -11,8 +14,12 @@ import (
 
 type GetListOutput struct {
 	List model.YataList
+	// Role is what the caller is allowed to do with the list.
+	Role model.Role
 }
 
+// GetList returns a list and the caller's role on it, or the list and its items as a Markdown checklist when the
+// request accepts text/markdown.
 func (s *Server) GetList(w http.ResponseWriter, r *http.Request) {
 	log := request.Logger(r.Context())
 	uid, ok := request.UserID(r.Context())
@@ -31,24 +38,47 @@ func (s *Server) GetList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	yl, err := s.Ydb.GetList(uid, listID)
-	if err != nil {
-		if errnf, ok := err.(database.ListNotFoundError); ok {
-			log.WithError(errnf).Info("list not found")
-			renderJSON(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+	contentType, ok := negotiate(r, listMediaTypes...)
+	if !ok {
+		renderNotAcceptable(w, r, "Lists", listMediaTypes...)
+		return
+	}
+
+	yl, role, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if contentType == mediaTypeMarkdown {
+		items, err := s.Ydb.GetListItems(yl.UserID, listID)
+		if err != nil {
+			log.WithError(err).Error("failed to get list items")
+			renderInternalServerError(w, r)
 			return
 		}
-		log.WithError(err).Error("failed to get list")
-		renderInternalServerError(w, r)
+		sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+		if err != nil {
+			log.WithError(err).Error("failed to get list sections")
+			renderInternalServerError(w, r)
+			return
+		}
+		sortSections(sections)
+		rollUpCompletion(items)
+		groupItemsBySection(items, sections)
+		log.WithField("items", len(items)).Debug("list retrieved as markdown")
+		renderMarkdown(w, r, http.StatusOK, yl, sections, items)
 		return
 	}
 
-	out := GetListOutput{List: yl}
+	out := GetListOutput{List: yl, Role: role}
 	log.WithField("output", out).Debug("list retrieved")
-	renderJSON(w, r, http.StatusOK, out)
+	render(w, r, http.StatusOK, out)
 }
 
 type GetListsOutput struct {
+	// Lists are the caller's own lists followed by the lists shared with the caller, each in the order of their positions.
+	// Only archived lists are returned when the "archived" query parameter is true; otherwise archived lists are left out.
+	// Likewise only templates are returned when the "template" query parameter is true.
 	Lists []model.YataList
 }
 
@@ -62,13 +92,97 @@ func (s *Server) GetLists(w http.ResponseWriter, r *http.Request) {
 	}
 	log.WithField("userID", uid).Debug("get lists called")
 
+	archived, template, err := parseListFilters(r)
+	if err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
 	yl, err := s.Ydb.GetLists(uid)
 	if err != nil {
 		log.WithError(err).Error("failed to get lists")
 		renderInternalServerError(w, r)
+		return
+	}
+
+	memberships, err := s.Ydb.GetMemberships(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get memberships")
+		renderInternalServerError(w, r)
+		return
+	}
+	for _, m := range memberships {
+		shared, err := s.Ydb.GetList(m.OwnerID, m.ListID)
+		if err != nil {
+			if _, ok := err.(database.ListNotFoundError); ok {
+				// The membership outlived its list.
+				continue
+			}
+			log.WithError(err).WithField("membership", m).Error("failed to get shared list")
+			renderInternalServerError(w, r)
+			return
+		}
+		yl = append(yl, shared)
 	}
 
-	out := GetListsOutput{Lists: yl}
+	lists := filterLists(yl, archived, template)
+	sortLists(lists, uid)
+
+	out := GetListsOutput{Lists: lists}
 	log.WithField("output", out).Debug("lists retrieved")
-	renderJSON(w, r, http.StatusOK, out)
+	render(w, r, http.StatusOK, out)
+}
+
+// sortLists orders lists so that the user's own lists come before lists shared with them, and then by position.
+// Lists with the same position are ordered by ID so that the order is stable.
+func sortLists(lists []model.YataList, uid model.UserID) {
+	sort.SliceStable(lists, func(i, j int) bool {
+		if own := lists[i].UserID == uid; own != (lists[j].UserID == uid) {
+			return own
+		}
+		if lists[i].Position != lists[j].Position {
+			return lists[i].Position < lists[j].Position
+		}
+		if lists[i].UserID != lists[j].UserID {
+			return lists[i].UserID < lists[j].UserID
+		}
+		return lists[i].ListID < lists[j].ListID
+	})
+}
+
+// parseListFilters parses the "archived" and "template" query parameters. Archived lists and templates are left out
+// unless their parameter is true.
+func parseListFilters(r *http.Request) (archived bool, template bool, err error) {
+	q := r.URL.Query()
+	if archived, err = parseBoolParam("archived", q.Get("archived")); err != nil {
+		return false, false, err
+	}
+	if template, err = parseBoolParam("template", q.Get("template")); err != nil {
+		return false, false, err
+	}
+	return archived, template, nil
+}
+
+// parseBoolParam parses an optional boolean query parameter, which is false if it is empty.
+func parseBoolParam(name string, param string) (bool, error) {
+	if len(param) == 0 {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, fmt.Errorf("%s must be either true or false", name)
+	}
+	return b, nil
+}
+
+// filterLists returns only the lists whose archived and template states match archived and template.
+func filterLists(lists []model.YataList, archived bool, template bool) []model.YataList {
+	filtered := []model.YataList{}
+	for _, yl := range lists {
+		if yl.Archived == archived && yl.Template == template {
+			filtered = append(filtered, yl)
+		}
+	}
+	return filtered
 }
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_GetLists_Archived(t *testing.T) {
+	ydb := mockYdb{
+		MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+			return []model.YataList{
+				{UserID: "me", ListID: "active"},
+				{UserID: "me", ListID: "archived", Archived: true},
+				{UserID: "me", ListID: "template", Template: true},
+			}, nil
+		},
+		MockGetMemberships: func(member model.UserID) ([]model.YataListMember, error) {
+			return []model.YataListMember{{OwnerID: "them", ListID: "shared-archived", UserID: "me", Role: model.RoleViewer}}, nil
+		},
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid, Archived: true}, nil
+		},
+	}
+
+	tests := map[string]struct {
+		query string
+		code  int
+		lists []model.ListID
+	}{
+		"excludes-archived-by-default": {
+			query: "",
+			code:  http.StatusOK,
+			lists: []model.ListID{"active"},
+		},
+		"archived-false": {
+			query: "?archived=false",
+			code:  http.StatusOK,
+			lists: []model.ListID{"active"},
+		},
+		"archived-true": {
+			query: "?archived=true",
+			code:  http.StatusOK,
+			lists: []model.ListID{"archived", "shared-archived"},
+		},
+		"archived-invalid": {
+			query: "?archived=maybe",
+			code:  http.StatusBadRequest,
+		},
+		"template-true": {
+			query: "?template=true",
+			code:  http.StatusOK,
+			lists: []model.ListID{"template"},
+		},
+		"template-invalid": {
+			query: "?template=maybe",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter"+test.query, nil)
+
+			srvr := Server{Ydb: ydb}
+			srvr.GetLists(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			if test.code != http.StatusOK {
+				return
+			}
+			var out GetListsOutput
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+			var lists []model.ListID
+			for _, l := range out.Lists {
+				lists = append(lists, l.ListID)
+			}
+			assert.Equal(t, test.lists, lists)
+		})
+	}
+}
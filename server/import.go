@@ -0,0 +1,569 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/importer"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+)
+
+const (
+	// maxImportSize is the largest import that can be uploaded in bytes.
+	maxImportSize = 10 << 20
+	// importChunkSize is how many items an import writes at a time. The job's progress is updated after each chunk.
+	importChunkSize = 100
+)
+
+// ImportAction is what an import does with one of its lists.
+type ImportAction string
+
+const (
+	// ImportCreate creates a list that does not exist yet.
+	ImportCreate ImportAction = "create"
+	// ImportSkip leaves a list that already exists as it is and does not import it.
+	ImportSkip ImportAction = "skip"
+	// ImportRename imports a list that already exists as a new list with a different ID.
+	ImportRename ImportAction = "rename"
+	// ImportOverwrite replaces a list that already exists, moving its items that are not in the import to the trash.
+	ImportOverwrite ImportAction = "overwrite"
+)
+
+// ImportInput is read from the query string; the import itself is the request's body.
+type ImportInput struct {
+	// Format is the format of the import. It defaults to yata's own export format.
+	Format importer.Format
+	// Title is the title of the list for formats that do not give it one.
+	Title string
+	// Conflict is what to do with lists whose IDs are already used by the caller's lists; one of ImportSkip,
+	// ImportRename or ImportOverwrite. It defaults to ImportSkip.
+	Conflict ImportAction
+	// DryRun returns what the import would do without changing anything.
+	DryRun bool
+}
+
+func parseImportInput(r *http.Request) (ImportInput, error) {
+	q := r.URL.Query()
+	input := ImportInput{
+		Format:   importer.Format(q.Get("format")),
+		Title:    q.Get("title"),
+		Conflict: ImportAction(q.Get("conflict")),
+	}
+	if len(input.Format) == 0 {
+		input.Format = importer.FormatYata
+	}
+	if len(input.Conflict) == 0 {
+		input.Conflict = ImportSkip
+	}
+	var err error
+	input.DryRun, err = parseBoolParam("dryRun", q.Get("dryRun"))
+	return input, err
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *ImportInput) Validate() error {
+	ok := false
+	formats := make([]string, len(importer.Formats))
+	for i, f := range importer.Formats {
+		ok = ok || input.Format == f
+		formats[i] = strconv.Quote(string(f))
+	}
+	if !ok {
+		return fmt.Errorf("format must be one of %s", strings.Join(formats, ", "))
+	}
+	if len(input.Title) > 100 {
+		return errors.New("title length cannot exceed 100 characters")
+	}
+	if len(input.Title) != len(strings.TrimSpace(input.Title)) {
+		return errors.New("title cannot be prefixed or suffixed with spaces")
+	}
+	switch input.Conflict {
+	case ImportSkip, ImportRename, ImportOverwrite:
+	default:
+		return fmt.Errorf("conflict must be one of %q, %q or %q", ImportSkip, ImportRename, ImportOverwrite)
+	}
+	return nil
+}
+
+// ImportedList is what an import does with one of its lists.
+type ImportedList struct {
+	// SourceListID is the list's ID in the import and ListID is the ID it is imported as.
+	SourceListID string
+	ListID       string
+	Title        string
+	Action       ImportAction
+	// Sections and Items are how many of the list's sections and items are imported.
+	Sections int
+	Items    int
+	// Errors are why the list, or some of its sections and items, cannot be imported.
+	Errors []JobError `json:",omitempty"`
+}
+
+// ImportSummary is what an import does.
+type ImportSummary struct {
+	Format importer.Format
+	// Folders is how many folders are created. Folders that already exist are left as they are.
+	Folders int
+	Lists   []ImportedList
+	// Errors are why some of the import's folders cannot be imported.
+	Errors []JobError `json:",omitempty"`
+}
+
+type ImportOutput struct {
+	// Summary is what a dry run would do.
+	Summary *ImportSummary `json:",omitempty"`
+	// Job is the import's progress. Once it has succeeded its Result is the import's summary.
+	Job *JobStatus `json:",omitempty"`
+}
+
+// Import imports folders, lists, sections and items for the caller from yata's export format or another to-do app's.
+// Unless it is a dry run the import carries on in the background, and its progress can be followed with GetJob.
+func (s *Server) Import(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("import called")
+
+	input, err := parseImportInput(r)
+	if err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	body := &limitedReader{r: r.Body, n: maxImportSize}
+	a, err := importer.Parse(input.Format, body, importer.Options{Title: input.Title})
+	if err != nil {
+		if body.exceeded {
+			log.Info("import too large")
+			render(w, r, http.StatusRequestEntityTooLarge, responseError{Code: "ImportTooLarge", Message: fmt.Sprintf("Imports cannot exceed %d bytes", maxImportSize)})
+			return
+		}
+		log.WithError(err).Info("failed to parse import")
+		renderBadRequest(w, r, fmt.Sprintf("malformed %s import: %v", input.Format, err))
+		return
+	}
+
+	lists, err := s.Ydb.GetLists(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get lists")
+		renderInternalServerError(w, r)
+		return
+	}
+	folders, err := s.Ydb.GetFolders(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get folders")
+		renderInternalServerError(w, r)
+		return
+	}
+	plan := planImport(uid, input, a, lists, folders, time.Now().UTC())
+
+	if input.DryRun {
+		out := ImportOutput{Summary: &plan.summary}
+		log.WithField("output", out).Debug("import planned")
+		render(w, r, http.StatusOK, out)
+		return
+	}
+
+	// The job outlives the request, so it only keeps who the caller is.
+	actor := requestActor(r)
+	j, err := s.jobs.start(uid, "import", func(j *job) {
+		err := s.runImport(actor, j, plan)
+		if err != nil {
+			log.WithError(err).Error("import failed")
+		}
+		j.finish(plan.summary, err)
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to start job")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	status := j.Status()
+	out := ImportOutput{Job: &status}
+	log.WithField("output", out).Debug("import started")
+	render(w, r, http.StatusAccepted, out)
+}
+
+// importPlan is everything an import writes.
+type importPlan struct {
+	summary ImportSummary
+	folders []model.YataFolder
+	lists   []listImport
+}
+
+// listImport is a list to import. Lists that are skipped are left out.
+type listImport struct {
+	list     model.YataList
+	sections []model.YataSection
+	items    []model.YataItem
+	// source is the list's ID in the import.
+	source model.ListID
+	// existing is the list that is overwritten, if any.
+	existing *model.YataList
+}
+
+// planImport works out what importing a does for uid, whose lists and folders are lists and folders. Entries that do
+// not pass validation are left out of the plan and reported in its summary. Items without a creation time are given
+// now.
+func planImport(uid model.UserID, input ImportInput, a archive.Archive, lists []model.YataList, folders []model.YataFolder, now time.Time) importPlan {
+	plan := importPlan{summary: ImportSummary{Format: input.Format, Lists: []ImportedList{}}}
+
+	hasFolder := map[model.FolderID]bool{}
+	for _, f := range folders {
+		hasFolder[f.FolderID] = true
+	}
+	for _, f := range a.Folders {
+		fin := InsertFolderInput{FolderID: f.FolderID, Title: f.Title, Position: f.Position}
+		if err := fin.Validate(); err != nil {
+			plan.summary.Errors = append(plan.summary.Errors, JobError{ID: f.FolderID, responseError: responseError{Code: "BadRequest", Message: err.Error()}})
+			continue
+		}
+		if hasFolder[model.FolderID(f.FolderID)] {
+			continue
+		}
+		hasFolder[model.FolderID(f.FolderID)] = true
+		plan.folders = append(plan.folders, archive.ToFolder(uid, f))
+	}
+	plan.summary.Folders = len(plan.folders)
+
+	existing := map[model.ListID]model.YataList{}
+	taken := map[model.ListID]bool{}
+	for _, yl := range lists {
+		existing[yl.ListID] = yl
+		taken[yl.ListID] = true
+	}
+	imported := map[model.ListID]bool{}
+	for _, l := range a.Lists {
+		result := ImportedList{SourceListID: l.ListID, ListID: l.ListID, Title: l.Title, Action: ImportCreate}
+		fail := func(msg string) {
+			result.Action = ImportSkip
+			result.ListID = ""
+			result.Errors = []JobError{{ID: l.ListID, responseError: responseError{Code: "BadRequest", Message: msg}}}
+			plan.summary.Lists = append(plan.summary.Lists, result)
+		}
+
+		lin := InsertListInput{ListID: l.ListID, Title: l.Title, Description: l.Description, Color: l.Color, Icon: l.Icon, Position: l.Position}
+		if err := lin.Validate(); err != nil {
+			fail(err.Error())
+			continue
+		}
+		if imported[model.ListID(l.ListID)] {
+			fail(fmt.Sprintf("ListID %q cannot be imported more than once", l.ListID))
+			continue
+		}
+		imported[model.ListID(l.ListID)] = true
+
+		li := listImport{source: model.ListID(l.ListID)}
+		li.list, li.sections, li.items = archive.ToList(uid, l)
+		if !hasFolder[li.list.FolderID] {
+			li.list.FolderID = ""
+		}
+		if taken[li.list.ListID] {
+			result.Action = input.Conflict
+			switch input.Conflict {
+			case ImportSkip:
+				result.ListID = ""
+				plan.summary.Lists = append(plan.summary.Lists, result)
+				continue
+			case ImportRename:
+				li.list.ListID = renameListID(li.list.ListID, taken)
+				result.ListID = string(li.list.ListID)
+			case ImportOverwrite:
+				if yl, ok := existing[li.list.ListID]; ok {
+					li.existing = &yl
+				}
+			}
+		}
+		taken[li.list.ListID] = true
+
+		var errs []JobError
+		li.sections, li.items, errs = validateImportedEntries(uid, li.list, li.sections, li.items, now)
+		for _, e := range errs {
+			e.ID = importEntryID(li.source, e.ID)
+			result.Errors = append(result.Errors, e)
+		}
+		result.Sections = len(li.sections)
+		result.Items = len(li.items)
+		plan.summary.Lists = append(plan.summary.Lists, result)
+		plan.lists = append(plan.lists, li)
+	}
+	return plan
+}
+
+// renameListID returns the first ID of the form "lid-2", "lid-3" and so on that is not taken.
+func renameListID(lid model.ListID, taken map[model.ListID]bool) model.ListID {
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		base := string(lid)
+		if len(base)+len(suffix) > 100 {
+			base = base[:100-len(suffix)]
+		}
+		if id := model.ListID(base + suffix); !taken[id] {
+			return id
+		}
+	}
+}
+
+// validateImportedEntries moves the sections and items of an imported list onto yl and returns those that pass
+// validation, along with errors for those that do not. Sub-tasks whose parent is not imported are not imported either.
+func validateImportedEntries(uid model.UserID, yl model.YataList, sections []model.YataSection, items []model.YataItem, now time.Time) ([]model.YataSection, []model.YataItem, []JobError) {
+	var errs []JobError
+	fail := func(id, msg string) {
+		errs = append(errs, JobError{ID: id, responseError: responseError{Code: "BadRequest", Message: msg}})
+	}
+
+	validSections := []model.YataSection{}
+	hasSection := map[model.SectionID]bool{}
+	for _, section := range sections {
+		sin := InsertListSectionInput{SectionID: string(section.SectionID), Title: section.Title, Position: section.Position}
+		if err := sin.Validate(); err != nil {
+			fail(string(section.SectionID), err.Error())
+			continue
+		}
+		if hasSection[section.SectionID] {
+			fail(string(section.SectionID), fmt.Sprintf("SectionID %q cannot be imported more than once", section.SectionID))
+			continue
+		}
+		hasSection[section.SectionID] = true
+		section.ListID = yl.ListID
+		validSections = append(validSections, section)
+	}
+
+	var validItems []model.YataItem
+	seen := map[model.ItemID]bool{}
+	for _, item := range items {
+		in := InsertListItemInput{
+			ItemID:     string(item.ItemID),
+			ParentID:   string(item.ParentID),
+			SectionID:  string(item.SectionID),
+			Content:    item.Content,
+			Notes:      item.Notes,
+			Completed:  item.Completed,
+			Priority:   item.Priority,
+			DueAt:      item.DueAt,
+			AssigneeID: string(item.AssigneeID),
+			Tags:       item.Tags,
+		}
+		if err := in.Validate(); err != nil {
+			fail(in.ItemID, err.Error())
+			continue
+		}
+		if seen[item.ItemID] {
+			fail(in.ItemID, fmt.Sprintf("ItemID %q cannot be imported more than once", item.ItemID))
+			continue
+		}
+		seen[item.ItemID] = true
+		if len(item.ParentID) == 0 && len(item.SectionID) != 0 && !hasSection[item.SectionID] {
+			fail(in.ItemID, "SectionID must be the ID of one of the list's sections")
+			continue
+		}
+		item.ListID = yl.ListID
+		// Assignments are only kept for the caller, who is the only member a new list is sure to have.
+		if item.AssigneeID != uid {
+			item.AssigneeID = ""
+		}
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = now
+		}
+		validItems = append(validItems, item)
+	}
+
+	// Sub-tasks are kept once their parent is, as long as they are not nested too deeply, and are put in their
+	// parent's section. Keep going until no more are kept; those that are left have a parent that is not imported.
+	kept := map[model.ItemID]int{}
+	depth := map[model.ItemID]int{}
+	for changed := true; changed; {
+		changed = false
+		for i, item := range validItems {
+			if _, ok := kept[item.ItemID]; ok {
+				continue
+			}
+			if len(item.ParentID) == 0 {
+				kept[item.ItemID] = i
+				changed = true
+				continue
+			}
+			p, ok := kept[item.ParentID]
+			if !ok || depth[item.ParentID] >= maxItemNesting {
+				continue
+			}
+			kept[item.ItemID] = i
+			depth[item.ItemID] = depth[item.ParentID] + 1
+			validItems[i].SectionID = validItems[p].SectionID
+			changed = true
+		}
+	}
+	nested := make([]model.YataItem, 0, len(kept))
+	for _, item := range validItems {
+		if _, ok := kept[item.ItemID]; ok {
+			nested = append(nested, item)
+			continue
+		}
+		if p, ok := kept[item.ParentID]; ok && depth[validItems[p].ItemID] >= maxItemNesting {
+			fail(string(item.ItemID), errItemNestedTooDeep.Error())
+			continue
+		}
+		fail(string(item.ItemID), fmt.Sprintf("parent item %q is not imported", item.ParentID))
+	}
+	return validSections, nested, errs
+}
+
+// runImport writes the plan, updating the job's progress as it goes. The returned error is for failures that stop
+// the import.
+func (s *Server) runImport(actor activityActor, j *job, plan importPlan) error {
+	errs := plan.summary.Errors
+	for _, l := range plan.summary.Lists {
+		errs = append(errs, l.Errors...)
+	}
+	j.addTotal(len(errs))
+	for _, e := range errs {
+		j.fail(e.ID, e.responseError)
+	}
+	for _, li := range plan.lists {
+		j.addTotal(1 + len(li.items))
+	}
+
+	for _, folder := range plan.folders {
+		if err := s.Ydb.InsertFolder(folder); err != nil {
+			return fmt.Errorf("failed to insert folder %q: %v", folder.FolderID, err)
+		}
+	}
+
+	deletedAt := time.Now().UTC()
+	for _, li := range plan.lists {
+		yl := li.list
+		var err error
+		if li.existing != nil {
+			// The list is overwritten in place so that nothing else that refers to it, such as its members and share
+			// links, is lost.
+			err = s.Ydb.UpdateList(yl)
+		} else {
+			err = s.Ydb.InsertList(yl.UserID, yl)
+		}
+		if err != nil {
+			var errResp responseError
+			switch err.(type) {
+			case database.ListExistsError:
+				// The ID is taken by a list created since the import was planned.
+				errResp = responseError{Code: "ListExists", Message: "List already exists"}
+			case database.ListInTrashError:
+				errResp = listInTrashError
+			case database.ListNotFoundError:
+				// The list was deleted or moved to the trash since the import was planned.
+				errResp = responseError{Code: "ListDoesNotExist", Message: "List does not exist"}
+			default:
+				return fmt.Errorf("failed to write list %q: %v", yl.ListID, err)
+			}
+			j.fail(string(li.source), errResp)
+			for _, item := range li.items {
+				j.fail(importEntryID(li.source, string(item.ItemID)), responseError{Code: "ListNotImported", Message: "The item's list was not imported"})
+			}
+			continue
+		}
+		if li.existing != nil {
+			if err := s.clearOverwrittenList(yl, li.sections, li.items, deletedAt); err != nil {
+				return err
+			}
+			s.recordActorActivity(actor, yl, model.ActionUpdate, "list", string(yl.ListID), *li.existing, yl)
+		} else {
+			s.recordActorActivity(actor, yl, model.ActionCreate, "list", string(yl.ListID), nil, yl)
+		}
+
+		for _, section := range li.sections {
+			if err := s.Ydb.InsertSection(section); err != nil {
+				return fmt.Errorf("failed to insert section %q: %v", section.SectionID, err)
+			}
+		}
+		j.succeed(1)
+
+		for start := 0; start < len(li.items); start += importChunkSize {
+			end := start + importChunkSize
+			if end > len(li.items) {
+				end = len(li.items)
+			}
+			chunk := li.items[start:end]
+			failed := 0
+			if err := s.Ydb.InsertItems(chunk); err != nil {
+				errup, ok := err.(database.UnprocessedItemsError)
+				if !ok {
+					return fmt.Errorf("failed to insert items: %v", err)
+				}
+				for _, item := range errup.Items {
+					failed++
+					j.fail(importEntryID(li.source, string(item.ItemID)), responseError{Code: "ItemNotProcessed", Message: "The item could not be written; try again"})
+				}
+			}
+			j.succeed(len(chunk) - failed)
+		}
+	}
+	return nil
+}
+
+// clearOverwrittenList clears what is not being imported from the list yl overwrote. Its items that are not being
+// imported are moved to the trash and its sections that are not being imported are deleted. Its members and share links
+// are kept.
+func (s *Server) clearOverwrittenList(yl model.YataList, sections []model.YataSection, items []model.YataItem, deletedAt time.Time) error {
+	importing := map[model.ItemID]bool{}
+	for _, item := range items {
+		importing[item.ItemID] = true
+	}
+	oldItems, err := s.Ydb.GetListItems(yl.UserID, yl.ListID)
+	if err != nil {
+		return fmt.Errorf("failed to get items of list %q: %v", yl.ListID, err)
+	}
+	for _, item := range oldItems {
+		if importing[item.ItemID] {
+			continue
+		}
+		// Only the item's trash time is written, so that changes made to it since it was read are kept.
+		if err := s.Ydb.TrashItem(yl.UserID, yl.ListID, item.ItemID, deletedAt); err != nil {
+			if _, ok := err.(database.ItemNotFoundError); ok {
+				continue // Someone else deleted it in the meantime.
+			}
+			return fmt.Errorf("failed to trash item %q of list %q: %v", item.ItemID, yl.ListID, err)
+		}
+	}
+
+	importingSection := map[model.SectionID]bool{}
+	for _, section := range sections {
+		importingSection[section.SectionID] = true
+	}
+	oldSections, err := s.Ydb.GetListSections(yl.UserID, yl.ListID)
+	if err != nil {
+		return fmt.Errorf("failed to get sections of list %q: %v", yl.ListID, err)
+	}
+	for _, section := range oldSections {
+		if importingSection[section.SectionID] {
+			continue
+		}
+		if err := s.Ydb.DeleteSection(yl.UserID, yl.ListID, section.SectionID); err != nil {
+			return fmt.Errorf("failed to delete section %q: %v", section.SectionID, err)
+		}
+	}
+	return nil
+}
+
+// importEntryID returns the ID errors use for a section or an item of the list with the ID lid in the import.
+func importEntryID(lid model.ListID, id string) string {
+	return string(lid) + "/" + id
+}
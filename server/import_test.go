@@ -0,0 +1,303 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/importer"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input ImportInput
+		err   error
+	}{
+		"validate-input": {
+			input: ImportInput{Format: importer.FormatTodoist, Title: "Home", Conflict: ImportRename},
+		},
+		"unknown-format": {
+			input: ImportInput{Format: "xml", Conflict: ImportSkip},
+			err:   errors.New(`format must be one of "yata", "todoist", "microsoft-todo", "markdown"`),
+		},
+		"title-with-spaces": {
+			input: ImportInput{Format: importer.FormatMarkdown, Title: " Home", Conflict: ImportSkip},
+			err:   errors.New("title cannot be prefixed or suffixed with spaces"),
+		},
+		"unknown-conflict": {
+			input: ImportInput{Format: importer.FormatYata, Conflict: ImportCreate},
+			err:   errors.New(`conflict must be one of "skip", "rename" or "overwrite"`),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	existing := []model.YataList{{UserID: "me", ListID: "a", Title: "Old A"}, {UserID: "me", ListID: "a-2", Title: "Old A 2"}}
+	a := archive.Archive{
+		Folders: []archive.Folder{{FolderID: "home", Title: "Home"}, {FolderID: "work", Title: "Work"}, {FolderID: "bad", Title: " Bad"}},
+		Lists: []archive.List{
+			{
+				ListID:   "a",
+				Title:    "A",
+				FolderID: "bad",
+				Sections: []archive.Section{{SectionID: "s", Title: "S"}},
+				Items: []archive.Item{
+					{ItemID: "1", Content: "One", SectionID: "s", AssigneeID: "me"},
+					{ItemID: "2", ParentID: "1", Content: "Two", AssigneeID: "someone-else"},
+					{ItemID: "3", ParentID: "2", Content: "Three", CreatedAt: now.Add(-time.Hour)},
+					{ItemID: "4", ParentID: "3", Content: "Four"},
+					{ItemID: "5", ParentID: "4", Content: "Five"},
+					{ItemID: "6", Content: "Six", SectionID: "missing"},
+					{ItemID: "7", Content: ""},
+					{ItemID: "8", ParentID: "7", Content: "Eight"},
+				},
+			},
+			{ListID: "b", Title: "B", FolderID: "work"},
+			{ListID: "b", Title: "B again"},
+			{ListID: "c", Title: ""},
+		},
+	}
+
+	tests := map[string]struct {
+		conflict ImportAction
+		lists    []ImportedList
+	}{
+		"skip": {
+			conflict: ImportSkip,
+			lists: []ImportedList{
+				{SourceListID: "a", Title: "A", Action: ImportSkip},
+				{SourceListID: "b", ListID: "b", Title: "B", Action: ImportCreate},
+				{SourceListID: "b", Title: "B again", Action: ImportSkip, Errors: []JobError{{ID: "b", responseError: responseError{Code: "BadRequest", Message: `ListID "b" cannot be imported more than once`}}}},
+				{SourceListID: "c", Action: ImportSkip, Errors: []JobError{{ID: "c", responseError: responseError{Code: "BadRequest", Message: "Title cannot be empty"}}}},
+			},
+		},
+		"rename": {
+			conflict: ImportRename,
+			lists: []ImportedList{
+				{SourceListID: "a", ListID: "a-3", Title: "A", Action: ImportRename, Sections: 1, Items: 3, Errors: []JobError{
+					{ID: "a/6", responseError: responseError{Code: "BadRequest", Message: "SectionID must be the ID of one of the list's sections"}},
+					{ID: "a/7", responseError: responseError{Code: "BadRequest", Message: "Content cannot be empty"}},
+					{ID: "a/4", responseError: responseError{Code: "BadRequest", Message: errItemNestedTooDeep.Error()}},
+					{ID: "a/5", responseError: responseError{Code: "BadRequest", Message: `parent item "4" is not imported`}},
+					{ID: "a/8", responseError: responseError{Code: "BadRequest", Message: `parent item "7" is not imported`}},
+				}},
+				{SourceListID: "b", ListID: "b", Title: "B", Action: ImportCreate},
+				{SourceListID: "b", Title: "B again", Action: ImportSkip, Errors: []JobError{{ID: "b", responseError: responseError{Code: "BadRequest", Message: `ListID "b" cannot be imported more than once`}}}},
+				{SourceListID: "c", Action: ImportSkip, Errors: []JobError{{ID: "c", responseError: responseError{Code: "BadRequest", Message: "Title cannot be empty"}}}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			input := ImportInput{Format: importer.FormatYata, Conflict: test.conflict}
+			plan := planImport("me", input, a, existing, []model.YataFolder{{UserID: "me", FolderID: "home", Title: "Mine"}}, now)
+
+			assert.Equal(t, importer.FormatYata, plan.summary.Format)
+			assert.Equal(t, 1, plan.summary.Folders, "folders that exist are left as they are")
+			assert.Equal(t, []model.YataFolder{{UserID: "me", FolderID: "work", Title: "Work"}}, plan.folders)
+			assert.Equal(t, []JobError{{ID: "bad", responseError: responseError{Code: "BadRequest", Message: "Title cannot be prefixed or suffixed with spaces"}}}, plan.summary.Errors)
+			assert.Equal(t, test.lists, plan.summary.Lists)
+		})
+	}
+
+	t.Run("entries", func(t *testing.T) {
+		plan := planImport("me", ImportInput{Format: importer.FormatYata, Conflict: ImportOverwrite}, a, existing, nil, now)
+		require.Len(t, plan.lists, 2)
+		li := plan.lists[0]
+		assert.Equal(t, model.YataList{UserID: "me", ListID: "a", Title: "A"}, li.list, "folders that are not imported are dropped")
+		assert.Equal(t, &existing[0], li.existing)
+		assert.Equal(t, []model.YataSection{{UserID: "me", ListID: "a", SectionID: "s", Title: "S"}}, li.sections)
+		assert.Equal(t, []model.YataItem{
+			{UserID: "me", ListID: "a", ItemID: "1", SectionID: "s", Content: "One", AssigneeID: "me", CreatedAt: now},
+			{UserID: "me", ListID: "a", ItemID: "2", ParentID: "1", SectionID: "s", Content: "Two", CreatedAt: now},
+			{UserID: "me", ListID: "a", ItemID: "3", ParentID: "2", SectionID: "s", Content: "Three", CreatedAt: now.Add(-time.Hour)},
+		}, li.items)
+		assert.Equal(t, ImportOverwrite, plan.summary.Lists[0].Action)
+		assert.Nil(t, plan.lists[1].existing)
+	})
+}
+
+func TestServer_Import(t *testing.T) {
+	markdown := "# Groceries\n- [ ] Milk\n  - [x] Oat\n## Bakery\n- [ ] Bread\n"
+
+	t.Run("dry-run", func(t *testing.T) {
+		ydb := mockYdb{
+			MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+				return []model.YataList{{UserID: id, ListID: "groceries", Title: "Groceries"}}, nil
+			},
+			MockGetFolders: func(id model.UserID) ([]model.YataFolder, error) { return nil, nil },
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?format=markdown&conflict=rename&dryRun=true", bytes.NewBufferString(markdown))
+
+		srvr := &Server{Ydb: ydb}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"Summary":{"Format":"markdown","Folders":0,"Lists":[{"SourceListID":"groceries","ListID":"groceries-2","Title":"Groceries","Action":"rename","Sections":1,"Items":3}]}}`, rec.Body.String())
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		var lists []model.YataList
+		var sections []model.YataSection
+		var items []model.YataItem
+		var trashed []model.ItemID
+		var deletedSections []model.SectionID
+		ydb := mockYdb{
+			MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+				return []model.YataList{{UserID: id, ListID: "groceries", Title: "Old"}}, nil
+			},
+			MockGetFolders: func(id model.UserID) ([]model.YataFolder, error) { return nil, nil },
+			MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+				return []model.YataItem{{UserID: id, ListID: lid, ItemID: "1", Content: "Old milk"}, {UserID: id, ListID: lid, ItemID: "old", Content: "Old"}}, nil
+			},
+			MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+				return []model.YataSection{{UserID: id, ListID: lid, SectionID: "bakery"}, {UserID: id, ListID: lid, SectionID: "old"}}, nil
+			},
+			MockDeleteSection: func(id model.UserID, lid model.ListID, sid model.SectionID) error {
+				deletedSections = append(deletedSections, sid)
+				return nil
+			},
+			// The list is updated in place rather than deleted and inserted again.
+			MockUpdateList: func(yl model.YataList) error {
+				lists = append(lists, yl)
+				return nil
+			},
+			MockTrashItem: func(id model.UserID, lid model.ListID, iid model.ItemID, deletedAt time.Time) error {
+				trashed = append(trashed, iid)
+				return nil
+			},
+			MockInsertSection: func(section model.YataSection) error {
+				sections = append(sections, section)
+				return nil
+			},
+			MockInsertItems: func(batch []model.YataItem) error {
+				items = append(items, batch...)
+				return nil
+			},
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?format=markdown&conflict=overwrite", bytes.NewBufferString(markdown))
+
+		srvr := &Server{Ydb: ydb}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var out ImportOutput
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		require.NotNil(t, out.Job)
+		j, ok := srvr.jobs.get("me", out.Job.JobID)
+		require.True(t, ok)
+		<-j.done
+
+		status := j.Status()
+		assert.Equal(t, JobSucceeded, status.State)
+		assert.Equal(t, 4, status.Total)
+		assert.Equal(t, 4, status.Processed)
+		assert.Equal(t, 0, status.Failed)
+		assert.IsType(t, ImportSummary{}, status.Result)
+
+		assert.Equal(t, []model.ItemID{"old"}, trashed)
+		assert.Equal(t, []model.SectionID{"old"}, deletedSections)
+		assert.Equal(t, []model.YataList{{UserID: "me", ListID: "groceries", Title: "Groceries"}}, lists)
+		assert.Equal(t, []model.YataSection{{UserID: "me", ListID: "groceries", SectionID: "bakery", Title: "Bakery"}}, sections)
+		require.Len(t, items, 3)
+		assert.Equal(t, "Milk", items[0].Content)
+		assert.Equal(t, model.ItemID("1"), items[1].ParentID)
+		assert.Equal(t, model.SectionID("bakery"), items[2].SectionID)
+	})
+
+	t.Run("overwritten-list-trashed", func(t *testing.T) {
+		ydb := mockYdb{
+			MockGetLists: func(id model.UserID) ([]model.YataList, error) {
+				return []model.YataList{{UserID: id, ListID: "groceries", Title: "Old"}}, nil
+			},
+			MockGetFolders: func(id model.UserID) ([]model.YataFolder, error) { return nil, nil },
+			// The list is moved to the trash after the import is planned.
+			MockUpdateList: func(yl model.YataList) error { return database.ListNotFoundError{} },
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?format=markdown&conflict=overwrite", bytes.NewBufferString(markdown))
+
+		srvr := &Server{Ydb: ydb}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var out ImportOutput
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		j, ok := srvr.jobs.get("me", out.Job.JobID)
+		require.True(t, ok)
+		<-j.done
+
+		status := j.Status()
+		assert.Equal(t, JobSucceeded, status.State)
+		assert.Equal(t, 4, status.Failed)
+		assert.Equal(t, JobError{ID: "groceries", responseError: responseError{Code: "ListDoesNotExist", Message: "List does not exist"}}, status.Errors[0])
+	})
+
+	t.Run("list-exists", func(t *testing.T) {
+		ydb := mockYdb{
+			MockGetLists:   func(id model.UserID) ([]model.YataList, error) { return nil, nil },
+			MockGetFolders: func(id model.UserID) ([]model.YataFolder, error) { return nil, nil },
+			MockInsertList: func(id model.UserID, yl model.YataList) error { return database.ListExistsError{} },
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?format=markdown", bytes.NewBufferString(markdown))
+
+		srvr := &Server{Ydb: ydb}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var out ImportOutput
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		j, ok := srvr.jobs.get("me", out.Job.JobID)
+		require.True(t, ok)
+		<-j.done
+
+		status := j.Status()
+		assert.Equal(t, JobSucceeded, status.State)
+		assert.Equal(t, 4, status.Failed)
+		assert.Equal(t, JobError{ID: "groceries", responseError: responseError{Code: "ListExists", Message: "List already exists"}}, status.Errors[0])
+		assert.Equal(t, "groceries/1", status.Errors[1].ID)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?format=microsoft-todo", bytes.NewBufferString("{"))
+
+		srvr := &Server{}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"Code":"BadRequest","Message":"malformed microsoft-todo import: unexpected end of JSON input"}`+"\n", rec.Body.String())
+	})
+
+	t.Run("bad-dry-run", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://does.not/matter?dryRun=maybe", nil)
+
+		srvr := &Server{}
+		srvr.Import(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"Code":"BadRequest","Message":"dryRun must be either true or false"}`+"\n", rec.Body.String())
+	})
+}
@@ -2,17 +2,37 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/model"
 	"github.com/TheYeung1/yata-server/server/request"
 	"github.com/gorilla/mux"
 )
 
+// maxNotesLength is the maximum size of an item's notes in bytes.
+const maxNotesLength = 10000
+
+// maxItemTags is the most tags an item can have.
+const maxItemTags = 20
+
 type InsertListItemInput struct {
-	ItemID  string
-	Content string
+	ItemID   string
+	ParentID string
+	// SectionID must be the ID of one of the list's sections. Sub-tasks are always in their parent's section so it can
+	// be left empty for them.
+	SectionID string
+	Content   string
+	Notes     string
+	Completed bool
+	Priority  int
+	DueAt     *time.Time
+	// AssigneeID must be the ID of the list's owner or one of its members.
+	AssigneeID string
+	Tags       []string
 }
 
 // Validate returns an error if the input does not pass validation.
@@ -26,6 +46,21 @@ func (input *InsertListItemInput) Validate() error {
 	if len(input.ItemID) != len(strings.TrimSpace(input.ItemID)) {
 		return errors.New("ItemID cannot be prefixed or suffixed with spaces")
 	}
+	if len(input.ParentID) > 100 {
+		return errors.New("ParentID length cannot exceed 100 characters")
+	}
+	if len(input.ParentID) != len(strings.TrimSpace(input.ParentID)) {
+		return errors.New("ParentID cannot be prefixed or suffixed with spaces")
+	}
+	if input.ParentID == input.ItemID {
+		return errors.New("ParentID cannot be the same as ItemID")
+	}
+	if len(input.SectionID) > 100 {
+		return errors.New("SectionID length cannot exceed 100 characters")
+	}
+	if len(input.SectionID) != len(strings.TrimSpace(input.SectionID)) {
+		return errors.New("SectionID cannot be prefixed or suffixed with spaces")
+	}
 	if len(input.Content) == 0 {
 		return errors.New("Content cannot be empty")
 	}
@@ -35,6 +70,44 @@ func (input *InsertListItemInput) Validate() error {
 	if len(input.Content) != len(strings.TrimSpace(input.Content)) {
 		return errors.New("Content cannot be prefixed or suffixed with spaces")
 	}
+	if len(input.Notes) > maxNotesLength {
+		return fmt.Errorf("Notes length cannot exceed %d characters", maxNotesLength)
+	}
+	if input.Priority < 0 || input.Priority > model.MaxPriority {
+		return fmt.Errorf("Priority must be between 0 and %d", model.MaxPriority)
+	}
+	if len(input.AssigneeID) > 128 {
+		return errors.New("AssigneeID length cannot exceed 128 characters")
+	}
+	if len(input.AssigneeID) != len(strings.TrimSpace(input.AssigneeID)) {
+		return errors.New("AssigneeID cannot be prefixed or suffixed with spaces")
+	}
+	if len(input.Tags) > maxItemTags {
+		return fmt.Errorf("Tags cannot contain more than %d tags", maxItemTags)
+	}
+	seen := map[string]bool{}
+	for _, tag := range input.Tags {
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+		if seen[tag] {
+			return fmt.Errorf("Tags cannot contain %q more than once", tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+func validateTag(tag string) error {
+	if len(tag) == 0 {
+		return errors.New("Tag cannot be empty")
+	}
+	if len(tag) > 50 {
+		return errors.New("Tag length cannot exceed 50 characters")
+	}
+	if len(tag) != len(strings.TrimSpace(tag)) {
+		return errors.New("Tag cannot be prefixed or suffixed with spaces")
+	}
 	return nil
 }
 
@@ -50,11 +123,12 @@ func (s *Server) InsertListItem(w http.ResponseWriter, r *http.Request) {
 		renderInternalServerError(w, r)
 		return
 	}
+	log.WithField("userID", uid).Debug("insert list item called")
 
 	var input InsertListItemInput
-	if err := bindJSON(r.Body, &input); err != nil {
+	if err := bind(r, &input); err != nil {
 		log.WithError(err).Info("failed to bind input")
-		renderBadRequest(w, r, "malformed input")
+		renderBindError(w, r, err)
 		return
 	}
 	log.WithField("input", input).Debug("input bound")
@@ -72,11 +146,78 @@ func (s *Server) InsertListItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	yi := model.YataItem{
-		UserID:  uid,
-		ListID:  model.ListID(v["listID"]),
-		ItemID:  model.ItemID(input.ItemID),
-		Content: input.Content,
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	before, err := s.Ydb.GetItem(yl.UserID, listID, model.ItemID(input.ItemID))
+	exists := true
+	if err != nil {
+		if _, ok := err.(database.ItemNotFoundError); !ok {
+			log.WithError(err).Error("failed to get item")
+			renderInternalServerError(w, r)
+			return
+		}
+		exists = false
+	}
+	if !exists && yl.Archived {
+		log.Info("list is archived")
+		render(w, r, http.StatusConflict, responseError{Code: "ListArchived", Message: "Items cannot be added to an archived list"})
+		return
+	}
+
+	yi := itemFromInput(yl, input, before, exists)
+	if len(yi.AssigneeID) != 0 {
+		isMember, err := s.isListMember(yl, yi.AssigneeID)
+		if err != nil {
+			log.WithError(err).Error("failed to check assignee")
+			renderInternalServerError(w, r)
+			return
+		}
+		if !isMember {
+			log.WithField("assigneeID", yi.AssigneeID).Info("assignee is not a member of the list")
+			renderBadRequest(w, r, "AssigneeID must be the list's owner or one of its members")
+			return
+		}
+	}
+	var items []model.YataItem
+	if len(yi.ParentID) != 0 || (exists && before.SectionID != yi.SectionID) {
+		items, err = s.Ydb.GetListItems(yl.UserID, listID)
+		if err != nil {
+			log.WithError(err).Error("failed to get list items")
+			renderInternalServerError(w, r)
+			return
+		}
+	}
+	if len(yi.ParentID) != 0 {
+		if err := validateItemNesting(items, yi); err != nil {
+			log.WithError(err).Info("failed to validate item nesting")
+			renderBadRequest(w, r, err.Error())
+			return
+		}
+		for _, parent := range items {
+			if parent.ItemID != yi.ParentID {
+				continue
+			}
+			if len(yi.SectionID) != 0 && yi.SectionID != parent.SectionID {
+				log.WithField("sectionID", yi.SectionID).Info("sub-task is not in its parent's section")
+				renderBadRequest(w, r, "SectionID must be the same as the parent's SectionID")
+				return
+			}
+			yi.SectionID = parent.SectionID
+		}
+	} else if len(yi.SectionID) != 0 {
+		if _, err := s.Ydb.GetSection(yl.UserID, listID, yi.SectionID); err != nil {
+			if _, ok := err.(database.SectionNotFoundError); ok {
+				log.WithField("sectionID", yi.SectionID).Info("section not found")
+				renderBadRequest(w, r, "SectionID must be the ID of one of the list's sections")
+				return
+			}
+			log.WithError(err).Error("failed to get section")
+			renderInternalServerError(w, r)
+			return
+		}
 	}
 	log.WithField("item", yi).Debug("inserting item")
 	if err := s.Ydb.InsertItem(yi); err != nil {
@@ -84,8 +225,56 @@ func (s *Server) InsertListItem(w http.ResponseWriter, r *http.Request) {
 		renderInternalServerError(w, r)
 		return
 	}
+	if exists && before.SectionID != yi.SectionID {
+		// Sub-tasks move along with their parent.
+		for _, descendant := range itemDescendants(items, yi.ItemID) {
+			descendant.SectionID = yi.SectionID
+			if err := s.Ydb.InsertItem(descendant); err != nil {
+				log.WithError(err).WithField("itemID", descendant.ItemID).Error("failed to move sub-task to section")
+				renderInternalServerError(w, r)
+				return
+			}
+		}
+	}
+	s.recordItemInserted(r, yl, before, exists, yi)
 
 	out := InsertListItemOutput{ItemID: input.ItemID}
 	log.WithField("output", out).Debug("item inserted")
-	renderJSON(w, r, http.StatusCreated, out)
+	render(w, r, http.StatusCreated, out)
+}
+
+// itemFromInput returns the item described by input on the list yl. before is the item being replaced, if it exists.
+func itemFromInput(yl model.YataList, input InsertListItemInput, before model.YataItem, exists bool) model.YataItem {
+	yi := model.YataItem{
+		UserID:     yl.UserID,
+		ListID:     yl.ListID,
+		ItemID:     model.ItemID(input.ItemID),
+		ParentID:   model.ItemID(input.ParentID),
+		SectionID:  model.SectionID(input.SectionID),
+		Content:    input.Content,
+		Notes:      input.Notes,
+		Completed:  input.Completed,
+		Priority:   input.Priority,
+		DueAt:      input.DueAt,
+		AssigneeID: model.UserID(input.AssigneeID),
+		Tags:       input.Tags,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if exists {
+		yi.CreatedAt = before.CreatedAt
+	}
+	return yi
+}
+
+// recordItemInserted records the creation, completion or update of an item. before is the item that was replaced, if it
+// existed.
+func (s *Server) recordItemInserted(r *http.Request, yl model.YataList, before model.YataItem, exists bool, yi model.YataItem) {
+	switch {
+	case !exists:
+		s.recordActivity(r, yl, model.ActionCreate, "item", string(yi.ItemID), nil, yi)
+	case !before.Completed && yi.Completed:
+		s.recordActivity(r, yl, model.ActionComplete, "item", string(yi.ItemID), before, yi)
+	default:
+		s.recordActivity(r, yl, model.ActionUpdate, "item", string(yi.ItemID), before, yi)
+	}
 }
@@ -2,17 +2,35 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/model"
 	"github.com/TheYeung1/yata-server/server/request"
 )
 
+// maxListDescriptionLength is the maximum length of a list's description in bytes.
+const maxListDescriptionLength = 1000
+
+// listColorPattern matches the hex RGB colors lists can be given.
+var listColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 type InsertListInput struct {
-	ListID string
-	Title  string
+	ListID      string
+	Title       string
+	Description string
+	Color       string
+	Icon        string
+	Position    int
+	// FolderID must be the ID of one of the caller's folders, if it is set.
+	FolderID string
+	// Template lists are hidden from list views unless templates are asked for.
+	Template bool
 }
 
 // Validate returns an error if the input does not pass validation.
@@ -26,18 +44,79 @@ func (input *InsertListInput) Validate() error {
 	if len(input.ListID) != len(strings.TrimSpace(input.ListID)) {
 		return errors.New("ListID cannot be prefixed or suffixed with spaces")
 	}
-	if len(input.Title) == 0 {
+	if err := validateListDetails(input.Title, input.Description, input.Color, input.Icon); err != nil {
+		return err
+	}
+	if input.Position < 0 {
+		return errors.New("Position cannot be negative")
+	}
+	if len(input.FolderID) != 0 {
+		if err := validateFolderID(model.FolderID(input.FolderID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateListDetails returns an error if a list's title, description, color or icon do not pass validation.
+func validateListDetails(title, description, color, icon string) error {
+	if len(title) == 0 {
 		return errors.New("Title cannot be empty")
 	}
-	if len(input.Title) > 100 {
+	if len(title) > 100 {
 		return errors.New("Title length cannot exceed 100 characters")
 	}
-	if len(input.Title) != len(strings.TrimSpace(input.Title)) {
+	if len(title) != len(strings.TrimSpace(title)) {
 		return errors.New("Title cannot be prefixed or suffixed with spaces")
 	}
+	if len(description) > maxListDescriptionLength {
+		return fmt.Errorf("Description length cannot exceed %d characters", maxListDescriptionLength)
+	}
+	if len(color) != 0 && !listColorPattern.MatchString(color) {
+		return errors.New("Color must be a hex color such as \"#1e90ff\"")
+	}
+	if len(icon) != 0 && !isEmoji(icon) {
+		return errors.New("Icon must be a single emoji")
+	}
 	return nil
 }
 
+// isEmoji returns true if s looks like a single emoji, including emoji built from several code points such as flags,
+// skin tones and sequences joined with zero width joiners.
+func isEmoji(s string) bool {
+	const (
+		zeroWidthJoiner    = '\u200d'
+		maxEmojiCodePoints = 10
+	)
+	if utf8.RuneCountInString(s) > maxEmojiCodePoints {
+		return false
+	}
+	pictographs := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r):
+			pictographs++
+		case r == zeroWidthJoiner:
+			pictographs--
+		case unicode.Is(unicode.Variation_Selector, r), unicode.Is(unicode.Sk, r) && r >= 0x1f3fb && r <= 0x1f3ff:
+			// Variation selectors and skin tones modify the preceding pictograph.
+		default:
+			return false
+		}
+	}
+	// Flags are a pair of regional indicators.
+	return pictographs == 1 || (pictographs == 2 && isRegionalIndicatorPair(s))
+}
+
+func isRegionalIndicatorPair(s string) bool {
+	for _, r := range s {
+		if r < 0x1f1e6 || r > 0x1f1ff {
+			return false
+		}
+	}
+	return utf8.RuneCountInString(s) == 2
+}
+
 type InsertListOutput struct {
 	ListID string
 }
@@ -53,9 +132,9 @@ func (s *Server) InsertList(w http.ResponseWriter, r *http.Request) {
 	log.WithField("userID", uid).Debug("insert list called")
 
 	var input InsertListInput
-	if err := bindJSON(r.Body, &input); err != nil {
+	if err := bind(r, &input); err != nil {
 		log.WithError(err).Info("failed to bind input")
-		renderBadRequest(w, r, "malformed input")
+		renderBindError(w, r, err)
 		return
 	}
 	log.WithField("input", input).Debug("input bound")
@@ -67,23 +146,40 @@ func (s *Server) InsertList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	yl := model.YataList{
-		UserID: uid,
-		ListID: model.ListID(input.ListID),
-		Title:  input.Title,
+		UserID:      uid,
+		ListID:      model.ListID(input.ListID),
+		Title:       input.Title,
+		Description: input.Description,
+		Color:       input.Color,
+		Icon:        input.Icon,
+		Position:    input.Position,
+		FolderID:    model.FolderID(input.FolderID),
+		Template:    input.Template,
+	}
+	if len(yl.FolderID) != 0 {
+		if _, ok := s.getFolder(w, r, uid, yl.FolderID); !ok {
+			return
+		}
 	}
 	log.WithField("list", yl).Debug("inserting list")
 	if err := s.Ydb.InsertList(yl.UserID, yl); err != nil {
-		if errnf, ok := err.(database.ListExistsError); ok {
-			log.WithError(errnf).Info("list not found")
-			renderJSON(w, r, http.StatusConflict, responseError{Code: "ListExists", Message: "List already exists"})
+		switch err := err.(type) {
+		case database.ListExistsError:
+			log.WithError(err).Info("list exists")
+			render(w, r, http.StatusConflict, responseError{Code: "ListExists", Message: "List already exists"})
+			return
+		case database.ListInTrashError:
+			log.WithError(err).Info("list in trash")
+			renderListInTrash(w, r)
 			return
 		}
 		log.WithError(err).Error("failed to insert list")
 		renderInternalServerError(w, r)
 		return
 	}
+	s.recordActivity(r, yl, model.ActionCreate, "list", string(yl.ListID), nil, yl)
 
 	out := InsertListOutput{ListID: input.ListID}
 	log.WithField("output", out).Debug("list inserted")
-	renderJSON(w, r, http.StatusCreated, out)
+	render(w, r, http.StatusCreated, out)
 }
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/model"
@@ -54,6 +56,37 @@ func TestInsertListInput_Validate(t *testing.T) {
 			input: InsertListInput{ListID: "ID", Title: " Title"},
 			err:   errors.New("Title cannot be prefixed or suffixed with spaces"),
 		},
+		"metadata": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Description: "Groceries for the week", Color: "#1E90ff", Icon: "🛒", Position: 2},
+		},
+		"description-too-long": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Description: strings.Repeat("a", 1001)},
+			err:   errors.New("Description length cannot exceed 1000 characters"),
+		},
+		"color-not-hex": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Color: "blue"},
+			err:   errors.New("Color must be a hex color such as \"#1e90ff\""),
+		},
+		"color-short-hex": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Color: "#fff"},
+			err:   errors.New("Color must be a hex color such as \"#1e90ff\""),
+		},
+		"icon-not-emoji": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Icon: "a"},
+			err:   errors.New("Icon must be a single emoji"),
+		},
+		"icon-two-emoji": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Icon: "🛒🛒"},
+			err:   errors.New("Icon must be a single emoji"),
+		},
+		"position-negative": {
+			input: InsertListInput{ListID: "ID", Title: "Title", Position: -1},
+			err:   errors.New("Position cannot be negative"),
+		},
+		"folder-id-with-spaces": {
+			input: InsertListInput{ListID: "ID", Title: "Title", FolderID: "home "},
+			err:   errors.New("FolderID cannot be prefixed or suffixed with spaces"),
+		},
 	}
 
 	for name, test := range tests {
@@ -65,6 +98,25 @@ func TestInsertListInput_Validate(t *testing.T) {
 	}
 }
 
+func TestIsEmoji(t *testing.T) {
+	tests := map[string]bool{
+		"👍":     true,
+		"❤️":    true,
+		"👍🏽":    true,
+		"👨‍👩‍👧": true,
+		"🇨🇦":    true,
+		"":      false,
+		"a":     false,
+		"👍👍":    false,
+		"👍a":    false,
+		"🇨🇦🇨🇦":  false,
+	}
+
+	for s, want := range tests {
+		assert.Equal(t, want, isEmoji(s), s)
+	}
+}
+
 func TestServer_InsertList(t *testing.T) {
 	tests := map[string]struct {
 		input      string
@@ -108,6 +160,16 @@ func TestServer_InsertList(t *testing.T) {
 			outCode: http.StatusConflict,
 			outBody: "{\"Code\":\"ListExists\",\"Message\":\"List already exists\"}\n",
 		},
+		"list-in-trash": {
+			input: "{\"ListID\":\"ID\",\"Title\":\"Title\"}",
+			insertList: func(t *testing.T) func(id model.UserID, list model.YataList) error {
+				return func(id model.UserID, list model.YataList) error {
+					return database.ListInTrashError{}
+				}
+			},
+			outCode: http.StatusConflict,
+			outBody: "{\"Code\":\"ListInTrash\",\"Message\":\"A list with this ID is in the trash\"}\n",
+		},
 	}
 
 	for name, test := range tests {
@@ -131,29 +193,283 @@ func TestServer_InsertList(t *testing.T) {
 var _ database.YataDatabase = mockYdb{}
 
 type mockYdb struct {
-	MockInsertList func(id model.UserID, list model.YataList) error
+	MockGetList            func(id model.UserID, id2 model.ListID) (model.YataList, error)
+	MockGetLists           func(id model.UserID) ([]model.YataList, error)
+	MockUpdateList         func(yl model.YataList) error
+	MockSetListDetails     func(yl model.YataList) error
+	MockSetListTemplate    func(id model.UserID, lid model.ListID, template bool) error
+	MockInsertList         func(id model.UserID, list model.YataList) error
+	MockGetFolders         func(id model.UserID) ([]model.YataFolder, error)
+	MockInsertFolder       func(folder model.YataFolder) error
+	MockGetFolder          func(id model.UserID, fid model.FolderID) (model.YataFolder, error)
+	MockGetFolderLists     func(id model.UserID, fid model.FolderID) ([]model.YataList, error)
+	MockSetListFolder      func(id model.UserID, lid model.ListID, fid model.FolderID) error
+	MockDeleteFolder       func(id model.UserID, fid model.FolderID) error
+	MockRestoreList        func(id model.UserID, lid model.ListID) error
+	MockTrashList          func(id model.UserID, lid model.ListID, deletedAt time.Time) error
+	MockDeleteList         func(id model.UserID, id2 model.ListID) error
+	MockGetAllItems        func(id model.UserID) ([]model.YataItem, error)
+	MockGetListItems       func(id model.UserID, id2 model.ListID) ([]model.YataItem, error)
+	MockInsertItems        func(items []model.YataItem) error
+	MockMoveItems          func(moves []database.ItemMove) error
+	MockGetAllAttachments  func(id model.UserID) ([]model.YataAttachment, error)
+	MockGetItemAttachments func(id model.UserID, id2 model.ListID, id3 model.ItemID) ([]model.YataAttachment, error)
+	MockInsertAttachment   func(attachment model.YataAttachment) error
+	MockGetListSections    func(id model.UserID, id2 model.ListID) ([]model.YataSection, error)
+	MockInsertSection      func(section model.YataSection) error
+	MockDeleteSection      func(id model.UserID, id2 model.ListID, id3 model.SectionID) error
+	MockGetListMember      func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error)
+	MockGetMemberships     func(member model.UserID) ([]model.YataListMember, error)
+	MockGetShareLink       func(token string) (model.YataShareLink, error)
+	MockGetCalendarFeed    func(token string) (model.YataCalendarFeed, error)
+	MockUpdateItems        func(updates []database.ItemUpdate) error
+	MockGetAppPassword     func(hash string) (model.YataAppPassword, error)
+	MockGetAppPasswords    func(id model.UserID) ([]model.YataAppPassword, error)
+	MockInsertAppPassword  func(p model.YataAppPassword) error
+	MockDeleteAppPassword  func(hash string) error
+	MockGetItem            func(id model.UserID, id2 model.ListID, id3 model.ItemID) (model.YataItem, error)
+	MockInsertItem         func(item model.YataItem) error
+	MockDeleteItem         func(id model.UserID, lid model.ListID, iid model.ItemID) error
+	MockTrashItem          func(id model.UserID, id2 model.ListID, id3 model.ItemID, deletedAt time.Time) error
+	MockRestoreItem        func(id model.UserID, lid model.ListID, iid model.ItemID) error
+	MockGetTrash           func(id model.UserID) ([]model.YataList, []model.YataItem, error)
+	MockGetExpiredTrash    func(before time.Time) ([]model.YataList, []model.YataItem, error)
+	MockDeleteAttachment   func(id model.UserID, lid model.ListID, iid model.ItemID, aid model.AttachmentID) error
+	MockGetListMembers     func(owner model.UserID, lid model.ListID) ([]model.YataListMember, error)
+	MockDeleteListMember   func(owner model.UserID, lid model.ListID, member model.UserID) error
+	MockGetListShareLinks  func(id model.UserID, lid model.ListID) ([]model.YataShareLink, error)
+	MockDeleteShareLink    func(token string) error
+	MockInsertActivity     func(activity model.YataActivity) error
 }
 
 func (m mockYdb) GetList(id model.UserID, id2 model.ListID) (model.YataList, error) {
-	panic("implement me")
+	return m.MockGetList(id, id2)
 }
 
 func (m mockYdb) GetLists(id model.UserID) ([]model.YataList, error) {
-	panic("implement me")
+	return m.MockGetLists(id)
 }
 
 func (m mockYdb) InsertList(id model.UserID, list model.YataList) error {
 	return m.MockInsertList(id, list)
 }
 
-func (m mockYdb) GetAllItems(id model.UserID) ([]model.YataItem, error) {
+func (m mockYdb) UpdateList(yl model.YataList) error {
+	return m.MockUpdateList(yl)
+}
+
+func (m mockYdb) SetListDetails(yl model.YataList) error {
+	return m.MockSetListDetails(yl)
+}
+
+func (m mockYdb) SetListArchived(id model.UserID, id2 model.ListID, archived bool) error {
 	panic("implement me")
 }
 
-func (m mockYdb) GetListItems(id model.UserID, id2 model.ListID) ([]model.YataItem, error) {
+func (m mockYdb) SetListTemplate(id model.UserID, lid model.ListID, template bool) error {
+	return m.MockSetListTemplate(id, lid, template)
+}
+
+func (m mockYdb) SetListPosition(id model.UserID, id2 model.ListID, position int) error {
 	panic("implement me")
 }
 
+func (m mockYdb) SetListFolder(id model.UserID, id2 model.ListID, id3 model.FolderID) error {
+	return m.MockSetListFolder(id, id2, id3)
+}
+
+func (m mockYdb) GetFolderLists(id model.UserID, id2 model.FolderID) ([]model.YataList, error) {
+	return m.MockGetFolderLists(id, id2)
+}
+
+func (m mockYdb) GetFolders(id model.UserID) ([]model.YataFolder, error) {
+	return m.MockGetFolders(id)
+}
+
+func (m mockYdb) GetFolder(id model.UserID, id2 model.FolderID) (model.YataFolder, error) {
+	return m.MockGetFolder(id, id2)
+}
+
+func (m mockYdb) InsertFolder(folder model.YataFolder) error {
+	return m.MockInsertFolder(folder)
+}
+
+func (m mockYdb) DeleteFolder(id model.UserID, id2 model.FolderID) error {
+	return m.MockDeleteFolder(id, id2)
+}
+
+func (m mockYdb) TrashList(id model.UserID, id2 model.ListID, deletedAt time.Time) error {
+	return m.MockTrashList(id, id2, deletedAt)
+}
+
+func (m mockYdb) RestoreList(id model.UserID, id2 model.ListID) error {
+	return m.MockRestoreList(id, id2)
+}
+
+func (m mockYdb) DeleteList(id model.UserID, id2 model.ListID) error {
+	return m.MockDeleteList(id, id2)
+}
+
+func (m mockYdb) GetAllItems(id model.UserID) ([]model.YataItem, error) {
+	return m.MockGetAllItems(id)
+}
+
+func (m mockYdb) GetListItems(id model.UserID, id2 model.ListID) ([]model.YataItem, error) {
+	return m.MockGetListItems(id, id2)
+}
+
+func (m mockYdb) GetItem(id model.UserID, id2 model.ListID, id3 model.ItemID) (model.YataItem, error) {
+	return m.MockGetItem(id, id2, id3)
+}
+
 func (m mockYdb) InsertItem(item model.YataItem) error {
+	return m.MockInsertItem(item)
+}
+
+func (m mockYdb) InsertItems(items []model.YataItem) error {
+	return m.MockInsertItems(items)
+}
+
+func (m mockYdb) MoveItems(moves []database.ItemMove) error {
+	return m.MockMoveItems(moves)
+}
+
+func (m mockYdb) UpdateItems(updates []database.ItemUpdate) error {
+	return m.MockUpdateItems(updates)
+}
+
+func (m mockYdb) DeleteItem(id model.UserID, id2 model.ListID, id3 model.ItemID) error {
+	return m.MockDeleteItem(id, id2, id3)
+}
+
+func (m mockYdb) TrashItem(id model.UserID, id2 model.ListID, id3 model.ItemID, deletedAt time.Time) error {
+	return m.MockTrashItem(id, id2, id3, deletedAt)
+}
+
+func (m mockYdb) RestoreItem(id model.UserID, id2 model.ListID, id3 model.ItemID) error {
+	return m.MockRestoreItem(id, id2, id3)
+}
+
+func (m mockYdb) GetTrash(id model.UserID) ([]model.YataList, []model.YataItem, error) {
+	return m.MockGetTrash(id)
+}
+
+func (m mockYdb) GetExpiredTrash(before time.Time) ([]model.YataList, []model.YataItem, error) {
+	return m.MockGetExpiredTrash(before)
+}
+
+func (m mockYdb) GetListSections(id model.UserID, id2 model.ListID) ([]model.YataSection, error) {
+	return m.MockGetListSections(id, id2)
+}
+
+func (m mockYdb) GetSection(id model.UserID, id2 model.ListID, id3 model.SectionID) (model.YataSection, error) {
+	panic("implement me")
+}
+
+func (m mockYdb) InsertSection(section model.YataSection) error {
+	return m.MockInsertSection(section)
+}
+
+func (m mockYdb) DeleteSection(id model.UserID, id2 model.ListID, id3 model.SectionID) error {
+	return m.MockDeleteSection(id, id2, id3)
+}
+
+func (m mockYdb) GetAllAttachments(id model.UserID) ([]model.YataAttachment, error) {
+	return m.MockGetAllAttachments(id)
+}
+
+func (m mockYdb) GetItemAttachments(id model.UserID, id2 model.ListID, id3 model.ItemID) ([]model.YataAttachment, error) {
+	return m.MockGetItemAttachments(id, id2, id3)
+}
+
+func (m mockYdb) GetAttachment(id model.UserID, id2 model.ListID, id3 model.ItemID, id4 model.AttachmentID) (model.YataAttachment, error) {
+	panic("implement me")
+}
+
+func (m mockYdb) InsertAttachment(attachment model.YataAttachment) error {
+	return m.MockInsertAttachment(attachment)
+}
+
+func (m mockYdb) DeleteAttachment(id model.UserID, id2 model.ListID, id3 model.ItemID, id4 model.AttachmentID) error {
+	return m.MockDeleteAttachment(id, id2, id3, id4)
+}
+
+func (m mockYdb) GetListMembers(owner model.UserID, lid model.ListID) ([]model.YataListMember, error) {
+	return m.MockGetListMembers(owner, lid)
+}
+
+func (m mockYdb) GetListMember(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+	return m.MockGetListMember(owner, lid, member)
+}
+
+func (m mockYdb) GetMemberships(member model.UserID) ([]model.YataListMember, error) {
+	return m.MockGetMemberships(member)
+}
+
+func (m mockYdb) InsertListMember(member model.YataListMember) error {
+	panic("implement me")
+}
+
+func (m mockYdb) DeleteListMember(owner model.UserID, lid model.ListID, member model.UserID) error {
+	return m.MockDeleteListMember(owner, lid, member)
+}
+
+func (m mockYdb) GetShareLink(token string) (model.YataShareLink, error) {
+	return m.MockGetShareLink(token)
+}
+
+func (m mockYdb) GetListShareLinks(id model.UserID, id2 model.ListID) ([]model.YataShareLink, error) {
+	return m.MockGetListShareLinks(id, id2)
+}
+
+func (m mockYdb) InsertShareLink(link model.YataShareLink) error {
+	panic("implement me")
+}
+
+func (m mockYdb) DeleteShareLink(token string) error {
+	return m.MockDeleteShareLink(token)
+}
+
+func (m mockYdb) GetCalendarFeed(token string) (model.YataCalendarFeed, error) {
+	return m.MockGetCalendarFeed(token)
+}
+
+func (m mockYdb) GetCalendarFeeds(id model.UserID) ([]model.YataCalendarFeed, error) {
+	panic("implement me")
+}
+
+func (m mockYdb) InsertCalendarFeed(feed model.YataCalendarFeed) error {
 	panic("implement me")
 }
+
+func (m mockYdb) DeleteCalendarFeed(token string) error {
+	panic("implement me")
+}
+
+func (m mockYdb) GetAppPassword(hash string) (model.YataAppPassword, error) {
+	return m.MockGetAppPassword(hash)
+}
+
+func (m mockYdb) GetAppPasswords(id model.UserID) ([]model.YataAppPassword, error) {
+	return m.MockGetAppPasswords(id)
+}
+
+func (m mockYdb) InsertAppPassword(p model.YataAppPassword) error {
+	return m.MockInsertAppPassword(p)
+}
+
+func (m mockYdb) DeleteAppPassword(hash string) error {
+	return m.MockDeleteAppPassword(hash)
+}
+
+func (m mockYdb) GetListActivity(owner model.UserID, lid model.ListID, limit int64, pageToken string) ([]model.YataActivity, string, error) {
+	panic("implement me")
+}
+
+func (m mockYdb) InsertActivity(activity model.YataActivity) error {
+	// Most handlers record activities; tests that do not check them can leave MockInsertActivity unset.
+	if m.MockInsertActivity == nil {
+		return nil
+	}
+	return m.MockInsertActivity(activity)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// jobRetention is how long a finished job's status can be retrieved for.
+const jobRetention = time.Hour
+
+// maxJobErrors is the most errors a job keeps. Further errors are still counted in Failed.
+const maxJobErrors = 100
+
+// JobState is the state of a background job.
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	// JobFailed means the job stopped before it finished. Jobs that finish with some failed entries still succeed.
+	JobFailed JobState = "failed"
+)
+
+// JobError is why one of a job's entries failed.
+type JobError struct {
+	// ID identifies the entry that failed, such as an item's ID.
+	ID string
+	responseError
+}
+
+// JobStatus is a snapshot of a background job's progress.
+type JobStatus struct {
+	JobID string
+	// Kind is what the job does, such as "bulk" or "import".
+	Kind  string
+	State JobState
+	// Total is the number of entries the job works on. Processed counts those that are done, including the Failed ones.
+	Total     int
+	Processed int
+	Failed    int
+	// Errors are the first maxJobErrors errors of the entries that failed.
+	Errors []JobError `json:",omitempty"`
+	// Result is set by some jobs once they succeed.
+	Result     interface{} `json:",omitempty"`
+	CreatedAt  time.Time
+	FinishedAt *time.Time `json:",omitempty"`
+}
+
+// job is a background job that belongs to a user. Its status is safe to update from the goroutine running it while
+// it is being read.
+type job struct {
+	uid  model.UserID
+	done chan struct{}
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// Status returns a snapshot of the job's status.
+func (j *job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	status.Errors = append([]JobError(nil), j.status.Errors...)
+	return status
+}
+
+// addTotal adds n entries to the job's total.
+func (j *job) addTotal(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Total += n
+}
+
+// succeed records that n entries were processed successfully.
+func (j *job) succeed(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Processed += n
+}
+
+// fail records that the entry with the ID id failed.
+func (j *job) fail(id string, e responseError) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Processed++
+	j.status.Failed++
+	if len(j.status.Errors) < maxJobErrors {
+		j.status.Errors = append(j.status.Errors, JobError{ID: id, responseError: e})
+	}
+}
+
+// finish marks the job as finished. The job failed if err is not nil.
+func (j *job) finish(result interface{}, err error) {
+	j.mu.Lock()
+	now := time.Now().UTC()
+	j.status.FinishedAt = &now
+	j.status.Result = result
+	j.status.State = JobSucceeded
+	if err != nil {
+		j.status.State = JobFailed
+	}
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// jobRegistry keeps track of the server's background jobs. Its zero value is ready to use.
+// Jobs only live in memory so they are lost if the server restarts.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// start runs fn in the background as a new job belonging to uid. fn must call finish on the job once it is done.
+func (reg *jobRegistry) start(uid model.UserID, kind string, fn func(*job)) (*job, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	j := &job{
+		uid:  uid,
+		done: make(chan struct{}),
+		status: JobStatus{
+			JobID:     id.String(),
+			Kind:      kind,
+			State:     JobRunning,
+			CreatedAt: time.Now().UTC(),
+		},
+	}
+
+	reg.mu.Lock()
+	if reg.jobs == nil {
+		reg.jobs = map[string]*job{}
+	}
+	reg.prune()
+	reg.jobs[j.status.JobID] = j
+	reg.mu.Unlock()
+
+	go fn(j)
+	return j, nil
+}
+
+// get returns the job with the ID id if it belongs to uid.
+func (reg *jobRegistry) get(uid model.UserID, id string) (*job, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	j, ok := reg.jobs[id]
+	if !ok || j.uid != uid {
+		return nil, false
+	}
+	return j, true
+}
+
+// prune forgets jobs that finished more than jobRetention ago. reg.mu must be held.
+func (reg *jobRegistry) prune() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, j := range reg.jobs {
+		status := j.Status()
+		if status.FinishedAt != nil && status.FinishedAt.Before(cutoff) {
+			delete(reg.jobs, id)
+		}
+	}
+}
+
+type GetJobOutput struct {
+	Job JobStatus
+}
+
+// GetJob returns the status of one of the caller's background jobs.
+func (s *Server) GetJob(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get job called")
+
+	jobID := mux.Vars(r)["jobID"]
+	if err := validateJobID(jobID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	j, ok := s.jobs.get(uid, jobID)
+	if !ok {
+		log.WithField("jobID", jobID).Info("job not found")
+		render(w, r, http.StatusNotFound, responseError{Code: "JobDoesNotExist", Message: "Job does not exist"})
+		return
+	}
+
+	out := GetJobOutput{Job: j.Status()}
+	log.WithField("output", out).Debug("job retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+func validateJobID(id string) error {
+	if len(id) == 0 {
+		return errors.New("JobID cannot be empty")
+	}
+	if len(id) > 100 {
+		return errors.New("JobID length cannot exceed 100 characters")
+	}
+	if len(id) != len(strings.TrimSpace(id)) {
+		return errors.New("JobID cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRegistry(t *testing.T) {
+	var reg jobRegistry
+	j, err := reg.start("me", "test", func(j *job) {
+		j.addTotal(2)
+		j.succeed(1)
+		j.fail("b", responseError{Code: "Oops"})
+		j.finish("result", nil)
+	})
+	assert.NoError(t, err)
+	<-j.done
+
+	status := j.Status()
+	assert.Equal(t, JobSucceeded, status.State)
+	assert.Equal(t, 2, status.Total)
+	assert.Equal(t, 2, status.Processed)
+	assert.Equal(t, 1, status.Failed)
+	assert.Equal(t, []JobError{{ID: "b", responseError: responseError{Code: "Oops"}}}, status.Errors)
+	assert.Equal(t, "result", status.Result)
+
+	got, ok := reg.get("me", status.JobID)
+	assert.True(t, ok)
+	assert.Equal(t, j, got)
+	_, ok = reg.get("someone-else", status.JobID)
+	assert.False(t, ok, "jobs are only visible to the user who started them")
+
+	failed, err := reg.start("me", "test", func(j *job) { j.finish(nil, errors.New("boom")) })
+	assert.NoError(t, err)
+	<-failed.done
+	assert.Equal(t, JobFailed, failed.Status().State)
+}
+
+func TestServer_GetJob(t *testing.T) {
+	srvr := Server{}
+	j, err := srvr.jobs.start("me", "test", func(j *job) { j.finish(nil, nil) })
+	assert.NoError(t, err)
+	<-j.done
+
+	tests := map[string]struct {
+		uid  string
+		code int
+	}{
+		"owner":        {uid: "me", code: http.StatusOK},
+		"someone-else": {uid: "them", code: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+			req = mux.SetURLVars(req, map[string]string{"jobID": j.Status().JobID})
+			srvr.GetJob(rec, req.WithContext(request.WithUserID(req.Context(), test.uid)))
+			assert.Equal(t, test.code, rec.Code)
+		})
+	}
+}
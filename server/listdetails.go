@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type SetListDetailsInput struct {
+	Title string
+	// Description, Color and Icon are removed from the list if they are empty.
+	Description string
+	Color       string
+	Icon        string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *SetListDetailsInput) Validate() error {
+	return validateListDetails(input.Title, input.Description, input.Color, input.Icon)
+}
+
+type SetListDetailsOutput struct {
+	ListID      model.ListID
+	Title       string
+	Description string `json:",omitempty"`
+	Color       string `json:",omitempty"`
+	Icon        string `json:",omitempty"`
+}
+
+// SetListDetails changes a list's title, description, color and icon. Only the list's owner can change them.
+func (s *Server) SetListDetails(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("set list details called")
+
+	var input SetListDetailsInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	out := SetListDetailsOutput{ListID: listID, Title: input.Title, Description: input.Description, Color: input.Color, Icon: input.Icon}
+	after := yl
+	after.Title, after.Description, after.Color, after.Icon = input.Title, input.Description, input.Color, input.Icon
+	if after == yl {
+		log.WithField("output", out).Debug("list already has requested details")
+		render(w, r, http.StatusOK, out)
+		return
+	}
+	if err := s.Ydb.SetListDetails(after); err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to set list details")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionUpdate, "list", string(listID), yl, after)
+
+	log.WithField("output", out).Debug("list details set")
+	render(w, r, http.StatusOK, out)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_SetListDetails(t *testing.T) {
+	list := model.YataList{UserID: "me", ListID: "groceries", Title: "Groceries", Color: "#1e90ff", Position: 2, Archived: true}
+
+	tests := map[string]struct {
+		body    string
+		owner   string
+		setErr  error
+		code    int
+		outBody string
+		set     []model.YataList
+	}{
+		"changes-details": {
+			body:    `{"Title":"Weekend","Description":"For the weekend","Icon":"🧺"}`,
+			code:    http.StatusOK,
+			outBody: "{\"ListID\":\"groceries\",\"Title\":\"Weekend\",\"Description\":\"For the weekend\",\"Icon\":\"🧺\"}\n",
+			// The color is removed, and the list's other fields are left as they are.
+			set: []model.YataList{{UserID: "me", ListID: "groceries", Title: "Weekend", Description: "For the weekend", Icon: "🧺", Position: 2, Archived: true}},
+		},
+		"unchanged": {
+			body:    `{"Title":"Groceries","Color":"#1e90ff"}`,
+			code:    http.StatusOK,
+			outBody: "{\"ListID\":\"groceries\",\"Title\":\"Groceries\",\"Color\":\"#1e90ff\"}\n",
+		},
+		"invalid": {
+			body:    `{"Title":"Groceries","Color":"blue"}`,
+			code:    http.StatusBadRequest,
+			outBody: "{\"Code\":\"BadRequest\",\"Message\":\"Color must be a hex color such as \\\"#1e90ff\\\"\"}\n",
+		},
+		"not-owner": {
+			body:    `{"Title":"Weekend"}`,
+			owner:   "owner",
+			code:    http.StatusForbidden,
+			outBody: "{\"Code\":\"Forbidden\",\"Message\":\"You do not have permission to do that to this list\"}\n",
+		},
+		"trashed-in-the-meantime": {
+			body:    `{"Title":"Weekend"}`,
+			setErr:  database.ListNotFoundError{},
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"ListDoesNotExist\",\"Message\":\"List does not exist\"}\n",
+			set:     []model.YataList{{UserID: "me", ListID: "groceries", Title: "Weekend", Position: 2, Archived: true}},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var set []model.YataList
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					yl := list
+					yl.UserID = id
+					return yl, nil
+				},
+				MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+					return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleEditor}, nil
+				},
+				MockSetListDetails: func(yl model.YataList) error {
+					set = append(set, yl)
+					return test.setErr
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			target := "https://does.not/lists/groceries/details"
+			if len(test.owner) != 0 {
+				target += "?owner=" + test.owner
+			}
+			req := httptest.NewRequest(http.MethodPut, target, bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+
+			srvr := Server{Ydb: ydb}
+			srvr.SetListDetails(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.set, set)
+		})
+	}
+}
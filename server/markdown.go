@@ -0,0 +1,332 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/importer"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// mediaTypeMarkdown is the media type lists are read and written as GitHub style Markdown checklists.
+const mediaTypeMarkdown = "text/markdown"
+
+// maxMarkdownSize is the largest Markdown checklist that can be put on a list.
+const maxMarkdownSize = 1 << 20
+
+// writeMarkdown writes a list as a Markdown checklist: a heading with the list's title, its description, and a
+// "- [ ]" or "- [x]" task for each item. Items in a section follow a lower level heading with the section's title,
+// and sub-tasks are indented under their parent. sections and items must already be in the order to write them in.
+func writeMarkdown(w io.Writer, yl model.YataList, sections []model.YataSection, items []model.YataItem) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", markdownLine(yl.Title))
+	if len(yl.Description) != 0 {
+		fmt.Fprintf(bw, "\n%s\n", markdownLine(yl.Description))
+	}
+
+	present := map[model.ItemID]bool{}
+	for _, item := range items {
+		present[item.ItemID] = true
+	}
+	children := map[model.ItemID][]model.YataItem{}
+	bySection := map[model.SectionID][]model.YataItem{}
+	for _, item := range items {
+		if len(item.ParentID) != 0 && present[item.ParentID] {
+			children[item.ParentID] = append(children[item.ParentID], item)
+		} else {
+			bySection[item.SectionID] = append(bySection[item.SectionID], item)
+		}
+	}
+
+	var writeItem func(item model.YataItem, depth int)
+	writeItem = func(item model.YataItem, depth int) {
+		box := " "
+		if item.Completed {
+			box = "x"
+		}
+		fmt.Fprintf(bw, "%s- [%s] %s\n", strings.Repeat("  ", depth), box, markdownLine(item.Content))
+		for _, child := range children[item.ItemID] {
+			writeItem(child, depth+1)
+		}
+	}
+	writeItems := func(items []model.YataItem) {
+		if len(items) == 0 {
+			return
+		}
+		bw.WriteString("\n")
+		for _, item := range items {
+			writeItem(item, 0)
+		}
+	}
+
+	// Items without a section, or whose section no longer exists, come first.
+	hasSection := map[model.SectionID]bool{}
+	for _, section := range sections {
+		hasSection[section.SectionID] = true
+	}
+	var unsectioned []model.YataItem
+	for _, item := range items {
+		if !hasSection[item.SectionID] && (len(item.ParentID) == 0 || !present[item.ParentID]) {
+			unsectioned = append(unsectioned, item)
+		}
+	}
+	writeItems(unsectioned)
+	for _, section := range sections {
+		fmt.Fprintf(bw, "\n## %s\n", markdownLine(section.Title))
+		writeItems(bySection[section.SectionID])
+	}
+	return bw.Flush()
+}
+
+// markdownLine returns s on a single line so that it cannot start a new task or heading.
+func markdownLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// renderMarkdown writes the response code, sets the content type for Markdown, and writes the list to w as a checklist.
+func renderMarkdown(w http.ResponseWriter, r *http.Request, code int, yl model.YataList, sections []model.YataSection, items []model.YataItem) {
+	w.Header().Set("Content-Type", mediaTypeMarkdown+"; charset=utf-8")
+	w.WriteHeader(code)
+	if err := writeMarkdown(w, yl, sections, items); err != nil {
+		request.Logger(r.Context()).WithError(err).Warn("failed to render markdown")
+	}
+}
+
+// markdownPlan is how a Markdown checklist is put on a list.
+type markdownPlan struct {
+	// sections are the sections to create for headings that do not match one of the list's sections.
+	sections []model.YataSection
+	input    []InsertListItemInput
+}
+
+// planMarkdown matches the sections and tasks of a checklist parsed from Markdown to the list's sections and items.
+// Headings match the section with the same title, ignoring case, and new sections are added after the list's sections.
+// Tasks match an item with the same content that no earlier task matched; matched items keep everything but their
+// completion, parent and section. Tasks that do not match an item become new items with a random ID.
+func planMarkdown(yl model.YataList, sections []model.YataSection, items []model.YataItem, l archive.List, newID func() (string, error)) (markdownPlan, error) {
+	var plan markdownPlan
+
+	sectionIDs := map[string]model.SectionID{}
+	byTitle := map[string]model.SectionID{}
+	taken := map[model.SectionID]bool{}
+	position := 0
+	for _, section := range sections {
+		key := strings.ToLower(section.Title)
+		if _, ok := byTitle[key]; !ok {
+			byTitle[key] = section.SectionID
+		}
+		taken[section.SectionID] = true
+		if section.Position >= position {
+			position = section.Position + 1
+		}
+	}
+	for _, section := range l.Sections {
+		key := strings.ToLower(section.Title)
+		if id, ok := byTitle[key]; ok {
+			sectionIDs[section.SectionID] = id
+			continue
+		}
+		id := model.SectionID(section.SectionID)
+		if taken[id] {
+			generated, err := newID()
+			if err != nil {
+				return markdownPlan{}, err
+			}
+			id = model.SectionID(generated)
+		}
+		taken[id] = true
+		byTitle[key] = id
+		sectionIDs[section.SectionID] = id
+		plan.sections = append(plan.sections, model.YataSection{
+			UserID:    yl.UserID,
+			ListID:    yl.ListID,
+			SectionID: id,
+			Title:     section.Title,
+			Position:  position,
+		})
+		position++
+	}
+
+	byContent := map[string][]model.YataItem{}
+	for _, item := range items {
+		byContent[item.Content] = append(byContent[item.Content], item)
+	}
+	itemIDs := map[string]string{}
+	for _, task := range l.Items {
+		var in InsertListItemInput
+		if matches := byContent[task.Content]; len(matches) != 0 {
+			in = itemInput(matches[0])
+			byContent[task.Content] = matches[1:]
+		} else {
+			id, err := newID()
+			if err != nil {
+				return markdownPlan{}, err
+			}
+			in = InsertListItemInput{ItemID: id, Content: task.Content}
+		}
+		itemIDs[task.ItemID] = in.ItemID
+		in.Completed = task.Completed
+		in.ParentID = itemIDs[task.ParentID]
+		in.SectionID = ""
+		if len(in.ParentID) == 0 {
+			in.SectionID = string(sectionIDs[task.SectionID])
+		}
+		plan.input = append(plan.input, in)
+	}
+	return plan, nil
+}
+
+// itemInput returns the input that inserts item as it is.
+func itemInput(item model.YataItem) InsertListItemInput {
+	return InsertListItemInput{
+		ItemID:     string(item.ItemID),
+		ParentID:   string(item.ParentID),
+		SectionID:  string(item.SectionID),
+		Content:    item.Content,
+		Notes:      item.Notes,
+		Completed:  item.Completed,
+		Priority:   item.Priority,
+		DueAt:      item.DueAt,
+		AssigneeID: string(item.AssigneeID),
+		Tags:       item.Tags,
+	}
+}
+
+// PutList creates and updates the items of a list from a Markdown checklist, such as one returned by GetList. The
+// list's title and description in the checklist are ignored, and items that are not in the checklist are kept as they
+// are. The response is the same as InsertListItems' with a result for each task in the checklist, in order.
+func (s *Server) PutList(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("put list called")
+
+	if requestMediaType(r) != mediaTypeMarkdown {
+		renderUnsupportedMediaType(w, r, "Lists", mediaTypeMarkdown)
+		return
+	}
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	body := &limitedReader{r: r.Body, n: maxMarkdownSize}
+	lists, err := importer.ParseMarkdown(body, importer.Options{})
+	if err != nil {
+		if body.exceeded {
+			log.Info("markdown too large")
+			render(w, r, http.StatusRequestEntityTooLarge, responseError{Code: "ListTooLarge", Message: fmt.Sprintf("Lists cannot exceed %d bytes", maxMarkdownSize)})
+			return
+		}
+		log.WithError(err).Info("failed to bind input")
+		renderBadRequest(w, r, "malformed input")
+		return
+	}
+	if len(lists) != 1 {
+		log.WithField("lists", len(lists)).Info("markdown is not a single list")
+		renderBadRequest(w, r, "Markdown must contain exactly one list, with at most one top level heading before its tasks")
+		return
+	}
+	if len(lists[0].Items) > maxBatchItems {
+		log.WithField("items", len(lists[0].Items)).Info("too many items in markdown")
+		renderBadRequest(w, r, fmt.Sprintf("Markdown cannot contain more than %d tasks", maxBatchItems))
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+	if yl.Archived {
+		log.Info("list is archived")
+		render(w, r, http.StatusConflict, responseError{Code: "ListArchived", Message: "Archived lists cannot be changed"})
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortSections(sections)
+	groupItemsBySection(items, sections)
+
+	plan, err := planMarkdown(yl, sections, items, lists[0], func() (string, error) {
+		id, err := uuid.NewRandom()
+		return id.String(), err
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to plan markdown")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("input", plan.input).Debug("input bound")
+
+	for _, section := range plan.sections {
+		if err := s.Ydb.InsertSection(section); err != nil {
+			log.WithError(err).Error("failed to insert section")
+			renderInternalServerError(w, r)
+			return
+		}
+		s.recordActivity(r, yl, model.ActionCreate, "section", string(section.SectionID), nil, section)
+		sections = append(sections, section)
+	}
+
+	b, err := s.newItemBatch(yl, items, sections, plan.input)
+	if err != nil {
+		log.WithError(err).Error("failed to prepare batch")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if len(b.writes) != 0 {
+		if err := s.Ydb.InsertItems(b.writes); err != nil {
+			errup, ok := err.(database.UnprocessedItemsError)
+			if !ok {
+				log.WithError(err).Error("failed to insert items")
+				renderInternalServerError(w, r)
+				return
+			}
+			log.WithError(errup).Warn("some items were not inserted")
+			for _, item := range errup.Items {
+				for _, i := range b.causes[item.ItemID] {
+					b.results[i].Error = &responseError{Code: "ItemNotProcessed", Message: "The item could not be inserted; try again"}
+				}
+			}
+		}
+	}
+
+	for i, result := range b.results {
+		if result.Error == nil {
+			e := b.entries[i]
+			s.recordItemInserted(r, yl, e.before, e.exists, e.item)
+		}
+	}
+
+	out := InsertListItemsOutput{Results: b.results}
+	log.WithField("output", out).Debug("list put")
+	render(w, r, http.StatusOK, out)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/importer"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// markdownListItems are the items of the list rendered as markdownList.
+var markdownListItems = []model.YataItem{
+	{ItemID: "bread", Content: "Bread", SectionID: "gone"},
+	{ItemID: "milk", Content: "Milk", SectionID: "dairy", Completed: true},
+	{ItemID: "oat", ParentID: "milk", Content: "Oat\nmilk", Completed: true},
+	{ItemID: "cheese", Content: "Cheese", SectionID: "dairy"},
+}
+
+// markdownListSections are the sections of the list rendered as markdownList.
+var markdownListSections = []model.YataSection{
+	{SectionID: "dairy", Title: "Dairy"},
+	{SectionID: "produce", Title: "Produce", Position: 1},
+}
+
+const markdownList = `# Groceries
+
+For the weekend
+
+- [ ] Bread
+
+## Dairy
+
+- [x] Milk
+  - [x] Oat milk
+- [ ] Cheese
+
+## Produce
+`
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	yl := model.YataList{ListID: "groceries", Title: "Groceries", Description: "For the\nweekend"}
+	require.NoError(t, writeMarkdown(&buf, yl, markdownListSections, markdownListItems))
+	assert.Equal(t, markdownList, buf.String())
+}
+
+func TestPlanMarkdown(t *testing.T) {
+	yl := model.YataList{UserID: "me", ListID: "groceries"}
+	lists, err := importer.ParseMarkdown(strings.NewReader(`# Groceries
+- [x] Bread
+## dairy
+- [x] Cheese
+  - [ ] Milk
+- [ ] Milk
+## Bakery
+- [ ] Bagels
+## Produce
+- [ ] Apples
+`), importer.Options{})
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+
+	ids := 0
+	plan, err := planMarkdown(yl, markdownListSections, markdownListItems, lists[0], func() (string, error) {
+		ids++
+		return "new-" + strconv.Itoa(ids), nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []model.YataSection{
+		{UserID: "me", ListID: "groceries", SectionID: "bakery", Title: "Bakery", Position: 2},
+	}, plan.sections)
+	assert.Equal(t, []InsertListItemInput{
+		{ItemID: "bread", Content: "Bread", Completed: true},
+		{ItemID: "cheese", Content: "Cheese", SectionID: "dairy", Completed: true},
+		{ItemID: "milk", ParentID: "cheese", Content: "Milk"},
+		{ItemID: "new-1", Content: "Milk", SectionID: "dairy"},
+		{ItemID: "new-2", Content: "Bagels", SectionID: "bakery"},
+		{ItemID: "new-3", Content: "Apples", SectionID: "produce"},
+	}, plan.input)
+}
+
+func TestServer_GetList_Markdown(t *testing.T) {
+	ydb := mockYdb{
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid, Title: "Groceries", Description: "For the weekend"}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return append([]model.YataItem(nil), markdownListItems...), nil
+		},
+		MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+			return []model.YataSection{markdownListSections[1], markdownListSections[0]}, nil
+		},
+	}
+
+	tests := map[string]struct {
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		"markdown": {
+			accept:      "text/markdown",
+			code:        http.StatusOK,
+			contentType: "text/markdown; charset=utf-8",
+			body:        markdownList,
+		},
+		"json": {
+			accept:      "application/json, text/markdown;q=0.5",
+			code:        http.StatusOK,
+			contentType: "application/json",
+			body:        `{"List":{"UserID":"me","ListID":"groceries","Title":"Groceries","Description":"For the weekend"},"Role":"owner"}` + "\n",
+		},
+		"not-acceptable": {
+			accept:      "text/html",
+			code:        http.StatusNotAcceptable,
+			contentType: "application/json",
+			body:        `{"Code":"NotAcceptable","Message":"Lists can be returned as application/json, application/cbor or text/markdown"}` + "\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+			req.Header.Set("Accept", test.accept)
+
+			srvr := Server{Ydb: ydb}
+			srvr.GetList(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			if test.contentType == "application/json" {
+				assert.JSONEq(t, test.body, rec.Body.String())
+			} else {
+				assert.Equal(t, test.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_PutList(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		body        string
+		archived    bool
+		code        int
+		results     []InsertListItemsResult
+		sections    []model.SectionID
+		written     []model.ItemID
+	}{
+		"markdown": {
+			contentType: "text/markdown",
+			body:        "# Groceries\n\n- [x] Butter\n\n## Bakery\n\n- [ ] Bread\n",
+			code:        http.StatusOK,
+			results:     []InsertListItemsResult{{ItemID: "butter"}, {ItemID: "new"}},
+			sections:    []model.SectionID{"bakery"},
+			written:     []model.ItemID{"butter", "new"},
+		},
+		"no-tasks": {
+			contentType: "text/markdown; charset=utf-8",
+			body:        "# Groceries\n",
+			code:        http.StatusOK,
+			results:     []InsertListItemsResult{},
+		},
+		"not-markdown": {
+			contentType: "application/json",
+			body:        `[]`,
+			code:        http.StatusUnsupportedMediaType,
+		},
+		"many-lists": {
+			contentType: "text/markdown",
+			body:        "- [ ] Butter\n# Groceries\n- [ ] Bread\n",
+			code:        http.StatusBadRequest,
+		},
+		"archived": {
+			contentType: "text/markdown",
+			body:        "- [x] Butter\n",
+			archived:    true,
+			code:        http.StatusConflict,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var sections []model.SectionID
+			var written []model.ItemID
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid, Archived: test.archived}, nil
+				},
+				MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+					return []model.YataItem{{UserID: id, ListID: lid, ItemID: "butter", Content: "Butter", Priority: 2}}, nil
+				},
+				MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+					return []model.YataSection{{UserID: id, ListID: lid, SectionID: "dairy", Title: "Dairy"}}, nil
+				},
+				MockInsertSection: func(section model.YataSection) error {
+					sections = append(sections, section.SectionID)
+					return nil
+				},
+				MockInsertItems: func(items []model.YataItem) error {
+					for _, item := range items {
+						if item.ItemID == "butter" {
+							assert.True(t, item.Completed)
+							assert.Equal(t, 2, item.Priority, "matched items keep their other fields")
+						}
+						if item.Content == "Bread" {
+							assert.Equal(t, model.SectionID("bakery"), item.SectionID)
+							item.ItemID = "new"
+						}
+						written = append(written, item.ItemID)
+					}
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "https://does.not/matter", strings.NewReader(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+			req.Header.Set("Content-Type", test.contentType)
+
+			srvr := Server{Ydb: ydb}
+			srvr.PutList(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			require.Equal(t, test.code, rec.Code, rec.Body.String())
+			assert.Equal(t, test.sections, sections)
+			assert.Equal(t, test.written, written)
+			if test.code != http.StatusOK {
+				return
+			}
+			var out InsertListItemsOutput
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+			for i := range out.Results {
+				if i < len(test.results) && test.results[i].ItemID == "new" {
+					assert.Len(t, out.Results[i].ItemID, 36)
+					out.Results[i].ItemID = "new"
+				}
+			}
+			assert.Equal(t, test.results, out.Results)
+		})
+	}
+}
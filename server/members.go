@@ -0,0 +1,213 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type GetListMembersOutput struct {
+	// Members are the list's owner followed by every user the list is shared with.
+	Members []model.YataListMember
+}
+
+func (s *Server) GetListMembers(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list members called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := s.Ydb.GetListMembers(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list members")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	owner := model.YataListMember{OwnerID: yl.UserID, ListID: listID, UserID: yl.UserID, Role: model.RoleOwner}
+	out := GetListMembersOutput{Members: append([]model.YataListMember{owner}, members...)}
+	log.WithField("output", out).Debug("list members retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertListMemberInput struct {
+	UserID string
+	Role   string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *InsertListMemberInput) Validate() error {
+	if len(input.UserID) == 0 {
+		return errors.New("UserID cannot be empty")
+	}
+	if len(input.UserID) > 128 {
+		return errors.New("UserID length cannot exceed 128 characters")
+	}
+	if len(input.UserID) != len(strings.TrimSpace(input.UserID)) {
+		return errors.New("UserID cannot be prefixed or suffixed with spaces")
+	}
+	if strings.Contains(input.UserID, ":") {
+		return errors.New("UserID cannot contain \":\"")
+	}
+	if model.Role(input.Role) != model.RoleEditor && model.Role(input.Role) != model.RoleViewer {
+		return errors.New("Role must be either \"editor\" or \"viewer\"")
+	}
+	return nil
+}
+
+type InsertListMemberOutput struct {
+	UserID string
+}
+
+// InsertListMember shares a list with a user, or changes the role of a user the list is already shared with.
+// Only the list's owner can share it.
+func (s *Server) InsertListMember(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert list member called")
+
+	var input InsertListMemberInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+	if model.UserID(input.UserID) == yl.UserID {
+		log.Info("owner cannot be added as a member")
+		renderBadRequest(w, r, "the list's owner cannot be added as a member")
+		return
+	}
+
+	var before interface{}
+	existing, err := s.Ydb.GetListMember(yl.UserID, listID, model.UserID(input.UserID))
+	if err == nil {
+		before = existing
+	} else if _, ok := err.(database.MemberNotFoundError); !ok {
+		log.WithError(err).Error("failed to get list member")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	m := model.YataListMember{
+		OwnerID: yl.UserID,
+		ListID:  listID,
+		UserID:  model.UserID(input.UserID),
+		Role:    model.Role(input.Role),
+	}
+	log.WithField("member", m).Debug("inserting list member")
+	if err := s.Ydb.InsertListMember(m); err != nil {
+		log.WithError(err).Error("failed to insert list member")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionShare, "member", string(m.UserID), before, m)
+
+	out := InsertListMemberOutput{UserID: input.UserID}
+	log.WithField("output", out).Debug("list member inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteListMemberOutput struct {
+	UserID string
+}
+
+// DeleteListMember stops sharing a list with a user.
+// The list's owner can remove any member; every other member can only remove themselves.
+func (s *Server) DeleteListMember(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list member called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	member := model.UserID(v["userID"])
+
+	yl, role, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+	if role != model.RoleOwner && member != uid {
+		log.WithField("role", role).Info("caller cannot remove other members")
+		renderForbidden(w, r, "Only the list's owner can remove other members")
+		return
+	}
+
+	m, err := s.Ydb.GetListMember(yl.UserID, listID, member)
+	if err != nil {
+		if errnf, ok := err.(database.MemberNotFoundError); ok {
+			log.WithError(errnf).Info("member not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "MemberDoesNotExist", Message: "Member does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get list member")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if err := s.Ydb.DeleteListMember(yl.UserID, listID, member); err != nil {
+		log.WithError(err).Error("failed to delete list member")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionShare, "member", string(member), m, nil)
+
+	out := DeleteListMemberOutput{UserID: string(member)}
+	log.WithField("output", out).Debug("list member deleted")
+	render(w, r, http.StatusOK, out)
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type MoveListItemInput struct {
+	// ListID is the list to move the item to.
+	ListID string
+	// OwnerID is the owner of the list to move the item to. It defaults to the caller.
+	OwnerID string
+	// SectionID is the section of the new list to move the item into, if any.
+	SectionID string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *MoveListItemInput) Validate() error {
+	if err := validateListID(model.ListID(input.ListID)); err != nil {
+		return err
+	}
+	if len(input.SectionID) != 0 {
+		if err := validateSectionID(model.SectionID(input.SectionID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type MoveListItemOutput struct {
+	ListID model.ListID
+	// ItemIDs are the IDs of the moved item and its sub-tasks.
+	ItemIDs []model.ItemID
+}
+
+// MoveListItem moves an item, along with its sub-tasks and attachments, to another list. The caller must be able to
+// edit both lists. A moved sub-task is detached from its parent, and assignees who cannot see the new list are
+// unassigned; everything else about the items is kept.
+func (s *Server) MoveListItem(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("move list item called")
+
+	var input MoveListItemInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	itemID := model.ItemID(v["itemID"])
+	if err := validateItemID(itemID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	src, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+	dst, _, ok := s.authorizeOwnedList(w, r, model.UserID(input.OwnerID), model.ListID(input.ListID), model.RoleEditor)
+	if !ok {
+		return
+	}
+	if dst.UserID == src.UserID && dst.ListID == src.ListID {
+		log.Info("item moved to its own list")
+		renderBadRequest(w, r, "ListID must be a different list")
+		return
+	}
+	if dst.Archived {
+		log.Info("list is archived")
+		render(w, r, http.StatusConflict, responseError{Code: "ListArchived", Message: "Items cannot be added to an archived list"})
+		return
+	}
+	sectionID := model.SectionID(input.SectionID)
+	if len(sectionID) != 0 {
+		if _, err := s.Ydb.GetSection(dst.UserID, dst.ListID, sectionID); err != nil {
+			if errnf, ok := err.(database.SectionNotFoundError); ok {
+				log.WithError(errnf).Info("section not found")
+				render(w, r, http.StatusNotFound, responseError{Code: "SectionDoesNotExist", Message: "Section does not exist"})
+				return
+			}
+			log.WithError(err).Error("failed to get section")
+			renderInternalServerError(w, r)
+			return
+		}
+	}
+
+	items, err := s.Ydb.GetListItems(src.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	var moving []model.YataItem
+	for _, item := range items {
+		if item.ItemID == itemID {
+			moving = append(itemDescendants(items, itemID), item)
+			break
+		}
+	}
+	if len(moving) == 0 {
+		log.WithField("itemID", itemID).Info("item not found")
+		render(w, r, http.StatusNotFound, responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"})
+		return
+	}
+
+	moves, errResp, err := s.itemMoves(moving, itemID, dst, sectionID)
+	if err != nil {
+		log.WithError(err).Error("failed to prepare item moves")
+		renderInternalServerError(w, r)
+		return
+	}
+	if errResp != nil {
+		log.WithField("error", *errResp).Info("item cannot be moved")
+		render(w, r, http.StatusConflict, *errResp)
+		return
+	}
+
+	if err := s.Ydb.MoveItems(moves); err != nil {
+		code, errResp := moveErrorResponse(err)
+		if errResp == nil {
+			log.WithError(err).Error("failed to move items")
+			renderInternalServerError(w, r)
+			return
+		}
+		log.WithError(err).Info("failed to move items")
+		render(w, r, code, *errResp)
+		return
+	}
+
+	out := MoveListItemOutput{ListID: dst.ListID, ItemIDs: []model.ItemID{}}
+	for _, m := range moves {
+		s.recordActivity(r, src, model.ActionDelete, "item", string(m.From.ItemID), m.From, nil)
+		s.recordActivity(r, dst, model.ActionCreate, "item", string(m.To.ItemID), nil, m.To)
+		out.ItemIDs = append(out.ItemIDs, m.To.ItemID)
+	}
+
+	log.WithField("output", out).Debug("list item moved")
+	render(w, r, http.StatusOK, out)
+}
+
+// moveErrorResponse returns the response code and error for an error returned by MoveItems. The error is nil if the
+// move failed for an unexpected reason.
+func moveErrorResponse(err error) (int, *responseError) {
+	switch err.(type) {
+	case database.ItemNotFoundError:
+		return http.StatusNotFound, &responseError{Code: "ItemDoesNotExist", Message: "Item does not exist"}
+	case database.ItemExistsError:
+		return http.StatusConflict, &responseError{Code: "ItemExists", Message: "The list already has an item with the same ID"}
+	case database.TooManyWritesError:
+		return http.StatusConflict, &responseError{Code: "TooManyItems", Message: "The item has too many sub-tasks and attachments to move at once"}
+	}
+	return http.StatusInternalServerError, nil
+}
+
+// itemMoves returns the moves of items to dst, where the item with the ID root is the one being moved and the rest are
+// its sub-tasks. If the items cannot be moved a response error explaining why is returned.
+func (s *Server) itemMoves(items []model.YataItem, root model.ItemID, dst model.YataList, sid model.SectionID) ([]database.ItemMove, *responseError, error) {
+	assignable := map[model.UserID]bool{}
+	var moves []database.ItemMove
+	var size int64
+	for _, item := range items {
+		to := item
+		to.UserID = dst.UserID
+		to.ListID = dst.ListID
+		// Sub-tasks are always in their parent's section.
+		to.SectionID = sid
+		if item.ItemID == root {
+			to.ParentID = ""
+		}
+		if len(to.AssigneeID) != 0 {
+			ok, seen := assignable[to.AssigneeID]
+			if !seen {
+				var err error
+				if ok, err = s.isListMember(dst, to.AssigneeID); err != nil {
+					return nil, nil, err
+				}
+				assignable[to.AssigneeID] = ok
+			}
+			if !ok {
+				to.AssigneeID = ""
+			}
+		}
+
+		attachments, err := s.Ydb.GetItemAttachments(item.UserID, item.ListID, item.ItemID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get item attachments: %v", err)
+		}
+		for _, a := range attachments {
+			size += a.Size
+		}
+		moves = append(moves, database.ItemMove{From: item, To: to, Attachments: attachments})
+	}
+
+	if size == 0 || len(items) == 0 || items[0].UserID == dst.UserID {
+		return moves, nil, nil
+	}
+	// Attachments count towards the quota of the owner of the list they are on.
+	all, err := s.Ydb.GetAllAttachments(dst.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get all attachments: %v", err)
+	}
+	var used int64
+	for _, a := range all {
+		used += a.Size
+	}
+	if used+size > s.AttachmentQuota {
+		return nil, &responseError{Code: "AttachmentQuotaExceeded", Message: "The item's attachments do not fit in the attachment quota of the list's owner"}, nil
+	}
+	return moves, nil, nil
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveListItemInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input MoveListItemInput
+		err   error
+	}{
+		"valid": {
+			input: MoveListItemInput{ListID: "groceries", SectionID: "produce"},
+		},
+		"list-id-empty": {
+			input: MoveListItemInput{},
+			err:   errors.New("ListID cannot be empty"),
+		},
+		"section-id-with-spaces": {
+			input: MoveListItemInput{ListID: "groceries", SectionID: "produce "},
+			err:   errors.New("SectionID cannot be prefixed or suffixed with spaces"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestServer_MoveListItem(t *testing.T) {
+	items := []model.YataItem{
+		{UserID: "me", ListID: "todo", ItemID: "milk", SectionID: "errands", Completed: true, Priority: 2, AssigneeID: "stranger"},
+		{UserID: "me", ListID: "todo", ItemID: "oat", ParentID: "milk", SectionID: "errands"},
+		{UserID: "me", ListID: "todo", ItemID: "other"},
+	}
+
+	tests := map[string]struct {
+		body     string
+		moveErr  error
+		code     int
+		errCode  string
+		wantMove []database.ItemMove
+	}{
+		"moves-item-and-sub-tasks": {
+			body: `{"ListID":"groceries"}`,
+			code: http.StatusOK,
+			wantMove: []database.ItemMove{
+				{
+					From: items[1],
+					To:   model.YataItem{UserID: "me", ListID: "groceries", ItemID: "oat", ParentID: "milk"},
+				},
+				{
+					From: items[0],
+					To:   model.YataItem{UserID: "me", ListID: "groceries", ItemID: "milk", Completed: true, Priority: 2},
+				},
+			},
+		},
+		"same-list": {
+			body: `{"ListID":"todo"}`,
+			code: http.StatusBadRequest,
+		},
+		"archived-list": {
+			body:    `{"ListID":"archived"}`,
+			code:    http.StatusConflict,
+			errCode: "ListArchived",
+		},
+		"item-exists": {
+			body:    `{"ListID":"groceries"}`,
+			moveErr: database.ItemExistsError{},
+			code:    http.StatusConflict,
+			errCode: "ItemExists",
+		},
+		"item-gone": {
+			body:    `{"ListID":"groceries"}`,
+			moveErr: database.ItemNotFoundError{},
+			code:    http.StatusNotFound,
+			errCode: "ItemDoesNotExist",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var moved []database.ItemMove
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid, Archived: lid == "archived"}, nil
+				},
+				MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+					return items, nil
+				},
+				MockGetListMember: func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+					return model.YataListMember{}, database.MemberNotFoundError{}
+				},
+				MockGetItemAttachments: func(id model.UserID, lid model.ListID, iid model.ItemID) ([]model.YataAttachment, error) {
+					return nil, nil
+				},
+				MockMoveItems: func(moves []database.ItemMove) error {
+					moved = moves
+					return test.moveErr
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", bytes.NewBufferString(test.body))
+			req = mux.SetURLVars(req, map[string]string{"listID": "todo", "itemID": "milk"})
+
+			srvr := Server{Ydb: ydb}
+			srvr.MoveListItem(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			if len(test.errCode) != 0 {
+				var out responseError
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+				assert.Equal(t, test.errCode, out.Code)
+			}
+			if test.wantMove != nil {
+				assert.Equal(t, test.wantMove, moved)
+			}
+		})
+	}
+}
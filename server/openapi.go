@@ -0,0 +1,453 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/TheYeung1/yata-server/archive"
+	"github.com/TheYeung1/yata-server/cbor"
+	"github.com/TheYeung1/yata-server/ical"
+	"github.com/TheYeung1/yata-server/importer"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/openapi"
+	"github.com/TheYeung1/yata-server/server/request"
+)
+
+// apiVersion is the version of the API described by the OpenAPI document.
+const apiVersion = "1.0.0"
+
+// Names of the security schemes of the OpenAPI document.
+const (
+	// securityBearer is a Cognito ID token, which authenticates every route that is not public or CalDAV.
+	securityBearer = "bearerAuth"
+	// securityAppPassword is a user ID and app password, which authenticates the CalDAV routes.
+	securityAppPassword = "appPassword"
+)
+
+// apiParam is a query parameter of an operation.
+type apiParam struct {
+	name        string
+	description string
+	// kind is the parameter's JSON schema type, such as "boolean". It defaults to "string".
+	kind string
+}
+
+// apiOperation describes one of the routes registered by Router. Operations whose routes have a method OpenAPI cannot
+// describe, such as the PROPFIND and REPORT routes of CalDAV, are left out.
+type apiOperation struct {
+	method string
+	path   string
+	// handler is the name of the Server method that handles the operation, which is also its operationId. Routes
+	// without a Server method, such as redirects, are given a name of their own.
+	handler string
+	summary string
+	// security is the name of the security scheme that authenticates the operation, or empty if it is public.
+	security string
+	query    []apiParam
+	// body is a value of the request body's type, or nil for operations without a request body.
+	body interface{}
+	// bodyMediaTypes are the media types the request body can be given as. They default to requestMediaTypes.
+	bodyMediaTypes []string
+	// responses maps the status codes of successful responses to a value of the response body's type, or nil for
+	// responses without a body.
+	responses map[int]interface{}
+	// responseMediaTypes are the media types of successful responses. They default to the route's media types in
+	// routeResponseMediaTypes.
+	responseMediaTypes []string
+}
+
+// Query parameters that are shared by several operations.
+var (
+	paramArchived = apiParam{name: "archived", kind: "boolean", description: "If true, only archived lists are returned; otherwise archived lists are left out."}
+	paramTemplate = apiParam{name: "template", kind: "boolean", description: "If true, only templates are returned; otherwise templates are left out."}
+	paramSort     = apiParam{name: "sort", description: `Comma separated keys to sort items by, such as "-priority,dueAt".`}
+	paramInclude  = apiParam{name: "include", description: `Comma separated optional fields to include; only "notes".`}
+	paramOwner    = apiParam{name: "owner", description: "The ID of the list's owner, for lists shared with the caller. It defaults to the caller."}
+)
+
+// apiOperations are the operations in the OpenAPI document, in the order they are registered by Router.
+var apiOperations = []apiOperation{
+	{method: http.MethodGet, path: "/openapi.json", handler: "GetOpenAPI", summary: "Returns this OpenAPI document.",
+		responses: map[int]interface{}{http.StatusOK: map[string]interface{}{}}},
+	{method: http.MethodGet, path: "/shared/{token}", handler: "GetSharedList", summary: "Returns the list, and its items, that a share link points to.",
+		responses: map[int]interface{}{http.StatusOK: GetSharedListOutput{}}},
+	{method: http.MethodGet, path: "/calendar/{token}.ics", handler: "GetCalendar", summary: "Returns an iCalendar feed of the items with a due date.",
+		query:     []apiParam{{name: "component", description: "VEVENT, the default, or VTODO."}},
+		responses: map[int]interface{}{http.StatusOK: ""}},
+	{method: http.MethodGet, path: "/.well-known/caldav", handler: "RedirectCalDAV", summary: "Redirects CalDAV clients to the caller's principal.",
+		responses: map[int]interface{}{http.StatusMovedPermanently: nil}},
+
+	{method: http.MethodGet, path: "/dav/lists/{owner}/{listID}/{itemID}.ics", handler: "GetDAVObject", security: securityAppPassword,
+		summary:   "Returns an item as a calendar object resource.",
+		responses: map[int]interface{}{http.StatusOK: "", http.StatusNotModified: nil}, responseMediaTypes: []string{ical.MediaType}},
+	{method: http.MethodPut, path: "/dav/lists/{owner}/{listID}/{itemID}.ics", handler: "PutDAVObject", security: securityAppPassword,
+		summary: "Creates or updates an item from a calendar object resource with a single VTODO.",
+		body:    "", bodyMediaTypes: []string{ical.MediaType},
+		responses: map[int]interface{}{http.StatusCreated: nil, http.StatusNoContent: nil}},
+	{method: http.MethodDelete, path: "/dav/lists/{owner}/{listID}/{itemID}.ics", handler: "DeleteDAVObject", security: securityAppPassword,
+		summary:   "Moves an item, and all of its sub-tasks, to the trash of the list's owner.",
+		responses: map[int]interface{}{http.StatusNoContent: nil}},
+	{method: http.MethodOptions, path: "/dav/", handler: "OptionsDAV", security: securityAppPassword,
+		summary:   "Tells clients which parts of WebDAV and CalDAV the server supports, for any path under /dav/.",
+		responses: map[int]interface{}{http.StatusOK: nil}},
+
+	{method: http.MethodGet, path: "/items", handler: "GetAllItems", security: securityBearer, summary: "Returns every item on the caller's own lists.",
+		query:     []apiParam{paramSort, paramInclude, {name: "assignee", description: "Only returns items assigned to the user with this ID."}},
+		responses: map[int]interface{}{http.StatusOK: GetAllItemsOutput{}}},
+	{method: http.MethodGet, path: "/lists", handler: "GetLists", security: securityBearer, summary: "Returns the caller's lists and the lists shared with them.",
+		query:     []apiParam{paramArchived, paramTemplate},
+		responses: map[int]interface{}{http.StatusOK: GetListsOutput{}}},
+	{method: http.MethodPut, path: "/lists", handler: "InsertList", security: securityBearer, summary: "Creates a list. It fails with ListExists if the caller already has a list with the ID, or ListInTrash if that list is in the trash.",
+		body: InsertListInput{}, responses: map[int]interface{}{http.StatusCreated: InsertListOutput{}}},
+	{method: http.MethodPut, path: "/lists/order", handler: "OrderLists", security: securityBearer, summary: "Changes the positions of the caller's lists.",
+		body: OrderListsInput{}, responses: map[int]interface{}{http.StatusOK: OrderListsOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/", handler: "GetList", security: securityBearer,
+		summary:   "Returns a list and the caller's role on it, or the list and its items as a Markdown checklist.",
+		responses: map[int]interface{}{http.StatusOK: GetListOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/", handler: "PutList", security: securityBearer,
+		summary: "Creates and updates the items of a list from a Markdown checklist.",
+		body:    "", bodyMediaTypes: []string{mediaTypeMarkdown},
+		responses: map[int]interface{}{http.StatusOK: InsertListItemsOutput{}}, responseMediaTypes: responseMediaTypes},
+	{method: http.MethodDelete, path: "/lists/{listID}/", handler: "DeleteList", security: securityBearer,
+		summary:   "Moves a list, and every item on it, to the trash.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListOutput{}}, responseMediaTypes: responseMediaTypes},
+	{method: http.MethodPut, path: "/lists/{listID}/details", handler: "SetListDetails", security: securityBearer,
+		summary: "Changes a list's title, description, color and icon.",
+		body:    SetListDetailsInput{}, responses: map[int]interface{}{http.StatusOK: SetListDetailsOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/folder", handler: "SetListFolder", security: securityBearer,
+		summary: "Moves a list into one of the caller's folders, or out of its folder.",
+		body:    SetListFolderInput{}, responses: map[int]interface{}{http.StatusOK: SetListFolderOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/archive", handler: "ArchiveList", security: securityBearer,
+		summary:   "Hides a list from list views without deleting it.",
+		responses: map[int]interface{}{http.StatusOK: ArchiveListOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/unarchive", handler: "UnarchiveList", security: securityBearer,
+		summary:   "Returns an archived list to list views.",
+		responses: map[int]interface{}{http.StatusOK: ArchiveListOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/template", handler: "MarkListTemplate", security: securityBearer,
+		summary:   "Makes a list a template, hiding it from list views unless templates are asked for.",
+		responses: map[int]interface{}{http.StatusOK: TemplateListOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/untemplate", handler: "UnmarkListTemplate", security: securityBearer,
+		summary:   "Makes a template an ordinary list again.",
+		responses: map[int]interface{}{http.StatusOK: TemplateListOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/copy", handler: "CopyList", security: securityBearer,
+		summary: "Copies a list, its sections and its items to a new list owned by the caller.",
+		body:    CopyListInput{}, responses: map[int]interface{}{http.StatusCreated: CopyListOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/members", handler: "GetListMembers", security: securityBearer,
+		summary:   "Returns the users a list is shared with.",
+		responses: map[int]interface{}{http.StatusOK: GetListMembersOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/members", handler: "InsertListMember", security: securityBearer,
+		summary: "Shares a list with a user, or changes their role on it.",
+		body:    InsertListMemberInput{}, responses: map[int]interface{}{http.StatusCreated: InsertListMemberOutput{}}},
+	{method: http.MethodDelete, path: "/lists/{listID}/members/{userID}", handler: "DeleteListMember", security: securityBearer,
+		summary:   "Stops sharing a list with a user.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListMemberOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/share-links", handler: "GetListShareLinks", security: securityBearer,
+		summary:   "Returns a list's share links.",
+		responses: map[int]interface{}{http.StatusOK: GetListShareLinksOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/share-links", handler: "InsertListShareLink", security: securityBearer,
+		summary: "Creates a new share link for a list.",
+		body:    InsertListShareLinkInput{}, responses: map[int]interface{}{http.StatusCreated: InsertListShareLinkOutput{}}},
+	{method: http.MethodDelete, path: "/lists/{listID}/share-links/{token}", handler: "DeleteListShareLink", security: securityBearer,
+		summary:   "Revokes a share link.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListShareLinkOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/activity", handler: "GetListActivity", security: securityBearer,
+		summary: "Returns a list's activity, most recent first.",
+		query: []apiParam{
+			{name: "limit", kind: "integer", description: "The most activities to return."},
+			{name: "next", description: "The Next token of the previous page."},
+		},
+		responses: map[int]interface{}{http.StatusOK: GetListActivityOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/sections", handler: "GetListSections", security: securityBearer,
+		summary:   "Returns a list's sections.",
+		responses: map[int]interface{}{http.StatusOK: GetListSectionsOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/sections", handler: "InsertListSection", security: securityBearer,
+		summary: "Creates a section, or renames and repositions an existing one.",
+		body:    InsertListSectionInput{}, responses: map[int]interface{}{http.StatusCreated: InsertListSectionOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/sections/order", handler: "OrderListSections", security: securityBearer,
+		summary: "Changes the positions of a list's sections.",
+		body:    OrderListSectionsInput{}, responses: map[int]interface{}{http.StatusOK: OrderListSectionsOutput{}}},
+	{method: http.MethodDelete, path: "/lists/{listID}/sections/{sectionID}", handler: "DeleteListSection", security: securityBearer,
+		summary:   "Deletes a section. Its items are kept on the list without a section.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListSectionOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/items", handler: "GetListItems", security: securityBearer,
+		summary: "Returns the items on a list, or the list as todo.txt tasks.",
+		query:   []apiParam{paramSort, paramInclude}, responses: map[int]interface{}{http.StatusOK: GetListItemsOutput{}}},
+	{method: http.MethodPut, path: "/lists/{listID}/items", handler: "InsertListItem", security: securityBearer,
+		summary: "Creates an item, or updates an existing one.",
+		body:    InsertListItemInput{}, responses: map[int]interface{}{http.StatusCreated: InsertListItemOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/items:batch", handler: "InsertListItems", security: securityBearer,
+		summary: "Inserts a batch of items, or todo.txt tasks, on a list.",
+		body:    []InsertListItemInput{}, bodyMediaTypes: []string{mediaTypeJSON, cbor.MediaType, mediaTypeTodoTxt},
+		responses: map[int]interface{}{http.StatusOK: InsertListItemsOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/items:bulk", handler: "BulkListItems", security: securityBearer,
+		summary: "Applies an action to many items on a list at once. Large actions carry on in the background.",
+		body:    BulkListItemsInput{}, responses: map[int]interface{}{http.StatusOK: BulkListItemsOutput{}, http.StatusAccepted: BulkListItemsOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/items/{itemID}", handler: "GetListItem", security: securityBearer,
+		summary:   "Returns an item.",
+		responses: map[int]interface{}{http.StatusOK: GetListItemOutput{}}},
+	{method: http.MethodDelete, path: "/lists/{listID}/items/{itemID}", handler: "DeleteListItem", security: securityBearer,
+		summary:   "Moves an item, and all of its sub-tasks, to the trash of the list's owner.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListItemOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/items/{itemID}/children", handler: "GetListItemChildren", security: securityBearer,
+		summary: "Returns the sub-tasks of an item.",
+		query:   []apiParam{paramSort, paramInclude}, responses: map[int]interface{}{http.StatusOK: GetListItemChildrenOutput{}}},
+	{method: http.MethodPost, path: "/lists/{listID}/items/{itemID}/move-to", handler: "MoveListItem", security: securityBearer,
+		summary: "Moves an item, along with its sub-tasks and attachments, to another list.",
+		body:    MoveListItemInput{}, responses: map[int]interface{}{http.StatusOK: MoveListItemOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/items/{itemID}/attachments", handler: "GetListItemAttachments", security: securityBearer,
+		summary:   "Returns an item's attachments.",
+		responses: map[int]interface{}{http.StatusOK: GetListItemAttachmentsOutput{}}},
+	{method: http.MethodGet, path: "/lists/{listID}/items/{itemID}/attachments/{attachmentID}", handler: "GetListItemAttachment", security: securityBearer,
+		summary:   "Downloads an attachment as the media type it was uploaded with.",
+		responses: map[int]interface{}{http.StatusOK: ""}},
+	{method: http.MethodPut, path: "/lists/{listID}/items/{itemID}/attachments/{attachmentID}", handler: "PutListItemAttachment", security: securityBearer,
+		summary: "Stores the request body as an attachment, replacing any attachment with the same ID.",
+		body:    "", bodyMediaTypes: []string{"*/*"},
+		responses: map[int]interface{}{http.StatusCreated: PutListItemAttachmentOutput{}}, responseMediaTypes: responseMediaTypes},
+	{method: http.MethodDelete, path: "/lists/{listID}/items/{itemID}/attachments/{attachmentID}", handler: "DeleteListItemAttachment", security: securityBearer,
+		summary:   "Deletes an attachment.",
+		responses: map[int]interface{}{http.StatusOK: DeleteListItemAttachmentOutput{}}, responseMediaTypes: responseMediaTypes},
+	{method: http.MethodGet, path: "/folders", handler: "GetFolders", security: securityBearer,
+		summary:   "Returns the caller's folders.",
+		responses: map[int]interface{}{http.StatusOK: GetFoldersOutput{}}},
+	{method: http.MethodPut, path: "/folders", handler: "InsertFolder", security: securityBearer,
+		summary: "Creates a folder, or renames and repositions an existing one.",
+		body:    InsertFolderInput{}, responses: map[int]interface{}{http.StatusCreated: InsertFolderOutput{}}},
+	{method: http.MethodGet, path: "/folders/{folderID}", handler: "GetFolder", security: securityBearer,
+		summary:   "Returns one of the caller's folders.",
+		responses: map[int]interface{}{http.StatusOK: GetFolderOutput{}}},
+	{method: http.MethodDelete, path: "/folders/{folderID}", handler: "DeleteFolder", security: securityBearer,
+		summary:   "Deletes a folder. Its lists are kept without a folder.",
+		responses: map[int]interface{}{http.StatusOK: DeleteFolderOutput{}}},
+	{method: http.MethodGet, path: "/folders/{folderID}/lists", handler: "GetFolderLists", security: securityBearer,
+		summary: "Returns the lists in one of the caller's folders.",
+		query:   []apiParam{paramArchived, paramTemplate}, responses: map[int]interface{}{http.StatusOK: GetFolderListsOutput{}}},
+	{method: http.MethodGet, path: "/trash", handler: "GetTrash", security: securityBearer,
+		summary:   "Returns the caller's deleted lists and the items deleted from them.",
+		responses: map[int]interface{}{http.StatusOK: GetTrashOutput{}}},
+	{method: http.MethodPost, path: "/trash/{trashID}/restore", handler: "RestoreTrash", security: securityBearer,
+		summary:   "Restores a trash entry, along with everything that was deleted with it.",
+		responses: map[int]interface{}{http.StatusOK: RestoreTrashOutput{}}},
+	{method: http.MethodGet, path: "/export", handler: "Export", security: securityBearer,
+		summary:   "Returns all of the caller's own folders, lists, sections and items as an archive.",
+		responses: map[int]interface{}{http.StatusOK: archive.Archive{}}},
+	{method: http.MethodPost, path: "/import", handler: "Import", security: securityBearer,
+		summary: "Imports the request body. Unless it is a dry run the import carries on in the background.",
+		query: []apiParam{
+			{name: "format", description: "The format of the import. It defaults to yata's own export format."},
+			{name: "title", description: "The title of the list for formats that do not give it one."},
+			{name: "conflict", description: "What to do with lists whose IDs are already used: skip, the default, rename or overwrite."},
+			{name: "dryRun", kind: "boolean", description: "Whether to return what the import would do without changing anything."},
+		},
+		body: "", bodyMediaTypes: []string{"*/*"},
+		responses: map[int]interface{}{http.StatusOK: ImportOutput{}, http.StatusAccepted: ImportOutput{}}},
+	{method: http.MethodGet, path: "/calendar-feeds", handler: "GetCalendarFeeds", security: securityBearer,
+		summary:   "Returns the caller's calendar feeds.",
+		responses: map[int]interface{}{http.StatusOK: GetCalendarFeedsOutput{}}},
+	{method: http.MethodPost, path: "/calendar-feeds", handler: "InsertCalendarFeed", security: securityBearer,
+		summary:   "Creates a new calendar feed of the caller's items.",
+		responses: map[int]interface{}{http.StatusCreated: InsertCalendarFeedOutput{}}},
+	{method: http.MethodDelete, path: "/calendar-feeds/{token}", handler: "DeleteCalendarFeed", security: securityBearer,
+		summary:   "Revokes one of the caller's calendar feeds.",
+		responses: map[int]interface{}{http.StatusOK: DeleteCalendarFeedOutput{}}},
+	{method: http.MethodGet, path: "/app-passwords", handler: "GetAppPasswords", security: securityBearer,
+		summary:   "Returns the caller's app passwords.",
+		responses: map[int]interface{}{http.StatusOK: GetAppPasswordsOutput{}}},
+	{method: http.MethodPost, path: "/app-passwords", handler: "InsertAppPassword", security: securityBearer,
+		summary: "Creates a new app password for the caller.",
+		body:    InsertAppPasswordInput{}, responses: map[int]interface{}{http.StatusCreated: InsertAppPasswordOutput{}}},
+	{method: http.MethodDelete, path: "/app-passwords/{appPasswordID}", handler: "DeleteAppPassword", security: securityBearer,
+		summary:   "Revokes one of the caller's app passwords.",
+		responses: map[int]interface{}{http.StatusOK: DeleteAppPasswordOutput{}}},
+	{method: http.MethodGet, path: "/jobs/{jobID}", handler: "GetJob", security: securityBearer,
+		summary:   "Returns the status of one of the caller's background jobs.",
+		responses: map[int]interface{}{http.StatusOK: GetJobOutput{}}},
+}
+
+// errorCodes are the codes of responseError, which the handlers respond with when something goes wrong.
+var errorCodes = []string{
+	"AppPasswordDoesNotExist",
+	"AttachmentDoesNotExist",
+	"AttachmentQuotaExceeded",
+	"AttachmentTooLarge",
+	"BadRequest",
+	"CalendarFeedDoesNotExist",
+	"FolderDoesNotExist",
+	"Forbidden",
+	"ImportTooLarge",
+	"InternalServerError",
+	"ItemDoesNotExist",
+	"ItemExists",
+	"ItemNotProcessed",
+	"JobDoesNotExist",
+	"ListArchived",
+	"ListDoesNotExist",
+	"ListExists",
+	"ListInTrash",
+	"ListNotImported",
+	"ListTooLarge",
+	"MemberDoesNotExist",
+	"NotAcceptable",
+	"ParentInTrash",
+	"PreconditionFailed",
+	"RequestTooLarge",
+	"SectionDoesNotExist",
+	"ShareLinkDoesNotExist",
+	"TooManyItems",
+	"TrashEntryDoesNotExist",
+	"Unauthorized",
+	"UnsupportedMediaType",
+}
+
+// pathParamPattern matches the parameters of path templates, such as "{listID}".
+var pathParamPattern = regexp.MustCompile(`{([^}]+)}`)
+
+var (
+	apiDocumentOnce sync.Once
+	apiDocumentJSON []byte
+)
+
+// GetOpenAPI returns the OpenAPI document that describes the server's routes.
+func (s *Server) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	log.Debug("get openapi called")
+
+	apiDocumentOnce.Do(func() {
+		b, err := json.Marshal(apiDocument())
+		if err != nil {
+			panic(err) // The document is made of types that always encode.
+		}
+		apiDocumentJSON = b
+	})
+
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(apiDocumentJSON); err != nil {
+		log.WithError(err).Warn("failed to write openapi document")
+	}
+}
+
+// apiDocument returns the OpenAPI document of apiOperations.
+func apiDocument() openapi.Document {
+	doc := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "yata",
+			Description: "Yet another to-do app. Errors are responded with as an Error, whose Code says what went wrong.",
+			Version:     apiVersion,
+		},
+		Components: openapi.Components{
+			Schemas: map[string]*openapi.Schema{},
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				securityBearer:      {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "A Cognito ID token."},
+				securityAppPassword: {Type: "http", Scheme: "basic", Description: "A user ID and one of their app passwords."},
+			},
+		},
+	}
+
+	schemas := openapi.NewSchemas(doc.Components.Schemas)
+	schemas.Enum(reflect.TypeOf(model.Role("")), string(model.RoleOwner), string(model.RoleEditor), string(model.RoleViewer))
+	schemas.Enum(reflect.TypeOf(BulkAction("")), string(BulkComplete), string(BulkUncomplete), string(BulkDelete), string(BulkMove), string(BulkTag), string(BulkUntag))
+	schemas.Enum(reflect.TypeOf(JobState("")), string(JobRunning), string(JobSucceeded), string(JobFailed))
+	schemas.Enum(reflect.TypeOf(ImportAction("")), string(ImportCreate), string(ImportSkip), string(ImportRename), string(ImportOverwrite))
+	formats := make([]string, len(importer.Formats))
+	for i, f := range importer.Formats {
+		formats[i] = string(f)
+	}
+	schemas.Enum(reflect.TypeOf(importer.Format("")), formats...)
+	errorSchema := schemas.Define("Error", &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"Code":    {Type: "string", Enum: errorCodes},
+			"Message": {Type: "string"},
+		},
+		Required: []string{"Code"},
+	})
+
+	for _, o := range apiOperations {
+		op := &openapi.Operation{
+			OperationID: o.handler,
+			Summary:     o.summary,
+			Responses: map[string]*openapi.Response{
+				"default": {Description: "Error", Content: apiContent(responseMediaTypes, errorSchema)},
+			},
+		}
+		if len(o.security) != 0 {
+			op.Security = []openapi.SecurityRequirement{{o.security: []string{}}}
+		}
+
+		for _, m := range pathParamPattern.FindAllStringSubmatch(o.path, -1) {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: m[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+		}
+		query := o.query
+		if o.security == securityBearer && strings.HasPrefix(o.path, "/lists/{listID}/") {
+			query = append(query, paramOwner)
+		}
+		for _, p := range query {
+			kind := p.kind
+			if len(kind) == 0 {
+				kind = "string"
+			}
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: p.name, In: "query", Description: p.description, Schema: &openapi.Schema{Type: kind}})
+		}
+
+		if o.body != nil {
+			mediaTypes := o.bodyMediaTypes
+			if len(mediaTypes) == 0 {
+				mediaTypes = requestMediaTypes
+			}
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: apiBodyContent(schemas, mediaTypes, o.body)}
+		}
+
+		mediaTypes := o.responseMediaTypes
+		if len(mediaTypes) == 0 {
+			mediaTypes = responseMediaTypes
+			if m, ok := routeResponseMediaTypes[routeKey(o.method, o.path)]; ok {
+				mediaTypes = m
+			}
+		}
+		for code, v := range o.responses {
+			resp := &openapi.Response{Description: http.StatusText(code)}
+			if v != nil {
+				resp.Content = apiBodyContent(schemas, mediaTypes, v)
+			}
+			op.Responses[strconv.Itoa(code)] = resp
+		}
+
+		if err := doc.AddOperation(o.method, o.path, op); err != nil {
+			panic(err) // apiOperations is wrong, which TestAPIDocument catches.
+		}
+	}
+	return doc
+}
+
+// apiBodyContent returns the content of a request or response body of v's type in each of mediaTypes. Bodies that are
+// encoded by codecs are described by v's schema and every other body is a string.
+func apiBodyContent(schemas *openapi.Schemas, mediaTypes []string, v interface{}) map[string]*openapi.MediaType {
+	content := map[string]*openapi.MediaType{}
+	for _, mediaType := range mediaTypes {
+		switch _, isCodec := codecs[mediaType]; {
+		case isCodec && reflect.TypeOf(v).Kind() != reflect.String:
+			content[mediaType] = &openapi.MediaType{Schema: schemas.For(reflect.TypeOf(v))}
+		case mediaType == "*/*":
+			content[mediaType] = &openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}
+		default:
+			content[mediaType] = &openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+		}
+	}
+	return content
+}
+
+// apiContent returns content with the same schema in each of mediaTypes.
+func apiContent(mediaTypes []string, schema *openapi.Schema) map[string]*openapi.MediaType {
+	content := map[string]*openapi.MediaType{}
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = &openapi.MediaType{Schema: schema}
+	}
+	return content
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/openapi"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverMethodPattern matches the names of functions that are Server methods, such as
+// "github.com/TheYeung1/yata-server/server.(*Server).GetLists-fm".
+var serverMethodPattern = regexp.MustCompile(`\.\(\*Server\)\.(\w+)-fm$`)
+
+// TestAPIDocument fails when the routes registered by Router and the operations of the OpenAPI document drift apart.
+func TestAPIDocument(t *testing.T) {
+	// Routes are keyed by method and path template, such as "GET /lists", and map to the name of their handler.
+	routes := map[string]string{}
+	router := (&Server{}).Router().(*mux.Router)
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil // A subrouter.
+		}
+		tmpl, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet} // Routes that match any method, such as redirects, are described by GET.
+		}
+		handler := ""
+		if h, ok := route.GetHandler().(http.HandlerFunc); ok {
+			name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+			if m := serverMethodPattern.FindStringSubmatch(name); m != nil {
+				handler = m[1]
+			}
+		}
+		for _, method := range methods {
+			if method == "PROPFIND" || method == "REPORT" {
+				continue // OpenAPI cannot describe WebDAV's methods.
+			}
+			routes[method+" "+tmpl] = handler
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	documented := map[string]string{}
+	for _, o := range apiOperations {
+		documented[o.method+" "+o.path] = o.handler
+	}
+	for route, handler := range routes {
+		h, ok := documented[route]
+		if assert.True(t, ok, "%s is not in the OpenAPI document", route) && len(handler) != 0 {
+			assert.Equal(t, handler, h, "%s is handled by another method", route)
+		}
+	}
+	for op := range documented {
+		_, ok := routes[op]
+		assert.True(t, ok, "%s is in the OpenAPI document but not routed", op)
+	}
+
+	doc := apiDocument()
+	n := 0
+	for _, item := range doc.Paths {
+		n += len(item)
+	}
+	assert.Equal(t, len(apiOperations), n)
+}
+
+// TestAPIDocument_ErrorCodes fails when a handler responds with an error code that the OpenAPI document does not list,
+// or the document lists a code that no handler responds with.
+func TestAPIDocument_ErrorCodes(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }, 0)
+	require.NoError(t, err)
+
+	used := map[string]bool{}
+	for _, file := range pkgs["server"].Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if ident, ok := lit.Type.(*ast.Ident); !ok || ident.Name != "responseError" {
+				return true
+			}
+			for _, elt := range lit.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok || kv.Key.(*ast.Ident).Name != "Code" {
+					continue
+				}
+				if code, ok := kv.Value.(*ast.BasicLit); ok {
+					s, err := strconv.Unquote(code.Value)
+					require.NoError(t, err)
+					used[s] = true
+				}
+			}
+			return true
+		})
+	}
+
+	var codes []string
+	for code := range used {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	listed := append([]string(nil), errorCodes...)
+	sort.Strings(listed)
+	assert.Equal(t, codes, listed)
+}
+
+// TestAPIDocument_QueryParams fails when an operation of the OpenAPI document lists different query parameters than its
+// handler, or the functions it calls, reads with Query().Get.
+func TestAPIDocument_QueryParams(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }, 0)
+	require.NoError(t, err)
+
+	// Functions are keyed by name, and Server methods by their name prefixed with "Server.", such as "Server.GetLists".
+	// Each has the query parameters it reads itself and the functions and Server methods it calls.
+	params := map[string][]string{}
+	calls := map[string][]string{}
+	for _, file := range pkgs["server"].Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			name, receiver := fn.Name.Name, ""
+			if fn.Recv != nil {
+				star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+				if !ok || star.X.(*ast.Ident).Name != "Server" || len(fn.Recv.List[0].Names) == 0 {
+					continue
+				}
+				name, receiver = "Server."+name, fn.Recv.List[0].Names[0].Name
+			}
+
+			// queries are the variables that hold a request's query, such as q in q := r.URL.Query().
+			queries := map[string]bool{}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					for i, rhs := range n.Rhs {
+						if isQueryCall(rhs) && i < len(n.Lhs) {
+							queries[n.Lhs[i].(*ast.Ident).Name] = true
+						}
+					}
+				case *ast.CallExpr:
+					switch fun := n.Fun.(type) {
+					case *ast.Ident:
+						calls[name] = append(calls[name], fun.Name)
+					case *ast.SelectorExpr:
+						if x, ok := fun.X.(*ast.Ident); ok && len(receiver) != 0 && x.Name == receiver {
+							calls[name] = append(calls[name], "Server."+fun.Sel.Name)
+						}
+						if fun.Sel.Name != "Get" || len(n.Args) != 1 {
+							break
+						}
+						if x, ok := fun.X.(*ast.Ident); !isQueryCall(fun.X) && (!ok || !queries[x.Name]) {
+							break
+						}
+						if lit, ok := n.Args[0].(*ast.BasicLit); ok {
+							param, err := strconv.Unquote(lit.Value)
+							require.NoError(t, err)
+							params[name] = append(params[name], param)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var read func(name string, seen map[string]bool)
+	read = func(name string, seen map[string]bool) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, callee := range calls[name] {
+			read(callee, seen)
+		}
+	}
+
+	doc := apiDocument()
+	for _, o := range apiOperations {
+		if _, ok := calls["Server."+o.handler]; !ok {
+			continue // Routes without a Server method, such as redirects.
+		}
+		seen := map[string]bool{}
+		read("Server."+o.handler, seen)
+		used := map[string]bool{}
+		for name := range seen {
+			for _, param := range params[name] {
+				used[param] = true
+			}
+		}
+		var want []string
+		for param := range used {
+			want = append(want, param)
+		}
+		sort.Strings(want)
+
+		var got []string
+		for _, p := range doc.Paths[o.path][strings.ToLower(o.method)].Parameters {
+			if p.In == "query" {
+				got = append(got, p.Name)
+			}
+		}
+		sort.Strings(got)
+		assert.Equal(t, want, got, "%s %s", o.method, o.path)
+	}
+}
+
+// isQueryCall returns whether an expression is a call of a Query method, such as r.URL.Query().
+func isQueryCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 0 {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Query"
+}
+
+func TestServer_GetOpenAPI(t *testing.T) {
+	router := (&Server{}).Router()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://does.not/openapi.json", nil)
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, mediaTypeJSON, rec.Header().Get("Content-Type"))
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+
+	op := doc.Paths["/lists/{listID}/items"]["put"]
+	require.NotNil(t, op)
+	assert.Equal(t, "InsertListItem", op.OperationID)
+	assert.Equal(t, []openapi.SecurityRequirement{{securityBearer: []string{}}}, op.Security)
+	assert.Equal(t, []openapi.Parameter{
+		{Name: "listID", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		{Name: "owner", In: "query", Description: paramOwner.description, Schema: &openapi.Schema{Type: "string"}},
+	}, op.Parameters)
+	assert.Equal(t, &openapi.Schema{Ref: "#/components/schemas/InsertListItemInput"}, op.RequestBody.Content[mediaTypeJSON].Schema)
+	assert.Equal(t, &openapi.Schema{Ref: "#/components/schemas/InsertListItemOutput"}, op.Responses["201"].Content[mediaTypeJSON].Schema)
+	assert.Equal(t, &openapi.Schema{Ref: "#/components/schemas/Error"}, op.Responses["default"].Content[mediaTypeJSON].Schema)
+
+	// Every schema that is referred to is defined.
+	refs := regexp.MustCompile(`"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(rec.Body.String(), -1)
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, ref[1])
+	}
+	assert.Equal(t, []string{"owner", "editor", "viewer"}, doc.Components.Schemas["Role"].Enum)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+)
+
+type OrderListsInput struct {
+	// ListIDs are the caller's lists in their new order. Lists that are left out keep their order but come after them.
+	ListIDs []string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *OrderListsInput) Validate() error {
+	if len(input.ListIDs) == 0 {
+		return errors.New("ListIDs cannot be empty")
+	}
+	seen := map[string]bool{}
+	for _, id := range input.ListIDs {
+		if err := validateListID(model.ListID(id)); err != nil {
+			return err
+		}
+		if seen[id] {
+			return fmt.Errorf("ListIDs cannot contain %q more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+type OrderListsOutput struct {
+	// ListIDs are all of the caller's lists in their new order.
+	ListIDs []model.ListID
+}
+
+// OrderLists changes the positions of the caller's lists.
+func (s *Server) OrderLists(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("order lists called")
+
+	var input OrderListsInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	lists, err := s.Ydb.GetLists(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get lists")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortLists(lists, uid)
+
+	ordered, err := orderLists(lists, input.ListIDs)
+	if err != nil {
+		log.WithError(err).Info("list not found")
+		render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: err.Error()})
+		return
+	}
+
+	out := OrderListsOutput{ListIDs: []model.ListID{}}
+	for position, yl := range ordered {
+		out.ListIDs = append(out.ListIDs, yl.ListID)
+		if yl.Position == position {
+			continue
+		}
+		if err := s.Ydb.SetListPosition(uid, yl.ListID, position); err != nil {
+			if _, ok := err.(database.ListNotFoundError); ok {
+				// The list was deleted after the caller's lists were retrieved.
+				continue
+			}
+			log.WithError(err).WithField("listID", yl.ListID).Error("failed to set list position")
+			renderInternalServerError(w, r)
+			return
+		}
+		after := yl
+		after.Position = position
+		s.recordActivity(r, yl, model.ActionUpdate, "list", string(yl.ListID), yl, after)
+	}
+
+	log.WithField("output", out).Debug("lists ordered")
+	render(w, r, http.StatusOK, out)
+}
+
+// orderLists returns the lists with the IDs ids first, in that order, followed by the rest in their current order.
+// It returns an error if an ID is not one of the lists.
+func orderLists(lists []model.YataList, ids []string) ([]model.YataList, error) {
+	current := make([]string, len(lists))
+	for i, yl := range lists {
+		current[i] = string(yl.ListID)
+	}
+	order, unknown := reorder(current, ids)
+	if len(unknown) != 0 {
+		return nil, fmt.Errorf("List %q does not exist", unknown)
+	}
+
+	ordered := make([]model.YataList, len(order))
+	for i, j := range order {
+		ordered[i] = lists[j]
+	}
+	return ordered, nil
+}
+
+// reorder returns the indexes of current in their new order; the IDs first, in that order, followed by the rest in
+// their current order. If one of first is not in current it is returned instead.
+func reorder(current []string, first []string) ([]int, string) {
+	index := map[string]int{}
+	for i, id := range current {
+		index[id] = i
+	}
+
+	order := make([]int, 0, len(current))
+	moved := map[int]bool{}
+	for _, id := range first {
+		i, ok := index[id]
+		if !ok {
+			return nil, id
+		}
+		order = append(order, i)
+		moved[i] = true
+	}
+	for i := range current {
+		if !moved[i] {
+			order = append(order, i)
+		}
+	}
+	return order, ""
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderListsInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input OrderListsInput
+		err   error
+	}{
+		"valid": {
+			input: OrderListsInput{ListIDs: []string{"a", "b"}},
+		},
+		"empty": {
+			input: OrderListsInput{},
+			err:   errors.New("ListIDs cannot be empty"),
+		},
+		"invalid-list-id": {
+			input: OrderListsInput{ListIDs: []string{"a", " b"}},
+			err:   errors.New("ListID cannot be prefixed or suffixed with spaces"),
+		},
+		"duplicate": {
+			input: OrderListsInput{ListIDs: []string{"a", "b", "a"}},
+			err:   errors.New("ListIDs cannot contain \"a\" more than once"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestOrderLists(t *testing.T) {
+	lists := []model.YataList{{ListID: "a"}, {ListID: "b"}, {ListID: "c"}, {ListID: "d"}}
+
+	tests := map[string]struct {
+		ids  []string
+		want []model.ListID
+		err  error
+	}{
+		"all": {
+			ids:  []string{"d", "c", "b", "a"},
+			want: []model.ListID{"d", "c", "b", "a"},
+		},
+		"some-keep-their-order-after": {
+			ids:  []string{"c", "a"},
+			want: []model.ListID{"c", "a", "b", "d"},
+		},
+		"unknown-list": {
+			ids: []string{"c", "z"},
+			err: errors.New("List \"z\" does not exist"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			ordered, err := orderLists(lists, test.ids)
+			assert.Equal(t, test.err, err)
+			var ids []model.ListID
+			for _, yl := range ordered {
+				ids = append(ids, yl.ListID)
+			}
+			assert.Equal(t, test.want, ids)
+		})
+	}
+}
+
+func TestSortLists(t *testing.T) {
+	lists := []model.YataList{
+		{UserID: "them", ListID: "shared", Position: 0},
+		{UserID: "me", ListID: "second", Position: 1},
+		{UserID: "me", ListID: "b-first", Position: 0},
+		{UserID: "me", ListID: "a-first", Position: 0},
+	}
+	sortLists(lists, "me")
+
+	var ids []model.ListID
+	for _, yl := range lists {
+		ids = append(ids, yl.ListID)
+	}
+	assert.Equal(t, []model.ListID{"a-first", "b-first", "second", "shared"}, ids)
+}
+
+func TestReorder(t *testing.T) {
+	order, unknown := reorder([]string{"a", "b", "c"}, []string{"c"})
+	assert.Equal(t, []int{2, 0, 1}, order)
+	assert.Empty(t, unknown)
+
+	order, unknown = reorder([]string{"a", "b", "c"}, []string{"b", "z"})
+	assert.Nil(t, order)
+	assert.Equal(t, "z", unknown)
+}
@@ -43,3 +43,18 @@ func Logger(ctx context.Context) *logrus.Entry {
 func WithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
 	return context.WithValue(ctx, loggerCtxKey, logger)
 }
+
+const requestIDCtxKey ctxKey = "requestID"
+
+// WithRequestID stores the request ID on the returned context.
+// Should only be used by the RequestLogger middleware and tests.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, requestID)
+}
+
+// RequestID returns the request ID stored on the context.
+// If the request ID was found it will return true, false otherwise.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDCtxKey).(string)
+	return id, ok
+}
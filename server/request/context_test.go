@@ -16,3 +16,14 @@ func TestLogger(t *testing.T) {
 	lggr := logrus.WithField("foo", "bar")
 	assert.Equal(t, lggr, Logger(WithLogger(context.Background(), lggr)))
 }
+
+func TestRequestID(t *testing.T) {
+	// Test when no request ID is set on the context.
+	_, ok := RequestID(context.Background())
+	assert.False(t, ok)
+
+	// Test when a request ID is set on the context.
+	id, ok := RequestID(WithRequestID(context.Background(), "id"))
+	assert.True(t, ok)
+	assert.Equal(t, "id", id)
+}
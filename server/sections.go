@@ -0,0 +1,378 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type GetListSectionsOutput struct {
+	// Sections are in the order of their positions.
+	Sections []model.YataSection
+}
+
+func (s *Server) GetListSections(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list sections called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleViewer)
+	if !ok {
+		return
+	}
+
+	sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortSections(sections)
+
+	out := GetListSectionsOutput{Sections: sections}
+	log.WithField("output", out).Debug("list sections retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertListSectionInput struct {
+	SectionID string
+	Title     string
+	Position  int
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *InsertListSectionInput) Validate() error {
+	if err := validateSectionID(model.SectionID(input.SectionID)); err != nil {
+		return err
+	}
+	if len(input.Title) == 0 {
+		return errors.New("Title cannot be empty")
+	}
+	if len(input.Title) > 100 {
+		return errors.New("Title length cannot exceed 100 characters")
+	}
+	if len(input.Title) != len(strings.TrimSpace(input.Title)) {
+		return errors.New("Title cannot be prefixed or suffixed with spaces")
+	}
+	if input.Position < 0 {
+		return errors.New("Position cannot be negative")
+	}
+	return nil
+}
+
+type InsertListSectionOutput struct {
+	SectionID string
+}
+
+// InsertListSection creates a section, or renames and repositions an existing one.
+func (s *Server) InsertListSection(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert list section called")
+
+	var input InsertListSectionInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var before interface{}
+	existing, err := s.Ydb.GetSection(yl.UserID, listID, model.SectionID(input.SectionID))
+	if err == nil {
+		before = existing
+	} else if _, ok := err.(database.SectionNotFoundError); !ok {
+		log.WithError(err).Error("failed to get section")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	section := model.YataSection{
+		UserID:    yl.UserID,
+		ListID:    listID,
+		SectionID: model.SectionID(input.SectionID),
+		Title:     input.Title,
+		Position:  input.Position,
+	}
+	log.WithField("section", section).Debug("inserting section")
+	if err := s.Ydb.InsertSection(section); err != nil {
+		log.WithError(err).Error("failed to insert section")
+		renderInternalServerError(w, r)
+		return
+	}
+	if before == nil {
+		s.recordActivity(r, yl, model.ActionCreate, "section", input.SectionID, nil, section)
+	} else {
+		s.recordActivity(r, yl, model.ActionUpdate, "section", input.SectionID, before, section)
+	}
+
+	out := InsertListSectionOutput{SectionID: input.SectionID}
+	log.WithField("output", out).Debug("section inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteListSectionOutput struct {
+	SectionID model.SectionID
+	// ItemIDs are the IDs of the items that were in the section. They are kept but no longer belong to a section.
+	ItemIDs []model.ItemID
+}
+
+// DeleteListSection deletes a section. Its items are kept on the list without a section.
+func (s *Server) DeleteListSection(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list section called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	sectionID := model.SectionID(v["sectionID"])
+	if err := validateSectionID(sectionID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	section, err := s.Ydb.GetSection(yl.UserID, listID, sectionID)
+	if err != nil {
+		if errnf, ok := err.(database.SectionNotFoundError); ok {
+			log.WithError(errnf).Info("section not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "SectionDoesNotExist", Message: "Section does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get section")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	// Items are taken out of the section before it is deleted so that a failure part way through never leaves items
+	// in a section that does not exist.
+	out := DeleteListSectionOutput{SectionID: sectionID, ItemIDs: []model.ItemID{}}
+	for _, item := range items {
+		if item.SectionID != sectionID {
+			continue
+		}
+		item.SectionID = ""
+		if err := s.Ydb.InsertItem(item); err != nil {
+			log.WithError(err).WithField("itemID", item.ItemID).Error("failed to insert item")
+			renderInternalServerError(w, r)
+			return
+		}
+		out.ItemIDs = append(out.ItemIDs, item.ItemID)
+	}
+	if err := s.Ydb.DeleteSection(yl.UserID, listID, sectionID); err != nil {
+		log.WithError(err).Error("failed to delete section")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionDelete, "section", string(sectionID), section, nil)
+
+	log.WithField("output", out).Debug("section deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+type OrderListSectionsInput struct {
+	// SectionIDs are the list's sections in their new order. Sections that are left out keep their order but come
+	// after them.
+	SectionIDs []string
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *OrderListSectionsInput) Validate() error {
+	if len(input.SectionIDs) == 0 {
+		return errors.New("SectionIDs cannot be empty")
+	}
+	seen := map[string]bool{}
+	for _, id := range input.SectionIDs {
+		if err := validateSectionID(model.SectionID(id)); err != nil {
+			return err
+		}
+		if seen[id] {
+			return fmt.Errorf("SectionIDs cannot contain %q more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+type OrderListSectionsOutput struct {
+	// SectionIDs are all of the list's sections in their new order.
+	SectionIDs []model.SectionID
+}
+
+// OrderListSections changes the positions of a list's sections. Items move along with their sections.
+func (s *Server) OrderListSections(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("order list sections called")
+
+	var input OrderListSectionsInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleEditor)
+	if !ok {
+		return
+	}
+
+	sections, err := s.Ydb.GetListSections(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list sections")
+		renderInternalServerError(w, r)
+		return
+	}
+	sortSections(sections)
+
+	current := make([]string, len(sections))
+	for i, section := range sections {
+		current[i] = string(section.SectionID)
+	}
+	order, unknown := reorder(current, input.SectionIDs)
+	if len(unknown) != 0 {
+		log.WithField("sectionID", unknown).Info("section not found")
+		render(w, r, http.StatusNotFound, responseError{Code: "SectionDoesNotExist", Message: fmt.Sprintf("Section %q does not exist", unknown)})
+		return
+	}
+
+	out := OrderListSectionsOutput{SectionIDs: []model.SectionID{}}
+	for position, i := range order {
+		before := sections[i]
+		out.SectionIDs = append(out.SectionIDs, before.SectionID)
+		if before.Position == position {
+			continue
+		}
+		after := before
+		after.Position = position
+		if err := s.Ydb.InsertSection(after); err != nil {
+			log.WithError(err).WithField("sectionID", after.SectionID).Error("failed to insert section")
+			renderInternalServerError(w, r)
+			return
+		}
+		s.recordActivity(r, yl, model.ActionUpdate, "section", string(after.SectionID), before, after)
+	}
+
+	log.WithField("output", out).Debug("list sections ordered")
+	render(w, r, http.StatusOK, out)
+}
+
+// sortSections orders sections by position. Sections with the same position are ordered by ID so that the order is stable.
+func sortSections(sections []model.YataSection) {
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i].Position != sections[j].Position {
+			return sections[i].Position < sections[j].Position
+		}
+		return sections[i].SectionID < sections[j].SectionID
+	})
+}
+
+// groupItemsBySection stably orders items by the position of their section in sections, which must already be sorted.
+// Items without a section, or whose section no longer exists, come first.
+func groupItemsBySection(items []model.YataItem, sections []model.YataSection) {
+	index := map[model.SectionID]int{}
+	for i, section := range sections {
+		index[section.SectionID] = i + 1
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return index[items[i].SectionID] < index[items[j].SectionID]
+	})
+}
+
+func validateSectionID(id model.SectionID) error {
+	if len(id) == 0 {
+		return errors.New("SectionID cannot be empty")
+	}
+	if len(id) > 100 {
+		return errors.New("SectionID length cannot exceed 100 characters")
+	}
+	if len(id) != len(strings.TrimSpace(string(id))) {
+		return errors.New("SectionID cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertListSectionInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input InsertListSectionInput
+		err   error
+	}{
+		"valid": {
+			input: InsertListSectionInput{SectionID: "produce", Title: "Produce", Position: 1},
+		},
+		"section-id-empty": {
+			input: InsertListSectionInput{Title: "Produce"},
+			err:   errors.New("SectionID cannot be empty"),
+		},
+		"section-id-with-spaces": {
+			input: InsertListSectionInput{SectionID: " produce", Title: "Produce"},
+			err:   errors.New("SectionID cannot be prefixed or suffixed with spaces"),
+		},
+		"title-empty": {
+			input: InsertListSectionInput{SectionID: "produce"},
+			err:   errors.New("Title cannot be empty"),
+		},
+		"position-negative": {
+			input: InsertListSectionInput{SectionID: "produce", Title: "Produce", Position: -1},
+			err:   errors.New("Position cannot be negative"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
+
+func TestGroupItemsBySection(t *testing.T) {
+	sections := []model.YataSection{
+		{SectionID: "dairy", Position: 0},
+		{SectionID: "produce", Position: 0},
+		{SectionID: "bakery", Position: 1},
+	}
+	sortSections(sections)
+	assert.Equal(t, model.SectionID("dairy"), sections[0].SectionID)
+	assert.Equal(t, model.SectionID("produce"), sections[1].SectionID)
+	assert.Equal(t, model.SectionID("bakery"), sections[2].SectionID)
+
+	items := []model.YataItem{
+		{ItemID: "bread", SectionID: "bakery"},
+		{ItemID: "apples", SectionID: "produce"},
+		{ItemID: "milk", SectionID: "dairy"},
+		{ItemID: "batteries"},
+		{ItemID: "pears", SectionID: "produce"},
+		{ItemID: "orphan", SectionID: "deleted"},
+	}
+	groupItemsBySection(items, sections)
+
+	var ids []model.ItemID
+	for _, item := range items {
+		ids = append(ids, item.ItemID)
+	}
+	assert.Equal(t, []model.ItemID{"batteries", "orphan", "milk", "apples", "pears", "bread"}, ids)
+}
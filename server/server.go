@@ -2,7 +2,9 @@ package server
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/TheYeung1/yata-server/blobstore"
 	"github.com/TheYeung1/yata-server/config"
 	"github.com/TheYeung1/yata-server/database"
 	"github.com/TheYeung1/yata-server/middleware"
@@ -15,11 +17,24 @@ import (
 type Server struct {
 	CognitoCfg config.AwsCognitoUserPoolConfig
 	Ydb        database.YataDatabase
+	Blobs      blobstore.BlobStore
+	// AttachmentQuota is the number of bytes of attachments each user can store.
+	AttachmentQuota int64
+	// TrashRetention is how long deleted lists and items stay in the trash. It defaults to DefaultTrashRetention.
+	TrashRetention time.Duration
+
+	jobs jobRegistry
 }
 
 func (s *Server) Start() {
 	addr := ":8888"
 	log.WithField("address", addr).Info("starting server")
+	go s.purgeTrashEvery(trashPurgeInterval)
+	log.Fatal(http.ListenAndServe(addr, s.Router()))
+}
+
+// Router returns the handler that routes requests to the server's endpoints.
+func (s *Server) Router() http.Handler {
 	r := mux.NewRouter()
 	r.Use(middleware.RequestLogger(func() string {
 		u, err := uuid.NewRandom()
@@ -28,12 +43,83 @@ func (s *Server) Start() {
 		}
 		return u.String()
 	}))
-	r.Use(auth.CognitoJwtAuthMiddleware{Cfg: s.CognitoCfg}.Execute)
-	r.HandleFunc("/items", s.GetAllItems).Methods(http.MethodGet)
-	r.HandleFunc("/lists", s.GetLists).Methods(http.MethodGet)
-	r.HandleFunc("/lists", s.InsertList).Methods(http.MethodPut)
-	r.HandleFunc("/lists/{listID}/", s.GetList).Methods(http.MethodGet)
-	r.HandleFunc("/lists/{listID}/items", s.GetListItems).Methods(http.MethodGet)
-	r.HandleFunc("/lists/{listID}/items", s.InsertListItem).Methods(http.MethodPut)
-	log.Fatal(http.ListenAndServe(addr, r))
+	r.Use(requireAcceptable)
+
+	// Routes that can be called without authenticating.
+	r.HandleFunc("/openapi.json", s.GetOpenAPI).Methods(http.MethodGet)
+	public := r.PathPrefix("/shared").Subrouter()
+	public.HandleFunc("/{token}", s.GetSharedList).Methods(http.MethodGet)
+	r.HandleFunc("/calendar/{token}.ics", s.GetCalendar).Methods(http.MethodGet)
+	r.Handle("/.well-known/caldav", http.RedirectHandler(davPrincipalPath, http.StatusMovedPermanently))
+
+	// CalDAV clients authenticate with app passwords rather than bearer tokens.
+	dav := r.PathPrefix("/dav").Subrouter()
+	dav.Use(s.authenticateAppPassword)
+	dav.HandleFunc("/", s.PropfindDAVPrincipal).Methods("PROPFIND")
+	dav.HandleFunc("/lists/", s.PropfindDAVCalendarHome).Methods("PROPFIND")
+	dav.HandleFunc("/lists/{owner}/{listID}/", s.PropfindDAVCalendar).Methods("PROPFIND")
+	dav.HandleFunc("/lists/{owner}/{listID}/", s.ReportDAVCalendar).Methods("REPORT")
+	dav.HandleFunc("/lists/{owner}/{listID}/{itemID}.ics", s.PropfindDAVObject).Methods("PROPFIND")
+	dav.HandleFunc("/lists/{owner}/{listID}/{itemID}.ics", s.GetDAVObject).Methods(http.MethodGet)
+	dav.HandleFunc("/lists/{owner}/{listID}/{itemID}.ics", s.PutDAVObject).Methods(http.MethodPut)
+	dav.HandleFunc("/lists/{owner}/{listID}/{itemID}.ics", s.DeleteDAVObject).Methods(http.MethodDelete)
+	dav.PathPrefix("/").Methods(http.MethodOptions).HandlerFunc(s.OptionsDAV)
+
+	// Every other route requires authentication.
+	authed := r.NewRoute().Subrouter()
+	authed.Use(auth.CognitoJwtAuthMiddleware{Cfg: s.CognitoCfg}.Execute)
+	authed.HandleFunc("/items", s.GetAllItems).Methods(http.MethodGet)
+	authed.HandleFunc("/lists", s.GetLists).Methods(http.MethodGet)
+	authed.HandleFunc("/lists", s.InsertList).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/order", s.OrderLists).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/", s.GetList).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/", s.PutList).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/", s.DeleteList).Methods(http.MethodDelete)
+	authed.HandleFunc("/lists/{listID}/details", s.SetListDetails).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/folder", s.SetListFolder).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/archive", s.ArchiveList).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/unarchive", s.UnarchiveList).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/template", s.MarkListTemplate).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/untemplate", s.UnmarkListTemplate).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/copy", s.CopyList).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/members", s.GetListMembers).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/members", s.InsertListMember).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/members/{userID}", s.DeleteListMember).Methods(http.MethodDelete)
+	authed.HandleFunc("/lists/{listID}/share-links", s.GetListShareLinks).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/share-links", s.InsertListShareLink).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/share-links/{token}", s.DeleteListShareLink).Methods(http.MethodDelete)
+	authed.HandleFunc("/lists/{listID}/activity", s.GetListActivity).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/sections", s.GetListSections).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/sections", s.InsertListSection).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/sections/order", s.OrderListSections).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/sections/{sectionID}", s.DeleteListSection).Methods(http.MethodDelete)
+	authed.HandleFunc("/lists/{listID}/items", s.GetListItems).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/items", s.InsertListItem).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/items:batch", s.InsertListItems).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/items:bulk", s.BulkListItems).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}", s.GetListItem).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}", s.DeleteListItem).Methods(http.MethodDelete)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/children", s.GetListItemChildren).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/move-to", s.MoveListItem).Methods(http.MethodPost)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/attachments", s.GetListItemAttachments).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/attachments/{attachmentID}", s.GetListItemAttachment).Methods(http.MethodGet)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/attachments/{attachmentID}", s.PutListItemAttachment).Methods(http.MethodPut)
+	authed.HandleFunc("/lists/{listID}/items/{itemID}/attachments/{attachmentID}", s.DeleteListItemAttachment).Methods(http.MethodDelete)
+	authed.HandleFunc("/folders", s.GetFolders).Methods(http.MethodGet)
+	authed.HandleFunc("/folders", s.InsertFolder).Methods(http.MethodPut)
+	authed.HandleFunc("/folders/{folderID}", s.GetFolder).Methods(http.MethodGet)
+	authed.HandleFunc("/folders/{folderID}", s.DeleteFolder).Methods(http.MethodDelete)
+	authed.HandleFunc("/folders/{folderID}/lists", s.GetFolderLists).Methods(http.MethodGet)
+	authed.HandleFunc("/trash", s.GetTrash).Methods(http.MethodGet)
+	authed.HandleFunc("/trash/{trashID}/restore", s.RestoreTrash).Methods(http.MethodPost)
+	authed.HandleFunc("/export", s.Export).Methods(http.MethodGet)
+	authed.HandleFunc("/import", s.Import).Methods(http.MethodPost)
+	authed.HandleFunc("/calendar-feeds", s.GetCalendarFeeds).Methods(http.MethodGet)
+	authed.HandleFunc("/calendar-feeds", s.InsertCalendarFeed).Methods(http.MethodPost)
+	authed.HandleFunc("/calendar-feeds/{token}", s.DeleteCalendarFeed).Methods(http.MethodDelete)
+	authed.HandleFunc("/app-passwords", s.GetAppPasswords).Methods(http.MethodGet)
+	authed.HandleFunc("/app-passwords", s.InsertAppPassword).Methods(http.MethodPost)
+	authed.HandleFunc("/app-passwords/{appPasswordID}", s.DeleteAppPassword).Methods(http.MethodDelete)
+	authed.HandleFunc("/jobs/{jobID}", s.GetJob).Methods(http.MethodGet)
+	return r
 }
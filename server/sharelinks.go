@@ -0,0 +1,269 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+)
+
+type GetListShareLinksOutput struct {
+	ShareLinks []model.YataShareLink
+}
+
+func (s *Server) GetListShareLinks(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get list share links called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	links, err := s.Ydb.GetListShareLinks(yl.UserID, listID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list share links")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetListShareLinksOutput{ShareLinks: links}
+	log.Debug("list share links retrieved") // The output is not logged; tokens are secrets.
+	render(w, r, http.StatusOK, out)
+}
+
+type InsertListShareLinkInput struct {
+	// ExpiresAt is when the share link stops working. Share links without one work until they are revoked.
+	ExpiresAt *time.Time
+}
+
+// Validate returns an error if the input does not pass validation.
+func (input *InsertListShareLinkInput) Validate(now time.Time) error {
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return errors.New("ExpiresAt must be in the future")
+	}
+	return nil
+}
+
+type InsertListShareLinkOutput struct {
+	Token     string
+	ExpiresAt *time.Time `json:",omitempty"`
+}
+
+// InsertListShareLink creates a new share link for a list. Only the list's owner can create share links.
+func (s *Server) InsertListShareLink(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("insert list share link called")
+
+	var input InsertListShareLinkInput
+	if err := bind(r, &input); err != nil {
+		log.WithError(err).Info("failed to bind input")
+		renderBindError(w, r, err)
+		return
+	}
+	log.WithField("input", input).Debug("input bound")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	if err := input.Validate(now); err != nil {
+		log.WithError(err).Info("failed to normalize and validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		log.WithError(err).Error("failed to generate share token")
+		renderInternalServerError(w, r)
+		return
+	}
+	sl := model.YataShareLink{
+		Token:     token,
+		UserID:    yl.UserID,
+		ListID:    listID,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.Ydb.InsertShareLink(sl); err != nil {
+		log.WithError(err).Error("failed to insert share link")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionShare, "shareLink", "", nil, shareLinkActivityFields(sl))
+
+	out := InsertListShareLinkOutput{Token: token, ExpiresAt: input.ExpiresAt}
+	log.Debug("share link inserted")
+	render(w, r, http.StatusCreated, out)
+}
+
+type DeleteListShareLinkOutput struct {
+	Token string
+}
+
+// DeleteListShareLink revokes a share link. Only the list's owner can revoke share links.
+func (s *Server) DeleteListShareLink(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("delete list share link called")
+
+	v := mux.Vars(r)
+	listID := model.ListID(v["listID"])
+	if err := validateListID(listID); err != nil {
+		log.WithError(err).Info("failed to validate input")
+		renderBadRequest(w, r, err.Error())
+		return
+	}
+	token := v["token"]
+
+	yl, _, ok := s.authorizeList(w, r, listID, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	sl, err := s.Ydb.GetShareLink(token)
+	if err == nil && (sl.UserID != yl.UserID || sl.ListID != listID) {
+		// Share links of other lists are indistinguishable from share links that do not exist.
+		err = database.ShareLinkNotFoundError{}
+	}
+	if err != nil {
+		if errnf, ok := err.(database.ShareLinkNotFoundError); ok {
+			log.WithError(errnf).Info("share link not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ShareLinkDoesNotExist", Message: "Share link does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get share link")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	if err := s.Ydb.DeleteShareLink(token); err != nil {
+		log.WithError(err).Error("failed to delete share link")
+		renderInternalServerError(w, r)
+		return
+	}
+	s.recordActivity(r, yl, model.ActionShare, "shareLink", "", shareLinkActivityFields(sl), nil)
+
+	out := DeleteListShareLinkOutput{Token: token}
+	log.Debug("share link deleted")
+	render(w, r, http.StatusOK, out)
+}
+
+type GetSharedListOutput struct {
+	List  model.YataList
+	Items []model.YataItem
+}
+
+// GetSharedList returns the list, and its items, that a share link points to.
+// It is called without authenticating so the user IDs of the list's owner and members, including those of the items'
+// assignees, are left out of the output.
+func (s *Server) GetSharedList(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	log.Debug("get shared list called")
+
+	sl, err := s.Ydb.GetShareLink(mux.Vars(r)["token"])
+	if err == nil && sl.ExpiresAt != nil && !sl.ExpiresAt.After(time.Now()) {
+		// Expired share links are indistinguishable from share links that do not exist.
+		err = database.ShareLinkNotFoundError{}
+	}
+	if err != nil {
+		if errnf, ok := err.(database.ShareLinkNotFoundError); ok {
+			log.WithError(errnf).Info("share link not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ShareLinkDoesNotExist", Message: "Share link does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get share link")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	yl, err := s.Ydb.GetList(sl.UserID, sl.ListID)
+	if err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ShareLinkDoesNotExist", Message: "Share link does not exist"})
+			return
+		}
+		log.WithError(err).Error("failed to get list")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	items, err := s.Ydb.GetListItems(sl.UserID, sl.ListID)
+	if err != nil {
+		log.WithError(err).Error("failed to get list items")
+		renderInternalServerError(w, r)
+		return
+	}
+	rollUpCompletion(items)
+	stripNotes(items)
+
+	yl.UserID = ""
+	for i := range items {
+		items[i].UserID = ""
+		items[i].AssigneeID = ""
+	}
+	out := GetSharedListOutput{List: yl, Items: items}
+	log.WithField("output", out).Debug("shared list retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+// newShareToken returns a new random, URL safe, share link token.
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// shareLinkActivityFields returns the fields of a share link to record in the activity log.
+// The token is left out; everyone who can see the list's activity could otherwise use it.
+func shareLinkActivityFields(sl model.YataShareLink) map[string]interface{} {
+	return map[string]interface{}{
+		"CreatedAt": sl.CreatedAt,
+		"ExpiresAt": sl.ExpiresAt,
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertListShareLinkInput_Validate(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := map[string]struct {
+		input InsertListShareLinkInput
+		err   error
+	}{
+		"never-expires": {
+			input: InsertListShareLinkInput{},
+		},
+		"expires-in-the-future": {
+			input: InsertListShareLinkInput{ExpiresAt: &future},
+		},
+		"expires-now": {
+			input: InsertListShareLinkInput{ExpiresAt: &now},
+			err:   errors.New("ExpiresAt must be in the future"),
+		},
+		"expires-in-the-past": {
+			input: InsertListShareLinkInput{ExpiresAt: &past},
+			err:   errors.New("ExpiresAt must be in the future"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate(now))
+		})
+	}
+}
+
+func TestServer_GetSharedList(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	links := map[string]model.YataShareLink{
+		"valid":   {Token: "valid", UserID: "owner", ListID: "ID"},
+		"expires": {Token: "expires", UserID: "owner", ListID: "ID", ExpiresAt: &future},
+		"expired": {Token: "expired", UserID: "owner", ListID: "ID", ExpiresAt: &past},
+		"deleted": {Token: "deleted", UserID: "owner", ListID: "gone"},
+	}
+	ydb := mockYdb{
+		MockGetShareLink: func(token string) (model.YataShareLink, error) {
+			if sl, ok := links[token]; ok {
+				return sl, nil
+			}
+			return model.YataShareLink{}, database.ShareLinkNotFoundError{}
+		},
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			if lid == "ID" {
+				return model.YataList{UserID: id, ListID: lid, Title: "Title"}, nil
+			}
+			return model.YataList{}, database.ListNotFoundError{}
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return []model.YataItem{{UserID: id, ListID: lid, ItemID: "item", Content: "Content", Notes: "Notes", AssigneeID: "member"}}, nil
+		},
+	}
+	// Neither the owner's user ID nor the assignee's is returned.
+	sharedList := "{\"List\":{\"UserID\":\"\",\"ListID\":\"ID\",\"Title\":\"Title\"},\"Items\":[{\"UserID\":\"\",\"ListID\":\"ID\",\"ItemID\":\"item\",\"Content\":\"Content\",\"Completed\":false,\"Priority\":0,\"CreatedAt\":\"0001-01-01T00:00:00Z\"}]}\n"
+	notFound := "{\"Code\":\"ShareLinkDoesNotExist\",\"Message\":\"Share link does not exist\"}\n"
+
+	tests := map[string]struct {
+		token   string
+		outCode int
+		outBody string
+	}{
+		"valid": {
+			token:   "valid",
+			outCode: http.StatusOK,
+			outBody: sharedList,
+		},
+		"not-yet-expired": {
+			token:   "expires",
+			outCode: http.StatusOK,
+			outBody: sharedList,
+		},
+		"expired": {
+			token:   "expired",
+			outCode: http.StatusNotFound,
+			outBody: notFound,
+		},
+		"unknown": {
+			token:   "unknown",
+			outCode: http.StatusNotFound,
+			outBody: notFound,
+		},
+		"list-deleted": {
+			token:   "deleted",
+			outCode: http.StatusNotFound,
+			outBody: notFound,
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			// No Authorization header; the route must not require authentication.
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/shared/"+test.token, nil)
+
+			srvr := Server{Ydb: ydb}
+			srvr.Router().ServeHTTP(rec, req)
+
+			assert.Equal(t, test.outCode, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+		})
+	}
+}
+
+func TestServer_Router_RequiresAuthentication(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://does.not/lists", nil)
+
+	srvr := Server{Ydb: mockYdb{}}
+	srvr.Router().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "Authorization Missing", rec.Body.String())
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+)
+
+// roleRanks orders roles from least to most privileged.
+var roleRanks = map[model.Role]int{
+	model.RoleViewer: 1,
+	model.RoleEditor: 2,
+	model.RoleOwner:  3,
+}
+
+// roleAllows returns true if a user with the role has at least the privileges of min.
+func roleAllows(role, min model.Role) bool {
+	return roleRanks[role] >= roleRanks[min]
+}
+
+// authorizeList returns the list with the ID lid and the caller's role on it if the caller has at least the role min.
+// Lists shared with the caller are addressed by passing the ID of the list's owner in the "owner" query parameter;
+// without it the caller's own list is used.
+// If the list cannot be returned an error response is rendered and false is returned.
+func (s *Server) authorizeList(w http.ResponseWriter, r *http.Request, lid model.ListID, min model.Role) (model.YataList, model.Role, bool) {
+	return s.authorizeOwnedList(w, r, model.UserID(r.URL.Query().Get("owner")), lid, min)
+}
+
+// authorizeOwnedList is like authorizeList but the list's owner is given by owner, which defaults to the caller if it
+// is empty.
+func (s *Server) authorizeOwnedList(w http.ResponseWriter, r *http.Request, owner model.UserID, lid model.ListID, min model.Role) (model.YataList, model.Role, bool) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return model.YataList{}, "", false
+	}
+
+	role := model.RoleOwner
+	if len(owner) == 0 {
+		owner = uid
+	}
+	if owner != uid {
+		m, err := s.Ydb.GetListMember(owner, lid, uid)
+		if err != nil {
+			if errnf, ok := err.(database.MemberNotFoundError); ok {
+				// Lists the caller is not a member of are indistinguishable from lists that do not exist.
+				log.WithError(errnf).Info("member not found")
+				render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+				return model.YataList{}, "", false
+			}
+			log.WithError(err).Error("failed to get list member")
+			renderInternalServerError(w, r)
+			return model.YataList{}, "", false
+		}
+		role = m.Role
+	}
+
+	yl, err := s.Ydb.GetList(owner, lid)
+	if err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusNotFound, responseError{Code: "ListDoesNotExist", Message: "List does not exist"})
+			return model.YataList{}, "", false
+		}
+		log.WithError(err).Error("failed to get list")
+		renderInternalServerError(w, r)
+		return model.YataList{}, "", false
+	}
+
+	if !roleAllows(role, min) {
+		log.WithField("role", role).WithField("requiredRole", min).Info("caller does not have the required role")
+		renderForbidden(w, r, "You do not have permission to do that to this list")
+		return model.YataList{}, "", false
+	}
+	return yl, role, true
+}
+
+// isListMember returns true if the user is the list's owner or one of its members.
+func (s *Server) isListMember(yl model.YataList, uid model.UserID) (bool, error) {
+	if uid == yl.UserID {
+		return true, nil
+	}
+	if _, err := s.Ydb.GetListMember(yl.UserID, yl.ListID, uid); err != nil {
+		if _, ok := err.(database.MemberNotFoundError); ok {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get list member: %v", err)
+	}
+	return true, nil
+}
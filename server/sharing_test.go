@@ -0,0 +1,167 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_authorizeList(t *testing.T) {
+	getList := func(id model.UserID, lid model.ListID) (model.YataList, error) {
+		if id == "owner" && lid == "ID" {
+			return model.YataList{UserID: "owner", ListID: "ID", Title: "Title"}, nil
+		}
+		return model.YataList{}, database.ListNotFoundError{}
+	}
+	getListMember := func(owner model.UserID, lid model.ListID, member model.UserID) (model.YataListMember, error) {
+		switch member {
+		case "editor":
+			return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleEditor}, nil
+		case "viewer":
+			return model.YataListMember{OwnerID: owner, ListID: lid, UserID: member, Role: model.RoleViewer}, nil
+		case "broken":
+			return model.YataListMember{}, errors.New("boom")
+		}
+		return model.YataListMember{}, database.MemberNotFoundError{}
+	}
+
+	tests := map[string]struct {
+		caller  string
+		url     string
+		min     model.Role
+		ok      bool
+		role    model.Role
+		outCode int
+		outBody string
+	}{
+		"owner-without-owner-param": {
+			caller: "owner",
+			url:    "https://does.not/matter",
+			min:    model.RoleOwner,
+			ok:     true,
+			role:   model.RoleOwner,
+		},
+		"owner-with-owner-param": {
+			caller: "owner",
+			url:    "https://does.not/matter?owner=owner",
+			min:    model.RoleOwner,
+			ok:     true,
+			role:   model.RoleOwner,
+		},
+		"own-list-does-not-exist": {
+			caller:  "editor",
+			url:     "https://does.not/matter",
+			min:     model.RoleViewer,
+			outCode: http.StatusNotFound,
+			outBody: "{\"Code\":\"ListDoesNotExist\",\"Message\":\"List does not exist\"}\n",
+		},
+		"editor-editing": {
+			caller: "editor",
+			url:    "https://does.not/matter?owner=owner",
+			min:    model.RoleEditor,
+			ok:     true,
+			role:   model.RoleEditor,
+		},
+		"viewer-viewing": {
+			caller: "viewer",
+			url:    "https://does.not/matter?owner=owner",
+			min:    model.RoleViewer,
+			ok:     true,
+			role:   model.RoleViewer,
+		},
+		"viewer-editing": {
+			caller:  "viewer",
+			url:     "https://does.not/matter?owner=owner",
+			min:     model.RoleEditor,
+			outCode: http.StatusForbidden,
+			outBody: "{\"Code\":\"Forbidden\",\"Message\":\"You do not have permission to do that to this list\"}\n",
+		},
+		"editor-sharing": {
+			caller:  "editor",
+			url:     "https://does.not/matter?owner=owner",
+			min:     model.RoleOwner,
+			outCode: http.StatusForbidden,
+			outBody: "{\"Code\":\"Forbidden\",\"Message\":\"You do not have permission to do that to this list\"}\n",
+		},
+		"not-a-member": {
+			caller:  "stranger",
+			url:     "https://does.not/matter?owner=owner",
+			min:     model.RoleViewer,
+			outCode: http.StatusNotFound,
+			outBody: "{\"Code\":\"ListDoesNotExist\",\"Message\":\"List does not exist\"}\n",
+		},
+		"member-lookup-error": {
+			caller:  "broken",
+			url:     "https://does.not/matter?owner=owner",
+			min:     model.RoleViewer,
+			outCode: http.StatusInternalServerError,
+			outBody: "{\"Code\":\"InternalServerError\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+			srvr := Server{Ydb: mockYdb{MockGetList: getList, MockGetListMember: getListMember}}
+
+			yl, role, ok := srvr.authorizeList(rec, req.WithContext(request.WithUserID(req.Context(), test.caller)), "ID", test.min)
+
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.role, role)
+			if test.ok {
+				assert.Equal(t, model.UserID("owner"), yl.UserID)
+				assert.Empty(t, rec.Body.String())
+			} else {
+				assert.Equal(t, test.outCode, rec.Code)
+				assert.Equal(t, test.outBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestInsertListMemberInput_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input InsertListMemberInput
+		err   error
+	}{
+		"validate-input": {
+			input: InsertListMemberInput{UserID: "ID", Role: "editor"},
+		},
+		"user-id-empty": {
+			input: InsertListMemberInput{UserID: ""},
+			err:   errors.New("UserID cannot be empty"),
+		},
+		"user-id-with-colon": {
+			input: InsertListMemberInput{UserID: "a:b"},
+			err:   errors.New("UserID cannot contain \":\""),
+		},
+		"role-owner": {
+			input: InsertListMemberInput{UserID: "ID", Role: "owner"},
+			err:   errors.New("Role must be either \"editor\" or \"viewer\""),
+		},
+		"role-empty": {
+			input: InsertListMemberInput{UserID: "ID"},
+			err:   errors.New("Role must be either \"editor\" or \"viewer\""),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, test.input.Validate())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+)
+
+// itemSortKey is a single field of a "sort" query parameter.
+type itemSortKey struct {
+	field      string
+	descending bool
+}
+
+// itemSortFields maps the names accepted in a "sort" query parameter to a comparison of that field.
+// A comparison returns a negative number when a sorts before b, a positive number when b sorts before a, and zero when they are equal.
+var itemSortFields = map[string]func(a, b model.YataItem) int{
+	"priority": func(a, b model.YataItem) int {
+		return a.Priority - b.Priority
+	},
+	"dueAt": func(a, b model.YataItem) int {
+		// Items without a due date are treated as due after every item that has one.
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+			return 0
+		case a.DueAt == nil:
+			return 1
+		case b.DueAt == nil:
+			return -1
+		}
+		return compareTimes(*a.DueAt, *b.DueAt)
+	},
+	"createdAt": func(a, b model.YataItem) int {
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	},
+	"content": func(a, b model.YataItem) int {
+		return strings.Compare(a.Content, b.Content)
+	},
+	"itemID": func(a, b model.YataItem) int {
+		return strings.Compare(string(a.ItemID), string(b.ItemID))
+	},
+}
+
+// parseItemSort parses a "sort" query parameter such as "priority,-dueAt,createdAt".
+// Fields are applied in order; a field prefixed with "-" is sorted in descending order.
+// An empty parameter returns no keys.
+func parseItemSort(param string) ([]itemSortKey, error) {
+	if len(param) == 0 {
+		return nil, nil
+	}
+	var keys []itemSortKey
+	seen := map[string]bool{}
+	for _, f := range strings.Split(param, ",") {
+		key := itemSortKey{field: f}
+		if strings.HasPrefix(f, "-") {
+			key.field = strings.TrimPrefix(f, "-")
+			key.descending = true
+		}
+		if _, ok := itemSortFields[key.field]; !ok {
+			return nil, fmt.Errorf("cannot sort by %q", f)
+		}
+		if seen[key.field] {
+			return nil, fmt.Errorf("cannot sort by %q more than once", key.field)
+		}
+		seen[key.field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortItems sorts items in place by keys.
+// Items that compare equal on every key keep their original relative order.
+func sortItems(items []model.YataItem, keys []itemSortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, key := range keys {
+			c := itemSortFields[key.field](items[i], items[j])
+			if key.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
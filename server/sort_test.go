@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseItemSort(t *testing.T) {
+	tests := map[string]struct {
+		param string
+		keys  []itemSortKey
+		err   error
+	}{
+		"empty": {
+			param: "",
+		},
+		"single-field": {
+			param: "priority",
+			keys:  []itemSortKey{{field: "priority"}},
+		},
+		"multiple-fields": {
+			param: "priority,-dueAt,createdAt",
+			keys: []itemSortKey{
+				{field: "priority"},
+				{field: "dueAt", descending: true},
+				{field: "createdAt"},
+			},
+		},
+		"unknown-field": {
+			param: "priority,-color",
+			err:   errors.New("cannot sort by \"-color\""),
+		},
+		"empty-field": {
+			param: "priority,",
+			err:   errors.New("cannot sort by \"\""),
+		},
+		"repeated-field": {
+			param: "priority,-priority",
+			err:   errors.New("cannot sort by \"priority\" more than once"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			keys, err := parseItemSort(test.param)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.keys, keys)
+		})
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	items := []model.YataItem{
+		{ItemID: "a", Priority: 1, DueAt: &t1, CreatedAt: t2},
+		{ItemID: "b", Priority: 3, CreatedAt: t1},
+		{ItemID: "c", Priority: 1, DueAt: &t2, CreatedAt: t1},
+		{ItemID: "d", Priority: 1, CreatedAt: t1},
+		{ItemID: "e", Priority: 3, DueAt: &t1, CreatedAt: t2},
+	}
+
+	tests := map[string]struct {
+		param string
+		order []model.ItemID
+	}{
+		"no-keys-keeps-order": {
+			param: "",
+			order: []model.ItemID{"a", "b", "c", "d", "e"},
+		},
+		"priority-is-stable": {
+			param: "priority",
+			order: []model.ItemID{"a", "c", "d", "b", "e"},
+		},
+		"priority-descending-then-due-at": {
+			param: "-priority,dueAt",
+			order: []model.ItemID{"e", "b", "a", "c", "d"},
+		},
+		"priority-then-due-at-descending-then-created-at": {
+			param: "priority,-dueAt,createdAt",
+			order: []model.ItemID{"d", "c", "a", "b", "e"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			keys, err := parseItemSort(test.param)
+			assert.NoError(t, err)
+
+			sorted := append([]model.YataItem(nil), items...)
+			sortItems(sorted, keys)
+
+			var order []model.ItemID
+			for _, item := range sorted {
+				order = append(order, item.ItemID)
+			}
+			assert.Equal(t, test.order, order)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TheYeung1/yata-server/model"
+)
+
+// maxItemNesting is how many levels of sub-tasks an item can have below it.
+const maxItemNesting = 2
+
+// errItemNestedTooDeep is returned when a sub-task would be nested more than maxItemNesting levels deep.
+var errItemNestedTooDeep = fmt.Errorf("items cannot be nested more than %d levels deep", maxItemNesting)
+
+// itemRef uniquely identifies an item. Lists are keyed by their owner so items of different users' lists can share a list
+// ID.
+type itemRef struct {
+	UserID model.UserID
+	ListID model.ListID
+	ItemID model.ItemID
+}
+
+func refOf(item model.YataItem) itemRef {
+	return itemRef{UserID: item.UserID, ListID: item.ListID, ItemID: item.ItemID}
+}
+
+// validateItemNesting returns an error if writing item would leave the items of its list with a missing parent, a cycle,
+// or sub-tasks nested too deeply.
+// items must contain every item on item's list; an existing copy of item in items is ignored.
+func validateItemNesting(items []model.YataItem, item model.YataItem) error {
+	byID := map[model.ItemID]model.YataItem{}
+	for _, i := range items {
+		if i.ItemID != item.ItemID {
+			byID[i.ItemID] = i
+		}
+	}
+
+	depth := 0
+	for pid := item.ParentID; len(pid) != 0; pid = byID[pid].ParentID {
+		if pid == item.ItemID {
+			return errors.New("an item cannot be its own ancestor")
+		}
+		if _, ok := byID[pid]; !ok {
+			return fmt.Errorf("parent item %q does not exist", pid)
+		}
+		depth++
+		if depth > maxItemNesting {
+			return errItemNestedTooDeep
+		}
+	}
+
+	if depth+subtreeHeight(childrenByParent(items), item.ItemID, 0) > maxItemNesting {
+		return errItemNestedTooDeep
+	}
+	return nil
+}
+
+// subtreeHeight returns how many levels of sub-tasks are below the item with the ID iid.
+// The search stops once it goes deeper than maxItemNesting so that malformed data cannot cause unbounded recursion.
+func subtreeHeight(children map[model.ItemID][]model.YataItem, iid model.ItemID, depth int) int {
+	if depth > maxItemNesting {
+		return 0
+	}
+	height := 0
+	for _, child := range children[iid] {
+		if h := 1 + subtreeHeight(children, child.ItemID, depth+1); h > height {
+			height = h
+		}
+	}
+	return height
+}
+
+// childrenByParent groups items of a single list by their parent's ID.
+func childrenByParent(items []model.YataItem) map[model.ItemID][]model.YataItem {
+	children := map[model.ItemID][]model.YataItem{}
+	for _, item := range items {
+		if len(item.ParentID) != 0 {
+			children[item.ParentID] = append(children[item.ParentID], item)
+		}
+	}
+	return children
+}
+
+// itemDescendants returns every sub-task below the item with the ID iid, deepest first.
+// items must contain every item on the item's list.
+func itemDescendants(items []model.YataItem, iid model.ItemID) []model.YataItem {
+	children := childrenByParent(items)
+	var walk func(iid model.ItemID, depth int) []model.YataItem
+	walk = func(iid model.ItemID, depth int) []model.YataItem {
+		if depth > maxItemNesting {
+			return nil
+		}
+		var descendants []model.YataItem
+		for _, child := range children[iid] {
+			descendants = append(descendants, walk(child.ItemID, depth+1)...)
+			descendants = append(descendants, child)
+		}
+		return descendants
+	}
+	return walk(iid, 0)
+}
+
+// rollUpCompletion marks every item that has sub-tasks as completed if, and only if, all of its sub-tasks are completed.
+// items may span multiple lists, including those of different owners.
+func rollUpCompletion(items []model.YataItem) {
+	children := map[itemRef][]int{}
+	for i, item := range items {
+		if len(item.ParentID) != 0 {
+			parent := itemRef{UserID: item.UserID, ListID: item.ListID, ItemID: item.ParentID}
+			children[parent] = append(children[parent], i)
+		}
+	}
+
+	done := map[int]bool{}
+	var rollUp func(i, depth int) bool
+	rollUp = func(i, depth int) bool {
+		if done[i] || depth > maxItemNesting {
+			return items[i].Completed
+		}
+		done[i] = true
+		kids := children[refOf(items[i])]
+		if len(kids) == 0 {
+			return items[i].Completed
+		}
+		completed := true
+		for _, k := range kids {
+			// Every child has to be visited so that its own roll-up is applied.
+			if !rollUp(k, depth+1) {
+				completed = false
+			}
+		}
+		items[i].Completed = completed
+		return completed
+	}
+	for i := range items {
+		rollUp(i, 0)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateItemNesting(t *testing.T) {
+	// root -> child -> grandchild, and a lone item.
+	items := []model.YataItem{
+		{ItemID: "root"},
+		{ItemID: "child", ParentID: "root"},
+		{ItemID: "grandchild", ParentID: "child"},
+		{ItemID: "lone"},
+	}
+
+	tests := map[string]struct {
+		item model.YataItem
+		err  error
+	}{
+		"top-level-item": {
+			item: model.YataItem{ItemID: "new"},
+		},
+		"child-of-root": {
+			item: model.YataItem{ItemID: "new", ParentID: "root"},
+		},
+		"child-of-child": {
+			item: model.YataItem{ItemID: "new", ParentID: "child"},
+		},
+		"child-of-grandchild": {
+			item: model.YataItem{ItemID: "new", ParentID: "grandchild"},
+			err:  errItemNestedTooDeep,
+		},
+		"missing-parent": {
+			item: model.YataItem{ItemID: "new", ParentID: "nope"},
+			err:  errors.New("parent item \"nope\" does not exist"),
+		},
+		"moving-subtree-under-lone-item": {
+			item: model.YataItem{ItemID: "child", ParentID: "lone"},
+		},
+		"moving-deep-subtree-under-lone-item": {
+			item: model.YataItem{ItemID: "root", ParentID: "lone"},
+			err:  errItemNestedTooDeep,
+		},
+		"moving-item-under-its-descendant": {
+			item: model.YataItem{ItemID: "root", ParentID: "grandchild"},
+			err:  errors.New("an item cannot be its own ancestor"),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.err, validateItemNesting(items, test.item))
+		})
+	}
+}
+
+func TestItemDescendants(t *testing.T) {
+	items := []model.YataItem{
+		{ItemID: "root"},
+		{ItemID: "child1", ParentID: "root"},
+		{ItemID: "grandchild", ParentID: "child1"},
+		{ItemID: "child2", ParentID: "root"},
+		{ItemID: "other"},
+	}
+
+	var ids []model.ItemID
+	for _, item := range itemDescendants(items, "root") {
+		ids = append(ids, item.ItemID)
+	}
+	assert.Equal(t, []model.ItemID{"grandchild", "child1", "child2"}, ids)
+	assert.Empty(t, itemDescendants(items, "other"))
+}
+
+func TestRollUpCompletion(t *testing.T) {
+	items := []model.YataItem{
+		{UserID: "me", ListID: "l1", ItemID: "done-parent"},
+		{UserID: "me", ListID: "l1", ItemID: "c1", ParentID: "done-parent", Completed: true},
+		{UserID: "me", ListID: "l1", ItemID: "c2", ParentID: "done-parent"},
+		{UserID: "me", ListID: "l1", ItemID: "gc", ParentID: "c2", Completed: true},
+		{UserID: "me", ListID: "l1", ItemID: "open-parent", Completed: true},
+		{UserID: "me", ListID: "l1", ItemID: "c3", ParentID: "open-parent"},
+		{UserID: "me", ListID: "l2", ItemID: "done-parent", Completed: true},
+		// Another user's list with the same ID, as merged in from lists shared with the caller.
+		{UserID: "owner", ListID: "l1", ItemID: "done-parent", Completed: true},
+		{UserID: "owner", ListID: "l1", ItemID: "c1", ParentID: "done-parent"},
+	}
+
+	rollUpCompletion(items)
+
+	completed := map[itemRef]bool{}
+	for _, item := range items {
+		completed[refOf(item)] = item.Completed
+	}
+	assert.Equal(t, map[itemRef]bool{
+		{UserID: "me", ListID: "l1", ItemID: "done-parent"}:    true,
+		{UserID: "me", ListID: "l1", ItemID: "c1"}:             true,
+		{UserID: "me", ListID: "l1", ItemID: "c2"}:             true,
+		{UserID: "me", ListID: "l1", ItemID: "gc"}:             true,
+		{UserID: "me", ListID: "l1", ItemID: "open-parent"}:    false,
+		{UserID: "me", ListID: "l1", ItemID: "c3"}:             false,
+		{UserID: "me", ListID: "l2", ItemID: "done-parent"}:    true,
+		{UserID: "owner", ListID: "l1", ItemID: "done-parent"}: false,
+		{UserID: "owner", ListID: "l1", ItemID: "c1"}:          false,
+	}, completed)
+}
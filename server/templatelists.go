@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+)
+
+type TemplateListOutput struct {
+	ListID   model.ListID
+	Template bool
+}
+
+// MarkListTemplate makes a list a template, hiding it from list views unless templates are asked for. Only the list's
+// owner can mark it.
+func (s *Server) MarkListTemplate(w http.ResponseWriter, r *http.Request) {
+	s.setListFlag(w, r, templateFlag, true)
+}
+
+// UnmarkListTemplate makes a template an ordinary list again. Only the list's owner can unmark it.
+func (s *Server) UnmarkListTemplate(w http.ResponseWriter, r *http.Request) {
+	s.setListFlag(w, r, templateFlag, false)
+}
+
+var templateFlag = listFlag{
+	attr:  "Template",
+	field: func(yl *model.YataList) *bool { return &yl.Template },
+	write: database.YataDatabase.SetListTemplate,
+	output: func(lid model.ListID, value bool) interface{} {
+		return TemplateListOutput{ListID: lid, Template: value}
+	},
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_SetListTemplate(t *testing.T) {
+	tests := map[string]struct {
+		template bool
+		mark     bool
+		outBody  string
+		set      []bool
+	}{
+		"mark": {
+			mark:    true,
+			outBody: "{\"ListID\":\"onboarding\",\"Template\":true}\n",
+			set:     []bool{true},
+		},
+		"unmark": {
+			template: true,
+			outBody:  "{\"ListID\":\"onboarding\",\"Template\":false}\n",
+			set:      []bool{false},
+		},
+		"already-a-template": {
+			template: true,
+			mark:     true,
+			outBody:  "{\"ListID\":\"onboarding\",\"Template\":true}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var set []bool
+			ydb := mockYdb{
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					return model.YataList{UserID: id, ListID: lid, Template: test.template}, nil
+				},
+				MockSetListTemplate: func(id model.UserID, lid model.ListID, template bool) error {
+					set = append(set, template)
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/matter", nil)
+			req = mux.SetURLVars(req, map[string]string{"listID": "onboarding"})
+			req = req.WithContext(request.WithUserID(req.Context(), "me"))
+
+			srvr := Server{Ydb: ydb}
+			if test.mark {
+				srvr.MarkListTemplate(rec, req)
+			} else {
+				srvr.UnmarkListTemplate(rec, req)
+			}
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.set, set)
+		})
+	}
+}
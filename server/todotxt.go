@@ -0,0 +1,186 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/TheYeung1/yata-server/todotxt"
+	"github.com/google/uuid"
+)
+
+// mediaTypeTodoTxt is the media type items are read and written as in the todo.txt format.
+const mediaTypeTodoTxt = "text/plain"
+
+// itemTask returns item as a todo.txt task. Tags that start with "@" become contexts and other tags become projects.
+// The item's ID is kept in an "id" tag so that importing the task again updates the item. Notes, sections, sub-tasks and
+// assignees have no place in todo.txt and are left out; see keepTodoTxtItemFields.
+func itemTask(item model.YataItem) todotxt.Task {
+	t := todotxt.Task{
+		Completed: item.Completed,
+		CreatedOn: item.CreatedAt.UTC(),
+		Text:      item.Content,
+		Tags:      map[string]string{"id": url.QueryEscape(string(item.ItemID))},
+	}
+	if p := todoTxtPriority(item.Priority); p != 0 {
+		// Completed tasks keep their priority as a tag, as todo.txt clients do.
+		if item.Completed {
+			t.Tags["pri"] = string(p)
+		} else {
+			t.Priority = p
+		}
+	}
+	if item.DueAt != nil {
+		t.Tags["due"] = item.DueAt.UTC().Format(todotxt.DateLayout)
+	}
+	for _, tag := range item.Tags {
+		if len(tag) > 1 && tag[0] == '@' {
+			t.Contexts = append(t.Contexts, tag[1:])
+		} else {
+			t.Projects = append(t.Projects, tag)
+		}
+	}
+	return t
+}
+
+// taskInput returns the input to insert a todo.txt task as an item; the reverse of itemTask. Tasks without an "id" tag
+// are given the ID id. Tags other than "id", "pri" and "due" are kept in the item's content.
+func taskInput(t todotxt.Task, id string) InsertListItemInput {
+	in := InsertListItemInput{ItemID: id, Completed: t.Completed, Priority: itemPriority(t.Priority)}
+	if t.Priority == 0 {
+		in.Priority = itemPriority(todoTxtPriorityTag(t.Tags["pri"]))
+	}
+
+	var extra []string
+	keys := make([]string, 0, len(t.Tags))
+	for k := range t.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := t.Tags[k]
+		switch k {
+		case "id":
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				in.ItemID = unescaped
+				continue
+			}
+		case "pri":
+			if todoTxtPriorityTag(v) != 0 {
+				continue
+			}
+		case "due":
+			if due, err := time.Parse(todotxt.DateLayout, v); err == nil {
+				in.DueAt = &due
+				continue
+			}
+		}
+		extra = append(extra, k+":"+v)
+	}
+	in.Content = strings.Join(append([]string{t.Text}, extra...), " ")
+	in.Content = strings.TrimSpace(in.Content)
+
+	seen := map[string]bool{}
+	addTag := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			in.Tags = append(in.Tags, tag)
+		}
+	}
+	for _, p := range t.Projects {
+		addTag(p)
+	}
+	for _, c := range t.Contexts {
+		addTag("@" + c)
+	}
+	return in
+}
+
+// todoTxtPriority returns the todo.txt priority of an item's priority; 'A' for the highest. It is zero for items
+// without a priority.
+func todoTxtPriority(p int) byte {
+	if p <= 0 || p > model.MaxPriority {
+		return 0
+	}
+	return byte('A' + model.MaxPriority - p)
+}
+
+// itemPriority returns the item priority of a todo.txt priority. Priorities lower than the lowest item priority get
+// the lowest item priority.
+func itemPriority(p byte) int {
+	if p < 'A' || p > 'Z' {
+		return 0
+	}
+	if pri := model.MaxPriority - int(p-'A'); pri > 1 {
+		return pri
+	}
+	return 1
+}
+
+// todoTxtPriorityTag returns the priority of a "pri" tag, or zero if it is not a priority.
+func todoTxtPriorityTag(v string) byte {
+	if len(v) != 1 || v[0] < 'A' || v[0] > 'Z' {
+		return 0
+	}
+	return v[0]
+}
+
+// bindTodoTxtItems reads the input to insert each task in r. Tasks without an ID are given a random one.
+func bindTodoTxtItems(r io.Reader) ([]InsertListItemInput, error) {
+	tasks, err := todotxt.ParseAll(r)
+	if err != nil {
+		return nil, err
+	}
+	input := make([]InsertListItemInput, 0, len(tasks))
+	for _, t := range tasks {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, taskInput(t, id.String()))
+	}
+	return input, nil
+}
+
+// keepTodoTxtItemFields returns the input of todo.txt tasks with the fields todo.txt has no place for, such as notes,
+// parents, sections and assignees, set to those of the existing items the tasks update, so that importing tasks that
+// were exported from a list leaves those fields as they are.
+func keepTodoTxtItemFields(input []InsertListItemInput, items []model.YataItem) []InsertListItemInput {
+	existing := map[string]model.YataItem{}
+	for _, item := range items {
+		existing[string(item.ItemID)] = item
+	}
+	kept := make([]InsertListItemInput, len(input))
+	for i, in := range input {
+		item, ok := existing[in.ItemID]
+		if !ok {
+			kept[i] = in
+			continue
+		}
+		kept[i] = itemInput(item)
+		kept[i].Content = in.Content
+		kept[i].Completed = in.Completed
+		kept[i].Priority = in.Priority
+		kept[i].DueAt = in.DueAt
+		kept[i].Tags = in.Tags
+	}
+	return kept
+}
+
+// renderTodoTxt writes the response code, sets the content type for todo.txt, and writes items to w as tasks.
+func renderTodoTxt(w http.ResponseWriter, r *http.Request, code int, items []model.YataItem) {
+	tasks := make([]todotxt.Task, len(items))
+	for i, item := range items {
+		tasks[i] = itemTask(item)
+	}
+	w.Header().Set("Content-Type", mediaTypeTodoTxt+"; charset=utf-8")
+	w.WriteHeader(code)
+	if err := todotxt.WriteAll(w, tasks); err != nil {
+		request.Logger(r.Context()).WithError(err).Warn("failed to render todo.txt")
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/TheYeung1/yata-server/todotxt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemTask(t *testing.T) {
+	created := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2021, 1, 5, 9, 30, 0, 0, time.UTC)
+	tests := map[string]struct {
+		item model.YataItem
+		line string
+	}{
+		"everything": {
+			item: model.YataItem{ItemID: "call mom", Content: "Call Mom", Priority: 3, DueAt: &due, Tags: []string{"family", "@phone"}, CreatedAt: created},
+			line: "(A) 2021-01-01 Call Mom +family @phone due:2021-01-05 id:call+mom",
+		},
+		"completed-keeps-priority-as-tag": {
+			item: model.YataItem{ItemID: "1", Content: "Pay rent", Completed: true, Priority: 1, CreatedAt: created},
+			line: "x Pay rent id:1 pri:C",
+		},
+		"no-priority": {
+			item: model.YataItem{ItemID: "a:b/c", Content: "Milk", CreatedAt: created},
+			line: "2021-01-01 Milk id:a%3Ab%2Fc",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.line, itemTask(test.item).String())
+		})
+	}
+}
+
+func TestTaskInput(t *testing.T) {
+	due := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		line  string
+		input InsertListItemInput
+	}{
+		"everything": {
+			line:  "(A) 2021-01-01 Call Mom +family @phone @phone due:2021-01-05 id:call+mom",
+			input: InsertListItemInput{ItemID: "call mom", Content: "Call Mom", Priority: 3, DueAt: &due, Tags: []string{"family", "@phone"}},
+		},
+		"completed-with-priority-tag": {
+			line:  "x 2021-01-03 Pay rent pri:B",
+			input: InsertListItemInput{ItemID: "new", Content: "Pay rent", Completed: true, Priority: 2},
+		},
+		"low-priority": {
+			line:  "(Q) Someday",
+			input: InsertListItemInput{ItemID: "new", Content: "Someday", Priority: 1},
+		},
+		"other-tags-stay-in-content": {
+			line:  "Water plants rec:1w due:soon",
+			input: InsertListItemInput{ItemID: "new", Content: "Water plants due:soon rec:1w"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			task, ok := todotxt.Parse(test.line)
+			require.True(t, ok)
+			assert.Equal(t, test.input, taskInput(task, "new"))
+		})
+	}
+}
+
+func TestServer_GetListItems_TodoTxt(t *testing.T) {
+	ydb := mockYdb{
+		MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+			return model.YataList{UserID: id, ListID: lid}, nil
+		},
+		MockGetListItems: func(id model.UserID, lid model.ListID) ([]model.YataItem, error) {
+			return []model.YataItem{
+				{UserID: id, ListID: lid, ItemID: "2", Content: "Bread", Completed: true},
+				{UserID: id, ListID: lid, ItemID: "1", Content: "Milk", Priority: 2, Tags: []string{"dairy"}},
+			}, nil
+		},
+		MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+			return nil, nil
+		},
+	}
+
+	tests := map[string]struct {
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		"todo-txt": {
+			accept:      "text/plain",
+			code:        http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			body:        "x Bread id:2\n(B) Milk +dairy id:1\n",
+		},
+		"prefers-todo-txt": {
+			accept:      "application/json;q=0.5, text/*",
+			code:        http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			body:        "x Bread id:2\n(B) Milk +dairy id:1\n",
+		},
+		"not-acceptable": {
+			accept:      "application/xml",
+			code:        http.StatusNotAcceptable,
+			contentType: "application/json",
+			body:        `{"Code":"NotAcceptable","Message":"Items can be returned as application/json, application/cbor or text/plain"}` + "\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+			req = mux.SetURLVars(req, map[string]string{"listID": "groceries"})
+			req.Header.Set("Accept", test.accept)
+
+			srvr := Server{Ydb: ydb}
+			srvr.GetListItems(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.contentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, test.body, rec.Body.String())
+		})
+	}
+}
+
+func TestBindTodoTxtItems(t *testing.T) {
+	input, err := bindTodoTxtItems(bytes.NewBufferString("Milk\n\n(A) Eggs id:eggs\n"))
+	require.NoError(t, err)
+	require.Len(t, input, 2)
+	assert.Len(t, input[0].ItemID, 36, "tasks without an ID are given a random one")
+	assert.Equal(t, "Milk", input[0].Content)
+	assert.Equal(t, InsertListItemInput{ItemID: "eggs", Content: "Eggs", Priority: 3}, input[1])
+}
+
+func TestKeepTodoTxtItemFields_RoundTrip(t *testing.T) {
+	due := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	items := []model.YataItem{
+		{UserID: "me", ListID: "groceries", ItemID: "milk", SectionID: "dairy", Content: "Milk", Notes: "Oat if there is none", AssigneeID: "them"},
+		{UserID: "me", ListID: "groceries", ItemID: "oat", ParentID: "milk", Content: "Oat milk", Priority: 2, DueAt: &due, Tags: []string{"@shop"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, todotxt.WriteAll(&buf, []todotxt.Task{itemTask(items[0]), itemTask(items[1])}))
+	// The tasks are edited before being imported again, and a new one is added.
+	exported := strings.Replace(buf.String(), "Milk", "x Whole milk", 1) + "Bread\n"
+	input, err := bindTodoTxtItems(strings.NewReader(exported))
+	require.NoError(t, err)
+	input = keepTodoTxtItemFields(input, items)
+
+	require.Len(t, input, 3)
+	// Everything todo.txt has no place for is kept, and the rest is taken from the tasks.
+	assert.Equal(t, InsertListItemInput{ItemID: "milk", SectionID: "dairy", Content: "Whole milk", Notes: "Oat if there is none", Completed: true, AssigneeID: "them"}, input[0])
+	assert.Equal(t, itemInput(items[1]), input[1])
+	assert.Equal(t, "Bread", input[2].Content)
+	assert.Empty(t, input[2].Notes)
+}
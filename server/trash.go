@@ -0,0 +1,405 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTrashRetention is how long lists and items stay in the trash when the server's TrashRetention is not set.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// trashPurgeInterval is how often lists and items whose retention has run out are purged from the trash.
+const trashPurgeInterval = time.Hour
+
+// TrashEntry is a list or item that was deleted, along with everything that was deleted with it.
+type TrashEntry struct {
+	// TrashID identifies the entry when restoring it.
+	TrashID   string
+	DeletedAt time.Time
+	// PurgeAt is when the entry will be permanently deleted.
+	PurgeAt time.Time
+	// Exactly one of List or Item is set.
+	List *model.YataList `json:",omitempty"`
+	Item *model.YataItem `json:",omitempty"`
+}
+
+type GetTrashOutput struct {
+	// Entries are the most recently deleted first.
+	Entries []TrashEntry
+}
+
+// GetTrash returns the caller's trash. It holds the caller's deleted lists and the items deleted from them,
+// whoever deleted them.
+func (s *Server) GetTrash(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("get trash called")
+
+	lists, items, err := s.Ydb.GetTrash(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get trash")
+		renderInternalServerError(w, r)
+		return
+	}
+
+	out := GetTrashOutput{Entries: trashEntries(lists, items, s.trashRetention())}
+	log.WithField("output", out).Debug("trash retrieved")
+	render(w, r, http.StatusOK, out)
+}
+
+type RestoreTrashOutput struct {
+	ListID model.ListID
+	// ItemIDs are the IDs of every restored item.
+	ItemIDs []model.ItemID
+}
+
+// RestoreTrash restores a trash entry, along with everything that was deleted with it.
+func (s *Server) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	log := request.Logger(r.Context())
+	uid, ok := request.UserID(r.Context())
+	if !ok {
+		log.Error("failed to get user ID from request context")
+		renderInternalServerError(w, r)
+		return
+	}
+	log.WithField("userID", uid).Debug("restore trash called")
+
+	ref, err := decodeTrashID(mux.Vars(r)["trashID"])
+	if err != nil {
+		log.WithError(err).Info("failed to decode trash ID")
+		renderTrashEntryNotFound(w, r)
+		return
+	}
+
+	lists, items, err := s.Ydb.GetTrash(uid)
+	if err != nil {
+		log.WithError(err).Error("failed to get trash")
+		renderInternalServerError(w, r)
+		return
+	}
+	var entry *TrashEntry
+	for _, e := range trashEntries(lists, items, s.trashRetention()) {
+		if e.TrashID == encodeTrashID(ref) {
+			e := e
+			entry = &e
+			break
+		}
+	}
+	if entry == nil {
+		log.WithField("trashRef", ref).Info("trash entry not found")
+		renderTrashEntryNotFound(w, r)
+		return
+	}
+
+	if entry.List != nil {
+		s.restoreList(w, r, *entry.List, items)
+		return
+	}
+	s.restoreItem(w, r, *entry.Item, items)
+}
+
+// restoreList restores a list and the items that were deleted along with it. The list is taken out of its folder if
+// the folder was deleted while the list was in the trash.
+func (s *Server) restoreList(w http.ResponseWriter, r *http.Request, yl model.YataList, trashed []model.YataItem) {
+	log := request.Logger(r.Context())
+
+	// The list is restored before its items so that a failure part way through leaves the remaining items in the trash
+	// as entries of their own.
+	if err := s.Ydb.RestoreList(yl.UserID, yl.ListID); err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			renderTrashEntryNotFound(w, r)
+			return
+		}
+		log.WithError(err).Error("failed to restore list")
+		renderInternalServerError(w, r)
+		return
+	}
+	before := yl
+	yl.DeletedAt = nil
+	if len(yl.FolderID) != 0 {
+		// Deleting a folder only takes the lists that are not in the trash out of it.
+		if _, err := s.Ydb.GetFolder(yl.UserID, yl.FolderID); err != nil {
+			if _, ok := err.(database.FolderNotFoundError); !ok {
+				log.WithError(err).Error("failed to get folder")
+				renderInternalServerError(w, r)
+				return
+			}
+			if err := s.Ydb.SetListFolder(yl.UserID, yl.ListID, ""); err != nil {
+				log.WithError(err).Error("failed to set list folder")
+				renderInternalServerError(w, r)
+				return
+			}
+			yl.FolderID = ""
+		}
+	}
+	s.recordActivity(r, yl, model.ActionRestore, "list", string(yl.ListID), before, yl)
+
+	out := RestoreTrashOutput{ListID: yl.ListID, ItemIDs: []model.ItemID{}}
+	for _, item := range trashed {
+		if item.ListID != yl.ListID || !item.DeletedAt.Equal(*before.DeletedAt) {
+			continue
+		}
+		if !s.restoreTrashedItem(w, r, yl, item) {
+			return
+		}
+		out.ItemIDs = append(out.ItemIDs, item.ItemID)
+	}
+
+	log.WithField("output", out).Debug("list restored")
+	render(w, r, http.StatusOK, out)
+}
+
+// restoreItem restores an item and the sub-tasks that were deleted along with it.
+// Items whose list or parent is still in the trash cannot be restored until it is.
+func (s *Server) restoreItem(w http.ResponseWriter, r *http.Request, item model.YataItem, trashed []model.YataItem) {
+	log := request.Logger(r.Context())
+
+	yl, err := s.Ydb.GetList(item.UserID, item.ListID)
+	if err != nil {
+		if errnf, ok := err.(database.ListNotFoundError); ok {
+			log.WithError(errnf).Info("list not found")
+			render(w, r, http.StatusConflict, responseError{Code: "ListInTrash", Message: "The item's list must be restored first"})
+			return
+		}
+		log.WithError(err).Error("failed to get list")
+		renderInternalServerError(w, r)
+		return
+	}
+	if len(item.ParentID) != 0 {
+		if _, err := s.Ydb.GetItem(item.UserID, item.ListID, item.ParentID); err != nil {
+			if errnf, ok := err.(database.ItemNotFoundError); ok {
+				log.WithError(errnf).Info("parent item not found")
+				render(w, r, http.StatusConflict, responseError{Code: "ParentInTrash", Message: "The item's parent must be restored first"})
+				return
+			}
+			log.WithError(err).Error("failed to get parent item")
+			renderInternalServerError(w, r)
+			return
+		}
+	}
+
+	var listItems []model.YataItem
+	for _, t := range trashed {
+		if t.ListID == item.ListID {
+			listItems = append(listItems, t)
+		}
+	}
+	descendants := itemDescendants(listItems, item.ItemID)
+
+	// Parents are restored before their sub-tasks so that a failure part way through never leaves a sub-task whose
+	// parent is in the trash.
+	out := RestoreTrashOutput{ListID: item.ListID, ItemIDs: []model.ItemID{}}
+	for _, restore := range append([]model.YataItem{item}, reverseItems(descendants)...) {
+		if !restore.DeletedAt.Equal(*item.DeletedAt) {
+			continue
+		}
+		if !s.restoreTrashedItem(w, r, yl, restore) {
+			return
+		}
+		out.ItemIDs = append(out.ItemIDs, restore.ItemID)
+	}
+
+	log.WithField("output", out).Debug("item restored")
+	render(w, r, http.StatusOK, out)
+}
+
+// restoreTrashedItem restores a single item. If it cannot be restored an error response is rendered and false is returned.
+func (s *Server) restoreTrashedItem(w http.ResponseWriter, r *http.Request, yl model.YataList, item model.YataItem) bool {
+	log := request.Logger(r.Context())
+	if err := s.Ydb.RestoreItem(item.UserID, item.ListID, item.ItemID); err != nil {
+		if _, ok := err.(database.ItemNotFoundError); ok {
+			// The item was restored, or replaced, after the trash was retrieved.
+			return true
+		}
+		log.WithError(err).WithField("itemID", item.ItemID).Error("failed to restore item")
+		renderInternalServerError(w, r)
+		return false
+	}
+	restored := item
+	restored.DeletedAt = nil
+	s.recordActivity(r, yl, model.ActionRestore, "item", string(item.ItemID), item, restored)
+	return true
+}
+
+// PurgeTrash permanently deletes the lists and items, and their attachments, whose retention ran out before now.
+func (s *Server) PurgeTrash(now time.Time) error {
+	log := logrus.WithField("job", "purgeTrash")
+	lists, items, err := s.Ydb.GetExpiredTrash(now.Add(-s.trashRetention()))
+	if err != nil {
+		return fmt.Errorf("failed to get expired trash: %v", err)
+	}
+
+	// Items are purged before their lists so that a failure part way through never leaves items without a list.
+	for _, item := range items {
+		if err := s.deleteItemAttachments(log, item.UserID, item.ListID, item.ItemID); err != nil {
+			return fmt.Errorf("failed to delete attachments of item %q: %v", item.ItemID, err)
+		}
+		if err := s.Ydb.DeleteItem(item.UserID, item.ListID, item.ItemID); err != nil {
+			return fmt.Errorf("failed to delete item %q: %v", item.ItemID, err)
+		}
+	}
+	for _, yl := range lists {
+		members, err := s.Ydb.GetListMembers(yl.UserID, yl.ListID)
+		if err != nil {
+			return fmt.Errorf("failed to get members of list %q: %v", yl.ListID, err)
+		}
+		for _, m := range members {
+			if err := s.Ydb.DeleteListMember(m.OwnerID, m.ListID, m.UserID); err != nil {
+				return fmt.Errorf("failed to delete member of list %q: %v", yl.ListID, err)
+			}
+		}
+		sections, err := s.Ydb.GetListSections(yl.UserID, yl.ListID)
+		if err != nil {
+			return fmt.Errorf("failed to get sections of list %q: %v", yl.ListID, err)
+		}
+		for _, section := range sections {
+			if err := s.Ydb.DeleteSection(section.UserID, section.ListID, section.SectionID); err != nil {
+				return fmt.Errorf("failed to delete section of list %q: %v", yl.ListID, err)
+			}
+		}
+		links, err := s.Ydb.GetListShareLinks(yl.UserID, yl.ListID)
+		if err != nil {
+			return fmt.Errorf("failed to get share links of list %q: %v", yl.ListID, err)
+		}
+		for _, sl := range links {
+			if err := s.Ydb.DeleteShareLink(sl.Token); err != nil {
+				return fmt.Errorf("failed to delete share link of list %q: %v", yl.ListID, err)
+			}
+		}
+		if err := s.Ydb.DeleteList(yl.UserID, yl.ListID); err != nil {
+			return fmt.Errorf("failed to delete list %q: %v", yl.ListID, err)
+		}
+	}
+
+	log.WithField("lists", len(lists)).WithField("items", len(items)).Info("trash purged")
+	return nil
+}
+
+// purgeTrashEvery purges the trash every interval, forever.
+func (s *Server) purgeTrashEvery(interval time.Duration) {
+	for now := range time.Tick(interval) {
+		if err := s.PurgeTrash(now.UTC()); err != nil {
+			logrus.WithError(err).Error("failed to purge trash")
+		}
+	}
+}
+
+func (s *Server) trashRetention() time.Duration {
+	if s.TrashRetention <= 0 {
+		return DefaultTrashRetention
+	}
+	return s.TrashRetention
+}
+
+// trashEntries groups trashed lists and items into trash entries, most recently deleted first.
+// Items deleted along with their list or parent belong to its entry rather than having entries of their own.
+func trashEntries(lists []model.YataList, items []model.YataItem, retention time.Duration) []TrashEntry {
+	listDeletedAt := map[model.ListID]time.Time{}
+	for _, yl := range lists {
+		listDeletedAt[yl.ListID] = *yl.DeletedAt
+	}
+	itemDeletedAt := map[itemRef]time.Time{}
+	for _, item := range items {
+		itemDeletedAt[refOf(item)] = *item.DeletedAt
+	}
+
+	entries := []TrashEntry{}
+	for _, yl := range lists {
+		yl := yl
+		entries = append(entries, TrashEntry{
+			TrashID:   encodeTrashID(trashRef{ListID: yl.ListID}),
+			DeletedAt: *yl.DeletedAt,
+			PurgeAt:   yl.DeletedAt.Add(retention),
+			List:      &yl,
+		})
+	}
+	for _, item := range items {
+		item := item
+		if at, ok := listDeletedAt[item.ListID]; ok && at.Equal(*item.DeletedAt) {
+			continue
+		}
+		if len(item.ParentID) != 0 {
+			if at, ok := itemDeletedAt[itemRef{UserID: item.UserID, ListID: item.ListID, ItemID: item.ParentID}]; ok && at.Equal(*item.DeletedAt) {
+				continue
+			}
+		}
+		entries = append(entries, TrashEntry{
+			TrashID:   encodeTrashID(trashRef{ListID: item.ListID, ItemID: item.ItemID}),
+			DeletedAt: *item.DeletedAt,
+			PurgeAt:   item.DeletedAt.Add(retention),
+			Item:      &item,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].TrashID < entries[j].TrashID
+	})
+	return entries
+}
+
+// trashRef identifies a trashed list or, if ItemID is set, a trashed item.
+type trashRef struct {
+	ListID model.ListID
+	ItemID model.ItemID `json:",omitempty"`
+}
+
+// encodeTrashID returns the opaque, URL safe, trash ID of a trashed list or item.
+func encodeTrashID(ref trashRef) string {
+	b, _ := json.Marshal(ref) // Marshalling a struct of strings cannot fail.
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeTrashID(id string) (trashRef, error) {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return trashRef{}, fmt.Errorf("failed to decode: %v", err)
+	}
+	var ref trashRef
+	if err := json.Unmarshal(b, &ref); err != nil {
+		return trashRef{}, fmt.Errorf("failed to unmarshal: %v", err)
+	}
+	if len(ref.ListID) == 0 {
+		return trashRef{}, fmt.Errorf("trash ID %q has no ListID", id)
+	}
+	return ref, nil
+}
+
+// reverseItems returns the items in reverse order.
+func reverseItems(items []model.YataItem) []model.YataItem {
+	reversed := make([]model.YataItem, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		reversed = append(reversed, items[i])
+	}
+	return reversed
+}
+
+// listInTrashError is the error of creating a list whose ID is taken by a list in the trash. The list in the trash has
+// to be restored, or purged, before its ID can be used again.
+var listInTrashError = responseError{Code: "ListInTrash", Message: "A list with this ID is in the trash"}
+
+func renderListInTrash(w http.ResponseWriter, r *http.Request) {
+	render(w, r, http.StatusConflict, listInTrashError)
+}
+
+func renderTrashEntryNotFound(w http.ResponseWriter, r *http.Request) {
+	render(w, r, http.StatusNotFound, responseError{Code: "TrashEntryDoesNotExist", Message: "Trash entry does not exist"})
+}
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/TheYeung1/yata-server/database"
+	"github.com/TheYeung1/yata-server/model"
+	"github.com/TheYeung1/yata-server/server/request"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashID(t *testing.T) {
+	tests := map[string]trashRef{
+		"list":         {ListID: "list"},
+		"item":         {ListID: "list", ItemID: "item"},
+		"colons-in-id": {ListID: "a:b", ItemID: "c:d"},
+	}
+
+	for name, ref := range tests {
+		name, ref := name, ref
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			decoded, err := decodeTrashID(encodeTrashID(ref))
+			assert.NoError(t, err)
+			assert.Equal(t, ref, decoded)
+		})
+	}
+}
+
+func TestDecodeTrashID_Invalid(t *testing.T) {
+	for _, id := range []string{"", "not base64!", encodeTrashID(trashRef{ItemID: "item"})} {
+		_, err := decodeTrashID(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestTrashEntries(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	retention := 24 * time.Hour
+
+	lists := []model.YataList{
+		{UserID: "me", ListID: "trashed-list", DeletedAt: &t2},
+	}
+	items := []model.YataItem{
+		// Trashed along with its list.
+		{UserID: "me", ListID: "trashed-list", ItemID: "with-list", DeletedAt: &t2},
+		// Trashed before its list was.
+		{UserID: "me", ListID: "trashed-list", ItemID: "before-list", DeletedAt: &t1},
+		// Trashed along with its parent.
+		{UserID: "me", ListID: "list", ItemID: "parent", DeletedAt: &t1},
+		{UserID: "me", ListID: "list", ItemID: "child", ParentID: "parent", DeletedAt: &t1},
+		// Trashed before its parent was.
+		{UserID: "me", ListID: "list", ItemID: "other-parent", DeletedAt: &t2},
+		{UserID: "me", ListID: "list", ItemID: "early-child", ParentID: "other-parent", DeletedAt: &t1},
+	}
+
+	entries := trashEntries(lists, items, retention)
+
+	var ids []string
+	for _, e := range entries {
+		if e.List != nil {
+			ids = append(ids, string(e.List.ListID))
+		} else {
+			ids = append(ids, string(e.Item.ListID)+"/"+string(e.Item.ItemID))
+		}
+		assert.Equal(t, e.DeletedAt.Add(retention), e.PurgeAt)
+	}
+	// Entries deleted at the same time are ordered by their opaque trash IDs.
+	assert.Len(t, ids, 5)
+	newest, oldest := ids[:2], ids[2:]
+	sort.Strings(newest)
+	sort.Strings(oldest)
+	assert.Equal(t, []string{"list/other-parent", "trashed-list"}, newest)
+	assert.Equal(t, []string{"list/early-child", "list/parent", "trashed-list/before-list"}, oldest)
+}
+
+func TestServer_RestoreTrash_Folder(t *testing.T) {
+	deletedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		folderID model.FolderID
+		set      []model.FolderID
+	}{
+		"folder-exists": {
+			folderID: "home",
+		},
+		"folder-deleted-while-in-trash": {
+			folderID: "work",
+			set:      []model.FolderID{""},
+		},
+		"no-folder": {},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var restored []model.ListID
+			var set []model.FolderID
+			ydb := mockYdb{
+				MockGetTrash: func(id model.UserID) ([]model.YataList, []model.YataItem, error) {
+					return []model.YataList{{UserID: id, ListID: "groceries", FolderID: test.folderID, DeletedAt: &deletedAt}}, nil, nil
+				},
+				MockRestoreList: func(id model.UserID, lid model.ListID) error {
+					restored = append(restored, lid)
+					return nil
+				},
+				MockGetFolder: func(id model.UserID, fid model.FolderID) (model.YataFolder, error) {
+					if fid != "home" {
+						return model.YataFolder{}, database.FolderNotFoundError{}
+					}
+					return model.YataFolder{UserID: id, FolderID: fid}, nil
+				},
+				MockSetListFolder: func(id model.UserID, lid model.ListID, fid model.FolderID) error {
+					// The list must be restored before it can be taken out of its folder.
+					assert.Equal(t, []model.ListID{"groceries"}, restored)
+					set = append(set, fid)
+					return nil
+				},
+			}
+
+			trashID := encodeTrashID(trashRef{ListID: "groceries"})
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/trash/"+trashID+"/restore", nil)
+			req = mux.SetURLVars(req, map[string]string{"trashID": trashID})
+
+			srvr := Server{Ydb: ydb}
+			srvr.RestoreTrash(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "{\"ListID\":\"groceries\",\"ItemIDs\":[]}\n", rec.Body.String())
+			assert.Equal(t, []model.ListID{"groceries"}, restored)
+			assert.Equal(t, test.set, set)
+		})
+	}
+}
+
+func TestServer_RestoreTrash(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	lists := []model.YataList{
+		{UserID: "me", ListID: "trashed-list", DeletedAt: &t2},
+	}
+	items := []model.YataItem{
+		// Trashed along with its list.
+		{UserID: "me", ListID: "trashed-list", ItemID: "with-list", DeletedAt: &t2},
+		// Trashed before its list was.
+		{UserID: "me", ListID: "trashed-list", ItemID: "before-list", DeletedAt: &t1},
+		// Trashed along with its parent.
+		{UserID: "me", ListID: "list", ItemID: "parent", DeletedAt: &t1},
+		{UserID: "me", ListID: "list", ItemID: "child", ParentID: "parent", DeletedAt: &t1},
+		{UserID: "me", ListID: "list", ItemID: "grandchild", ParentID: "child", DeletedAt: &t1},
+		// Trashed on its own, after its parent was.
+		{UserID: "me", ListID: "list", ItemID: "orphan", ParentID: "gone", DeletedAt: &t2},
+	}
+
+	tests := map[string]struct {
+		ref     trashRef
+		trashID string
+		code    int
+		outBody string
+		// restored are the restored lists and items, in the order they are restored.
+		restored []string
+	}{
+		"list-with-its-items": {
+			ref:      trashRef{ListID: "trashed-list"},
+			code:     http.StatusOK,
+			outBody:  "{\"ListID\":\"trashed-list\",\"ItemIDs\":[\"with-list\"]}\n",
+			restored: []string{"list trashed-list", "item with-list"},
+		},
+		"item-with-its-sub-tasks": {
+			ref:     trashRef{ListID: "list", ItemID: "parent"},
+			code:    http.StatusOK,
+			outBody: "{\"ListID\":\"list\",\"ItemIDs\":[\"parent\",\"child\",\"grandchild\"]}\n",
+			// Parents are restored before their sub-tasks.
+			restored: []string{"item parent", "item child", "item grandchild"},
+		},
+		"item-whose-list-is-in-the-trash": {
+			ref:     trashRef{ListID: "trashed-list", ItemID: "before-list"},
+			code:    http.StatusConflict,
+			outBody: "{\"Code\":\"ListInTrash\",\"Message\":\"The item's list must be restored first\"}\n",
+		},
+		"item-whose-parent-is-in-the-trash": {
+			ref:     trashRef{ListID: "list", ItemID: "orphan"},
+			code:    http.StatusConflict,
+			outBody: "{\"Code\":\"ParentInTrash\",\"Message\":\"The item's parent must be restored first\"}\n",
+		},
+		"sub-task-trashed-with-its-parent": {
+			ref:     trashRef{ListID: "list", ItemID: "child"},
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"TrashEntryDoesNotExist\",\"Message\":\"Trash entry does not exist\"}\n",
+		},
+		"invalid-trash-id": {
+			trashID: "not base64!",
+			code:    http.StatusNotFound,
+			outBody: "{\"Code\":\"TrashEntryDoesNotExist\",\"Message\":\"Trash entry does not exist\"}\n",
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var restored []string
+			ydb := mockYdb{
+				MockGetTrash: func(id model.UserID) ([]model.YataList, []model.YataItem, error) {
+					assert.Equal(t, model.UserID("me"), id)
+					return lists, items, nil
+				},
+				MockGetList: func(id model.UserID, lid model.ListID) (model.YataList, error) {
+					if lid == "trashed-list" {
+						return model.YataList{}, database.ListNotFoundError{}
+					}
+					return model.YataList{UserID: id, ListID: lid}, nil
+				},
+				MockGetItem: func(id model.UserID, lid model.ListID, iid model.ItemID) (model.YataItem, error) {
+					return model.YataItem{}, database.ItemNotFoundError{}
+				},
+				MockRestoreList: func(id model.UserID, lid model.ListID) error {
+					restored = append(restored, "list "+string(lid))
+					return nil
+				},
+				MockRestoreItem: func(id model.UserID, lid model.ListID, iid model.ItemID) error {
+					restored = append(restored, "item "+string(iid))
+					return nil
+				},
+			}
+
+			trashID := test.trashID
+			if len(trashID) == 0 {
+				trashID = encodeTrashID(test.ref)
+			}
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://does.not/trash/restore", nil)
+			req = mux.SetURLVars(req, map[string]string{"trashID": trashID})
+
+			srvr := Server{Ydb: ydb}
+			srvr.RestoreTrash(rec, req.WithContext(request.WithUserID(req.Context(), "me")))
+
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.outBody, rec.Body.String())
+			assert.Equal(t, test.restored, restored)
+		})
+	}
+}
+
+func TestServer_PurgeTrash(t *testing.T) {
+	now := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := now.Add(-48 * time.Hour)
+
+	tests := map[string]struct {
+		deleteItemErr error
+		err           bool
+		writes        []string
+		blobs         []string
+	}{
+		"purges-items-then-lists": {
+			// Items are purged before their lists, and a list's members, sections and share links before the list.
+			writes: []string{
+				"attachment milk/receipt.png", "item milk",
+				"member friend", "section produce", "share link token", "list groceries",
+			},
+			blobs: []string{"me/receipt"},
+		},
+		"item-cannot-be-purged": {
+			deleteItemErr: errors.New("boom"),
+			err:           true,
+			// The list is kept so that no item is left without a list.
+			writes: []string{"attachment milk/receipt.png", "item milk"},
+			blobs:  []string{"me/receipt"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+
+			var writes []string
+			blobs := &mockBlobStore{}
+			srvr := Server{
+				TrashRetention: 24 * time.Hour,
+				Blobs:          blobs,
+				Ydb: mockYdb{
+					MockGetExpiredTrash: func(before time.Time) ([]model.YataList, []model.YataItem, error) {
+						assert.Equal(t, now.Add(-24*time.Hour), before)
+						return []model.YataList{{UserID: "me", ListID: "groceries", DeletedAt: &deletedAt}},
+							[]model.YataItem{{UserID: "me", ListID: "groceries", ItemID: "milk", DeletedAt: &deletedAt}}, nil
+					},
+					MockGetItemAttachments: func(id model.UserID, lid model.ListID, iid model.ItemID) ([]model.YataAttachment, error) {
+						return []model.YataAttachment{{UserID: id, ListID: lid, ItemID: iid, AttachmentID: "receipt.png", BlobKey: "me/receipt"}}, nil
+					},
+					MockDeleteAttachment: func(id model.UserID, lid model.ListID, iid model.ItemID, aid model.AttachmentID) error {
+						writes = append(writes, "attachment "+string(iid)+"/"+string(aid))
+						return nil
+					},
+					MockDeleteItem: func(id model.UserID, lid model.ListID, iid model.ItemID) error {
+						writes = append(writes, "item "+string(iid))
+						return test.deleteItemErr
+					},
+					MockGetListMembers: func(owner model.UserID, lid model.ListID) ([]model.YataListMember, error) {
+						return []model.YataListMember{{OwnerID: owner, ListID: lid, UserID: "friend"}}, nil
+					},
+					MockDeleteListMember: func(owner model.UserID, lid model.ListID, member model.UserID) error {
+						writes = append(writes, "member "+string(member))
+						return nil
+					},
+					MockGetListSections: func(id model.UserID, lid model.ListID) ([]model.YataSection, error) {
+						return []model.YataSection{{UserID: id, ListID: lid, SectionID: "produce"}}, nil
+					},
+					MockDeleteSection: func(id model.UserID, lid model.ListID, sid model.SectionID) error {
+						writes = append(writes, "section "+string(sid))
+						return nil
+					},
+					MockGetListShareLinks: func(id model.UserID, lid model.ListID) ([]model.YataShareLink, error) {
+						return []model.YataShareLink{{Token: "token", UserID: id, ListID: lid}}, nil
+					},
+					MockDeleteShareLink: func(token string) error {
+						writes = append(writes, "share link "+token)
+						return nil
+					},
+					MockDeleteList: func(id model.UserID, lid model.ListID) error {
+						writes = append(writes, "list "+string(lid))
+						return nil
+					},
+				},
+			}
+
+			err := srvr.PurgeTrash(now)
+			assert.Equal(t, test.err, err != nil, err)
+			assert.Equal(t, test.writes, writes)
+			assert.Equal(t, test.blobs, blobs.deleted)
+		})
+	}
+}
@@ -1,10 +1,11 @@
 package server
 
 import (
-	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/TheYeung1/yata-server/model"
@@ -22,37 +23,106 @@ func getUserIDFromContext(r *http.Request) (model.UserID, error) {
 	return "", errors.New("userID context is not a string value")
 }
 
-// bindJSON decodes r into v.
-// v must be a pointer.
-// Q: Why such a small function?
-// A: We might want to put limits on how much we read in the future.
-// A: If we decide to ever support other input formats (XML?) this can become a generic "Bind" function.
-// In an ideal world we might want to look at the incoming request's "Content-Type" header.
-func bindJSON(r io.Reader, v interface{}) error {
-	return json.NewDecoder(r).Decode(v)
-}
-
 type responseError struct {
 	Code    string
 	Message string `json:",omitempty"`
 }
 
-// renderJSON writes the response code, sets the content type for JSON, and encodes v as JSON to w.
-// In an ideal world we might want to look at the incoming request's "Accept" header.
-func renderJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
-	w.WriteHeader(code)
-	w.Header().Set("Content-Type", "application/json") // FYI: https://stackoverflow.com/questions/477816/what-is-the-correct-json-content-type
-	if err := json.NewEncoder(w).Encode(v); err != nil {
-		request.Logger(r.Context()).WithError(err).Warn("failed to render json")
+// negotiate returns the media type out of offers, such as "application/json", that the request's Accept header
+// prefers. Offers earlier in the list win ties, and requests without an Accept header get the first offer.
+// It returns false if the request accepts none of the offers.
+func negotiate(r *http.Request, offers ...string) (string, bool) {
+	header := strings.Join(r.Header["Accept"], ",")
+	if len(strings.TrimSpace(header)) == 0 {
+		return offers[0], true
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// The most specific range that matches the offer decides its quality.
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			s := -1
+			switch {
+			case ar.mediaType == offer:
+				s = 2
+			case strings.HasSuffix(ar.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(ar.mediaType, "*")):
+				s = 1
+			case ar.mediaType == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// requestMediaType returns the media type of the request's body, without any parameters. It is empty if the request
+// does not have a valid Content-Type.
+func requestMediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// renderNotAcceptable tells the client that a response can only be rendered as one of offers.
+func renderNotAcceptable(w http.ResponseWriter, r *http.Request, what string, offers ...string) {
+	request.Logger(r.Context()).WithField("accept", r.Header.Get("Accept")).Info("no acceptable content type")
+	msg := fmt.Sprintf("%s can be returned as %s", what, joinOr(offers))
+	render(w, r, http.StatusNotAcceptable, responseError{Code: "NotAcceptable", Message: msg})
+}
+
+// renderUnsupportedMediaType tells the client that a request body can only be given as one of accepted.
+func renderUnsupportedMediaType(w http.ResponseWriter, r *http.Request, what string, accepted ...string) {
+	request.Logger(r.Context()).WithField("contentType", r.Header.Get("Content-Type")).Info("unsupported content type")
+	msg := fmt.Sprintf("%s can be given as %s", what, joinOr(accepted))
+	render(w, r, http.StatusUnsupportedMediaType, responseError{Code: "UnsupportedMediaType", Message: msg})
+}
+
+// joinOr joins words as in "a, b or c".
+func joinOr(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
 	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
 }
 
 func renderInternalServerError(w http.ResponseWriter, r *http.Request) {
-	renderJSON(w, r, http.StatusInternalServerError, responseError{Code: "InternalServerError"})
+	render(w, r, http.StatusInternalServerError, responseError{Code: "InternalServerError"})
 }
 
 func renderBadRequest(w http.ResponseWriter, r *http.Request, msg string) {
-	renderJSON(w, r, http.StatusBadRequest, responseError{Code: "BadRequest", Message: msg})
+	render(w, r, http.StatusBadRequest, responseError{Code: "BadRequest", Message: msg})
+}
+
+func renderForbidden(w http.ResponseWriter, r *http.Request, msg string) {
+	render(w, r, http.StatusForbidden, responseError{Code: "Forbidden", Message: msg})
 }
 
 func validateListID(id model.ListID) error {
@@ -67,3 +137,39 @@ func validateListID(id model.ListID) error {
 	}
 	return nil
 }
+
+func validateItemID(id model.ItemID) error {
+	if len(id) == 0 {
+		return errors.New("ItemID cannot be empty")
+	}
+	if len(id) > 100 {
+		return errors.New("ItemID length cannot exceed 100 characters")
+	}
+	if len(id) != len(strings.TrimSpace(string(id))) {
+		return errors.New("ItemID cannot be prefixed or suffixed with spaces")
+	}
+	return nil
+}
+
+// parseInclude parses an "include" query parameter such as "notes"; a comma separated list of optional fields to add to a response.
+// It returns an error if the parameter names a field that is not allowed.
+func parseInclude(param string, allowed ...string) (map[string]bool, error) {
+	include := map[string]bool{}
+	if len(param) == 0 {
+		return include, nil
+	}
+	for _, f := range strings.Split(param, ",") {
+		ok := false
+		for _, a := range allowed {
+			if f == a {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot include %q", f)
+		}
+		include[f] = true
+	}
+	return include, nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInclude(t *testing.T) {
+	tests := map[string]struct {
+		param   string
+		include map[string]bool
+		err     error
+	}{
+		"empty": {
+			param:   "",
+			include: map[string]bool{},
+		},
+		"allowed-field": {
+			param:   "notes",
+			include: map[string]bool{"notes": true},
+		},
+		"unknown-field": {
+			param: "notes,secrets",
+			err:   errors.New("cannot include \"secrets\""),
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			include, err := parseInclude(test.param, "notes")
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.include, include)
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]struct {
+		accept string
+		offer  string
+		ok     bool
+	}{
+		"no-accept":             {accept: "", offer: "application/json", ok: true},
+		"anything":              {accept: "*/*", offer: "application/json", ok: true},
+		"exact":                 {accept: "text/plain", offer: "text/plain", ok: true},
+		"wildcard-subtype":      {accept: "text/*", offer: "text/plain", ok: true},
+		"quality":               {accept: "application/json;q=0.2, text/plain;q=0.9", offer: "text/plain", ok: true},
+		"tie-goes-to-first":     {accept: "application/json, text/plain", offer: "application/json", ok: true},
+		"specific-beats-range":  {accept: "*/*, application/json;q=0", offer: "text/plain", ok: true},
+		"refused":               {accept: "application/json;q=0", offer: "", ok: false},
+		"unknown":               {accept: "application/xml", offer: "", ok: false},
+		"malformed-range-skips": {accept: "text/plain;q=high, application/json", offer: "application/json", ok: true},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(fmt.Sprintf(name), func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "https://does.not/matter", nil)
+			if len(test.accept) != 0 {
+				req.Header.Set("Accept", test.accept)
+			}
+			offer, ok := negotiate(req, "application/json", "text/plain")
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.offer, offer)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package todotxt reads and writes tasks in the todo.txt format described at https://github.com/todotxt/todo.txt.
+package todotxt
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of the dates in a task.
+const DateLayout = "2006-01-02"
+
+// Task is a single line of a todo.txt file.
+type Task struct {
+	Completed bool
+	// Priority is from 'A', the highest, to 'Z'. It is zero for tasks without a priority. Only tasks that are not
+	// completed are written with their priority.
+	Priority byte
+	// CompletedOn and CreatedOn are zero when they are not known. A completed task is only written with its creation
+	// date if it also has a completion date.
+	CompletedOn time.Time
+	CreatedOn   time.Time
+	// Text is the task's description without its projects, contexts and tags.
+	Text string
+	// Projects and Contexts are written as "+project" and "@context".
+	Projects []string
+	Contexts []string
+	// Tags are the task's "key:value" pairs. They are written in the order of their keys.
+	Tags map[string]string
+}
+
+// Parse reads a task from a line. It returns false if the line is blank.
+func Parse(line string) (Task, bool) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return Task{}, false
+	}
+
+	var t Task
+	if words[0] == "x" {
+		t.Completed = true
+		words = words[1:]
+		if len(words) != 0 {
+			if d, ok := parseDate(words[0]); ok {
+				t.CompletedOn = d
+				words = words[1:]
+			}
+		}
+		if !t.CompletedOn.IsZero() && len(words) != 0 {
+			if d, ok := parseDate(words[0]); ok {
+				t.CreatedOn = d
+				words = words[1:]
+			}
+		}
+	} else {
+		if len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' && words[0][1] >= 'A' && words[0][1] <= 'Z' {
+			t.Priority = words[0][1]
+			words = words[1:]
+		}
+		if len(words) != 0 {
+			if d, ok := parseDate(words[0]); ok {
+				t.CreatedOn = d
+				words = words[1:]
+			}
+		}
+	}
+
+	var text []string
+	for _, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			t.Projects = append(t.Projects, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			t.Contexts = append(t.Contexts, word[1:])
+		case isTag(word):
+			if t.Tags == nil {
+				t.Tags = map[string]string{}
+			}
+			i := strings.IndexByte(word, ':')
+			t.Tags[word[:i]] = word[i+1:]
+		default:
+			text = append(text, word)
+		}
+	}
+	t.Text = strings.Join(text, " ")
+	return t, true
+}
+
+// isTag returns true if word is a "key:value" pair. URLs such as "https://example.com" are not.
+func isTag(word string) bool {
+	i := strings.IndexByte(word, ':')
+	if i <= 0 || i == len(word)-1 {
+		return false
+	}
+	return !strings.ContainsAny(word[i+1:], ":/")
+}
+
+func parseDate(word string) (time.Time, bool) {
+	if len(word) != len(DateLayout) {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(DateLayout, word)
+	return d, err == nil
+}
+
+// String returns the task as a line, without a line break.
+func (t Task) String() string {
+	var words []string
+	if t.Completed {
+		words = append(words, "x")
+		if !t.CompletedOn.IsZero() {
+			words = append(words, t.CompletedOn.Format(DateLayout))
+			if !t.CreatedOn.IsZero() {
+				words = append(words, t.CreatedOn.Format(DateLayout))
+			}
+		}
+	} else {
+		if t.Priority != 0 {
+			words = append(words, "("+string(t.Priority)+")")
+		}
+		if !t.CreatedOn.IsZero() {
+			words = append(words, t.CreatedOn.Format(DateLayout))
+		}
+	}
+	if len(t.Text) != 0 {
+		words = append(words, strings.Fields(t.Text)...)
+	}
+	for _, p := range t.Projects {
+		words = append(words, "+"+word(p))
+	}
+	for _, c := range t.Contexts {
+		words = append(words, "@"+word(c))
+	}
+	keys := make([]string, 0, len(t.Tags))
+	for k := range t.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		words = append(words, word(k)+":"+word(t.Tags[k]))
+	}
+	return strings.Join(words, " ")
+}
+
+// word returns s with its white space replaced by underscores, so that it is written as a single word.
+func word(s string) string {
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// ParseAll reads every task in r, skipping blank lines.
+func ParseAll(r io.Reader) ([]Task, error) {
+	var tasks []Task
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if t, ok := Parse(scanner.Text()); ok {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, scanner.Err()
+}
+
+// WriteAll writes tasks to w, one per line.
+func WriteAll(w io.Writer, tasks []Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range tasks {
+		if _, err := bw.WriteString(t.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,119 @@
+package todotxt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		line string
+		task Task
+		ok   bool
+	}{
+		"blank": {
+			line: "  \t",
+		},
+		"text": {
+			line: "Call Mom",
+			task: Task{Text: "Call Mom"},
+			ok:   true,
+		},
+		"everything": {
+			line: "(A) 2021-01-01 Call  Mom +Family @phone due:2021-01-05 see https://example.com",
+			task: Task{
+				Priority:  'A',
+				CreatedOn: date("2021-01-01"),
+				Text:      "Call Mom see https://example.com",
+				Projects:  []string{"Family"},
+				Contexts:  []string{"phone"},
+				Tags:      map[string]string{"due": "2021-01-05"},
+			},
+			ok: true,
+		},
+		"completed-with-dates": {
+			line: "x 2021-01-03 2021-01-01 Call Mom pri:A",
+			task: Task{Completed: true, CompletedOn: date("2021-01-03"), CreatedOn: date("2021-01-01"), Text: "Call Mom", Tags: map[string]string{"pri": "A"}},
+			ok:   true,
+		},
+		"completed-with-one-date": {
+			line: "x 2021-01-03 Call Mom",
+			task: Task{Completed: true, CompletedOn: date("2021-01-03"), Text: "Call Mom"},
+			ok:   true,
+		},
+		"priority-not-first": {
+			line: "Really (A) Call Mom",
+			task: Task{Text: "Really (A) Call Mom"},
+			ok:   true,
+		},
+		"lone-markers": {
+			line: "Meet @ 5 + snacks x",
+			task: Task{Text: "Meet @ 5 + snacks x"},
+			ok:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			task, ok := Parse(test.line)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.task, task)
+		})
+	}
+}
+
+func TestTask_String(t *testing.T) {
+	tests := map[string]struct {
+		task Task
+		line string
+	}{
+		"everything": {
+			task: Task{
+				Priority:  'B',
+				CreatedOn: date("2021-01-01"),
+				Text:      "Call Mom",
+				Projects:  []string{"Family stuff"},
+				Contexts:  []string{"phone"},
+				Tags:      map[string]string{"id": "1", "due": "2021-01-05"},
+			},
+			line: "(B) 2021-01-01 Call Mom +Family_stuff @phone due:2021-01-05 id:1",
+		},
+		"completed-without-completion-date": {
+			task: Task{Completed: true, Priority: 'A', CreatedOn: date("2021-01-01"), Text: "Call Mom"},
+			line: "x Call Mom",
+		},
+		"completed": {
+			task: Task{Completed: true, CompletedOn: date("2021-01-03"), CreatedOn: date("2021-01-01"), Text: "Call Mom"},
+			line: "x 2021-01-03 2021-01-01 Call Mom",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.line, test.task.String())
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := "(A) 2021-01-01 Call Mom +Family @phone due:2021-01-05\n\nx 2021-01-03 2021-01-02 Pay rent\n"
+	tasks, err := ParseAll(bytes.NewBufferString(in))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteAll(&out, tasks))
+	assert.Equal(t, "(A) 2021-01-01 Call Mom +Family @phone due:2021-01-05\nx 2021-01-03 2021-01-02 Pay rent\n", out.String())
+}